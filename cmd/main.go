@@ -1,11 +1,16 @@
 package main
 
 import (
+	"context"
+	"fmt"
 	"log"
+	"log/slog"
 	"os"
+	"path/filepath"
 	"time"
 
 	"github.com/go-playground/validator/v10"
+	localstorage "github.com/lafetz/weavo/internal/adapters/local_storage"
 	mockcache "github.com/lafetz/weavo/internal/adapters/mock_cache"
 	openweather "github.com/lafetz/weavo/internal/adapters/open_weather"
 	"github.com/lafetz/weavo/internal/adapters/repository"
@@ -13,6 +18,7 @@ import (
 	"github.com/lafetz/weavo/internal/adapters/web/webutils"
 	"github.com/lafetz/weavo/internal/config"
 	"github.com/lafetz/weavo/internal/core/service/location"
+	"github.com/lafetz/weavo/internal/core/service/user"
 	"github.com/lafetz/weavo/internal/core/service/weather"
 	customlogger "github.com/lafetz/weavo/internal/logger"
 )
@@ -27,9 +33,24 @@ import (
 
 // @externalDocs.description  OpenAPI
 // @externalDocs.url          https://swagger.io/resources/open-api/
-const dataRetention = 24 * time.Hour
+
+// sessionMaxAge is how long the session cookie lasts when locations are kept
+// forever.
+const sessionMaxAge = 365 * 24 * time.Hour
 
 func main() {
+	// gen-session-key prints a new key pair for SESSION_KEYS. To rotate,
+	// put it first and keep the old keys until their sessions expire.
+	if len(os.Args) > 1 && os.Args[1] == "gen-session-key" {
+		fmt.Println(webutils.NewSessionKey())
+		return
+	}
+	// gen-encryption-key prints a new key for ENCRYPTION_KEYS. To rotate,
+	// put it first and drop the old key once a snapshot has been saved.
+	if len(os.Args) > 1 && os.Args[1] == "gen-encryption-key" {
+		fmt.Println(repository.NewMasterKey())
+		return
+	}
 	config, err := config.NewConfig()
 	if err != nil {
 		log.Printf("error creating config: %v", err)
@@ -37,17 +58,120 @@ func main() {
 	}
 	ow := openweather.NewOpenWeather(config.Open_URL, config.Open_Key, 2)
 	logger := customlogger.NewLogger(config.LogLevel, config.Env)
-	store := repository.NewInMemoryLocationRepo(dataRetention)
-	locationSvc := location.NewService(store)
+	var users user.UserRepo = repository.NewInMemoryUserRepo()
+	if config.UserStoreFile != "" {
+		users, err = repository.NewFileUserRepo(config.UserStoreFile)
+		if err != nil {
+			log.Printf("error loading users: %v", err)
+			os.Exit(1)
+		}
+	}
+	userSvc := user.NewService(users)
+	store := repository.NewInMemoryLocationRepo(config.Retention,
+		repository.WithPurgeWindow(config.PurgeWindow),
+		repository.WithRegisteredUsers(userSvc.IsRegistered),
+	)
+	history := repository.NewInMemoryHistoryRepo()
+	attachments := repository.NewInMemoryAttachmentRepo()
+	checkIns := repository.NewInMemoryCheckInRepo()
+	workers := []func(ctx context.Context){store.RunCleanup}
+	if config.SnapshotDir != "" {
+		worker, err := loadSnapshots(config, store, history, attachments, checkIns, logger)
+		if err != nil {
+			log.Printf("error loading snapshots: %v", err)
+			os.Exit(1)
+		}
+		workers = append(workers, worker)
+	}
+	blobs, err := localstorage.NewLocalStore(config.AttachmentDir)
+	if err != nil {
+		log.Printf("error creating attachment storage: %v", err)
+		os.Exit(1)
+	}
 	mc := mockcache.NewMockCache()
 	weatherSvc := weather.NewService(ow, mc)
+	locationSvc := location.NewService(store,
+		location.WithCursorKey([]byte(config.CursorKey)),
+		location.WithLogger(logger),
+		location.WithHistory(history),
+		location.WithAdmins(config.AdminUserIDs...),
+		location.WithDuplicateRadius(config.DuplicateRadiusKm),
+		location.WithQuota(location.Quota{
+			MaxLocations:       config.MaxLocations,
+			MaxNotesLength:     config.MaxNotesLength,
+			MaxAttachmentBytes: config.MaxAttachmentBytes,
+		}),
+		location.WithAttachments(attachments, blobs),
+		location.WithCheckIns(checkIns, weatherSvc),
+	)
 	val := validator.New()
 	custonmVal := webutils.NewCustomValidator(val)
-	cookieStore := webutils.CookieStore(dataRetention)
-	web := web.NewApp(config.Port, logger, cookieStore, custonmVal, locationSvc, weatherSvc)
+	// Anonymous users lose their locations with their session, so the
+	// session lasts as long as the retention period.
+	cookieMaxAge := config.Retention.Period
+	if cookieMaxAge == 0 {
+		cookieMaxAge = sessionMaxAge
+	}
+	// Random keys would end every session on restart and differ between
+	// replicas, so production refuses to start without real ones.
+	sessionKeys, err := webutils.LoadSessionKeys(config.SessionKeys, config.SessionKeysFile, config.Env == "production")
+	if err != nil {
+		log.Printf("error loading session keys: %v", err)
+		os.Exit(1)
+	}
+	cookieStore := webutils.CookieStore(cookieMaxAge, config.Env == "production", sessionKeys...)
+	idempotency := webutils.NewIdempotencyStore(config.IdempotencyWindow)
+	web := web.NewApp(config.Port, logger, cookieStore, idempotency, custonmVal, locationSvc, weatherSvc, userSvc, config.MaxAttachmentBytes)
 	logger.Info("running web server")
-	err = web.Run()
+	err = web.Run(workers...)
 	if err != nil {
 		logger.Error("web server error", "error", err)
 	}
 }
+
+// loadSnapshots restores the locations, history, attachment metadata and
+// check-ins saved in config.SnapshotDir and returns the worker that keeps
+// saving them, with notes and comments encrypted.
+func loadSnapshots(config config.Config, store *repository.InMemoryLocationRepo, history *repository.InMemoryHistoryRepo, attachments *repository.InMemoryAttachmentRepo, checkIns *repository.InMemoryCheckInRepo, logger *slog.Logger) (func(ctx context.Context), error) {
+	keys, err := repository.LoadMasterKeys(config.EncryptionKeys, config.EncryptionKeysFile)
+	if err != nil {
+		return nil, err
+	}
+	env, err := repository.NewEnvelope(keys...)
+	if err != nil {
+		return nil, err
+	}
+	locationsFile := filepath.Join(config.SnapshotDir, "locations.json")
+	historyFile := filepath.Join(config.SnapshotDir, "history.json")
+	attachmentsFile := filepath.Join(config.SnapshotDir, "attachments.json")
+	checkInsFile := filepath.Join(config.SnapshotDir, "checkins.json")
+	if err := store.LoadSnapshot(locationsFile, env); err != nil {
+		return nil, err
+	}
+	if err := history.LoadSnapshot(historyFile, env); err != nil {
+		return nil, err
+	}
+	if err := attachments.LoadSnapshot(attachmentsFile); err != nil {
+		return nil, err
+	}
+	if err := checkIns.LoadSnapshot(checkInsFile, env); err != nil {
+		return nil, err
+	}
+	save := func() error {
+		if err := store.SaveSnapshot(locationsFile, env); err != nil {
+			return err
+		}
+		if err := history.SaveSnapshot(historyFile, env); err != nil {
+			return err
+		}
+		if err := attachments.SaveSnapshot(attachmentsFile); err != nil {
+			return err
+		}
+		return checkIns.SaveSnapshot(checkInsFile, env)
+	}
+	return func(ctx context.Context) {
+		repository.RunSnapshots(ctx, config.SnapshotInterval, save, func(err error) {
+			logger.Error("snapshot error", "error", err)
+		})
+	}, nil
+}
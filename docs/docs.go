@@ -19,9 +19,9 @@ const docTemplate = `{
     "host": "{{.Host}}",
     "basePath": "{{.BasePath}}",
     "paths": {
-        "/api/v1/locations": {
+        "/api/v1/auth/login": {
             "post": {
-                "description": "Create a new location with the provided details",
+                "description": "Logs the session in to an account. Locations saved in the session while it was anonymous move to the account; likely duplicates of the account's own locations, and those over its location limit, go to its trash. After 5 wrong passwords in a row the email is locked for 15 minutes, and the response says when to retry.",
                 "consumes": [
                     "application/json"
                 ],
@@ -29,25 +29,115 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "locations"
+                    "users"
                 ],
-                "summary": "Create a new location",
+                "summary": "Log in",
                 "parameters": [
                     {
-                        "description": "Location request body",
-                        "name": "location",
+                        "description": "Email and password",
+                        "name": "credentials",
                         "in": "body",
                         "required": true,
                         "schema": {
-                            "$ref": "#/definitions/dto.LocationReq"
+                            "$ref": "#/definitions/dto.LoginReq"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "logged in successfully",
+                        "schema": {
+                            "$ref": "#/definitions/dto.UserRes"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid input format",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "401": {
+                        "description": "invalid email or password",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "422": {
+                        "description": "validation error",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "429": {
+                        "description": "too many failed login attempts",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "500": {
+                        "description": "internal server error",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/auth/logout": {
+            "post": {
+                "description": "Ends the session. The next request starts a new anonymous one.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "users"
+                ],
+                "summary": "Log out",
+                "responses": {
+                    "200": {
+                        "description": "logged out successfully",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "500": {
+                        "description": "internal server error",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/auth/register": {
+            "post": {
+                "description": "Creates an account and logs the session in to it. Locations saved in the session while it was anonymous move to the account.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "users"
+                ],
+                "summary": "Register",
+                "parameters": [
+                    {
+                        "description": "Email and password",
+                        "name": "user",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/dto.RegisterReq"
                         }
                     }
                 ],
                 "responses": {
                     "201": {
-                        "description": "Location created successfully",
+                        "description": "registered successfully",
                         "schema": {
-                            "$ref": "#/definitions/dto.LocationRes"
+                            "$ref": "#/definitions/dto.UserRes"
                         }
                     },
                     "400": {
@@ -56,8 +146,20 @@ const docTemplate = `{
                             "type": "string"
                         }
                     },
+                    "409": {
+                        "description": "email is already registered",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "422": {
+                        "description": "validation error",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
                     "500": {
-                        "description": "Internal server error",
+                        "description": "internal server error",
                         "schema": {
                             "type": "string"
                         }
@@ -65,9 +167,105 @@ const docTemplate = `{
                 }
             }
         },
-        "/api/v1/locations/{id}": {
+        "/api/v1/checkins": {
+            "get": {
+                "description": "Lists the user's check-ins at all of their locations, newest first, optionally only those between after and before.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "checkins"
+                ],
+                "summary": "Get the check-in timeline",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "default": 1,
+                        "description": "Page number",
+                        "name": "page",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "default": 5,
+                        "description": "Number of items per page",
+                        "name": "pageSize",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Only check-ins after this RFC 3339 time",
+                        "name": "after",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Only check-ins before this RFC 3339 time",
+                        "name": "before",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "timeline retrieved successfully",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/dto.CheckInRes"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "invalid page or date span",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "422": {
+                        "description": "validation error",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "500": {
+                        "description": "internal server error",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/collections": {
             "get": {
-                "description": "Retrieves a location from the service using the provided ID.",
+                "description": "Retrieves the user's collections ordered by name",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "collections"
+                ],
+                "summary": "List collections",
+                "responses": {
+                    "200": {
+                        "description": "collections retrieved successfully",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/dto.CollectionRes"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "internal server error",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            },
+            "post": {
+                "description": "Create a named collection for grouping locations",
                 "consumes": [
                     "application/json"
                 ],
@@ -75,13 +273,73 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "locations"
+                    "collections"
                 ],
-                "summary": "Retrieve a location by ID",
+                "summary": "Create a collection",
                 "parameters": [
+                    {
+                        "description": "Collection request body",
+                        "name": "collection",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/dto.CollectionReq"
+                        }
+                    },
                     {
                         "type": "string",
-                        "description": "Location ID",
+                        "description": "Retries with the same key replay the first response instead of running again",
+                        "name": "Idempotency-Key",
+                        "in": "header"
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "collection created successfully",
+                        "schema": {
+                            "$ref": "#/definitions/dto.CollectionRes"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid input format",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "409": {
+                        "description": "a request with this Idempotency-Key is still in progress",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "422": {
+                        "description": "validation error",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "500": {
+                        "description": "internal server error",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/collections/{id}": {
+            "get": {
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "collections"
+                ],
+                "summary": "Retrieve a collection by ID",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Collection ID",
                         "name": "id",
                         "in": "path",
                         "required": true
@@ -89,19 +347,19 @@ const docTemplate = `{
                 ],
                 "responses": {
                     "200": {
-                        "description": "location retrieved successfully",
+                        "description": "collection retrieved successfully",
                         "schema": {
-                            "$ref": "#/definitions/dto.LocationRes"
+                            "$ref": "#/definitions/dto.CollectionRes"
                         }
                     },
-                    "400": {
-                        "description": "invalid id",
+                    "403": {
+                        "description": "forbidden",
                         "schema": {
                             "type": "string"
                         }
                     },
                     "404": {
-                        "description": "location not found",
+                        "description": "collection not found",
                         "schema": {
                             "type": "string"
                         }
@@ -115,7 +373,6 @@ const docTemplate = `{
                 }
             },
             "put": {
-                "description": "Update an existing location with the provided details",
                 "consumes": [
                     "application/json"
                 ],
@@ -123,42 +380,54 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "locations"
+                    "collections"
                 ],
-                "summary": "Update a location",
+                "summary": "Rename a collection",
                 "parameters": [
                     {
                         "type": "string",
-                        "description": "Location ID",
+                        "description": "Collection ID",
                         "name": "id",
                         "in": "path",
                         "required": true
                     },
                     {
-                        "description": "Location request body",
-                        "name": "LocationReq",
+                        "description": "Collection request body",
+                        "name": "collection",
                         "in": "body",
                         "required": true,
                         "schema": {
-                            "$ref": "#/definitions/dto.LocationReq"
+                            "$ref": "#/definitions/dto.CollectionReq"
                         }
                     }
                 ],
                 "responses": {
                     "200": {
-                        "description": "location updated successfully",
+                        "description": "collection updated successfully",
                         "schema": {
-                            "$ref": "#/definitions/dto.LocationRes"
+                            "$ref": "#/definitions/dto.CollectionRes"
                         }
                     },
                     "400": {
-                        "description": "Invalid input format or invalid id",
+                        "description": "Invalid input format",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "403": {
+                        "description": "forbidden",
                         "schema": {
                             "type": "string"
                         }
                     },
                     "404": {
-                        "description": "location not found",
+                        "description": "collection not found",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "422": {
+                        "description": "validation error",
                         "schema": {
                             "type": "string"
                         }
@@ -172,21 +441,18 @@ const docTemplate = `{
                 }
             },
             "delete": {
-                "description": "Deletes a location by its ID",
-                "consumes": [
-                    "application/json"
-                ],
+                "description": "Deletes a collection; the locations in it are not deleted",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "locations"
+                    "collections"
                 ],
-                "summary": "Delete a location",
+                "summary": "Delete a collection",
                 "parameters": [
                     {
                         "type": "string",
-                        "description": "Location ID",
+                        "description": "Collection ID",
                         "name": "id",
                         "in": "path",
                         "required": true
@@ -194,19 +460,19 @@ const docTemplate = `{
                 ],
                 "responses": {
                     "200": {
-                        "description": "location deleted successfully",
+                        "description": "collection deleted successfully",
                         "schema": {
                             "type": "string"
                         }
                     },
-                    "400": {
-                        "description": "invalid id",
+                    "403": {
+                        "description": "forbidden",
                         "schema": {
                             "type": "string"
                         }
                     },
                     "404": {
-                        "description": "location not found",
+                        "description": "collection not found",
                         "schema": {
                             "type": "string"
                         }
@@ -220,43 +486,46 @@ const docTemplate = `{
                 }
             }
         },
-        "/api/v1/weather": {
-            "get": {
-                "description": "Retrieves weather information for a specified city.",
-                "consumes": [
-                    "application/json"
-                ],
+        "/api/v1/collections/{id}/locations/{locationId}": {
+            "put": {
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "weather"
+                    "collections"
                 ],
-                "summary": "Get weather information",
+                "summary": "Add a location to a collection",
                 "parameters": [
                     {
                         "type": "string",
-                        "description": "City name",
-                        "name": "city",
-                        "in": "query",
+                        "description": "Collection ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Location ID",
+                        "name": "locationId",
+                        "in": "path",
                         "required": true
                     }
                 ],
                 "responses": {
                     "200": {
-                        "description": "weather retrieved successfully",
+                        "description": "location added to collection",
                         "schema": {
-                            "$ref": "#/definitions/dto.WeatherRes"
+                            "type": "string"
                         }
                     },
-                    "400": {
-                        "description": "invalid city",
+                    "403": {
+                        "description": "forbidden",
                         "schema": {
                             "type": "string"
                         }
                     },
                     "404": {
-                        "description": "city not found",
+                        "description": "collection or location not found",
                         "schema": {
                             "type": "string"
                         }
@@ -268,22 +537,2146 @@ const docTemplate = `{
                         }
                     }
                 }
+            },
+            "delete": {
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "collections"
+                ],
+                "summary": "Remove a location from a collection",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Collection ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Location ID",
+                        "name": "locationId",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "location removed from collection",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "403": {
+                        "description": "forbidden",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "404": {
+                        "description": "collection or location not found",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "500": {
+                        "description": "internal server error",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/locations": {
+            "get": {
+                "description": "Retrieves a list of locations for the authenticated user with pagination support.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "locations"
+                ],
+                "summary": "Retrieve all locations",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "default": 1,
+                        "description": "Page number",
+                        "name": "page",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "default": 5,
+                        "description": "Number of items per page",
+                        "name": "pageSize",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Opaque cursor from a previous response's nextCursor or prevCursor",
+                        "name": "cursor",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "default": 5,
+                        "description": "Number of items per page when paginating by cursor",
+                        "name": "limit",
+                        "in": "query"
+                    },
+                    {
+                        "enum": [
+                            "position",
+                            "created_at",
+                            "nickname",
+                            "city"
+                        ],
+                        "type": "string",
+                        "default": "position",
+                        "description": "Sort field; position lists pinned locations first",
+                        "name": "sort",
+                        "in": "query"
+                    },
+                    {
+                        "enum": [
+                            "asc",
+                            "desc"
+                        ],
+                        "type": "string",
+                        "default": "asc",
+                        "description": "Sort direction",
+                        "name": "order",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Only locations in this city (case-insensitive)",
+                        "name": "city",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Substring to search for in nickname or notes",
+                        "name": "q",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Only locations created after this RFC 3339 time",
+                        "name": "created_after",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Only locations created before this RFC 3339 time",
+                        "name": "created_before",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Only locations inside minLon,minLat,maxLon,maxLat",
+                        "name": "bbox",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Only locations with this tag",
+                        "name": "tag",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Only locations in this collection",
+                        "name": "collection",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "locations retrieved successfully",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/dto.LocationRes"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "invalid cursor or bbox",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "422": {
+                        "description": "validation error",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "500": {
+                        "description": "internal server error",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            },
+            "post": {
+                "description": "Create a new location with the provided details. A location in the same city and close to one the user already has is rejected as a likely duplicate unless force is true; the response then lists the existing matches.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "locations"
+                ],
+                "summary": "Create a new location",
+                "parameters": [
+                    {
+                        "description": "Location request body",
+                        "name": "location",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/dto.LocationReq"
+                        }
+                    },
+                    {
+                        "type": "boolean",
+                        "default": false,
+                        "description": "Create the location even if it looks like a duplicate",
+                        "name": "force",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Retries with the same key replay the first response instead of running again",
+                        "name": "Idempotency-Key",
+                        "in": "header"
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Location created successfully",
+                        "schema": {
+                            "$ref": "#/definitions/dto.LocationRes"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid input format",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "403": {
+                        "description": "location limit reached",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "409": {
+                        "description": "location looks like a duplicate of an existing location, or a request with this Idempotency-Key is still in progress",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/dto.LocationRes"
+                            }
+                        }
+                    },
+                    "422": {
+                        "description": "validation error, notes longer than the user's limit, or Idempotency-Key reused for a different request",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/locations/duplicates": {
+            "get": {
+                "description": "Groups the user's locations that share a city and lie close to each other, in list order, so that all but one of each group can be removed.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "locations"
+                ],
+                "summary": "Find duplicate locations",
+                "responses": {
+                    "200": {
+                        "description": "duplicate locations retrieved successfully",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "type": "array",
+                                "items": {
+                                    "$ref": "#/definitions/dto.LocationRes"
+                                }
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "internal server error",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/locations/export": {
+            "get": {
+                "description": "Streams the user's locations as a JSON array, a CSV file or a GeoJSON FeatureCollection, which can all be imported again, or as GPX waypoints or KML placemarks for GPS units and Google Earth.\nIn GPX and KML the nickname becomes the name and the notes the description; with weather=true the current weather is appended to the description.",
+                "produces": [
+                    "application/json",
+                    "text/csv",
+                    "application/geo+json",
+                    "application/gpx+xml",
+                    "application/vnd.google-earth.kml+xml"
+                ],
+                "tags": [
+                    "locations"
+                ],
+                "summary": "Export locations",
+                "parameters": [
+                    {
+                        "enum": [
+                            "json",
+                            "csv",
+                            "geojson",
+                            "gpx",
+                            "kml"
+                        ],
+                        "type": "string",
+                        "default": "json",
+                        "description": "Export format",
+                        "name": "format",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Only locations with this tag",
+                        "name": "tag",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Only locations in this collection",
+                        "name": "collection",
+                        "in": "query"
+                    },
+                    {
+                        "type": "boolean",
+                        "default": false,
+                        "description": "Add the current weather to GPX and KML descriptions",
+                        "name": "weather",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "the exported locations",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/dto.LocationRes"
+                            }
+                        }
+                    },
+                    "422": {
+                        "description": "validation error",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "500": {
+                        "description": "internal server error",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/locations/import": {
+            "post": {
+                "description": "Imports locations from a JSON array, a CSV file or a GeoJSON FeatureCollection, sent either as the request body or as the \"file\" field of a multipart form.\nWithout format it is taken from the uploaded file's extension or the Content-Type. Every row is validated on its own and the report lists which rows were accepted, rejected or skipped as duplicates.\nA location is a duplicate when its city and coordinates match one the user already has or an earlier row.\nRows with notes longer than the user's limit are rejected. An import that would take the user over their location limit is refused as a whole, and if any accepted row fails to save none of them are kept.",
+                "consumes": [
+                    "application/json",
+                    "text/csv",
+                    "application/geo+json",
+                    "multipart/form-data"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "locations"
+                ],
+                "summary": "Import locations",
+                "parameters": [
+                    {
+                        "enum": [
+                            "json",
+                            "csv",
+                            "geojson"
+                        ],
+                        "type": "string",
+                        "description": "Import format",
+                        "name": "format",
+                        "in": "query"
+                    },
+                    {
+                        "type": "boolean",
+                        "default": false,
+                        "description": "Validate and report without saving anything",
+                        "name": "dry_run",
+                        "in": "query"
+                    },
+                    {
+                        "enum": [
+                            "skip",
+                            "create"
+                        ],
+                        "type": "string",
+                        "default": "skip",
+                        "description": "What to do with duplicates",
+                        "name": "on_duplicate",
+                        "in": "query"
+                    },
+                    {
+                        "type": "file",
+                        "description": "File to import",
+                        "name": "file",
+                        "in": "formData"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Retries with the same key replay the first response instead of running again",
+                        "name": "Idempotency-Key",
+                        "in": "header"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "import completed",
+                        "schema": {
+                            "$ref": "#/definitions/dto.ImportReportRes"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid input format",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "403": {
+                        "description": "location limit reached",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "409": {
+                        "description": "a request with this Idempotency-Key is still in progress",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "413": {
+                        "description": "import too large",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "422": {
+                        "description": "validation error",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "500": {
+                        "description": "internal server error",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/locations/nearby": {
+            "get": {
+                "description": "Retrieves the user's locations within radius_km of lat/lon, closest first, with their distance.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "locations"
+                ],
+                "summary": "Find nearby locations",
+                "parameters": [
+                    {
+                        "type": "number",
+                        "description": "Latitude of the search center",
+                        "name": "lat",
+                        "in": "query",
+                        "required": true
+                    },
+                    {
+                        "type": "number",
+                        "description": "Longitude of the search center",
+                        "name": "lon",
+                        "in": "query",
+                        "required": true
+                    },
+                    {
+                        "type": "number",
+                        "description": "Search radius in kilometres",
+                        "name": "radius_km",
+                        "in": "query",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Only locations inside minLon,minLat,maxLon,maxLat",
+                        "name": "bbox",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "default": 50,
+                        "description": "Maximum number of results",
+                        "name": "limit",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "nearby locations retrieved successfully",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/dto.NearbyLocationRes"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "invalid bbox",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "422": {
+                        "description": "validation error",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "500": {
+                        "description": "internal server error",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/locations/reorder": {
+            "post": {
+                "description": "Moves the listed locations, in order, to the front of the user's list. The change is applied atomically.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "locations"
+                ],
+                "summary": "Reorder locations",
+                "parameters": [
+                    {
+                        "description": "Ordered location IDs",
+                        "name": "order",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/dto.ReorderReq"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "locations reordered successfully",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid input format or duplicate ids",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "404": {
+                        "description": "location not found",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "422": {
+                        "description": "validation error",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "500": {
+                        "description": "internal server error",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/locations/trash": {
+            "get": {
+                "description": "Retrieves the user's deleted locations, most recently deleted first. They can be restored until the purge window passes.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "locations"
+                ],
+                "summary": "List deleted locations",
+                "responses": {
+                    "200": {
+                        "description": "trash retrieved successfully",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/dto.LocationRes"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "internal server error",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/locations/{id}": {
+            "get": {
+                "description": "Retrieves one of the user's locations. Admins can retrieve any location; anyone else needs the token of a share link covering it, passed as share.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "locations"
+                ],
+                "summary": "Retrieve a location by ID",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Location ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Token of a share link covering the location",
+                        "name": "share",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "location retrieved successfully",
+                        "schema": {
+                            "$ref": "#/definitions/dto.LocationRes"
+                        }
+                    },
+                    "400": {
+                        "description": "invalid id",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "403": {
+                        "description": "forbidden",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "404": {
+                        "description": "location not found",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "500": {
+                        "description": "internal server error",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            },
+            "put": {
+                "description": "Replace every editable field of an existing location and return the stored result. Send the ETag from a previous response as If-Match to reject the update when the location changed in the meantime.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "locations"
+                ],
+                "summary": "Replace a location",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Location ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "ETag of the version being replaced",
+                        "name": "If-Match",
+                        "in": "header"
+                    },
+                    {
+                        "description": "Location request body",
+                        "name": "LocationReq",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/dto.LocationReq"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "location updated successfully",
+                        "schema": {
+                            "$ref": "#/definitions/dto.LocationRes"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid input format or invalid id",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "403": {
+                        "description": "forbidden",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "404": {
+                        "description": "location not found",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "412": {
+                        "description": "location has been modified",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "422": {
+                        "description": "validation error",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "500": {
+                        "description": "internal server error",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            },
+            "delete": {
+                "description": "Moves a location to the trash, from where it can be restored until the purge window passes. With If-Match the delete only succeeds if the location is still at that version.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "locations"
+                ],
+                "summary": "Delete a location",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Location ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "ETag of the version being deleted",
+                        "name": "If-Match",
+                        "in": "header"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "location deleted successfully",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "400": {
+                        "description": "invalid id",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "403": {
+                        "description": "forbidden",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "404": {
+                        "description": "location not found",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "412": {
+                        "description": "location has been modified",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "500": {
+                        "description": "internal server error",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            },
+            "patch": {
+                "description": "Apply an RFC 7396 JSON merge patch to a location. Members set to null are cleared; the merged result is validated like a full update.\nWithout If-Match the patch applies to the version it was merged against and fails with 409 if the location changes concurrently.",
+                "consumes": [
+                    "application/merge-patch+json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "locations"
+                ],
+                "summary": "Patch a location",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Location ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "ETag of the version being patched",
+                        "name": "If-Match",
+                        "in": "header"
+                    },
+                    {
+                        "description": "Merge patch",
+                        "name": "patch",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "type": "object"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "location updated successfully",
+                        "schema": {
+                            "$ref": "#/definitions/dto.LocationRes"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid input format",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "403": {
+                        "description": "forbidden",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "404": {
+                        "description": "location not found",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "409": {
+                        "description": "location has been modified concurrently",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "412": {
+                        "description": "location has been modified",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "415": {
+                        "description": "unsupported media type",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "422": {
+                        "description": "validation error",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "500": {
+                        "description": "internal server error",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/locations/{id}/attachments": {
+            "get": {
+                "description": "Lists the files attached to a location, oldest first. Anyone who can read the location, including through a share link passed as share, can list them.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "attachments"
+                ],
+                "summary": "List attachments",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Location ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Token of a share link covering the location",
+                        "name": "share",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "attachments retrieved successfully",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/dto.AttachmentRes"
+                            }
+                        }
+                    },
+                    "403": {
+                        "description": "forbidden",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "404": {
+                        "description": "location not found",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "500": {
+                        "description": "internal server error",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            },
+            "post": {
+                "description": "Attaches the \"file\" field of a multipart form to one of the user's locations. The content type is sniffed from the file rather than taken from the request, and GIF, JPEG and PNG images get a thumbnail.\nA location may have at most 20 attachments, and each file is held to the user's size limit.",
+                "consumes": [
+                    "multipart/form-data"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "attachments"
+                ],
+                "summary": "Upload an attachment",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Location ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "file",
+                        "description": "File to attach",
+                        "name": "file",
+                        "in": "formData",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Retries with the same key replay the first response instead of running again",
+                        "name": "Idempotency-Key",
+                        "in": "header"
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "attachment uploaded successfully",
+                        "schema": {
+                            "$ref": "#/definitions/dto.AttachmentRes"
+                        }
+                    },
+                    "400": {
+                        "description": "invalid multipart body",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "403": {
+                        "description": "forbidden or attachment limit reached",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "404": {
+                        "description": "location not found",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "409": {
+                        "description": "a request with this Idempotency-Key is still in progress",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "413": {
+                        "description": "attachment is too large",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "500": {
+                        "description": "internal server error",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/locations/{id}/attachments/{attachmentId}": {
+            "get": {
+                "description": "Returns the attached file with its sniffed content type. Images are shown inline; anything else is sent as a download. With thumbnail=true a scaled-down JPEG or PNG of an image is returned instead.",
+                "produces": [
+                    "application/octet-stream"
+                ],
+                "tags": [
+                    "attachments"
+                ],
+                "summary": "Download an attachment",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Location ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Attachment ID",
+                        "name": "attachmentId",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "boolean",
+                        "default": false,
+                        "description": "Return the thumbnail instead of the file",
+                        "name": "thumbnail",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Token of a share link covering the location",
+                        "name": "share",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "the attachment's content",
+                        "schema": {
+                            "type": "file"
+                        }
+                    },
+                    "403": {
+                        "description": "forbidden",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "404": {
+                        "description": "location, attachment or thumbnail not found",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "500": {
+                        "description": "internal server error",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            },
+            "delete": {
+                "description": "Removes a file attached to one of the user's locations. Attachments are also removed when their location is purged from the trash or expires.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "attachments"
+                ],
+                "summary": "Delete an attachment",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Location ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Attachment ID",
+                        "name": "attachmentId",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "attachment deleted successfully",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "403": {
+                        "description": "forbidden",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "404": {
+                        "description": "location or attachment not found",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "500": {
+                        "description": "internal server error",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/locations/{id}/checkins": {
+            "get": {
+                "description": "Lists the visits recorded at a location, newest first, each with the weather at the time. Anyone who can read the location, including through a share link passed as share, can list them.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "checkins"
+                ],
+                "summary": "List check-ins at a location",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Location ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Token of a share link covering the location",
+                        "name": "share",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "check-ins retrieved successfully",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/dto.CheckInRes"
+                            }
+                        }
+                    },
+                    "403": {
+                        "description": "forbidden",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "404": {
+                        "description": "location not found",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "500": {
+                        "description": "internal server error",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            },
+            "post": {
+                "description": "Records a visit to one of the user's locations with an optional comment. The current weather in the location's city is stored with the check-in; if it cannot be looked up, weather is null.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "checkins"
+                ],
+                "summary": "Check in at a location",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Location ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Check-in comment",
+                        "name": "checkin",
+                        "in": "body",
+                        "schema": {
+                            "$ref": "#/definitions/dto.CheckInReq"
+                        }
+                    },
+                    {
+                        "type": "string",
+                        "description": "Retries with the same key replay the first response instead of running again",
+                        "name": "Idempotency-Key",
+                        "in": "header"
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "checked in successfully",
+                        "schema": {
+                            "$ref": "#/definitions/dto.CheckInRes"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid input format",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "403": {
+                        "description": "forbidden",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "404": {
+                        "description": "location not found",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "409": {
+                        "description": "a request with this Idempotency-Key is still in progress",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "422": {
+                        "description": "validation error",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "500": {
+                        "description": "internal server error",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/locations/{id}/history": {
+            "get": {
+                "description": "Lists every change made to a location, oldest first, with who made it, when, in which request and which fields changed. History remains available after the location is deleted.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "locations"
+                ],
+                "summary": "Get a location's history",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Location ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "history retrieved successfully",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/dto.HistoryEntryRes"
+                            }
+                        }
+                    },
+                    "403": {
+                        "description": "forbidden",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "404": {
+                        "description": "location not found",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "500": {
+                        "description": "internal server error",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/locations/{id}/history/{version}/revert": {
+            "post": {
+                "description": "Sets the location's editable fields back to how they were at the given version. The revert is recorded as a new version.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "locations"
+                ],
+                "summary": "Revert a location",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Location ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Version to revert to",
+                        "name": "version",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "ETag of the version being replaced",
+                        "name": "If-Match",
+                        "in": "header"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "location reverted successfully",
+                        "schema": {
+                            "$ref": "#/definitions/dto.LocationRes"
+                        }
+                    },
+                    "400": {
+                        "description": "invalid version",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "403": {
+                        "description": "forbidden",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "404": {
+                        "description": "location or version not found",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "412": {
+                        "description": "location has been modified",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "422": {
+                        "description": "the version's notes are longer than the user's limit",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "500": {
+                        "description": "internal server error",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/locations/{id}/restore": {
+            "post": {
+                "description": "Moves a location out of the trash and back to the end of the user's list",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "locations"
+                ],
+                "summary": "Restore a deleted location",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Location ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "location restored successfully",
+                        "schema": {
+                            "$ref": "#/definitions/dto.LocationRes"
+                        }
+                    },
+                    "403": {
+                        "description": "location limit reached",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "404": {
+                        "description": "location not found",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "500": {
+                        "description": "internal server error",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/locations:batch": {
+            "post": {
+                "description": "Applies up to 100 operations in order and reports the outcome of each with the status it would have had as a request of its own. With atomic=true any failure, including a validation error, leaves every location as it was; the other operations then report 424. Atomic batches can only change the user's own locations. Creates that look like duplicates of an existing location report 409 unless the operation sets force.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "locations"
+                ],
+                "summary": "Create, update and delete locations in one request",
+                "parameters": [
+                    {
+                        "description": "Operations",
+                        "name": "batch",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/dto.BatchReq"
+                        }
+                    },
+                    {
+                        "type": "boolean",
+                        "default": false,
+                        "description": "Apply all operations or none",
+                        "name": "atomic",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Retries with the same key replay the first response instead of running again",
+                        "name": "Idempotency-Key",
+                        "in": "header"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "batch processed",
+                        "schema": {
+                            "$ref": "#/definitions/dto.BatchRes"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid input format",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "409": {
+                        "description": "a request with this Idempotency-Key is still in progress",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "413": {
+                        "description": "too many operations",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "422": {
+                        "description": "validation error",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "500": {
+                        "description": "internal server error",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/me/usage": {
+            "get": {
+                "description": "Reports how many locations the user has, not counting the trash, and the limits they are held to. A limit of 0 means there is none.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "users"
+                ],
+                "summary": "Get the user's usage",
+                "responses": {
+                    "200": {
+                        "description": "usage retrieved successfully",
+                        "schema": {
+                            "$ref": "#/definitions/dto.UsageRes"
+                        }
+                    },
+                    "500": {
+                        "description": "internal server error",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/shared/{token}": {
+            "get": {
+                "description": "Retrieves the shared location or collection with the current weather in each city. No session is needed, and opening a share does not keep the shared locations from expiring.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "shares"
+                ],
+                "summary": "Open a share link",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Share token",
+                        "name": "token",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "shared content retrieved successfully",
+                        "schema": {
+                            "$ref": "#/definitions/dto.SharedRes"
+                        }
+                    },
+                    "404": {
+                        "description": "share not found",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "410": {
+                        "description": "share has expired",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "500": {
+                        "description": "internal server error",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/shares": {
+            "get": {
+                "description": "Retrieves the user's share links, newest first",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "shares"
+                ],
+                "summary": "List share links",
+                "responses": {
+                    "200": {
+                        "description": "shares retrieved successfully",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/dto.ShareRes"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "internal server error",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            },
+            "post": {
+                "description": "Creates a revocable token that gives anyone holding it read access to one location or one collection, with its live weather. Set exactly one of location_id and collection_id. precision rounds the shared coordinates to that many decimal places; without it, or with 0, the exact coordinates are shared.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "shares"
+                ],
+                "summary": "Create a share link",
+                "parameters": [
+                    {
+                        "description": "Share request body",
+                        "name": "share",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/dto.ShareReq"
+                        }
+                    },
+                    {
+                        "type": "string",
+                        "description": "Retries with the same key replay the first response instead of running again",
+                        "name": "Idempotency-Key",
+                        "in": "header"
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "share created successfully",
+                        "schema": {
+                            "$ref": "#/definitions/dto.ShareRes"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid input format or scope",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "403": {
+                        "description": "forbidden",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "404": {
+                        "description": "location or collection not found",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "409": {
+                        "description": "a request with this Idempotency-Key is still in progress",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "422": {
+                        "description": "validation error",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "500": {
+                        "description": "internal server error",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/shares/{token}": {
+            "delete": {
+                "description": "Deletes a share link; its token stops working immediately",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "shares"
+                ],
+                "summary": "Revoke a share link",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Share token",
+                        "name": "token",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "share revoked successfully",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "403": {
+                        "description": "forbidden",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "404": {
+                        "description": "share not found",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "500": {
+                        "description": "internal server error",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/weather": {
+            "get": {
+                "description": "Retrieves weather information for a specified city.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "weather"
+                ],
+                "summary": "Get weather information",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "City name",
+                        "name": "city",
+                        "in": "query",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "weather retrieved successfully",
+                        "schema": {
+                            "$ref": "#/definitions/dto.WeatherRes"
+                        }
+                    },
+                    "400": {
+                        "description": "invalid city",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "404": {
+                        "description": "city not found",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "500": {
+                        "description": "internal server error",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        }
+    },
+    "definitions": {
+        "dto.AttachmentRes": {
+            "type": "object",
+            "properties": {
+                "content_type": {
+                    "type": "string"
+                },
+                "created_at": {
+                    "type": "string"
+                },
+                "file_name": {
+                    "type": "string"
+                },
+                "has_thumbnail": {
+                    "type": "boolean"
+                },
+                "id": {
+                    "type": "string"
+                },
+                "size": {
+                    "type": "integer"
+                }
+            }
+        },
+        "dto.BatchOpReq": {
+            "type": "object",
+            "properties": {
+                "force": {
+                    "type": "boolean"
+                },
+                "id": {
+                    "type": "string"
+                },
+                "location": {
+                    "$ref": "#/definitions/dto.LocationReq"
+                },
+                "op": {
+                    "type": "string",
+                    "enum": [
+                        "create",
+                        "update",
+                        "delete"
+                    ]
+                },
+                "version": {
+                    "type": "integer",
+                    "minimum": 0
+                }
+            }
+        },
+        "dto.BatchReq": {
+            "type": "object",
+            "required": [
+                "operations"
+            ],
+            "properties": {
+                "operations": {
+                    "type": "array",
+                    "minItems": 1,
+                    "items": {
+                        "$ref": "#/definitions/dto.BatchOpReq"
+                    }
+                }
+            }
+        },
+        "dto.BatchRes": {
+            "type": "object",
+            "properties": {
+                "atomic": {
+                    "type": "boolean"
+                },
+                "failed": {
+                    "type": "integer"
+                },
+                "results": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/dto.BatchResultRes"
+                    }
+                },
+                "succeeded": {
+                    "type": "integer"
+                },
+                "total": {
+                    "type": "integer"
+                }
+            }
+        },
+        "dto.BatchResultRes": {
+            "type": "object",
+            "properties": {
+                "error": {
+                    "type": "string"
+                },
+                "errors": {
+                    "type": "object",
+                    "additionalProperties": {
+                        "type": "string"
+                    }
+                },
+                "index": {
+                    "type": "integer"
+                },
+                "location": {
+                    "$ref": "#/definitions/dto.LocationRes"
+                },
+                "op": {
+                    "type": "string"
+                },
+                "status": {
+                    "description": "Status is the HTTP status the operation would have had as a request\nof its own.",
+                    "type": "integer"
+                }
+            }
+        },
+        "dto.CheckInReq": {
+            "type": "object",
+            "properties": {
+                "comment": {
+                    "type": "string",
+                    "maxLength": 1000
+                }
             }
-        }
-    },
-    "definitions": {
-        "dto.Coordinates": {
+        },
+        "dto.CheckInRes": {
+            "type": "object",
+            "properties": {
+                "city": {
+                    "type": "string"
+                },
+                "comment": {
+                    "type": "string"
+                },
+                "created_at": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "string"
+                },
+                "location_id": {
+                    "type": "string"
+                },
+                "nickname": {
+                    "type": "string"
+                },
+                "weather": {
+                    "$ref": "#/definitions/dto.WeatherRes"
+                }
+            }
+        },
+        "dto.CollectionReq": {
             "type": "object",
             "required": [
-                "lat",
-                "lon"
+                "name"
             ],
+            "properties": {
+                "name": {
+                    "type": "string",
+                    "maxLength": 64,
+                    "minLength": 1
+                }
+            }
+        },
+        "dto.CollectionRes": {
+            "type": "object",
+            "properties": {
+                "created_at": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "string"
+                },
+                "name": {
+                    "type": "string"
+                }
+            }
+        },
+        "dto.Coordinates": {
+            "type": "object",
             "properties": {
                 "lat": {
-                    "type": "number"
+                    "type": "number",
+                    "maximum": 90,
+                    "minimum": -90
                 },
                 "lon": {
-                    "type": "number"
+                    "type": "number",
+                    "maximum": 180,
+                    "minimum": -180
+                }
+            }
+        },
+        "dto.FieldChangeRes": {
+            "type": "object",
+            "properties": {
+                "field": {
+                    "type": "string"
+                },
+                "new": {},
+                "old": {}
+            }
+        },
+        "dto.HistoryEntryRes": {
+            "type": "object",
+            "properties": {
+                "action": {
+                    "type": "string"
+                },
+                "actor_id": {
+                    "type": "string"
+                },
+                "at": {
+                    "type": "string"
+                },
+                "changes": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/dto.FieldChangeRes"
+                    }
+                },
+                "request_id": {
+                    "type": "string"
+                },
+                "version": {
+                    "type": "integer"
+                }
+            }
+        },
+        "dto.ImportReportRes": {
+            "type": "object",
+            "properties": {
+                "accepted": {
+                    "type": "integer"
+                },
+                "dry_run": {
+                    "type": "boolean"
+                },
+                "duplicates": {
+                    "type": "integer"
+                },
+                "rejected": {
+                    "type": "integer"
+                },
+                "rows": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/dto.ImportRowRes"
+                    }
+                },
+                "total": {
+                    "type": "integer"
+                }
+            }
+        },
+        "dto.ImportRowRes": {
+            "type": "object",
+            "properties": {
+                "duplicate_of": {
+                    "type": "string"
+                },
+                "errors": {
+                    "type": "object",
+                    "additionalProperties": {
+                        "type": "string"
+                    }
+                },
+                "location": {
+                    "$ref": "#/definitions/dto.LocationRes"
+                },
+                "row": {
+                    "type": "integer"
+                },
+                "status": {
+                    "type": "string"
                 }
             }
         },
@@ -291,7 +2684,6 @@ const docTemplate = `{
             "type": "object",
             "required": [
                 "city",
-                "coordinates",
                 "nickname",
                 "notes"
             ],
@@ -311,6 +2703,16 @@ const docTemplate = `{
                     "type": "string",
                     "minLength": 1
                 },
+                "pinned": {
+                    "type": "boolean"
+                },
+                "tags": {
+                    "type": "array",
+                    "maxItems": 20,
+                    "items": {
+                        "type": "string"
+                    }
+                },
                 "userID": {
                     "type": "string"
                 }
@@ -322,12 +2724,96 @@ const docTemplate = `{
                 "city": {
                     "type": "string"
                 },
+                "collections": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "coordinates": {
+                    "$ref": "#/definitions/dto.Coordinates"
+                },
+                "created_at": {
+                    "type": "string"
+                },
+                "deleted_at": {
+                    "type": "string"
+                },
+                "expires_at": {
+                    "description": "ExpiresAt warns when the location will be removed under the retention\npolicy. It is omitted for locations that are kept forever.",
+                    "type": "string"
+                },
+                "id": {
+                    "type": "string"
+                },
+                "nickname": {
+                    "type": "string"
+                },
+                "notes": {
+                    "type": "string"
+                },
+                "pinned": {
+                    "type": "boolean"
+                },
+                "position": {
+                    "type": "integer"
+                },
+                "tags": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "version": {
+                    "type": "integer"
+                }
+            }
+        },
+        "dto.LoginReq": {
+            "type": "object",
+            "required": [
+                "email",
+                "password"
+            ],
+            "properties": {
+                "email": {
+                    "type": "string",
+                    "maxLength": 254
+                },
+                "password": {
+                    "type": "string",
+                    "maxLength": 128
+                }
+            }
+        },
+        "dto.NearbyLocationRes": {
+            "type": "object",
+            "properties": {
+                "city": {
+                    "type": "string"
+                },
+                "collections": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
                 "coordinates": {
                     "$ref": "#/definitions/dto.Coordinates"
                 },
                 "created_at": {
                     "type": "string"
                 },
+                "deleted_at": {
+                    "type": "string"
+                },
+                "distance_km": {
+                    "type": "number"
+                },
+                "expires_at": {
+                    "description": "ExpiresAt warns when the location will be removed under the retention\npolicy. It is omitted for locations that are kept forever.",
+                    "type": "string"
+                },
                 "id": {
                     "type": "string"
                 },
@@ -336,6 +2822,186 @@ const docTemplate = `{
                 },
                 "notes": {
                     "type": "string"
+                },
+                "pinned": {
+                    "type": "boolean"
+                },
+                "position": {
+                    "type": "integer"
+                },
+                "tags": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "version": {
+                    "type": "integer"
+                }
+            }
+        },
+        "dto.RegisterReq": {
+            "type": "object",
+            "required": [
+                "email",
+                "password"
+            ],
+            "properties": {
+                "email": {
+                    "type": "string",
+                    "maxLength": 254
+                },
+                "password": {
+                    "type": "string",
+                    "maxLength": 128,
+                    "minLength": 8
+                }
+            }
+        },
+        "dto.ReorderReq": {
+            "type": "object",
+            "required": [
+                "ids"
+            ],
+            "properties": {
+                "ids": {
+                    "type": "array",
+                    "maxItems": 1000,
+                    "minItems": 1,
+                    "items": {
+                        "type": "string"
+                    }
+                }
+            }
+        },
+        "dto.ShareReq": {
+            "type": "object",
+            "properties": {
+                "collection_id": {
+                    "type": "string"
+                },
+                "expires_at": {
+                    "type": "string"
+                },
+                "location_id": {
+                    "type": "string"
+                },
+                "precision": {
+                    "description": "Precision is the number of decimal places coordinates are rounded to\nfor whoever opens the share; 0, the default, shares the exact\ncoordinates.",
+                    "type": "integer",
+                    "maximum": 6,
+                    "minimum": 0
+                }
+            }
+        },
+        "dto.ShareRes": {
+            "type": "object",
+            "properties": {
+                "collection_id": {
+                    "type": "string"
+                },
+                "created_at": {
+                    "type": "string"
+                },
+                "expires_at": {
+                    "type": "string"
+                },
+                "location_id": {
+                    "type": "string"
+                },
+                "path": {
+                    "type": "string"
+                },
+                "precision": {
+                    "type": "integer"
+                },
+                "token": {
+                    "type": "string"
+                }
+            }
+        },
+        "dto.SharedCollectionRes": {
+            "type": "object",
+            "properties": {
+                "locations": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/dto.SharedLocationRes"
+                    }
+                },
+                "name": {
+                    "type": "string"
+                }
+            }
+        },
+        "dto.SharedLocationRes": {
+            "type": "object",
+            "properties": {
+                "city": {
+                    "type": "string"
+                },
+                "coordinates": {
+                    "$ref": "#/definitions/dto.Coordinates"
+                },
+                "nickname": {
+                    "type": "string"
+                },
+                "notes": {
+                    "type": "string"
+                },
+                "tags": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "weather": {
+                    "$ref": "#/definitions/dto.WeatherRes"
+                }
+            }
+        },
+        "dto.SharedRes": {
+            "type": "object",
+            "properties": {
+                "collection": {
+                    "$ref": "#/definitions/dto.SharedCollectionRes"
+                },
+                "expires_at": {
+                    "type": "string"
+                },
+                "location": {
+                    "$ref": "#/definitions/dto.SharedLocationRes"
+                }
+            }
+        },
+        "dto.UsageRes": {
+            "type": "object",
+            "properties": {
+                "locations": {
+                    "type": "integer"
+                },
+                "max_attachment_bytes": {
+                    "type": "integer"
+                },
+                "max_locations": {
+                    "type": "integer"
+                },
+                "max_notes_length": {
+                    "type": "integer"
+                }
+            }
+        },
+        "dto.UserRes": {
+            "type": "object",
+            "properties": {
+                "created_at": {
+                    "type": "string"
+                },
+                "email": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "string"
                 }
             }
         },
@@ -345,7 +3011,7 @@ const docTemplate = `{
                 "condition": {
                     "type": "string"
                 },
-                "date_time": {
+                "dateTime": {
                     "type": "string"
                 },
                 "description": {
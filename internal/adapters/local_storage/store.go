@@ -0,0 +1,87 @@
+package localstorage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+
+	"github.com/lafetz/weavo/internal/core/service/location"
+)
+
+// validKey matches the keys the store accepts. Keys never contain path
+// separators, so a key cannot reach outside the root directory.
+var validKey = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9._-]*$`)
+
+var ErrInvalidKey = errors.New("invalid blob key")
+
+// LocalStore keeps blobs as files in a directory on the local filesystem.
+type LocalStore struct {
+	root string
+}
+
+// NewLocalStore returns a store under root, creating the directory if it
+// does not exist.
+func NewLocalStore(root string) (*LocalStore, error) {
+	if err := os.MkdirAll(root, 0o750); err != nil {
+		return nil, fmt.Errorf("creating storage directory: %w", err)
+	}
+	return &LocalStore{root: root}, nil
+}
+
+func (st *LocalStore) path(key string) (string, error) {
+	if !validKey.MatchString(key) {
+		return "", ErrInvalidKey
+	}
+	return filepath.Join(st.root, key), nil
+}
+
+// Put writes the blob to a temporary file first and renames it into place,
+// so that a failed or concurrent write never leaves a partial blob under
+// key.
+func (st *LocalStore) Put(ctx context.Context, key string, r io.Reader) error {
+	path, err := st.path(key)
+	if err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(st.root, ".upload-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := io.Copy(tmp, r); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+func (st *LocalStore) Open(ctx context.Context, key string) (io.ReadCloser, error) {
+	path, err := st.path(key)
+	if err != nil {
+		return nil, err
+	}
+	f, err := os.Open(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, location.ErrBlobNotFound
+	}
+	return f, err
+}
+
+func (st *LocalStore) Delete(ctx context.Context, key string) error {
+	path, err := st.path(key)
+	if err != nil {
+		return err
+	}
+	if err := os.Remove(path); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,58 @@
+package localstorage
+
+import (
+	"context"
+	"errors"
+	"io"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/lafetz/weavo/internal/core/service/location"
+)
+
+func TestLocalStore(t *testing.T) {
+	ctx := context.Background()
+	store, err := NewLocalStore(t.TempDir() + "/blobs")
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	if err := store.Put(ctx, "abc", strings.NewReader("hello")); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	// Writing again replaces the blob.
+	if err := store.Put(ctx, "abc", strings.NewReader("hello again")); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	r, err := store.Open(ctx, "abc")
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	data, _ := io.ReadAll(r)
+	r.Close()
+	if string(data) != "hello again" {
+		t.Errorf("expected %q, got %q", "hello again", data)
+	}
+
+	entries, _ := os.ReadDir(store.root)
+	if len(entries) != 1 {
+		t.Errorf("expected no temporary files to be left behind, got %d entries", len(entries))
+	}
+
+	if err := store.Delete(ctx, "abc"); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if _, err := store.Open(ctx, "abc"); !errors.Is(err, location.ErrBlobNotFound) {
+		t.Errorf("expected ErrBlobNotFound, got %v", err)
+	}
+	if err := store.Delete(ctx, "abc"); err != nil {
+		t.Errorf("expected deleting a missing blob to succeed, got %v", err)
+	}
+
+	for _, key := range []string{"", "../escape", "a/b", ".hidden"} {
+		if err := store.Put(ctx, key, strings.NewReader("x")); !errors.Is(err, ErrInvalidKey) {
+			t.Errorf("expected ErrInvalidKey for %q, got %v", key, err)
+		}
+	}
+}
@@ -0,0 +1,89 @@
+package repository
+
+import (
+	"context"
+	"sync"
+
+	"github.com/lafetz/weavo/internal/core/domain"
+	"github.com/lafetz/weavo/internal/core/service/location"
+)
+
+// InMemoryAttachmentRepo keeps the metadata of attachments in memory.
+// SaveSnapshot writes it to disk.
+type InMemoryAttachmentRepo struct {
+	mu          sync.RWMutex
+	attachments map[string]domain.Attachment // id -> attachment
+	byLocation  map[string][]string          // location id -> ids, oldest first
+}
+
+func NewInMemoryAttachmentRepo() *InMemoryAttachmentRepo {
+	return &InMemoryAttachmentRepo{
+		attachments: make(map[string]domain.Attachment),
+		byLocation:  make(map[string][]string),
+	}
+}
+
+func (repo *InMemoryAttachmentRepo) CreateAttachment(ctx context.Context, attachment domain.Attachment) (domain.Attachment, error) {
+	repo.mu.Lock()
+	defer repo.mu.Unlock()
+	repo.attachments[attachment.Id] = attachment
+	repo.byLocation[attachment.LocationID] = append(repo.byLocation[attachment.LocationID], attachment.Id)
+	return attachment, nil
+}
+
+func (repo *InMemoryAttachmentRepo) GetAttachment(ctx context.Context, id string) (domain.Attachment, error) {
+	repo.mu.RLock()
+	defer repo.mu.RUnlock()
+	attachment, exists := repo.attachments[id]
+	if !exists {
+		return domain.Attachment{}, location.ErrAttachmentNotFound
+	}
+	return attachment, nil
+}
+
+func (repo *InMemoryAttachmentRepo) GetAttachments(ctx context.Context, locationID string) ([]domain.Attachment, error) {
+	repo.mu.RLock()
+	defer repo.mu.RUnlock()
+	ids := repo.byLocation[locationID]
+	attachments := make([]domain.Attachment, 0, len(ids))
+	for _, id := range ids {
+		attachments = append(attachments, repo.attachments[id])
+	}
+	return attachments, nil
+}
+
+func (repo *InMemoryAttachmentRepo) DeleteAttachment(ctx context.Context, id string) error {
+	repo.mu.Lock()
+	defer repo.mu.Unlock()
+	attachment, exists := repo.attachments[id]
+	if !exists {
+		return location.ErrAttachmentNotFound
+	}
+	delete(repo.attachments, id)
+	ids := repo.byLocation[attachment.LocationID]
+	for i, other := range ids {
+		if other == id {
+			ids = append(ids[:i:i], ids[i+1:]...)
+			break
+		}
+	}
+	if len(ids) == 0 {
+		delete(repo.byLocation, attachment.LocationID)
+	} else {
+		repo.byLocation[attachment.LocationID] = ids
+	}
+	return nil
+}
+
+func (repo *InMemoryAttachmentRepo) DeleteAttachments(ctx context.Context, locationID string) ([]domain.Attachment, error) {
+	repo.mu.Lock()
+	defer repo.mu.Unlock()
+	ids := repo.byLocation[locationID]
+	removed := make([]domain.Attachment, 0, len(ids))
+	for _, id := range ids {
+		removed = append(removed, repo.attachments[id])
+		delete(repo.attachments, id)
+	}
+	delete(repo.byLocation, locationID)
+	return removed, nil
+}
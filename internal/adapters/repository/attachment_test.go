@@ -0,0 +1,41 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/lafetz/weavo/internal/core/domain"
+	"github.com/lafetz/weavo/internal/core/service/location"
+)
+
+func TestInMemoryAttachmentRepo(t *testing.T) {
+	ctx := context.Background()
+	repo := NewInMemoryAttachmentRepo()
+	for _, a := range []domain.Attachment{
+		{Id: "1", LocationID: "a"},
+		{Id: "2", LocationID: "a"},
+		{Id: "3", LocationID: "b"},
+	} {
+		repo.CreateAttachment(ctx, a)
+	}
+
+	if err := repo.DeleteAttachment(ctx, "1"); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if _, err := repo.GetAttachment(ctx, "1"); !errors.Is(err, location.ErrAttachmentNotFound) {
+		t.Errorf("expected ErrAttachmentNotFound, got %v", err)
+	}
+	list, _ := repo.GetAttachments(ctx, "a")
+	if len(list) != 1 || list[0].Id != "2" {
+		t.Errorf("expected only attachment 2 to be left, got %+v", list)
+	}
+
+	removed, _ := repo.DeleteAttachments(ctx, "a")
+	if len(removed) != 1 || removed[0].Id != "2" {
+		t.Errorf("expected attachment 2 to be removed, got %+v", removed)
+	}
+	if list, _ := repo.GetAttachments(ctx, "b"); len(list) != 1 {
+		t.Errorf("expected other locations to be unaffected, got %+v", list)
+	}
+}
@@ -0,0 +1,113 @@
+package repository
+
+import (
+	"context"
+	"slices"
+	"sync"
+
+	"github.com/google/uuid"
+	"github.com/lafetz/weavo/internal/core/domain"
+	"github.com/lafetz/weavo/internal/core/service/location"
+)
+
+// InMemoryCheckInRepo keeps check-ins in memory. SaveSnapshot writes them
+// to disk with the comments encrypted.
+type InMemoryCheckInRepo struct {
+	mu     sync.RWMutex
+	byUser map[string][]domain.CheckIn // userID -> check-ins, oldest first
+	owners map[string]string           // location id -> userID
+}
+
+func NewInMemoryCheckInRepo() *InMemoryCheckInRepo {
+	return &InMemoryCheckInRepo{
+		byUser: make(map[string][]domain.CheckIn),
+		owners: make(map[string]string),
+	}
+}
+
+func (repo *InMemoryCheckInRepo) CreateCheckIn(ctx context.Context, checkIn domain.CheckIn) (domain.CheckIn, error) {
+	checkIn.Id = uuid.New().String()
+	if checkIn.Weather != nil {
+		weather := *checkIn.Weather
+		checkIn.Weather = &weather
+	}
+	repo.mu.Lock()
+	repo.byUser[checkIn.UserID] = append(repo.byUser[checkIn.UserID], checkIn)
+	repo.owners[checkIn.LocationID] = checkIn.UserID
+	repo.mu.Unlock()
+	return checkIn, nil
+}
+
+func (repo *InMemoryCheckInRepo) GetCheckIns(ctx context.Context, locationID string) ([]domain.CheckIn, error) {
+	repo.mu.RLock()
+	defer repo.mu.RUnlock()
+	checkIns := []domain.CheckIn{}
+	userCheckIns := repo.byUser[repo.owners[locationID]]
+	for i := len(userCheckIns) - 1; i >= 0; i-- {
+		if userCheckIns[i].LocationID == locationID {
+			checkIns = append(checkIns, userCheckIns[i])
+		}
+	}
+	return checkIns, nil
+}
+
+func (repo *InMemoryCheckInRepo) GetTimeline(ctx context.Context, userID string, filter location.CheckInFilter) ([]domain.CheckIn, domain.Metadata, error) {
+	repo.mu.RLock()
+	userCheckIns := repo.byUser[userID]
+	checkIns := make([]domain.CheckIn, 0, len(userCheckIns))
+	for i := len(userCheckIns) - 1; i >= 0; i-- {
+		c := userCheckIns[i]
+		if (filter.After.IsZero() || c.CreatedAt.After(filter.After)) &&
+			(filter.Before.IsZero() || c.CreatedAt.Before(filter.Before)) {
+			checkIns = append(checkIns, c)
+		}
+	}
+	repo.mu.RUnlock()
+
+	totalRecords := len(checkIns)
+	start := min(filter.PageSize*(filter.Page-1), totalRecords)
+	end := min(start+filter.PageSize, totalRecords)
+	metadata := domain.CalculateMetadata(int32(totalRecords), int32(start), int32(filter.PageSize))
+	return checkIns[start:end], metadata, nil
+}
+
+func (repo *InMemoryCheckInRepo) DeleteCheckIns(ctx context.Context, locationID string) error {
+	repo.mu.Lock()
+	defer repo.mu.Unlock()
+	userID, exists := repo.owners[locationID]
+	if !exists {
+		return nil
+	}
+	delete(repo.owners, locationID)
+	userCheckIns := slices.DeleteFunc(repo.byUser[userID], func(c domain.CheckIn) bool {
+		return c.LocationID == locationID
+	})
+	if len(userCheckIns) == 0 {
+		delete(repo.byUser, userID)
+	} else {
+		repo.byUser[userID] = userCheckIns
+	}
+	return nil
+}
+
+func (repo *InMemoryCheckInRepo) MoveCheckIns(ctx context.Context, fromUserID, toUserID string) error {
+	repo.mu.Lock()
+	defer repo.mu.Unlock()
+	moved := repo.byUser[fromUserID]
+	if len(moved) == 0 || fromUserID == toUserID {
+		return nil
+	}
+	delete(repo.byUser, fromUserID)
+	checkIns := slices.Concat(repo.byUser[toUserID], moved)
+	for i := range checkIns {
+		if checkIns[i].UserID == fromUserID {
+			checkIns[i].UserID = toUserID
+			repo.owners[checkIns[i].LocationID] = toUserID
+		}
+	}
+	slices.SortStableFunc(checkIns, func(a, b domain.CheckIn) int {
+		return a.CreatedAt.Compare(b.CreatedAt)
+	})
+	repo.byUser[toUserID] = checkIns
+	return nil
+}
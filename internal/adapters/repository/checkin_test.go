@@ -0,0 +1,67 @@
+package repository
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/lafetz/weavo/internal/core/domain"
+	"github.com/lafetz/weavo/internal/core/service/location"
+)
+
+func TestInMemoryCheckInRepo(t *testing.T) {
+	ctx := context.Background()
+	repo := NewInMemoryCheckInRepo()
+	base := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	weather := domain.Weather{Temperature: 12}
+	for i, locationID := range []string{"a", "b", "a", "b"} {
+		repo.CreateCheckIn(ctx, domain.CheckIn{LocationID: locationID, UserID: "user1", Weather: &weather, CreatedAt: base.Add(time.Duration(i) * 24 * time.Hour)})
+	}
+	repo.CreateCheckIn(ctx, domain.CheckIn{LocationID: "c", UserID: "user2", CreatedAt: base})
+	// The stored snapshot must not change with the caller's copy.
+	weather.Temperature = 30
+
+	checkIns, _ := repo.GetCheckIns(ctx, "a")
+	if len(checkIns) != 2 || !checkIns[0].CreatedAt.After(checkIns[1].CreatedAt) {
+		t.Fatalf("expected 2 check-ins, newest first, got %+v", checkIns)
+	}
+	if checkIns[0].Weather.Temperature != 12 {
+		t.Errorf("expected the stored weather to be kept, got %v", checkIns[0].Weather.Temperature)
+	}
+
+	timeline, meta, _ := repo.GetTimeline(ctx, "user1", location.CheckInFilter{After: base, Before: base.Add(3 * 24 * time.Hour), Page: 1, PageSize: 1})
+	if meta.TotalRecords != 2 || len(timeline) != 1 || !timeline[0].CreatedAt.Equal(base.Add(2*24*time.Hour)) {
+		t.Errorf("expected the newest of 2 check-ins in the span, got %+v (%+v)", timeline, meta)
+	}
+
+	repo.DeleteCheckIns(ctx, "a")
+	if checkIns, _ := repo.GetCheckIns(ctx, "a"); len(checkIns) != 0 {
+		t.Errorf("expected the check-ins to be deleted, got %+v", checkIns)
+	}
+	if _, meta, _ := repo.GetTimeline(ctx, "user1", location.CheckInFilter{Page: 1, PageSize: 10}); meta.TotalRecords != 2 {
+		t.Errorf("expected the other location's check-ins to be kept, got %d", meta.TotalRecords)
+	}
+}
+
+func TestMoveCheckIns(t *testing.T) {
+	ctx := context.Background()
+	repo := NewInMemoryCheckInRepo()
+	base := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	repo.CreateCheckIn(ctx, domain.CheckIn{LocationID: "a", UserID: "account", CreatedAt: base})
+	repo.CreateCheckIn(ctx, domain.CheckIn{LocationID: "b", UserID: "anon", CreatedAt: base.Add(-time.Hour)})
+	repo.CreateCheckIn(ctx, domain.CheckIn{LocationID: "b", UserID: "anon", CreatedAt: base.Add(time.Hour)})
+
+	if err := repo.MoveCheckIns(ctx, "anon", "account"); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	timeline, _, _ := repo.GetTimeline(ctx, "account", location.CheckInFilter{Page: 1, PageSize: 10})
+	if len(timeline) != 3 || !timeline[0].CreatedAt.Equal(base.Add(time.Hour)) || !timeline[2].CreatedAt.Equal(base.Add(-time.Hour)) {
+		t.Fatalf("expected 3 check-ins, newest first, got %+v", timeline)
+	}
+	if checkIns, _ := repo.GetCheckIns(ctx, "b"); len(checkIns) != 2 || checkIns[0].UserID != "account" {
+		t.Errorf("expected the location's check-ins under the account, got %+v", checkIns)
+	}
+	if _, meta, _ := repo.GetTimeline(ctx, "anon", location.CheckInFilter{Page: 1, PageSize: 10}); meta.TotalRecords != 0 {
+		t.Errorf("expected nothing left for the anonymous user, got %d", meta.TotalRecords)
+	}
+}
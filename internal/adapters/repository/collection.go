@@ -0,0 +1,166 @@
+package repository
+
+import (
+	"context"
+	"sort"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/lafetz/weavo/internal/core/domain"
+	"github.com/lafetz/weavo/internal/core/service/location"
+)
+
+func (repo *InMemoryLocationRepo) CreateCollection(ctx context.Context, c domain.Collection) (domain.Collection, error) {
+	c.Id = uuid.New().String()
+	c.CreatedAt = repo.now()
+
+	s := repo.userShard(c.UserID)
+	unlock := s.lock(ctx)
+	userCollections, exists := s.collections[c.UserID]
+	if !exists {
+		userCollections = make(map[string]domain.Collection)
+		s.collections[c.UserID] = userCollections
+	}
+	userCollections[c.Id] = c
+	unlock()
+
+	repo.setOwner(c.Id, c.UserID)
+	return c, nil
+}
+
+func (repo *InMemoryLocationRepo) GetCollection(ctx context.Context, id string) (domain.Collection, error) {
+	userID, exists := repo.owner(id)
+	if !exists {
+		return domain.Collection{}, location.ErrCollectionNotFound
+	}
+	s := repo.userShard(userID)
+	runlock := s.rlock(ctx)
+	defer runlock()
+	c, exists := s.collections[userID][id]
+	if !exists {
+		return domain.Collection{}, location.ErrCollectionNotFound
+	}
+	return c, nil
+}
+
+func (repo *InMemoryLocationRepo) GetCollections(ctx context.Context, userID string) ([]domain.Collection, error) {
+	s := repo.userShard(userID)
+	runlock := s.rlock(ctx)
+	collections := make([]domain.Collection, 0, len(s.collections[userID]))
+	for _, c := range s.collections[userID] {
+		collections = append(collections, c)
+	}
+	runlock()
+
+	sort.Slice(collections, func(i, j int) bool {
+		a, b := strings.ToLower(collections[i].Name), strings.ToLower(collections[j].Name)
+		if a != b {
+			return a < b
+		}
+		return collections[i].Id < collections[j].Id
+	})
+	return collections, nil
+}
+
+func (repo *InMemoryLocationRepo) UpdateCollection(ctx context.Context, c domain.Collection) (domain.Collection, error) {
+	userID, exists := repo.owner(c.Id)
+	if !exists {
+		return domain.Collection{}, location.ErrCollectionNotFound
+	}
+	s := repo.userShard(userID)
+	unlock := s.lock(ctx)
+	defer unlock()
+	stored, exists := s.collections[userID][c.Id]
+	if !exists {
+		return domain.Collection{}, location.ErrCollectionNotFound
+	}
+	stored.Name = c.Name
+	s.collections[userID][c.Id] = stored
+	return stored, nil
+}
+
+func (repo *InMemoryLocationRepo) DeleteCollection(ctx context.Context, id string) error {
+	userID, exists := repo.owner(id)
+	if !exists {
+		return location.ErrCollectionNotFound
+	}
+	s := repo.userShard(userID)
+	unlock := s.lock(ctx)
+	userCollections := s.collections[userID]
+	if _, exists := userCollections[id]; !exists {
+		unlock()
+		return location.ErrCollectionNotFound
+	}
+	delete(userCollections, id)
+	if len(userCollections) == 0 {
+		delete(s.collections, userID)
+	}
+	for _, locs := range []map[string]domain.Location{s.users[userID], s.trash[userID]} {
+		for _, loc := range locs {
+			if loc.InCollection(id) {
+				loc.Collections = without(loc.Collections, id)
+				loc.Version++
+				locs[loc.Id] = loc
+			}
+		}
+	}
+	unlock()
+
+	repo.removeOwner(id)
+	return nil
+}
+
+func (repo *InMemoryLocationRepo) AddToCollection(ctx context.Context, collectionID, locationID string) error {
+	return repo.updateMembership(ctx, collectionID, locationID, func(loc domain.Location) []string {
+		if loc.InCollection(collectionID) {
+			return loc.Collections
+		}
+		// Copy rather than append in place: earlier readers may still hold
+		// the old slice.
+		collections := make([]string, len(loc.Collections), len(loc.Collections)+1)
+		copy(collections, loc.Collections)
+		return append(collections, collectionID)
+	})
+}
+
+func (repo *InMemoryLocationRepo) RemoveFromCollection(ctx context.Context, collectionID, locationID string) error {
+	return repo.updateMembership(ctx, collectionID, locationID, func(loc domain.Location) []string {
+		return without(loc.Collections, collectionID)
+	})
+}
+
+func (repo *InMemoryLocationRepo) updateMembership(ctx context.Context, collectionID, locationID string, update func(domain.Location) []string) error {
+	userID, exists := repo.owner(collectionID)
+	if !exists {
+		return location.ErrCollectionNotFound
+	}
+	s := repo.userShard(userID)
+	unlock := s.lock(ctx)
+	defer unlock()
+	if _, exists := s.collections[userID][collectionID]; !exists {
+		return location.ErrCollectionNotFound
+	}
+	loc, exists := s.users[userID][locationID]
+	if !exists {
+		return location.ErrLocationNotFound
+	}
+	collections := update(loc)
+	if len(collections) == len(loc.Collections) {
+		return nil
+	}
+	loc.Collections = collections
+	loc.Version++
+	s.users[userID][locationID] = loc
+	return nil
+}
+
+// without returns a new slice holding ids minus id.
+func without(ids []string, id string) []string {
+	var out []string
+	for _, v := range ids {
+		if v != id {
+			out = append(out, v)
+		}
+	}
+	return out
+}
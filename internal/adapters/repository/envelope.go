@@ -0,0 +1,229 @@
+package repository
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+)
+
+// masterKeyLength is the length of master and data keys, for AES-256.
+const masterKeyLength = 32
+
+// sealedPrefix starts every value Seal returns, followed by the ID of the
+// master key, the wrapped data key and the ciphertext, separated by dots.
+const sealedPrefix = "v1"
+
+var (
+	ErrNoMasterKeys     = errors.New("no encryption keys configured")
+	ErrInvalidMasterKey = errors.New("an encryption key must be an ID of letters, digits, '-' or '_' and a base64 key of 32 bytes, separated by ':'")
+	ErrUnknownMasterKey = errors.New("value was sealed with an unknown encryption key")
+	ErrInvalidSealed    = errors.New("value is not a sealed value or has been tampered with")
+)
+
+var keyIDPattern = regexp.MustCompile(`^[A-Za-z0-9_-]{1,64}$`)
+
+// MasterKey is a key that encrypts the data keys of sealed values. Its ID
+// is stored with every value it seals, so that values sealed with a retired
+// key can still be opened while they are being re-encrypted.
+type MasterKey struct {
+	ID  string
+	Key []byte
+}
+
+// NewMasterKey returns a random key with a random ID.
+func NewMasterKey() MasterKey {
+	id := make([]byte, 4)
+	rand.Read(id)
+	key := MasterKey{ID: hex.EncodeToString(id), Key: make([]byte, masterKeyLength)}
+	rand.Read(key.Key)
+	return key
+}
+
+// String encodes k in the form ParseMasterKeys reads.
+func (k MasterKey) String() string {
+	return k.ID + ":" + base64.StdEncoding.EncodeToString(k.Key)
+}
+
+// ParseMasterKeys reads keys separated by commas or newlines, newest first.
+// Blank lines and lines starting with # are skipped, so the same format
+// serves for an environment variable and a key file.
+func ParseMasterKeys(s string) ([]MasterKey, error) {
+	var keys []MasterKey
+	for _, line := range strings.Split(s, "\n") {
+		if line = strings.TrimSpace(line); strings.HasPrefix(line, "#") {
+			continue
+		}
+		for _, field := range strings.Split(line, ",") {
+			if field = strings.TrimSpace(field); field == "" {
+				continue
+			}
+			key, err := parseMasterKey(field)
+			if err != nil {
+				return nil, fmt.Errorf("encryption key %d: %w", len(keys)+1, err)
+			}
+			keys = append(keys, key)
+		}
+	}
+	return keys, nil
+}
+
+// LoadMasterKeys returns the keys in the file at path, or when path is
+// empty those in s, in the format of ParseMasterKeys.
+func LoadMasterKeys(s, path string) ([]MasterKey, error) {
+	if path != "" {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+		s = string(data)
+	}
+	return ParseMasterKeys(s)
+}
+
+func parseMasterKey(s string) (MasterKey, error) {
+	id, keyStr, found := strings.Cut(s, ":")
+	if !found || !keyIDPattern.MatchString(id) {
+		return MasterKey{}, ErrInvalidMasterKey
+	}
+	key, err := base64.StdEncoding.DecodeString(keyStr)
+	if err != nil || len(key) != masterKeyLength {
+		return MasterKey{}, ErrInvalidMasterKey
+	}
+	return MasterKey{ID: id, Key: key}, nil
+}
+
+// Envelope encrypts values with envelope encryption: every value gets its
+// own random data key, which encrypts it with AES-GCM and is itself
+// encrypted, or wrapped, with a master key. New values are sealed with the
+// first master key; the others are only used to open values sealed before
+// a rotation. Rewriting a value sealed with an old key re-encrypts it, after
+// which the old key can be dropped.
+type Envelope struct {
+	activeID string
+	masters  map[string]cipher.AEAD
+}
+
+// NewEnvelope returns an Envelope that seals with keys[0] and opens values
+// sealed with any of keys.
+func NewEnvelope(keys ...MasterKey) (*Envelope, error) {
+	if len(keys) == 0 {
+		return nil, ErrNoMasterKeys
+	}
+	env := &Envelope{activeID: keys[0].ID, masters: make(map[string]cipher.AEAD, len(keys))}
+	for _, k := range keys {
+		if _, exists := env.masters[k.ID]; exists {
+			return nil, fmt.Errorf("encryption key ID %q is used twice", k.ID)
+		}
+		aead, err := newAEAD(k.Key)
+		if err != nil {
+			return nil, err
+		}
+		env.masters[k.ID] = aead
+	}
+	return env, nil
+}
+
+// Seal encrypts plaintext. The empty string is returned as is, so that
+// absent values stay absent.
+func (env *Envelope) Seal(plaintext string) (string, error) {
+	if plaintext == "" {
+		return "", nil
+	}
+	dataKey := make([]byte, masterKeyLength)
+	if _, err := rand.Read(dataKey); err != nil {
+		return "", err
+	}
+	// The key ID is authenticated with the data key so that a value cannot
+	// be passed off as sealed with another master key.
+	wrapped, err := seal(env.masters[env.activeID], dataKey, []byte(env.activeID))
+	if err != nil {
+		return "", err
+	}
+	data, err := newAEAD(dataKey)
+	if err != nil {
+		return "", err
+	}
+	ciphertext, err := seal(data, []byte(plaintext), nil)
+	if err != nil {
+		return "", err
+	}
+	return strings.Join([]string{
+		sealedPrefix,
+		env.activeID,
+		base64.RawURLEncoding.EncodeToString(wrapped),
+		base64.RawURLEncoding.EncodeToString(ciphertext),
+	}, "."), nil
+}
+
+// Open decrypts a value returned by Seal.
+func (env *Envelope) Open(sealed string) (string, error) {
+	if sealed == "" {
+		return "", nil
+	}
+	parts := strings.Split(sealed, ".")
+	if len(parts) != 4 || parts[0] != sealedPrefix {
+		return "", ErrInvalidSealed
+	}
+	master, exists := env.masters[parts[1]]
+	if !exists {
+		return "", fmt.Errorf("%w: %q", ErrUnknownMasterKey, parts[1])
+	}
+	wrapped, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return "", ErrInvalidSealed
+	}
+	ciphertext, err := base64.RawURLEncoding.DecodeString(parts[3])
+	if err != nil {
+		return "", ErrInvalidSealed
+	}
+	dataKey, err := open(master, wrapped, []byte(parts[1]))
+	if err != nil {
+		return "", err
+	}
+	data, err := newAEAD(dataKey)
+	if err != nil {
+		return "", ErrInvalidSealed
+	}
+	plaintext, err := open(data, ciphertext, nil)
+	if err != nil {
+		return "", err
+	}
+	return string(plaintext), nil
+}
+
+func newAEAD(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// seal encrypts plaintext with a random nonce, which it puts in front of
+// the ciphertext.
+func seal(aead cipher.AEAD, plaintext, additional []byte) ([]byte, error) {
+	nonce := make([]byte, aead.NonceSize(), aead.NonceSize()+len(plaintext)+aead.Overhead())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+	return aead.Seal(nonce, nonce, plaintext, additional), nil
+}
+
+func open(aead cipher.AEAD, sealed, additional []byte) ([]byte, error) {
+	if len(sealed) < aead.NonceSize() {
+		return nil, ErrInvalidSealed
+	}
+	nonce, ciphertext := sealed[:aead.NonceSize()], sealed[aead.NonceSize():]
+	plaintext, err := aead.Open(nil, nonce, ciphertext, additional)
+	if err != nil {
+		return nil, ErrInvalidSealed
+	}
+	return plaintext, nil
+}
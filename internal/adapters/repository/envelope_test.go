@@ -0,0 +1,72 @@
+package repository
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestEnvelope(t *testing.T) {
+	old, current := NewMasterKey(), NewMasterKey()
+	before, err := NewEnvelope(old)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	sealedBefore, err := before.Seal("door code 1234")
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if strings.Contains(sealedBefore, "1234") || !strings.Contains(sealedBefore, old.ID) {
+		t.Fatalf("expected ciphertext tagged with the key ID, got %q", sealedBefore)
+	}
+	if again, _ := before.Seal("door code 1234"); again == sealedBefore {
+		t.Errorf("expected every seal to use a fresh data key")
+	}
+
+	rotated, err := NewEnvelope(current, old)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if opened, err := rotated.Open(sealedBefore); err != nil || opened != "door code 1234" {
+		t.Errorf("expected a value sealed before the rotation to open, got %q, %v", opened, err)
+	}
+	sealedAfter, _ := rotated.Seal("door code 1234")
+	if !strings.HasPrefix(sealedAfter, sealedPrefix+"."+current.ID+".") {
+		t.Errorf("expected new values to be sealed with the first key, got %q", sealedAfter)
+	}
+
+	if _, err := before.Open(sealedAfter); !errors.Is(err, ErrUnknownMasterKey) {
+		t.Errorf("expected ErrUnknownMasterKey, got %v", err)
+	}
+	tampered := sealedAfter[:len(sealedAfter)-2] + "AA"
+	if _, err := rotated.Open(tampered); !errors.Is(err, ErrInvalidSealed) {
+		t.Errorf("expected ErrInvalidSealed for a tampered value, got %v", err)
+	}
+	// Swapping the key ID must not let a value through under another key.
+	swapped := strings.Replace(sealedBefore, old.ID, current.ID, 1)
+	if _, err := rotated.Open(swapped); !errors.Is(err, ErrInvalidSealed) {
+		t.Errorf("expected ErrInvalidSealed for a swapped key ID, got %v", err)
+	}
+	if sealed, _ := rotated.Seal(""); sealed != "" {
+		t.Errorf("expected empty values to stay empty, got %q", sealed)
+	}
+	if _, err := NewEnvelope(); !errors.Is(err, ErrNoMasterKeys) {
+		t.Errorf("expected ErrNoMasterKeys, got %v", err)
+	}
+}
+
+func TestParseMasterKeys(t *testing.T) {
+	a, b := NewMasterKey(), NewMasterKey()
+	keys, err := ParseMasterKeys("# newest first\n" + a.String() + "\n\n" + b.String())
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if len(keys) != 2 || keys[0].ID != a.ID || keys[1].ID != b.ID {
+		t.Fatalf("expected both keys in order, got %+v", keys)
+	}
+	for _, s := range []string{"no-separator", "bad id:" + strings.SplitN(a.String(), ":", 2)[1], "short:c2hvcnQ="} {
+		if _, err := ParseMasterKeys(s); !errors.Is(err, ErrInvalidMasterKey) {
+			t.Errorf("%q: expected ErrInvalidMasterKey, got %v", s, err)
+		}
+	}
+}
@@ -0,0 +1,54 @@
+package repository
+
+import (
+	"container/heap"
+	"time"
+)
+
+// expiryEntry records when a location expires under the retention policy,
+// or in the purge heap when it was deleted, so the cleanup loop can find the
+// records that are due without scanning the whole shard.
+type expiryEntry struct {
+	id     string
+	userID string
+	at     time.Time
+}
+
+// expiryHeap is a min-heap of expiry entries ordered by at.
+type expiryHeap struct {
+	entries expiryEntries
+}
+
+func (h *expiryHeap) Len() int {
+	return len(h.entries)
+}
+
+func (h *expiryHeap) push(e expiryEntry) {
+	heap.Push(&h.entries, e)
+}
+
+func (h *expiryHeap) peek() expiryEntry {
+	return h.entries[0]
+}
+
+func (h *expiryHeap) pop() expiryEntry {
+	return heap.Pop(&h.entries).(expiryEntry)
+}
+
+type expiryEntries []expiryEntry
+
+func (e expiryEntries) Len() int           { return len(e) }
+func (e expiryEntries) Less(i, j int) bool { return e[i].at.Before(e[j].at) }
+func (e expiryEntries) Swap(i, j int)      { e[i], e[j] = e[j], e[i] }
+
+func (e *expiryEntries) Push(x any) {
+	*e = append(*e, x.(expiryEntry))
+}
+
+func (e *expiryEntries) Pop() any {
+	old := *e
+	n := len(old)
+	item := old[n-1]
+	*e = old[:n-1]
+	return item
+}
@@ -0,0 +1,42 @@
+package repository
+
+import (
+	"context"
+	"slices"
+	"sync"
+
+	"github.com/lafetz/weavo/internal/core/domain"
+)
+
+// InMemoryHistoryRepo keeps the change history of locations in memory for
+// the life of the process.
+type InMemoryHistoryRepo struct {
+	mu      sync.RWMutex
+	entries map[string][]domain.HistoryEntry // location id -> entries, oldest first
+}
+
+func NewInMemoryHistoryRepo() *InMemoryHistoryRepo {
+	return &InMemoryHistoryRepo{entries: make(map[string][]domain.HistoryEntry)}
+}
+
+func (repo *InMemoryHistoryRepo) AppendHistory(ctx context.Context, entry domain.HistoryEntry) error {
+	entry.Snapshot.Tags = slices.Clone(entry.Snapshot.Tags)
+	entry.Snapshot.Collections = slices.Clone(entry.Snapshot.Collections)
+	repo.mu.Lock()
+	repo.entries[entry.LocationID] = append(repo.entries[entry.LocationID], entry)
+	repo.mu.Unlock()
+	return nil
+}
+
+func (repo *InMemoryHistoryRepo) GetHistory(ctx context.Context, locationID string) ([]domain.HistoryEntry, error) {
+	repo.mu.RLock()
+	defer repo.mu.RUnlock()
+	return append([]domain.HistoryEntry{}, repo.entries[locationID]...), nil
+}
+
+func (repo *InMemoryHistoryRepo) DeleteHistory(ctx context.Context, locationID string) error {
+	repo.mu.Lock()
+	delete(repo.entries, locationID)
+	repo.mu.Unlock()
+	return nil
+}
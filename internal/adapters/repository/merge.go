@@ -0,0 +1,126 @@
+package repository
+
+import (
+	"cmp"
+	"context"
+	"maps"
+	"slices"
+	"strings"
+
+	"github.com/lafetz/weavo/internal/core/domain"
+	"github.com/lafetz/weavo/internal/core/service/location"
+)
+
+// lockUsers write-locks the shards of both users, in shard order so that
+// two merges in opposite directions cannot deadlock, and returns the
+// matching unlock.
+func (repo *InMemoryLocationRepo) lockUsers(a, b string) func() {
+	i, j := shardIndex(a), shardIndex(b)
+	if i == j {
+		repo.shards[i].mu.Lock()
+		return repo.shards[i].mu.Unlock
+	}
+	if i > j {
+		i, j = j, i
+	}
+	repo.shards[i].mu.Lock()
+	repo.shards[j].mu.Lock()
+	return func() {
+		repo.shards[j].mu.Unlock()
+		repo.shards[i].mu.Unlock()
+	}
+}
+
+// MergeUser moves every record of fromUserID to toUserID while holding the
+// write locks of both users' shards, so other callers see either all of
+// fromUserID's records or none of them under toUserID. It cannot run inside
+// a transaction.
+func (repo *InMemoryLocationRepo) MergeUser(ctx context.Context, fromUserID, toUserID string, maxLocations int, isDuplicate func(a, b domain.Location) bool) (location.MergeResult, error) {
+	if _, ok := ctx.Value(txKey{}).(*tx); ok {
+		return location.MergeResult{}, errNestedTx
+	}
+	if fromUserID == toUserID {
+		return location.MergeResult{}, nil
+	}
+	from, to := repo.userShard(fromUserID), repo.userShard(toUserID)
+	unlock := repo.lockUsers(fromUserID, toUserID)
+	defer unlock()
+
+	var result location.MergeResult
+	now := repo.now()
+	existing := slices.Collect(maps.Values(to.users[toUserID]))
+	live := slices.SortedFunc(maps.Values(from.users[fromUserID]), func(a, b domain.Location) int {
+		return cmp.Or(cmp.Compare(a.Position, b.Position), strings.Compare(a.Id, b.Id))
+	})
+	position := to.nextPosition(toUserID)
+	count := len(existing)
+	for _, loc := range live {
+		from.remove(fromUserID, loc.Id)
+		loc.UserID = toUserID
+		loc.Version++
+		duplicate := slices.ContainsFunc(existing, func(e domain.Location) bool { return isDuplicate(loc, e) })
+		if duplicate || (maxLocations > 0 && count >= maxLocations) {
+			loc.DeletedAt = now
+			to.putTrashed(loc)
+			to.purge.push(expiryEntry{id: loc.Id, userID: toUserID, at: now})
+			if duplicate {
+				result.Duplicates++
+			} else {
+				result.Overflow++
+			}
+		} else {
+			loc.Position = position
+			position++
+			count++
+			to.put(loc)
+			result.Locations++
+		}
+		repo.moveOwner(to, loc)
+	}
+	for _, loc := range from.trash[fromUserID] {
+		loc.UserID = toUserID
+		loc.Version++
+		to.putTrashed(loc)
+		to.purge.push(expiryEntry{id: loc.Id, userID: toUserID, at: loc.DeletedAt})
+		repo.moveOwner(to, loc)
+	}
+	delete(from.trash, fromUserID)
+
+	for id, c := range from.collections[fromUserID] {
+		c.UserID = toUserID
+		putFor(to.collections, toUserID, id, c)
+		repo.setOwner(id, toUserID)
+		result.Collections++
+	}
+	delete(from.collections, fromUserID)
+	for token, share := range from.shares[fromUserID] {
+		share.UserID = toUserID
+		putFor(to.shares, toUserID, token, share)
+		repo.setOwner(token, toUserID)
+		result.Shares++
+	}
+	delete(from.shares, fromUserID)
+	return result, nil
+}
+
+// moveOwner points the owner index at loc's new owner and schedules loc
+// for the retention policy as it now applies. The entries left in the heaps
+// under the old owner no longer match any record and are discarded by the
+// cleanup loop. The caller holds s.mu.
+func (repo *InMemoryLocationRepo) moveOwner(s *locationShard, loc domain.Location) {
+	if expiresAt := repo.expiresAt(loc); !expiresAt.IsZero() {
+		s.expiry.push(expiryEntry{id: loc.Id, userID: loc.UserID, at: expiresAt})
+	}
+	repo.setOwner(loc.Id, loc.UserID)
+}
+
+// putFor stores v under userID and key in m, creating the user's map if
+// needed.
+func putFor[V any](m map[string]map[string]V, userID, key string, v V) {
+	userValues, exists := m[userID]
+	if !exists {
+		userValues = make(map[string]V)
+		m[userID] = userValues
+	}
+	userValues[key] = v
+}
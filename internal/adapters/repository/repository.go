@@ -2,6 +2,8 @@ package repository
 
 import (
 	"context"
+	"hash/fnv"
+	"sort"
 	"sync"
 	"time"
 
@@ -10,51 +12,258 @@ import (
 	"github.com/lafetz/weavo/internal/core/service/location"
 )
 
+const (
+	// shardCount is the number of independently locked partitions. Users are
+	// assigned to a shard by hashing their ID, so one user's records never
+	// span shards.
+	shardCount = 64
+	// cleanupBatch bounds how many records a single cleanup step removes
+	// while holding a shard's write lock.
+	cleanupBatch = 256
+	// defaultPurgeWindow is how long deleted locations stay in the trash
+	// unless WithPurgeWindow says otherwise.
+	defaultPurgeWindow = 7 * 24 * time.Hour
+	// defaultCleanupInterval is how often RunCleanup looks for expired
+	// records unless WithCleanupInterval says otherwise.
+	defaultCleanupInterval = time.Minute
+)
+
+type locationShard struct {
+	mu          sync.RWMutex
+	users       map[string]map[string]domain.Location   // userID -> id -> location
+	spatial     map[string]spatialIndex                 // userID -> index
+	collections map[string]map[string]domain.Collection // userID -> id -> collection
+	trash       map[string]map[string]domain.Location   // userID -> id -> deleted location
+	shares      map[string]map[string]domain.Share      // userID -> token -> share
+	expiry      expiryHeap                              // by expiry, for the retention policy
+	purge       expiryHeap                              // by deletion, for the purge window
+}
+
+// put stores loc in the user and spatial indexes. The caller holds s.mu.
+func (s *locationShard) put(loc domain.Location) {
+	userLocs, exists := s.users[loc.UserID]
+	if !exists {
+		userLocs = make(map[string]domain.Location)
+		s.users[loc.UserID] = userLocs
+		s.spatial[loc.UserID] = make(spatialIndex)
+	}
+	if old, exists := userLocs[loc.Id]; exists {
+		s.spatial[loc.UserID].remove(old.Id, old.Coordinates)
+	}
+	userLocs[loc.Id] = loc
+	s.spatial[loc.UserID].add(loc.Id, loc.Coordinates)
+}
+
+// nextPosition returns the position that places a new location at the end
+// of the user's list. The caller holds s.mu.
+func (s *locationShard) nextPosition(userID string) int {
+	next := 0
+	for _, loc := range s.users[userID] {
+		if loc.Position >= next {
+			next = loc.Position + 1
+		}
+	}
+	return next
+}
+
+// remove drops a location from the user and spatial indexes. The caller
+// holds s.mu.
+func (s *locationShard) remove(userID, id string) (domain.Location, bool) {
+	userLocs := s.users[userID]
+	loc, exists := userLocs[id]
+	if !exists {
+		return domain.Location{}, false
+	}
+	delete(userLocs, id)
+	s.spatial[userID].remove(id, loc.Coordinates)
+	if len(userLocs) == 0 {
+		delete(s.users, userID)
+		delete(s.spatial, userID)
+	}
+	return loc, true
+}
+
+// trashed returns the user's deleted location with the given id. The caller
+// holds s.mu.
+func (s *locationShard) trashed(userID, id string) (domain.Location, bool) {
+	loc, exists := s.trash[userID][id]
+	return loc, exists
+}
+
+// putTrashed stores a deleted location. The caller holds s.mu.
+func (s *locationShard) putTrashed(loc domain.Location) {
+	userTrash, exists := s.trash[loc.UserID]
+	if !exists {
+		userTrash = make(map[string]domain.Location)
+		s.trash[loc.UserID] = userTrash
+	}
+	userTrash[loc.Id] = loc
+}
+
+// removeTrashed drops a deleted location for good. The caller holds s.mu.
+func (s *locationShard) removeTrashed(userID, id string) {
+	userTrash := s.trash[userID]
+	delete(userTrash, id)
+	if len(userTrash) == 0 {
+		delete(s.trash, userID)
+	}
+}
+
+type ownerShard struct {
+	mu     sync.RWMutex
+	owners map[string]string // location or collection id, or share token -> userID
+}
+
+// InMemoryLocationRepo keeps every record in process memory. SaveSnapshot
+// writes them to disk with the notes encrypted.
 type InMemoryLocationRepo struct {
-	mu            sync.RWMutex
-	locations     map[string]domain.Location
-	dataRetention time.Duration
+	shards          [shardCount]*locationShard
+	owners          [shardCount]*ownerShard
+	retention       domain.RetentionPolicy
+	isRegistered    func(userID string) bool
+	purgeWindow     time.Duration
+	cleanupInterval time.Duration
+	now             func() time.Time
+
+	removeMu  sync.RWMutex
+	onRemoved []func(ids []string)
 }
 
-func NewInMemoryLocationRepo(dataRetention time.Duration) *InMemoryLocationRepo {
+type Option func(*InMemoryLocationRepo)
+
+// WithPurgeWindow sets how long deleted locations can be restored from the
+// trash before the cleanup loop removes them permanently.
+func WithPurgeWindow(d time.Duration) Option {
+	return func(repo *InMemoryLocationRepo) {
+		repo.purgeWindow = d
+	}
+}
+
+// WithClock sets the source of the current time, for tests.
+func WithClock(now func() time.Time) Option {
+	return func(repo *InMemoryLocationRepo) {
+		repo.now = now
+	}
+}
+
+// NewInMemoryLocationRepo returns an empty repository that removes
+// locations as retention dictates. Nothing is removed until RunCleanup is
+// started.
+func NewInMemoryLocationRepo(retention domain.RetentionPolicy, opts ...Option) *InMemoryLocationRepo {
 	repo := &InMemoryLocationRepo{
-		locations:     make(map[string]domain.Location),
-		dataRetention: dataRetention,
+		retention:       retention,
+		isRegistered:    func(string) bool { return false },
+		purgeWindow:     defaultPurgeWindow,
+		cleanupInterval: defaultCleanupInterval,
+		now:             time.Now,
+	}
+	for _, opt := range opts {
+		opt(repo)
+	}
+	for i := range repo.shards {
+		repo.shards[i] = &locationShard{
+			users:       make(map[string]map[string]domain.Location),
+			spatial:     make(map[string]spatialIndex),
+			collections: make(map[string]map[string]domain.Collection),
+			trash:       make(map[string]map[string]domain.Location),
+			shares:      make(map[string]map[string]domain.Share),
+		}
+		repo.owners[i] = &ownerShard{owners: make(map[string]string)}
 	}
-	go repo.cleanupExpiredLocations()
 	return repo
 }
 
+func shardIndex(key string) int {
+	h := fnv.New32a()
+	h.Write([]byte(key))
+	return int(h.Sum32() % shardCount)
+}
+
+func (repo *InMemoryLocationRepo) userShard(userID string) *locationShard {
+	return repo.shards[shardIndex(userID)]
+}
+
+func (repo *InMemoryLocationRepo) ownerShard(id string) *ownerShard {
+	return repo.owners[shardIndex(id)]
+}
+
+func (repo *InMemoryLocationRepo) owner(id string) (string, bool) {
+	o := repo.ownerShard(id)
+	o.mu.RLock()
+	defer o.mu.RUnlock()
+	userID, exists := o.owners[id]
+	return userID, exists
+}
+
+func (repo *InMemoryLocationRepo) setOwner(id, userID string) {
+	o := repo.ownerShard(id)
+	o.mu.Lock()
+	o.owners[id] = userID
+	o.mu.Unlock()
+}
+
+func (repo *InMemoryLocationRepo) removeOwner(id string) {
+	o := repo.ownerShard(id)
+	o.mu.Lock()
+	delete(o.owners, id)
+	o.mu.Unlock()
+}
+
 func (repo *InMemoryLocationRepo) CreateLocation(ctx context.Context, loc domain.Location) (domain.Location, error) {
-	repo.mu.Lock()
-	defer repo.mu.Unlock()
 	loc.Id = uuid.New().String()
-	loc.CreatedAt = time.Now()
-	repo.locations[loc.Id] = loc
-	return loc, nil
+	loc.CreatedAt = repo.now()
+	loc.AccessedAt = loc.CreatedAt
+	loc.Version = 1
+
+	s := repo.userShard(loc.UserID)
+	unlock := s.lock(ctx)
+	loc.Position = s.nextPosition(loc.UserID)
+	s.put(loc)
+	if expiresAt := repo.expiresAt(loc); !expiresAt.IsZero() {
+		s.expiry.push(expiryEntry{id: loc.Id, userID: loc.UserID, at: expiresAt})
+	}
+	unlock()
+
+	repo.setOwner(loc.Id, loc.UserID)
+	return repo.withExpiry(loc), nil
 }
 
 func (repo *InMemoryLocationRepo) GetLocation(ctx context.Context, id string) (domain.Location, error) {
-	repo.mu.RLock()
-	defer repo.mu.RUnlock()
-	loc, exists := repo.locations[id]
+	userID, exists := repo.owner(id)
+	if !exists {
+		return domain.Location{}, location.ErrLocationNotFound
+	}
+	s := repo.userShard(userID)
+	unlock := repo.accessLock(ctx, s)
+	defer unlock()
+	loc, exists := s.users[userID][id]
 	if !exists {
 		return domain.Location{}, location.ErrLocationNotFound
 	}
-	return loc, nil
+	return repo.withExpiry(repo.touch(ctx, s, loc)), nil
 }
 
 func (repo *InMemoryLocationRepo) GetLocations(ctx context.Context, userID string, filter location.Filter) ([]domain.Location, domain.Metadata, error) {
-	repo.mu.RLock()
-	defer repo.mu.RUnlock()
-	locations := []domain.Location{}
-	for _, loc := range repo.locations {
-		if loc.UserID == userID {
+	s := repo.userShard(userID)
+	unlock := repo.accessLock(ctx, s)
+	defer unlock()
+	userLocs := s.users[userID]
+	locations := make([]domain.Location, 0, len(userLocs))
+	for _, loc := range userLocs {
+		if filter.Matches(loc) {
 			locations = append(locations, loc)
 		}
 	}
+	filter.Sort(locations)
 
 	totalRecords := len(locations)
+	if filter.Limit > 0 {
+		return repo.touchAll(ctx, s, filter.Window(locations)), domain.Metadata{PageSize: int32(filter.Limit), TotalRecords: int32(totalRecords)}, nil
+	}
+	if filter.Page < 1 || filter.PageSize < 1 {
+		return nil, domain.Metadata{}, location.ErrInvalidPage
+	}
+
 	start := filter.PageSize * (filter.Page - 1)
 	end := start + filter.PageSize
 	if start > totalRecords {
@@ -64,47 +273,181 @@ func (repo *InMemoryLocationRepo) GetLocations(ctx context.Context, userID strin
 		end = totalRecords
 	}
 
-	paginatedLocations := locations[start:end]
-	metadata := domain.CalculateMetadata(int32(totalRecords), int32(filter.Page), int32(filter.PageSize))
+	paginatedLocations := repo.touchAll(ctx, s, locations[start:end])
+	metadata := domain.CalculateMetadata(int32(totalRecords), int32(start), int32(filter.PageSize))
 
 	return paginatedLocations, metadata, nil
 }
 
+func (repo *InMemoryLocationRepo) CountLocations(ctx context.Context, userID string) (int, error) {
+	s := repo.userShard(userID)
+	unlock := s.rlock(ctx)
+	defer unlock()
+	return len(s.users[userID]), nil
+}
+
 func (repo *InMemoryLocationRepo) UpdateLocation(ctx context.Context, loc domain.Location) (domain.Location, error) {
-	repo.mu.Lock()
-	defer repo.mu.Unlock()
-	el, exists := repo.locations[loc.Id]
+	userID, exists := repo.owner(loc.Id)
 	if !exists {
 		return domain.Location{}, location.ErrLocationNotFound
 	}
+	s := repo.userShard(userID)
+	unlock := s.lock(ctx)
+	defer unlock()
+	el, exists := s.users[userID][loc.Id]
+	if !exists {
+		return domain.Location{}, location.ErrLocationNotFound
+	}
+	if loc.Version != 0 && loc.Version != el.Version {
+		return domain.Location{}, location.ErrVersionMismatch
+	}
+	// Replace every user-editable field; identity, ownership, membership
+	// and ordering are managed elsewhere.
 	el.Nickname = loc.Nickname
 	el.Notes = loc.Notes
-	loc.CreatedAt = el.CreatedAt
-	repo.locations[loc.Id] = el
-	return loc, nil
+	el.City = loc.City
+	el.Coordinates = loc.Coordinates
+	el.Tags = loc.Tags
+	el.Pinned = loc.Pinned
+	el.Version++
+	s.put(el)
+	return repo.withExpiry(repo.touch(ctx, s, el)), nil
+}
+
+func (repo *InMemoryLocationRepo) DeleteLocation(ctx context.Context, id string, version int64) (domain.Location, error) {
+	userID, exists := repo.owner(id)
+	if !exists {
+		return domain.Location{}, location.ErrLocationNotFound
+	}
+	s := repo.userShard(userID)
+	unlock := s.lock(ctx)
+	loc, exists := s.users[userID][id]
+	if !exists {
+		unlock()
+		return domain.Location{}, location.ErrLocationNotFound
+	}
+	if version != 0 && version != loc.Version {
+		unlock()
+		return domain.Location{}, location.ErrVersionMismatch
+	}
+	// Deleted locations move to the trash; the owner index keeps pointing
+	// at them until they are purged.
+	s.remove(userID, id)
+	loc.DeletedAt = repo.now()
+	loc.Version++
+	s.putTrashed(loc)
+	s.purge.push(expiryEntry{id: loc.Id, userID: userID, at: loc.DeletedAt})
+	unlock()
+	return repo.withExpiry(loc), nil
 }
 
-func (repo *InMemoryLocationRepo) DeleteLocation(ctx context.Context, id string) error {
-	repo.mu.Lock()
-	defer repo.mu.Unlock()
-	_, exists := repo.locations[id]
+func (repo *InMemoryLocationRepo) GetTrashedLocations(ctx context.Context, userID string) ([]domain.Location, error) {
+	s := repo.userShard(userID)
+	runlock := s.rlock(ctx)
+	locations := make([]domain.Location, 0, len(s.trash[userID]))
+	for _, loc := range s.trash[userID] {
+		locations = append(locations, repo.withExpiry(loc))
+	}
+	runlock()
+
+	sort.Slice(locations, func(i, j int) bool {
+		if !locations[i].DeletedAt.Equal(locations[j].DeletedAt) {
+			return locations[i].DeletedAt.After(locations[j].DeletedAt)
+		}
+		return locations[i].Id < locations[j].Id
+	})
+	return locations, nil
+}
+
+func (repo *InMemoryLocationRepo) RestoreLocation(ctx context.Context, userID, id string) (domain.Location, error) {
+	s := repo.userShard(userID)
+	unlock := s.lock(ctx)
+	defer unlock()
+	loc, exists := s.trashed(userID, id)
 	if !exists {
-		return location.ErrLocationNotFound
+		return domain.Location{}, location.ErrLocationNotFound
+	}
+	s.removeTrashed(userID, id)
+	loc.DeletedAt = time.Time{}
+	loc.Position = s.nextPosition(userID)
+	loc.Version++
+	s.put(loc)
+	return repo.withExpiry(repo.touch(ctx, s, loc)), nil
+}
+
+func (repo *InMemoryLocationRepo) ReorderLocations(ctx context.Context, userID string, ids []string) error {
+	s := repo.userShard(userID)
+	unlock := s.lock(ctx)
+	defer unlock()
+	userLocs := s.users[userID]
+	for _, id := range ids {
+		if _, exists := userLocs[id]; !exists {
+			return location.ErrLocationNotFound
+		}
+	}
+
+	listed := make(map[string]bool, len(ids))
+	for _, id := range ids {
+		listed[id] = true
+	}
+	rest := make([]domain.Location, 0, len(userLocs)-len(ids))
+	for _, loc := range userLocs {
+		if !listed[loc.Id] {
+			rest = append(rest, loc)
+		}
+	}
+	sort.Slice(rest, func(i, j int) bool {
+		if rest[i].Position != rest[j].Position {
+			return rest[i].Position < rest[j].Position
+		}
+		return rest[i].Id < rest[j].Id
+	})
+
+	setPosition := func(loc domain.Location, position int) {
+		if loc.Position != position {
+			loc.Position = position
+			loc.Version++
+			userLocs[loc.Id] = loc
+		}
+	}
+	for i, id := range ids {
+		setPosition(userLocs[id], i)
+	}
+	for i, loc := range rest {
+		setPosition(loc, len(ids)+i)
 	}
-	delete(repo.locations, id)
 	return nil
 }
 
-func (repo *InMemoryLocationRepo) cleanupExpiredLocations() {
-	ticker := time.NewTicker(repo.dataRetention)
-	for {
-		<-ticker.C
-		repo.mu.Lock()
-		for id, loc := range repo.locations {
-			if time.Since(loc.CreatedAt) > repo.dataRetention {
-				delete(repo.locations, id)
-			}
+func (repo *InMemoryLocationRepo) FindNearby(ctx context.Context, userID string, query location.NearbyQuery) ([]location.NearbyLocation, error) {
+	box := domain.BoundingBoxAround(query.Center, query.RadiusKm)
+	s := repo.userShard(userID)
+	unlock := repo.accessLock(ctx, s)
+	defer unlock()
+	userLocs := s.users[userID]
+	results := []location.NearbyLocation{}
+	for _, id := range s.spatial[userID].candidates(box) {
+		loc := userLocs[id]
+		if query.Box != nil && !query.Box.Contains(loc.Coordinates) {
+			continue
+		}
+		distance := domain.DistanceKm(query.Center, loc.Coordinates)
+		if distance <= query.RadiusKm {
+			results = append(results, location.NearbyLocation{Location: loc, DistanceKm: distance})
+		}
+	}
+
+	sort.Slice(results, func(i, j int) bool {
+		if results[i].DistanceKm != results[j].DistanceKm {
+			return results[i].DistanceKm < results[j].DistanceKm
 		}
-		repo.mu.Unlock()
+		return results[i].Location.Id < results[j].Location.Id
+	})
+	if query.Limit > 0 && len(results) > query.Limit {
+		results = results[:query.Limit]
+	}
+	for i := range results {
+		results[i].Location = repo.withExpiry(repo.touch(ctx, s, results[i].Location))
 	}
+	return results, nil
 }
@@ -3,15 +3,18 @@ package repository
 import (
 	"context"
 	"errors"
+	"fmt"
+	"sync"
 	"testing"
 	"time"
 
+	"github.com/google/uuid"
 	"github.com/lafetz/weavo/internal/core/domain"
 	"github.com/lafetz/weavo/internal/core/service/location"
 )
 
 func TestCreateLocation(t *testing.T) {
-	repo := NewInMemoryLocationRepo(24 * time.Hour)
+	repo := NewInMemoryLocationRepo(domain.RetentionPolicy{Period: 24 * time.Hour})
 	location := domain.Location{
 		Id:       "1",
 		UserID:   "user1",
@@ -35,7 +38,7 @@ func TestCreateLocation(t *testing.T) {
 }
 
 func TestGetLocation(t *testing.T) {
-	repo := NewInMemoryLocationRepo(24 * time.Hour)
+	repo := NewInMemoryLocationRepo(domain.RetentionPolicy{Period: 24 * time.Hour})
 	location := domain.Location{
 		Id:       "1",
 		UserID:   "user1",
@@ -63,7 +66,7 @@ func TestGetLocation(t *testing.T) {
 }
 
 func TestGetLocations(t *testing.T) {
-	repo := NewInMemoryLocationRepo(24 * time.Hour)
+	repo := NewInMemoryLocationRepo(domain.RetentionPolicy{Period: 24 * time.Hour})
 	location1 := domain.Location{
 		Id:       "1",
 		UserID:   "user1",
@@ -105,7 +108,7 @@ func TestGetLocations(t *testing.T) {
 }
 
 func TestUpdateLocation(t *testing.T) {
-	repo := NewInMemoryLocationRepo(24 * time.Hour)
+	repo := NewInMemoryLocationRepo(domain.RetentionPolicy{Period: 24 * time.Hour})
 	location := domain.Location{
 
 		UserID:   "user1",
@@ -149,7 +152,7 @@ func TestUpdateLocation(t *testing.T) {
 }
 
 func TestDeleteLocation(t *testing.T) {
-	repo := NewInMemoryLocationRepo(24 * time.Hour)
+	repo := NewInMemoryLocationRepo(domain.RetentionPolicy{Period: 24 * time.Hour})
 	loc := domain.Location{
 		Id:       "1",
 		UserID:   "user1",
@@ -165,7 +168,7 @@ func TestDeleteLocation(t *testing.T) {
 	if err != nil {
 		t.Fatalf("expected no error, got %v", err)
 	}
-	err = repo.DeleteLocation(context.Background(), loc.Id)
+	_, err = repo.DeleteLocation(context.Background(), loc.Id, 0)
 	if err != nil {
 		t.Fatalf("expected no error, got %v", err)
 	}
@@ -176,26 +179,779 @@ func TestDeleteLocation(t *testing.T) {
 	}
 }
 func TestCleanupExpiredLocations(t *testing.T) {
-	repo := NewInMemoryLocationRepo(1 * time.Second)
-	loc := domain.Location{
-		Id:        "1",
-		UserID:    "user1",
-		Notes:     "Test notes",
-		Nickname:  "Home",
-		City:      "City1",
-		CreatedAt: time.Now().Add(-25 * time.Hour), // Set CreatedAt to more than 24 hours ago
-		Coordinates: domain.Coordinates{
-			Lat: 1.0,
-			Lon: 1.0,
-		},
+	clock := newTestClock()
+	repo := NewInMemoryLocationRepo(domain.RetentionPolicy{Period: time.Hour}, WithClock(clock.Now), WithCleanupInterval(time.Millisecond))
+	loc, _ := repo.CreateLocation(context.Background(), domain.Location{UserID: "user1", City: "City1"})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		repo.RunCleanup(ctx)
+		close(done)
+	}()
+
+	clock.Advance(2 * time.Hour)
+	deadline := time.Now().Add(time.Second)
+	for {
+		if _, err := repo.GetLocation(context.Background(), loc.Id); errors.Is(err, location.ErrLocationNotFound) {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("expected the expired location to be removed")
+		}
+		time.Sleep(time.Millisecond)
 	}
-	repo.CreateLocation(context.Background(), loc)
 
-	dataRetention := 1 * time.Second
-	time.Sleep(dataRetention + 1*time.Microsecond)
+	cancel()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatalf("expected RunCleanup to stop once its context is done")
+	}
+}
 
-	_, err := repo.GetLocation(context.Background(), "1")
-	if !errors.Is(err, location.ErrLocationNotFound) {
-		t.Fatalf("expected error %v, got %v", location.ErrLocationNotFound, err)
+func TestCleanupShardIsIncremental(t *testing.T) {
+	repo := NewInMemoryLocationRepo(domain.RetentionPolicy{Period: time.Hour})
+	total := cleanupBatch + 10
+	for i := 0; i < total; i++ {
+		repo.CreateLocation(context.Background(), domain.Location{UserID: "user1", City: "City1"})
+	}
+	s := repo.userShard("user1")
+	later := time.Now().Add(2 * time.Hour)
+
+	if more := repo.cleanupShard(s, later); !more {
+		t.Fatalf("expected first cleanup step to report remaining work")
+	}
+	if remaining := len(s.users["user1"]); remaining != total-cleanupBatch {
+		t.Fatalf("expected %d locations after first step, got %d", total-cleanupBatch, remaining)
+	}
+	if more := repo.cleanupShard(s, later); more {
+		t.Fatalf("expected second cleanup step to finish")
+	}
+	if _, exists := s.users["user1"]; exists {
+		t.Fatalf("expected user index to be removed once empty")
+	}
+}
+
+func TestCleanupShardSkipsDeletedLocations(t *testing.T) {
+	repo := NewInMemoryLocationRepo(domain.RetentionPolicy{Period: time.Hour})
+	loc, _ := repo.CreateLocation(context.Background(), domain.Location{UserID: "user1"})
+	if _, err := repo.DeleteLocation(context.Background(), loc.Id, 0); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	s := repo.userShard("user1")
+	repo.cleanupShard(s, time.Now().Add(2*time.Hour))
+	if s.expiry.Len() != 0 {
+		t.Fatalf("expected stale expiry entry to be discarded, %d left", s.expiry.Len())
+	}
+}
+
+// naiveLocationRepo is the previous single-lock design, kept here as a
+// baseline for the benchmarks below.
+type naiveLocationRepo struct {
+	mu        sync.RWMutex
+	locations map[string]domain.Location
+}
+
+func (repo *naiveLocationRepo) CreateLocation(ctx context.Context, loc domain.Location) (domain.Location, error) {
+	repo.mu.Lock()
+	defer repo.mu.Unlock()
+	loc.Id = uuid.New().String()
+	loc.CreatedAt = time.Now()
+	repo.locations[loc.Id] = loc
+	return loc, nil
+}
+
+func (repo *naiveLocationRepo) GetLocations(ctx context.Context, userID string, filter location.Filter) ([]domain.Location, domain.Metadata, error) {
+	repo.mu.RLock()
+	defer repo.mu.RUnlock()
+	locations := []domain.Location{}
+	for _, loc := range repo.locations {
+		if loc.UserID == userID {
+			locations = append(locations, loc)
+		}
+	}
+	return locations, domain.Metadata{}, nil
+}
+
+type benchRepo interface {
+	CreateLocation(ctx context.Context, loc domain.Location) (domain.Location, error)
+	GetLocations(ctx context.Context, userID string, filter location.Filter) ([]domain.Location, domain.Metadata, error)
+}
+
+const (
+	benchUsers       = 10_000
+	benchLocsPerUser = 5
+	benchPageSize    = 10
+)
+
+func seedBenchRepo(repo benchRepo) []string {
+	users := make([]string, benchUsers)
+	for i := range users {
+		users[i] = fmt.Sprintf("user-%d", i)
+		for j := 0; j < benchLocsPerUser; j++ {
+			repo.CreateLocation(context.Background(), domain.Location{UserID: users[i], City: "City"})
+		}
+	}
+	return users
+}
+
+func benchmarkGetLocations(b *testing.B, repo benchRepo) {
+	users := seedBenchRepo(repo)
+	filter := location.Filter{Page: 1, PageSize: benchPageSize}
+	b.ResetTimer()
+	b.RunParallel(func(pb *testing.PB) {
+		i := 0
+		for pb.Next() {
+			repo.GetLocations(context.Background(), users[i%len(users)], filter)
+			i++
+		}
+	})
+}
+
+func benchmarkMixed(b *testing.B, repo benchRepo) {
+	users := seedBenchRepo(repo)
+	filter := location.Filter{Page: 1, PageSize: benchPageSize}
+	b.ResetTimer()
+	b.RunParallel(func(pb *testing.PB) {
+		i := 0
+		for pb.Next() {
+			userID := users[i%len(users)]
+			if i%10 == 0 {
+				repo.CreateLocation(context.Background(), domain.Location{UserID: userID, City: "City"})
+			} else {
+				repo.GetLocations(context.Background(), userID, filter)
+			}
+			i++
+		}
+	})
+}
+
+func BenchmarkGetLocations(b *testing.B) {
+	b.Run("sharded", func(b *testing.B) {
+		benchmarkGetLocations(b, NewInMemoryLocationRepo(domain.RetentionPolicy{Period: 24 * time.Hour}))
+	})
+	b.Run("naive", func(b *testing.B) {
+		benchmarkGetLocations(b, &naiveLocationRepo{locations: make(map[string]domain.Location)})
+	})
+}
+
+func BenchmarkMixedReadWrite(b *testing.B) {
+	b.Run("sharded", func(b *testing.B) {
+		benchmarkMixed(b, NewInMemoryLocationRepo(domain.RetentionPolicy{Period: 24 * time.Hour}))
+	})
+	b.Run("naive", func(b *testing.B) {
+		benchmarkMixed(b, &naiveLocationRepo{locations: make(map[string]domain.Location)})
+	})
+}
+
+func TestGetLocationsSortAndFilter(t *testing.T) {
+	repo := NewInMemoryLocationRepo(domain.RetentionPolicy{Period: 24 * time.Hour})
+	for _, nickname := range []string{"Cabin", "apartment", "Boat"} {
+		repo.CreateLocation(context.Background(), domain.Location{UserID: "user1", Nickname: nickname, City: "Oslo"})
+	}
+	repo.CreateLocation(context.Background(), domain.Location{UserID: "user1", Nickname: "Office", City: "Bergen"})
+
+	filter := location.Filter{PageSize: 10, Page: 1, SortBy: location.SortByNickname, SortDir: location.SortAsc, City: "oslo"}
+	locations, metadata, err := repo.GetLocations(context.Background(), "user1", filter)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if metadata.TotalRecords != 3 {
+		t.Fatalf("expected total records 3, got %d", metadata.TotalRecords)
+	}
+	expected := []string{"apartment", "Boat", "Cabin"}
+	for i, nickname := range expected {
+		if locations[i].Nickname != nickname {
+			t.Fatalf("expected %s at position %d, got %s", nickname, i, locations[i].Nickname)
+		}
+	}
+}
+
+func TestGetLocationsPagesDoNotOverlap(t *testing.T) {
+	repo := NewInMemoryLocationRepo(domain.RetentionPolicy{Period: 24 * time.Hour})
+	for i := 0; i < 20; i++ {
+		repo.CreateLocation(context.Background(), domain.Location{UserID: "user1", Nickname: fmt.Sprintf("loc-%d", i)})
+	}
+
+	seen := make(map[string]bool)
+	for page := 1; page <= 4; page++ {
+		filter := location.Filter{PageSize: 5, Page: page, SortBy: location.SortByCreatedAt, SortDir: location.SortDesc}
+		locations, _, _ := repo.GetLocations(context.Background(), "user1", filter)
+		for _, loc := range locations {
+			if seen[loc.Id] {
+				t.Fatalf("location %s returned on more than one page", loc.Id)
+			}
+			seen[loc.Id] = true
+		}
+	}
+	if len(seen) != 20 {
+		t.Fatalf("expected 20 distinct locations, got %d", len(seen))
+	}
+}
+
+func TestGetLocationsMetadata(t *testing.T) {
+	repo := NewInMemoryLocationRepo(domain.RetentionPolicy{Period: 24 * time.Hour})
+	for i := 0; i < 12; i++ {
+		repo.CreateLocation(context.Background(), domain.Location{UserID: "user1"})
+	}
+
+	locations, metadata, err := repo.GetLocations(context.Background(), "user1", location.Filter{Page: 2, PageSize: 5})
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if len(locations) != 5 {
+		t.Fatalf("expected 5 locations, got %d", len(locations))
+	}
+	if metadata.CurrentPage != 2 || metadata.LastPage != 3 {
+		t.Fatalf("expected page 2 of 3, got %+v", metadata)
+	}
+
+	_, _, err = repo.GetLocations(context.Background(), "user1", location.Filter{Page: 1, PageSize: 0})
+	if !errors.Is(err, location.ErrInvalidPage) {
+		t.Fatalf("expected error %v, got %v", location.ErrInvalidPage, err)
+	}
+}
+
+func TestFindNearby(t *testing.T) {
+	repo := NewInMemoryLocationRepo(domain.RetentionPolicy{Period: 24 * time.Hour})
+	places := map[string]domain.Coordinates{
+		"London":    {Lat: 51.5074, Lon: -0.1278},
+		"Reading":   {Lat: 51.4543, Lon: -0.9781},
+		"Paris":     {Lat: 48.8566, Lon: 2.3522},
+		"Fiji":      {Lat: -17.7134, Lon: 179.9},
+		"Fiji East": {Lat: -17.7134, Lon: -179.9},
+	}
+	for city, coords := range places {
+		repo.CreateLocation(context.Background(), domain.Location{UserID: "user1", City: city, Coordinates: coords})
+	}
+	repo.CreateLocation(context.Background(), domain.Location{UserID: "user2", City: "London", Coordinates: places["London"]})
+
+	t.Run("sorted by distance", func(t *testing.T) {
+		results, err := repo.FindNearby(context.Background(), "user1", location.NearbyQuery{Center: places["London"], RadiusKm: 100})
+		if err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+		if len(results) != 2 {
+			t.Fatalf("expected 2 results, got %d", len(results))
+		}
+		if results[0].Location.City != "London" || results[1].Location.City != "Reading" {
+			t.Fatalf("expected London then Reading, got %s then %s", results[0].Location.City, results[1].Location.City)
+		}
+		if results[1].DistanceKm <= results[0].DistanceKm {
+			t.Fatalf("expected increasing distances, got %f and %f", results[0].DistanceKm, results[1].DistanceKm)
+		}
+	})
+
+	t.Run("restricted to bounding box", func(t *testing.T) {
+		box := &domain.BoundingBox{MinLat: 51, MinLon: -0.5, MaxLat: 52, MaxLon: 0}
+		results, _ := repo.FindNearby(context.Background(), "user1", location.NearbyQuery{Center: places["London"], RadiusKm: 100, Box: box})
+		if len(results) != 1 || results[0].Location.City != "London" {
+			t.Fatalf("expected only London, got %+v", results)
+		}
+	})
+
+	t.Run("across the antimeridian", func(t *testing.T) {
+		results, _ := repo.FindNearby(context.Background(), "user1", location.NearbyQuery{Center: places["Fiji"], RadiusKm: 50})
+		if len(results) != 2 {
+			t.Fatalf("expected both sides of the antimeridian, got %+v", results)
+		}
+	})
+
+	t.Run("removed from the index on delete", func(t *testing.T) {
+		results, _ := repo.FindNearby(context.Background(), "user1", location.NearbyQuery{Center: places["Paris"], RadiusKm: 10})
+		if len(results) != 1 {
+			t.Fatalf("expected Paris, got %+v", results)
+		}
+		repo.DeleteLocation(context.Background(), results[0].Location.Id, 0)
+		results, _ = repo.FindNearby(context.Background(), "user1", location.NearbyQuery{Center: places["Paris"], RadiusKm: 10})
+		if len(results) != 0 {
+			t.Fatalf("expected no results after delete, got %+v", results)
+		}
+	})
+}
+
+func TestCollections(t *testing.T) {
+	repo := NewInMemoryLocationRepo(domain.RetentionPolicy{Period: 24 * time.Hour})
+	ctx := context.Background()
+
+	skiing, _ := repo.CreateCollection(ctx, domain.Collection{UserID: "user1", Name: "Ski trips"})
+	family, _ := repo.CreateCollection(ctx, domain.Collection{UserID: "user1", Name: "Family"})
+	chalet, _ := repo.CreateLocation(ctx, domain.Location{UserID: "user1", Nickname: "Chalet", Tags: []string{"snow"}})
+	repo.CreateLocation(ctx, domain.Location{UserID: "user1", Nickname: "Home"})
+
+	collections, _ := repo.GetCollections(ctx, "user1")
+	if len(collections) != 2 || collections[0].Name != "Family" {
+		t.Fatalf("expected collections ordered by name, got %+v", collections)
+	}
+
+	if err := repo.AddToCollection(ctx, skiing.Id, chalet.Id); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if err := repo.AddToCollection(ctx, family.Id, chalet.Id); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	repo.AddToCollection(ctx, skiing.Id, chalet.Id)
+
+	byCollection, _, _ := repo.GetLocations(ctx, "user1", location.Filter{Page: 1, PageSize: 10, Collection: skiing.Id})
+	if len(byCollection) != 1 || len(byCollection[0].Collections) != 2 {
+		t.Fatalf("expected chalet once in two collections, got %+v", byCollection)
+	}
+	byTag, _, _ := repo.GetLocations(ctx, "user1", location.Filter{Page: 1, PageSize: 10, Tag: "SNOW"})
+	if len(byTag) != 1 || byTag[0].Id != chalet.Id {
+		t.Fatalf("expected chalet by tag, got %+v", byTag)
+	}
+
+	if err := repo.DeleteCollection(ctx, skiing.Id); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	loc, err := repo.GetLocation(ctx, chalet.Id)
+	if err != nil {
+		t.Fatalf("expected location to survive collection delete, got %v", err)
+	}
+	if len(loc.Collections) != 1 || loc.Collections[0] != family.Id {
+		t.Fatalf("expected only the family collection to remain, got %v", loc.Collections)
+	}
+	if _, err := repo.GetCollection(ctx, skiing.Id); !errors.Is(err, location.ErrCollectionNotFound) {
+		t.Fatalf("expected error %v, got %v", location.ErrCollectionNotFound, err)
+	}
+
+	if err := repo.RemoveFromCollection(ctx, family.Id, chalet.Id); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	loc, _ = repo.GetLocation(ctx, chalet.Id)
+	if len(loc.Collections) != 0 {
+		t.Fatalf("expected no collections, got %v", loc.Collections)
+	}
+}
+
+func TestReorderLocations(t *testing.T) {
+	repo := NewInMemoryLocationRepo(domain.RetentionPolicy{Period: 24 * time.Hour})
+	ctx := context.Background()
+	var ids []string
+	for _, nickname := range []string{"a", "b", "c", "d"} {
+		loc, _ := repo.CreateLocation(ctx, domain.Location{UserID: "user1", Nickname: nickname})
+		ids = append(ids, loc.Id)
+	}
+	other, _ := repo.CreateLocation(ctx, domain.Location{UserID: "user2", Nickname: "x"})
+
+	listing := func() string {
+		locations, _, _ := repo.GetLocations(ctx, "user1", location.Filter{Page: 1, PageSize: 10, SortBy: location.SortByPosition, SortDir: location.SortAsc})
+		order := ""
+		for _, loc := range locations {
+			order += loc.Nickname
+		}
+		return order
+	}
+
+	if got := listing(); got != "abcd" {
+		t.Fatalf("expected creation order abcd, got %s", got)
+	}
+
+	if err := repo.ReorderLocations(ctx, "user1", []string{ids[2], ids[0]}); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if got := listing(); got != "cabd" {
+		t.Fatalf("expected cabd after reorder, got %s", got)
+	}
+
+	if err := repo.ReorderLocations(ctx, "user1", []string{ids[3], other.Id}); !errors.Is(err, location.ErrLocationNotFound) {
+		t.Fatalf("expected error %v for another user's location, got %v", location.ErrLocationNotFound, err)
+	}
+	if got := listing(); got != "cabd" {
+		t.Fatalf("expected failed reorder to change nothing, got %s", got)
+	}
+
+	pinned, _ := repo.GetLocation(ctx, ids[3])
+	pinned.Pinned = true
+	repo.UpdateLocation(ctx, pinned)
+	if got := listing(); got != "dcab" {
+		t.Fatalf("expected pinned location first, got %s", got)
+	}
+}
+
+func TestUpdateLocationReplacesAllFields(t *testing.T) {
+	repo := NewInMemoryLocationRepo(domain.RetentionPolicy{Period: 24 * time.Hour})
+	ctx := context.Background()
+	created, _ := repo.CreateLocation(ctx, domain.Location{
+		UserID:      "user1",
+		Nickname:    "Home",
+		City:        "London",
+		Coordinates: domain.Coordinates{Lat: 51.5074, Lon: -0.1278},
+	})
+
+	updated, err := repo.UpdateLocation(ctx, domain.Location{
+		Id:          created.Id,
+		UserID:      "user1",
+		Nickname:    "Home",
+		City:        "Paris",
+		Coordinates: domain.Coordinates{Lat: 48.8566, Lon: 2.3522},
+	})
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if updated.City != "Paris" || !updated.CreatedAt.Equal(created.CreatedAt) || updated.Position != created.Position {
+		t.Fatalf("expected stored state with new city and original metadata, got %+v", updated)
+	}
+
+	stored, _ := repo.GetLocation(ctx, created.Id)
+	if stored.City != "Paris" || stored.Coordinates.Lat != 48.8566 {
+		t.Fatalf("expected city and coordinates to be saved, got %+v", stored)
+	}
+
+	near := func(c domain.Coordinates) int {
+		results, _ := repo.FindNearby(ctx, "user1", location.NearbyQuery{Center: c, RadiusKm: 10})
+		return len(results)
+	}
+	if near(domain.Coordinates{Lat: 51.5074, Lon: -0.1278}) != 0 || near(domain.Coordinates{Lat: 48.8566, Lon: 2.3522}) != 1 {
+		t.Fatalf("expected the spatial index to follow the new coordinates")
+	}
+}
+
+func TestVersionChecks(t *testing.T) {
+	repo := NewInMemoryLocationRepo(domain.RetentionPolicy{Period: 24 * time.Hour})
+	ctx := context.Background()
+	created, _ := repo.CreateLocation(ctx, domain.Location{UserID: "user1", Nickname: "Home", City: "London"})
+	if created.Version != 1 {
+		t.Fatalf("expected new location at version 1, got %d", created.Version)
+	}
+
+	update := domain.Location{Id: created.Id, UserID: "user1", Nickname: "Home", City: "Paris", Version: created.Version}
+	updated, err := repo.UpdateLocation(ctx, update)
+	if err != nil || updated.Version != 2 {
+		t.Fatalf("expected version 2, got %d (%v)", updated.Version, err)
+	}
+	if _, err := repo.UpdateLocation(ctx, update); !errors.Is(err, location.ErrVersionMismatch) {
+		t.Fatalf("expected ErrVersionMismatch for a stale update, got %v", err)
+	}
+	if _, err := repo.DeleteLocation(ctx, created.Id, created.Version); !errors.Is(err, location.ErrVersionMismatch) {
+		t.Fatalf("expected ErrVersionMismatch for a stale delete, got %v", err)
+	}
+	if _, err := repo.DeleteLocation(ctx, created.Id, updated.Version); err != nil {
+		t.Fatalf("expected delete at current version to succeed, got %v", err)
+	}
+}
+
+func TestConcurrentConditionalUpdates(t *testing.T) {
+	repo := NewInMemoryLocationRepo(domain.RetentionPolicy{Period: 24 * time.Hour})
+	ctx := context.Background()
+	created, _ := repo.CreateLocation(ctx, domain.Location{UserID: "user1", Nickname: "Home", City: "London"})
+
+	const writers = 16
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	succeeded := 0
+	for i := 0; i < writers; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			_, err := repo.UpdateLocation(ctx, domain.Location{
+				Id:       created.Id,
+				UserID:   "user1",
+				Nickname: fmt.Sprintf("writer %d", i),
+				Version:  created.Version,
+			})
+			if err == nil {
+				mu.Lock()
+				succeeded++
+				mu.Unlock()
+			}
+		}(i)
+	}
+	wg.Wait()
+	if succeeded != 1 {
+		t.Fatalf("expected exactly one conditional update to win, got %d", succeeded)
+	}
+}
+
+func TestTrashAndRestore(t *testing.T) {
+	repo := NewInMemoryLocationRepo(domain.RetentionPolicy{Period: 24 * time.Hour})
+	ctx := context.Background()
+	first, _ := repo.CreateLocation(ctx, domain.Location{UserID: "user1", City: "London", Coordinates: domain.Coordinates{Lat: 51.5074, Lon: -0.1278}})
+	second, _ := repo.CreateLocation(ctx, domain.Location{UserID: "user1", City: "Paris"})
+
+	if _, err := repo.DeleteLocation(ctx, first.Id, first.Version); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if _, err := repo.GetLocation(ctx, first.Id); !errors.Is(err, location.ErrLocationNotFound) {
+		t.Fatalf("expected trashed location to be hidden from GetLocation, got %v", err)
+	}
+	if _, err := repo.UpdateLocation(ctx, domain.Location{Id: first.Id, UserID: "user1"}); !errors.Is(err, location.ErrLocationNotFound) {
+		t.Fatalf("expected trashed location to reject updates, got %v", err)
+	}
+	if _, err := repo.DeleteLocation(ctx, first.Id, 0); !errors.Is(err, location.ErrLocationNotFound) {
+		t.Fatalf("expected a second delete to report not found, got %v", err)
+	}
+	locs, _, _ := repo.GetLocations(ctx, "user1", location.Filter{Page: 1, PageSize: 10})
+	if len(locs) != 1 || locs[0].Id != second.Id {
+		t.Fatalf("expected only the live location to be listed, got %+v", locs)
+	}
+	nearby, _ := repo.FindNearby(ctx, "user1", location.NearbyQuery{Center: first.Coordinates, RadiusKm: 1})
+	if len(nearby) != 0 {
+		t.Fatalf("expected trashed location to be excluded from nearby results, got %+v", nearby)
+	}
+
+	trash, _ := repo.GetTrashedLocations(ctx, "user1")
+	if len(trash) != 1 || trash[0].Id != first.Id || trash[0].DeletedAt.IsZero() {
+		t.Fatalf("expected the deleted location in the trash, got %+v", trash)
+	}
+	if _, err := repo.RestoreLocation(ctx, "user2", first.Id); !errors.Is(err, location.ErrLocationNotFound) {
+		t.Fatalf("expected another user's restore to fail, got %v", err)
+	}
+
+	restored, err := repo.RestoreLocation(ctx, "user1", first.Id)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if !restored.DeletedAt.IsZero() || restored.Position <= second.Position || restored.Version <= trash[0].Version {
+		t.Fatalf("expected restored location at the end of the list with a new version, got %+v", restored)
+	}
+	if _, err := repo.GetLocation(ctx, first.Id); err != nil {
+		t.Fatalf("expected restored location to be readable, got %v", err)
+	}
+	if trash, _ := repo.GetTrashedLocations(ctx, "user1"); len(trash) != 0 {
+		t.Fatalf("expected the trash to be empty after restore, got %+v", trash)
+	}
+}
+
+func TestCleanupShardPurgesTrash(t *testing.T) {
+	repo := NewInMemoryLocationRepo(domain.RetentionPolicy{Period: 24 * time.Hour}, WithPurgeWindow(time.Hour))
+	ctx := context.Background()
+	purged, _ := repo.CreateLocation(ctx, domain.Location{UserID: "user1"})
+	restored, _ := repo.CreateLocation(ctx, domain.Location{UserID: "user1"})
+	repo.DeleteLocation(ctx, purged.Id, 0)
+	repo.DeleteLocation(ctx, restored.Id, 0)
+	repo.RestoreLocation(ctx, "user1", restored.Id)
+
+	s := repo.userShard("user1")
+	repo.cleanupShard(s, time.Now().Add(30*time.Minute))
+	if trash, _ := repo.GetTrashedLocations(ctx, "user1"); len(trash) != 1 {
+		t.Fatalf("expected the trash to be kept inside the purge window, got %+v", trash)
+	}
+
+	repo.cleanupShard(s, time.Now().Add(2*time.Hour))
+	if trash, _ := repo.GetTrashedLocations(ctx, "user1"); len(trash) != 0 {
+		t.Fatalf("expected the trash to be purged, got %+v", trash)
+	}
+	if _, exists := repo.owner(purged.Id); exists {
+		t.Fatalf("expected the purged location's owner entry to be removed")
+	}
+	if _, err := repo.GetLocation(ctx, restored.Id); err != nil {
+		t.Fatalf("expected the restored location to survive the purge, got %v", err)
+	}
+}
+
+func TestInMemoryHistoryRepo(t *testing.T) {
+	repo := NewInMemoryHistoryRepo()
+	ctx := context.Background()
+
+	entries, err := repo.GetHistory(ctx, "a")
+	if err != nil || entries == nil || len(entries) != 0 {
+		t.Fatalf("expected an empty history, got %v (%v)", entries, err)
+	}
+
+	tags := []string{"home"}
+	repo.AppendHistory(ctx, domain.HistoryEntry{LocationID: "a", Version: 1, Snapshot: domain.Location{Tags: tags}})
+	repo.AppendHistory(ctx, domain.HistoryEntry{LocationID: "a", Version: 2})
+	tags[0] = "changed"
+
+	entries, _ = repo.GetHistory(ctx, "a")
+	if len(entries) != 2 || entries[0].Version != 1 || entries[1].Version != 2 {
+		t.Fatalf("expected both entries oldest first, got %+v", entries)
+	}
+	if entries[0].Snapshot.Tags[0] != "home" {
+		t.Errorf("expected the stored snapshot to be unaffected by the caller, got %v", entries[0].Snapshot.Tags)
+	}
+	repo.DeleteHistory(ctx, "a")
+	if entries, _ := repo.GetHistory(ctx, "a"); len(entries) != 0 {
+		t.Errorf("expected the history to be deleted, got %+v", entries)
+	}
+}
+
+func TestShares(t *testing.T) {
+	repo := NewInMemoryLocationRepo(domain.RetentionPolicy{Period: time.Hour})
+	ctx := context.Background()
+
+	first, _ := repo.CreateShare(ctx, domain.Share{Token: "t1", UserID: "user1", LocationID: "a"})
+	repo.CreateShare(ctx, domain.Share{Token: "t2", UserID: "user1", CollectionID: "c"})
+	repo.CreateShare(ctx, domain.Share{Token: "t3", UserID: "user2", LocationID: "b"})
+
+	got, err := repo.GetShare(ctx, "t1")
+	if err != nil || got.LocationID != "a" || !got.CreatedAt.Equal(first.CreatedAt) {
+		t.Fatalf("expected share t1, got %+v (%v)", got, err)
+	}
+	shares, _ := repo.GetShares(ctx, "user1")
+	if len(shares) != 2 {
+		t.Fatalf("expected 2 shares for user1, got %d", len(shares))
+	}
+
+	if err := repo.DeleteShare(ctx, "t1"); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if _, err := repo.GetShare(ctx, "t1"); !errors.Is(err, location.ErrShareNotFound) {
+		t.Fatalf("expected ErrShareNotFound after deleting, got %v", err)
+	}
+	if err := repo.DeleteShare(ctx, "t1"); !errors.Is(err, location.ErrShareNotFound) {
+		t.Fatalf("expected ErrShareNotFound deleting twice, got %v", err)
+	}
+}
+
+func TestWithinTx(t *testing.T) {
+	repo := NewInMemoryLocationRepo(domain.RetentionPolicy{Period: time.Hour})
+	ctx := context.Background()
+	kept, _ := repo.CreateLocation(ctx, domain.Location{UserID: "user1", Nickname: "Kept", Coordinates: domain.Coordinates{Lat: 1, Lon: 1}})
+	deleted, _ := repo.CreateLocation(ctx, domain.Location{UserID: "user1", Nickname: "Deleted"})
+
+	var created domain.Location
+	errFail := errors.New("fail")
+	err := repo.WithinTx(ctx, "user1", func(ctx context.Context) error {
+		created, _ = repo.CreateLocation(ctx, domain.Location{UserID: "user1", Nickname: "Created"})
+		kept.Nickname = "Changed"
+		kept.Coordinates = domain.Coordinates{Lat: 50, Lon: 50}
+		if _, err := repo.UpdateLocation(ctx, kept); err != nil {
+			return err
+		}
+		if _, err := repo.DeleteLocation(ctx, deleted.Id, 0); err != nil {
+			return err
+		}
+		// Reads inside the transaction see its writes.
+		if _, err := repo.GetLocation(ctx, created.Id); err != nil {
+			return err
+		}
+		return errFail
+	})
+	if !errors.Is(err, errFail) {
+		t.Fatalf("expected fn's error, got %v", err)
+	}
+
+	if _, err := repo.GetLocation(ctx, created.Id); !errors.Is(err, location.ErrLocationNotFound) {
+		t.Errorf("expected the created location to be gone, got %v", err)
+	}
+	if loc, _ := repo.GetLocation(ctx, kept.Id); loc.Nickname != "Kept" || loc.Version != 1 {
+		t.Errorf("expected the update to be undone, got %+v", loc)
+	}
+	if loc, err := repo.GetLocation(ctx, deleted.Id); err != nil || !loc.DeletedAt.IsZero() {
+		t.Errorf("expected the delete to be undone, got %+v (%v)", loc, err)
+	}
+	if trash, _ := repo.GetTrashedLocations(ctx, "user1"); len(trash) != 0 {
+		t.Errorf("expected an empty trash, got %d", len(trash))
+	}
+	if nearby, _ := repo.FindNearby(ctx, "user1", location.NearbyQuery{Center: domain.Coordinates{Lat: 1, Lon: 1}, RadiusKm: 10}); len(nearby) != 1 {
+		t.Errorf("expected the spatial index to be restored, got %d results", len(nearby))
+	}
+
+	err = repo.WithinTx(ctx, "user1", func(ctx context.Context) error {
+		_, err := repo.CreateLocation(ctx, domain.Location{UserID: "user1", Nickname: "Committed"})
+		return err
+	})
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	locations, _, _ := repo.GetLocations(ctx, "user1", location.Filter{Page: 1, PageSize: 10})
+	if len(locations) != 3 {
+		t.Errorf("expected the committed location to be kept, got %d locations", len(locations))
+	}
+}
+
+func TestMergeUser(t *testing.T) {
+	ctx := context.Background()
+	clock := newTestClock()
+	policy := domain.RetentionPolicy{Period: time.Hour, KeepRegistered: true}
+	repo := NewInMemoryLocationRepo(policy, WithClock(clock.Now), WithRegisteredUsers(func(userID string) bool { return userID == "account" }))
+	sameCity := func(a, b domain.Location) bool { return a.City == b.City }
+
+	mine, _ := repo.CreateLocation(ctx, domain.Location{UserID: "account", City: "Paris"})
+	second, _ := repo.CreateLocation(ctx, domain.Location{UserID: "anon", City: "Oslo"})
+	first, _ := repo.CreateLocation(ctx, domain.Location{UserID: "anon", City: "Lyon"})
+	dup, _ := repo.CreateLocation(ctx, domain.Location{UserID: "anon", City: "Paris"})
+	trashed, _ := repo.CreateLocation(ctx, domain.Location{UserID: "anon", City: "Rome"})
+	repo.DeleteLocation(ctx, trashed.Id, 0)
+	repo.ReorderLocations(ctx, "anon", []string{first.Id, second.Id})
+	collection, _ := repo.CreateCollection(ctx, domain.Collection{UserID: "anon", Name: "Trips"})
+	repo.CreateShare(ctx, domain.Share{Token: "t1", UserID: "anon", LocationID: first.Id})
+
+	result, err := repo.MergeUser(ctx, "anon", "account", 0, sameCity)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	expected := location.MergeResult{Locations: 2, Duplicates: 1, Collections: 1, Shares: 1}
+	if result != expected {
+		t.Errorf("expected %+v, got %+v", expected, result)
+	}
+
+	locations, _, _ := repo.GetLocations(ctx, "account", location.Filter{SortBy: location.SortByPosition, Page: 1, PageSize: 10})
+	var order []string
+	for _, loc := range locations {
+		order = append(order, loc.Id)
+	}
+	if fmt.Sprint(order) != fmt.Sprint([]string{mine.Id, first.Id, second.Id}) {
+		t.Errorf("expected the merged locations after the account's own, in their order, got %v", order)
+	}
+	trash, _ := repo.GetTrashedLocations(ctx, "account")
+	if len(trash) != 2 {
+		t.Errorf("expected the duplicate and the trashed location in the account's trash, got %+v", trash)
+	}
+	if loc, err := repo.GetLocation(ctx, first.Id); err != nil || loc.UserID != "account" {
+		t.Errorf("expected the location to be found under the account, got %+v (%v)", loc, err)
+	}
+	if _, err := repo.RestoreLocation(ctx, "account", dup.Id); err != nil {
+		t.Errorf("expected the duplicate to be restorable, got %v", err)
+	}
+	if c, err := repo.GetCollection(ctx, collection.Id); err != nil || c.UserID != "account" {
+		t.Errorf("expected the collection to move, got %+v (%v)", c, err)
+	}
+	if share, err := repo.GetShare(ctx, "t1"); err != nil || share.UserID != "account" {
+		t.Errorf("expected the share to move, got %+v (%v)", share, err)
+	}
+	if locations, _, _ := repo.GetLocations(ctx, "anon", location.Filter{Page: 1, PageSize: 10}); len(locations) != 0 {
+		t.Errorf("expected nothing left for the anonymous user, got %+v", locations)
+	}
+
+	// The account's registration now keeps the merged locations forever.
+	clock.Advance(2 * time.Hour)
+	repo.cleanup(ctx)
+	if _, err := repo.GetLocation(ctx, first.Id); err != nil {
+		t.Errorf("expected the merged location to be kept, got %v", err)
+	}
+}
+
+func TestMergeUserOverflow(t *testing.T) {
+	ctx := context.Background()
+	repo := NewInMemoryLocationRepo(domain.RetentionPolicy{})
+	never := func(a, b domain.Location) bool { return false }
+
+	repo.CreateLocation(ctx, domain.Location{UserID: "account", City: "Paris"})
+	first, _ := repo.CreateLocation(ctx, domain.Location{UserID: "anon", City: "Lyon"})
+	last, _ := repo.CreateLocation(ctx, domain.Location{UserID: "anon", City: "Oslo"})
+
+	result, err := repo.MergeUser(ctx, "anon", "account", 2, never)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if result.Locations != 1 || result.Overflow != 1 {
+		t.Errorf("expected 1 location to move and 1 to overflow, got %+v", result)
+	}
+	if count, _ := repo.CountLocations(ctx, "account"); count != 2 {
+		t.Errorf("expected the account to stay within 2 locations, got %d", count)
+	}
+	if loc, err := repo.GetLocation(ctx, first.Id); err != nil || loc.UserID != "account" {
+		t.Errorf("expected the first location to move, got %+v (%v)", loc, err)
+	}
+	if trash, _ := repo.GetTrashedLocations(ctx, "account"); len(trash) != 1 || trash[0].Id != last.Id {
+		t.Errorf("expected the last location in the account's trash, got %+v", trash)
+	}
+}
+
+func TestMergeUserConcurrentOpposite(t *testing.T) {
+	repo := NewInMemoryLocationRepo(domain.RetentionPolicy{Period: time.Hour})
+	ctx := context.Background()
+	never := func(a, b domain.Location) bool { return false }
+	var wg sync.WaitGroup
+	for i := range 50 {
+		a, b := fmt.Sprintf("a%d", i), fmt.Sprintf("b%d", i)
+		wg.Add(2)
+		go func() { defer wg.Done(); repo.MergeUser(ctx, a, b, 0, never) }()
+		go func() { defer wg.Done(); repo.MergeUser(ctx, b, a, 0, never) }()
 	}
+	wg.Wait()
 }
@@ -0,0 +1,181 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/lafetz/weavo/internal/core/domain"
+)
+
+// WithRegisteredUsers tells the repository which users are registered, for
+// retention policies that keep their locations forever. Without it every
+// user is treated as anonymous.
+func WithRegisteredUsers(isRegistered func(userID string) bool) Option {
+	return func(repo *InMemoryLocationRepo) {
+		repo.isRegistered = isRegistered
+	}
+}
+
+// WithCleanupInterval sets how often RunCleanup looks for expired records.
+func WithCleanupInterval(d time.Duration) Option {
+	return func(repo *InMemoryLocationRepo) {
+		repo.cleanupInterval = d
+	}
+}
+
+// expiresAt returns when loc expires under the retention policy, or the
+// zero time if it is kept forever.
+func (repo *InMemoryLocationRepo) expiresAt(loc domain.Location) time.Time {
+	return repo.retention.ExpiresAt(loc, repo.isRegistered(loc.UserID))
+}
+
+// withExpiry sets loc.ExpiresAt for returning loc to a caller. Trashed
+// locations expire when they are purged, if that comes first.
+func (repo *InMemoryLocationRepo) withExpiry(loc domain.Location) domain.Location {
+	loc.ExpiresAt = repo.expiresAt(loc)
+	if !loc.DeletedAt.IsZero() {
+		purgeAt := loc.DeletedAt.Add(repo.purgeWindow)
+		if loc.ExpiresAt.IsZero() || purgeAt.Before(loc.ExpiresAt) {
+			loc.ExpiresAt = purgeAt
+		}
+	}
+	return loc
+}
+
+// touches reports whether reads with ctx record an access, which they do
+// when the retention policy measures from access, unless ctx says the read
+// does not count as one.
+func (repo *InMemoryLocationRepo) touches(ctx context.Context) bool {
+	return repo.retention.Basis == domain.RetainFromAccess && domain.CountsAsAccess(ctx)
+}
+
+// accessLock locks s for a read that touches the locations it returns.
+// Touching writes AccessedAt, so a read that touches needs the write lock.
+func (repo *InMemoryLocationRepo) accessLock(ctx context.Context, s *locationShard) func() {
+	if repo.touches(ctx) {
+		return s.lock(ctx)
+	}
+	return s.rlock(ctx)
+}
+
+// touch records that loc, one of the live locations in s, has been
+// accessed, if reads with ctx touch. The caller holds the lock from
+// accessLock. The expiry heap is not updated: the cleanup loop finds the
+// location's new expiry when its old one comes up.
+func (repo *InMemoryLocationRepo) touch(ctx context.Context, s *locationShard, loc domain.Location) domain.Location {
+	if !repo.touches(ctx) {
+		return loc
+	}
+	loc.AccessedAt = repo.now()
+	s.users[loc.UserID][loc.Id] = loc
+	return loc
+}
+
+// touchAll touches each of locs in place, as a page about to be returned,
+// and fills in their expiry. The caller holds the lock from accessLock.
+func (repo *InMemoryLocationRepo) touchAll(ctx context.Context, s *locationShard, locs []domain.Location) []domain.Location {
+	for i, loc := range locs {
+		locs[i] = repo.withExpiry(repo.touch(ctx, s, loc))
+	}
+	return locs
+}
+
+// RunCleanup removes expired locations and purges the trash every cleanup
+// interval until ctx is done.
+func (repo *InMemoryLocationRepo) RunCleanup(ctx context.Context) {
+	ticker := time.NewTicker(repo.cleanupInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			repo.cleanup(ctx)
+		}
+	}
+}
+
+// cleanup runs cleanup steps over every shard until nothing is left to
+// remove or ctx is done.
+func (repo *InMemoryLocationRepo) cleanup(ctx context.Context) {
+	for _, s := range repo.shards {
+		for ctx.Err() == nil && repo.cleanupShard(s, repo.now()) {
+		}
+	}
+}
+
+// cleanupShard removes at most cleanupBatch locations from s that have
+// expired under the retention policy or have sat in the trash longer than
+// the purge window, and reports whether more may remain. Heap entries whose
+// location is already gone are discarded; entries whose location now
+// expires later, because it has been accessed or its owner has registered,
+// are put back with the new expiry or dropped if it is kept forever.
+func (repo *InMemoryLocationRepo) cleanupShard(s *locationShard, now time.Time) bool {
+	var removed []string
+	s.mu.Lock()
+	for len(removed) < cleanupBatch && s.expiry.Len() > 0 {
+		next := s.expiry.peek()
+		if !now.After(next.at) {
+			break
+		}
+		s.expiry.pop()
+		loc, live := s.users[next.userID][next.id]
+		if !live {
+			var trashed bool
+			if loc, trashed = s.trashed(next.userID, next.id); !trashed {
+				continue
+			}
+		}
+		expiresAt := repo.expiresAt(loc)
+		switch {
+		case expiresAt.IsZero():
+			// Kept forever now; the entry is dropped.
+		case !now.After(expiresAt):
+			s.expiry.push(expiryEntry{id: next.id, userID: next.userID, at: expiresAt})
+		case live:
+			s.remove(next.userID, next.id)
+			removed = append(removed, next.id)
+		default:
+			s.removeTrashed(next.userID, next.id)
+			removed = append(removed, next.id)
+		}
+	}
+	for len(removed) < cleanupBatch && s.purge.Len() > 0 {
+		next := s.purge.peek()
+		if now.Sub(next.at) <= repo.purgeWindow {
+			break
+		}
+		s.purge.pop()
+		if loc, exists := s.trashed(next.userID, next.id); exists && loc.DeletedAt.Equal(next.at) {
+			s.removeTrashed(next.userID, next.id)
+			removed = append(removed, next.id)
+		}
+	}
+	more := len(removed) == cleanupBatch
+	s.mu.Unlock()
+
+	for _, id := range removed {
+		repo.removeOwner(id)
+	}
+	repo.notifyRemoved(removed)
+	return more
+}
+
+// OnRemove registers fn to be called with the IDs of the locations each
+// cleanup step removes permanently.
+func (repo *InMemoryLocationRepo) OnRemove(fn func(ids []string)) {
+	repo.removeMu.Lock()
+	repo.onRemoved = append(repo.onRemoved, fn)
+	repo.removeMu.Unlock()
+}
+
+func (repo *InMemoryLocationRepo) notifyRemoved(ids []string) {
+	if len(ids) == 0 {
+		return
+	}
+	repo.removeMu.RLock()
+	defer repo.removeMu.RUnlock()
+	for _, fn := range repo.onRemoved {
+		fn(ids)
+	}
+}
@@ -0,0 +1,241 @@
+package repository
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/lafetz/weavo/internal/core/domain"
+	"github.com/lafetz/weavo/internal/core/service/location"
+)
+
+// testClock is a clock for WithClock that only moves when told to.
+type testClock struct {
+	mu  sync.Mutex
+	now time.Time
+}
+
+func newTestClock() *testClock {
+	return &testClock{now: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)}
+}
+
+func (c *testClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+func (c *testClock) Advance(d time.Duration) {
+	c.mu.Lock()
+	c.now = c.now.Add(d)
+	c.mu.Unlock()
+}
+
+func TestRetentionFromAccess(t *testing.T) {
+	ctx := context.Background()
+	clock := newTestClock()
+	repo := NewInMemoryLocationRepo(domain.RetentionPolicy{Period: time.Hour, Basis: domain.RetainFromAccess}, WithClock(clock.Now))
+	used, _ := repo.CreateLocation(ctx, domain.Location{UserID: "user1", Nickname: "Used"})
+	unused, _ := repo.CreateLocation(ctx, domain.Location{UserID: "user1", Nickname: "Unused"})
+	s := repo.userShard("user1")
+
+	clock.Advance(45 * time.Minute)
+	read, err := repo.GetLocation(ctx, used.Id)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if want := clock.Now().Add(time.Hour); !read.ExpiresAt.Equal(want) {
+		t.Fatalf("expected reading to push expiry to %v, got %v", want, read.ExpiresAt)
+	}
+
+	clock.Advance(30 * time.Minute)
+	repo.cleanupShard(s, clock.Now())
+	if _, err := repo.GetLocation(ctx, unused.Id); err == nil {
+		t.Fatalf("expected the unused location to expire")
+	}
+	if _, err := repo.GetLocation(ctx, used.Id); err != nil {
+		t.Fatalf("expected the used location to be kept, got %v", err)
+	}
+	if s.expiry.Len() != 1 {
+		t.Fatalf("expected the used location to be queued again, got %d entries", s.expiry.Len())
+	}
+}
+
+func TestRetentionExemptions(t *testing.T) {
+	ctx := context.Background()
+	clock := newTestClock()
+	policy := domain.RetentionPolicy{
+		Period:         time.Hour,
+		KeepRegistered: true,
+		Overrides:      map[string]time.Duration{"vip": 0, "short": time.Minute},
+	}
+	registered := map[string]bool{}
+	var mu sync.Mutex
+	isRegistered := func(userID string) bool {
+		mu.Lock()
+		defer mu.Unlock()
+		return registered[userID]
+	}
+	repo := NewInMemoryLocationRepo(policy, WithClock(clock.Now), WithRegisteredUsers(isRegistered))
+
+	ids := map[string]string{}
+	for _, userID := range []string{"anonymous", "registering", "vip", "short"} {
+		loc, _ := repo.CreateLocation(ctx, domain.Location{UserID: userID})
+		ids[userID] = loc.Id
+		if userID == "vip" && !loc.ExpiresAt.IsZero() {
+			t.Errorf("expected no expiry for an override of 0, got %v", loc.ExpiresAt)
+		}
+		if userID == "short" && !loc.ExpiresAt.Equal(clock.Now().Add(time.Minute)) {
+			t.Errorf("expected the override to set the expiry, got %v", loc.ExpiresAt)
+		}
+	}
+	mu.Lock()
+	registered["registering"] = true
+	mu.Unlock()
+
+	clock.Advance(2 * time.Hour)
+	repo.cleanup(ctx)
+	for userID, kept := range map[string]bool{"anonymous": false, "registering": true, "vip": true, "short": false} {
+		if _, err := repo.GetLocation(ctx, ids[userID]); (err == nil) != kept {
+			t.Errorf("%s: expected kept=%t, got error %v", userID, kept, err)
+		}
+	}
+}
+
+func TestTrashedLocationsExpireWhenPurged(t *testing.T) {
+	ctx := context.Background()
+	clock := newTestClock()
+	repo := NewInMemoryLocationRepo(domain.RetentionPolicy{Period: 24 * time.Hour}, WithClock(clock.Now), WithPurgeWindow(time.Hour))
+	loc, _ := repo.CreateLocation(ctx, domain.Location{UserID: "user1"})
+	repo.DeleteLocation(ctx, loc.Id, 0)
+
+	trash, _ := repo.GetTrashedLocations(ctx, "user1")
+	if len(trash) != 1 || !trash[0].ExpiresAt.Equal(clock.Now().Add(time.Hour)) {
+		t.Fatalf("expected the trashed location to expire at the end of the purge window, got %+v", trash)
+	}
+}
+
+func TestOnRemove(t *testing.T) {
+	ctx := context.Background()
+	clock := newTestClock()
+	repo := NewInMemoryLocationRepo(domain.RetentionPolicy{}, WithClock(clock.Now), WithPurgeWindow(time.Hour))
+	var removed []string
+	repo.OnRemove(func(ids []string) { removed = append(removed, ids...) })
+	trashed, _ := repo.CreateLocation(ctx, domain.Location{UserID: "user1"})
+	repo.CreateLocation(ctx, domain.Location{UserID: "user1"})
+
+	if _, err := repo.DeleteLocation(ctx, trashed.Id, 0); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	repo.cleanup(ctx)
+	if len(removed) != 0 {
+		t.Fatalf("expected nothing to be reported while in the trash, got %v", removed)
+	}
+
+	clock.Advance(2 * time.Hour)
+	repo.cleanup(ctx)
+	if len(removed) != 1 || removed[0] != trashed.Id {
+		t.Errorf("expected %s to be reported, got %v", trashed.Id, removed)
+	}
+}
+
+func TestHistoryRemovedWithLocation(t *testing.T) {
+	ctx := context.Background()
+	clock := newTestClock()
+	repo := NewInMemoryLocationRepo(domain.RetentionPolicy{Period: 24 * time.Hour}, WithClock(clock.Now), WithPurgeWindow(time.Hour))
+	history := NewInMemoryHistoryRepo()
+	svc := location.NewService(repo, location.WithHistory(history))
+
+	trashed, _ := svc.CreateLocation(ctx, domain.Location{UserID: "user1", Notes: "door code 1234"}, true)
+	expired, _ := svc.CreateLocation(ctx, domain.Location{UserID: "user1", City: "Elsewhere"}, true)
+	if err := svc.DeleteLocation(ctx, trashed.Id, "user1", 0); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	repo.cleanup(ctx)
+	entries, _ := history.GetHistory(ctx, trashed.Id)
+	if len(entries) != 2 {
+		t.Fatalf("expected the history to be kept while in the trash, got %d entries", len(entries))
+	}
+	if deleted := entries[1].Snapshot; !deleted.DeletedAt.Equal(clock.Now()) || deleted.Version != trashed.Version+1 {
+		t.Errorf("expected the trashed location as the repository saved it, got %+v", deleted)
+	}
+
+	clock.Advance(2 * time.Hour)
+	repo.cleanup(ctx)
+	if entries, _ := history.GetHistory(ctx, trashed.Id); len(entries) != 0 {
+		t.Errorf("expected the history to be purged with the location, got %d entries", len(entries))
+	}
+	if entries, _ := history.GetHistory(ctx, expired.Id); len(entries) != 1 {
+		t.Fatalf("expected the live location's history to be kept, got %d entries", len(entries))
+	}
+
+	clock.Advance(24 * time.Hour)
+	repo.cleanup(ctx)
+	if entries, _ := history.GetHistory(ctx, expired.Id); len(entries) != 0 {
+		t.Errorf("expected the history to expire with the location, got %d entries", len(entries))
+	}
+}
+
+func TestOnlyReturnedLocationsAreTouched(t *testing.T) {
+	ctx := context.Background()
+	clock := newTestClock()
+	repo := NewInMemoryLocationRepo(domain.RetentionPolicy{Period: time.Hour, Basis: domain.RetainFromAccess}, WithClock(clock.Now))
+	svc := location.NewService(repo, location.WithQuota(location.Quota{MaxLocations: 10}))
+
+	first, _ := svc.CreateLocation(ctx, domain.Location{UserID: "user1", Nickname: "First"}, true)
+	clock.Advance(50 * time.Minute)
+	// Checking the quota counts the user's locations without touching them.
+	second, err := svc.CreateLocation(ctx, domain.Location{UserID: "user1", Nickname: "Second"}, true)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if _, err := svc.Usage(ctx, "user1"); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	// Only the page of one is touched, not every match.
+	page, _, err := repo.GetLocations(ctx, "user1", location.Filter{Limit: 1, SortBy: location.SortByPosition, SortDir: location.SortDesc})
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if len(page) != 1 || page[0].Id != second.Id {
+		t.Fatalf("expected a page of the second location, got %+v", page)
+	}
+
+	clock.Advance(20 * time.Minute)
+	repo.cleanup(ctx)
+	if _, err := repo.GetLocation(ctx, first.Id); err == nil {
+		t.Errorf("expected the first location to expire")
+	}
+	if count, _ := repo.CountLocations(ctx, "user1"); count != 1 {
+		t.Errorf("expected 1 location to be left, got %d", count)
+	}
+}
+
+func TestSharedViewsDoNotTouch(t *testing.T) {
+	ctx := context.Background()
+	clock := newTestClock()
+	repo := NewInMemoryLocationRepo(domain.RetentionPolicy{Period: time.Hour, Basis: domain.RetainFromAccess}, WithClock(clock.Now))
+	svc := location.NewService(repo)
+
+	shared, _ := svc.CreateLocation(ctx, domain.Location{UserID: "user1", City: "Paris"}, true)
+	member, _ := svc.CreateLocation(ctx, domain.Location{UserID: "user1", City: "Lyon"}, true)
+	collection, _ := svc.CreateCollection(ctx, domain.Collection{UserID: "user1", Name: "Trips"})
+	if err := svc.AddToCollection(ctx, collection.Id, member.Id, "user1"); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	locationShare, _ := svc.CreateShare(ctx, domain.Share{UserID: "user1", LocationID: shared.Id})
+	collectionShare, _ := svc.CreateShare(ctx, domain.Share{UserID: "user1", CollectionID: collection.Id})
+
+	clock.Advance(50 * time.Minute)
+	for _, share := range []domain.Share{locationShare, collectionShare} {
+		if _, err := svc.GetShared(ctx, share.Token); err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+	}
+	clock.Advance(20 * time.Minute)
+	repo.cleanup(ctx)
+	if count, _ := repo.CountLocations(ctx, "user1"); count != 0 {
+		t.Errorf("expected the shared locations to expire despite being viewed, got %d left", count)
+	}
+}
@@ -0,0 +1,81 @@
+package repository
+
+import (
+	"context"
+	"sort"
+
+	"github.com/lafetz/weavo/internal/core/domain"
+	"github.com/lafetz/weavo/internal/core/service/location"
+)
+
+func (repo *InMemoryLocationRepo) CreateShare(ctx context.Context, share domain.Share) (domain.Share, error) {
+	share.CreatedAt = repo.now()
+
+	s := repo.userShard(share.UserID)
+	unlock := s.lock(ctx)
+	userShares, exists := s.shares[share.UserID]
+	if !exists {
+		userShares = make(map[string]domain.Share)
+		s.shares[share.UserID] = userShares
+	}
+	userShares[share.Token] = share
+	unlock()
+
+	repo.setOwner(share.Token, share.UserID)
+	return share, nil
+}
+
+func (repo *InMemoryLocationRepo) GetShare(ctx context.Context, token string) (domain.Share, error) {
+	userID, exists := repo.owner(token)
+	if !exists {
+		return domain.Share{}, location.ErrShareNotFound
+	}
+	s := repo.userShard(userID)
+	runlock := s.rlock(ctx)
+	defer runlock()
+	share, exists := s.shares[userID][token]
+	if !exists {
+		return domain.Share{}, location.ErrShareNotFound
+	}
+	return share, nil
+}
+
+func (repo *InMemoryLocationRepo) GetShares(ctx context.Context, userID string) ([]domain.Share, error) {
+	s := repo.userShard(userID)
+	runlock := s.rlock(ctx)
+	shares := make([]domain.Share, 0, len(s.shares[userID]))
+	for _, share := range s.shares[userID] {
+		shares = append(shares, share)
+	}
+	runlock()
+
+	sort.Slice(shares, func(i, j int) bool {
+		if !shares[i].CreatedAt.Equal(shares[j].CreatedAt) {
+			return shares[i].CreatedAt.After(shares[j].CreatedAt)
+		}
+		return shares[i].Token < shares[j].Token
+	})
+	return shares, nil
+}
+
+func (repo *InMemoryLocationRepo) DeleteShare(ctx context.Context, token string) error {
+	userID, exists := repo.owner(token)
+	if !exists {
+		return location.ErrShareNotFound
+	}
+	s := repo.userShard(userID)
+	unlock := s.lock(ctx)
+	userShares := s.shares[userID]
+	if _, exists := userShares[token]; !exists {
+		unlock()
+		return location.ErrShareNotFound
+	}
+	delete(userShares, token)
+	if len(userShares) == 0 {
+		delete(s.shares, userID)
+	}
+	unlock()
+
+	repo.removeOwner(token)
+	return nil
+}
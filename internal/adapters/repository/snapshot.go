@@ -0,0 +1,388 @@
+package repository
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"time"
+
+	"github.com/lafetz/weavo/internal/core/domain"
+)
+
+// notesField is the history field whose old and new values are notes.
+const notesField = "notes"
+
+// locationSnapshot is the file SaveSnapshot writes. Notes are sealed; every
+// other field is stored as is.
+type locationSnapshot struct {
+	Locations   []domain.Location   `json:"locations"`
+	Trash       []domain.Location   `json:"trash"`
+	Collections []domain.Collection `json:"collections"`
+	Shares      []domain.Share      `json:"shares"`
+}
+
+// SaveSnapshot writes every record of the repository to the file at path,
+// replacing it atomically. Notes are sealed with env's current key, so
+// saving after a key rotation re-encrypts everything sealed with an older
+// one. Each shard is copied under its read lock, so the file may hold the
+// effect of a concurrent transaction on one user but not on another.
+func (repo *InMemoryLocationRepo) SaveSnapshot(path string, env *Envelope) error {
+	var snap locationSnapshot
+	for _, s := range repo.shards {
+		s.mu.RLock()
+		err := snap.add(s, env)
+		s.mu.RUnlock()
+		if err != nil {
+			return fmt.Errorf("saving locations: %w", err)
+		}
+	}
+	data, err := json.Marshal(snap)
+	if err != nil {
+		return err
+	}
+	if err := writeFileAtomic(path, data); err != nil {
+		return fmt.Errorf("saving locations: %w", err)
+	}
+	return nil
+}
+
+// add copies the records of s, sealing their notes. The caller holds s.mu.
+func (snap *locationSnapshot) add(s *locationShard, env *Envelope) error {
+	for _, userLocs := range s.users {
+		for _, loc := range userLocs {
+			sealed, err := sealLocation(loc, env)
+			if err != nil {
+				return err
+			}
+			snap.Locations = append(snap.Locations, sealed)
+		}
+	}
+	for _, userTrash := range s.trash {
+		for _, loc := range userTrash {
+			sealed, err := sealLocation(loc, env)
+			if err != nil {
+				return err
+			}
+			snap.Trash = append(snap.Trash, sealed)
+		}
+	}
+	for _, userCollections := range s.collections {
+		for _, c := range userCollections {
+			snap.Collections = append(snap.Collections, c)
+		}
+	}
+	for _, userShares := range s.shares {
+		for _, share := range userShares {
+			snap.Shares = append(snap.Shares, share)
+		}
+	}
+	return nil
+}
+
+// LoadSnapshot adds the records in the file SaveSnapshot wrote at path to
+// the repository, which should be empty. A missing file is not an error, so
+// that the first start finds an empty store.
+func (repo *InMemoryLocationRepo) LoadSnapshot(path string, env *Envelope) error {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("reading locations: %w", err)
+	}
+	var snap locationSnapshot
+	if err := json.Unmarshal(data, &snap); err != nil {
+		return fmt.Errorf("reading locations: %w", err)
+	}
+	for _, loc := range snap.Locations {
+		if loc.Notes, err = env.Open(loc.Notes); err != nil {
+			return fmt.Errorf("reading notes of location %s: %w", loc.Id, err)
+		}
+		s := repo.userShard(loc.UserID)
+		s.mu.Lock()
+		s.put(loc)
+		if expiresAt := repo.expiresAt(loc); !expiresAt.IsZero() {
+			s.expiry.push(expiryEntry{id: loc.Id, userID: loc.UserID, at: expiresAt})
+		}
+		s.mu.Unlock()
+		repo.setOwner(loc.Id, loc.UserID)
+	}
+	for _, loc := range snap.Trash {
+		if loc.Notes, err = env.Open(loc.Notes); err != nil {
+			return fmt.Errorf("reading notes of location %s: %w", loc.Id, err)
+		}
+		s := repo.userShard(loc.UserID)
+		s.mu.Lock()
+		s.putTrashed(loc)
+		s.purge.push(expiryEntry{id: loc.Id, userID: loc.UserID, at: loc.DeletedAt})
+		s.mu.Unlock()
+		repo.setOwner(loc.Id, loc.UserID)
+	}
+	for _, c := range snap.Collections {
+		s := repo.userShard(c.UserID)
+		s.mu.Lock()
+		userCollections, exists := s.collections[c.UserID]
+		if !exists {
+			userCollections = make(map[string]domain.Collection)
+			s.collections[c.UserID] = userCollections
+		}
+		userCollections[c.Id] = c
+		s.mu.Unlock()
+		repo.setOwner(c.Id, c.UserID)
+	}
+	for _, share := range snap.Shares {
+		s := repo.userShard(share.UserID)
+		s.mu.Lock()
+		userShares, exists := s.shares[share.UserID]
+		if !exists {
+			userShares = make(map[string]domain.Share)
+			s.shares[share.UserID] = userShares
+		}
+		userShares[share.Token] = share
+		s.mu.Unlock()
+		repo.setOwner(share.Token, share.UserID)
+	}
+	return nil
+}
+
+func sealLocation(loc domain.Location, env *Envelope) (domain.Location, error) {
+	sealed, err := env.Seal(loc.Notes)
+	if err != nil {
+		return domain.Location{}, err
+	}
+	loc.Notes = sealed
+	loc.Tags = slices.Clone(loc.Tags)
+	loc.Collections = slices.Clone(loc.Collections)
+	return loc, nil
+}
+
+// SaveSnapshot writes every history entry to the file at path, replacing it
+// atomically. The notes in snapshots and in changes to the notes are sealed
+// with env's current key, like in InMemoryLocationRepo.SaveSnapshot.
+func (repo *InMemoryHistoryRepo) SaveSnapshot(path string, env *Envelope) error {
+	repo.mu.RLock()
+	entries := make([]domain.HistoryEntry, 0, len(repo.entries))
+	for _, locationEntries := range repo.entries {
+		entries = append(entries, locationEntries...)
+	}
+	repo.mu.RUnlock()
+
+	for i := range entries {
+		if err := mapNotes(&entries[i], env.Seal); err != nil {
+			return fmt.Errorf("saving history: %w", err)
+		}
+	}
+	data, err := json.Marshal(entries)
+	if err != nil {
+		return err
+	}
+	if err := writeFileAtomic(path, data); err != nil {
+		return fmt.Errorf("saving history: %w", err)
+	}
+	return nil
+}
+
+// LoadSnapshot adds the entries in the file SaveSnapshot wrote at path to
+// the repository, which should be empty. A missing file is not an error.
+// Values other than notes in the changes of an entry come back as decoded
+// JSON, not as the types they were recorded with.
+func (repo *InMemoryHistoryRepo) LoadSnapshot(path string, env *Envelope) error {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("reading history: %w", err)
+	}
+	var entries []domain.HistoryEntry
+	if err := json.Unmarshal(data, &entries); err != nil {
+		return fmt.Errorf("reading history: %w", err)
+	}
+	// Entries of one location were saved oldest first and are appended in
+	// the same order.
+	for i := range entries {
+		if err := mapNotes(&entries[i], env.Open); err != nil {
+			return fmt.Errorf("reading history of location %s: %w", entries[i].LocationID, err)
+		}
+		repo.AppendHistory(context.Background(), entries[i])
+	}
+	return nil
+}
+
+// mapNotes replaces the notes in entry with f applied to them. The changes
+// are copied first, as entries are shared with the repository.
+func mapNotes(entry *domain.HistoryEntry, f func(string) (string, error)) error {
+	var err error
+	if entry.Snapshot.Notes, err = f(entry.Snapshot.Notes); err != nil {
+		return err
+	}
+	entry.Changes = slices.Clone(entry.Changes)
+	for i, c := range entry.Changes {
+		if c.Field != notesField {
+			continue
+		}
+		if old, ok := c.Old.(string); ok {
+			if entry.Changes[i].Old, err = f(old); err != nil {
+				return err
+			}
+		}
+		if new, ok := c.New.(string); ok {
+			if entry.Changes[i].New, err = f(new); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// SaveSnapshot writes the metadata of every attachment to the file at path,
+// replacing it atomically. The files themselves stay in the blob store.
+func (repo *InMemoryAttachmentRepo) SaveSnapshot(path string) error {
+	repo.mu.RLock()
+	attachments := make([]domain.Attachment, 0, len(repo.attachments))
+	for _, ids := range repo.byLocation {
+		for _, id := range ids {
+			attachments = append(attachments, repo.attachments[id])
+		}
+	}
+	repo.mu.RUnlock()
+
+	data, err := json.Marshal(attachments)
+	if err != nil {
+		return err
+	}
+	if err := writeFileAtomic(path, data); err != nil {
+		return fmt.Errorf("saving attachments: %w", err)
+	}
+	return nil
+}
+
+// LoadSnapshot adds the attachments in the file SaveSnapshot wrote at path
+// to the repository, which should be empty. A missing file is not an error.
+func (repo *InMemoryAttachmentRepo) LoadSnapshot(path string) error {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("reading attachments: %w", err)
+	}
+	var attachments []domain.Attachment
+	if err := json.Unmarshal(data, &attachments); err != nil {
+		return fmt.Errorf("reading attachments: %w", err)
+	}
+	// Attachments of one location were saved oldest first and are added in
+	// the same order.
+	for _, attachment := range attachments {
+		repo.CreateAttachment(context.Background(), attachment)
+	}
+	return nil
+}
+
+// SaveSnapshot writes every check-in to the file at path, replacing it
+// atomically. Comments are sealed with env's current key, like notes in
+// InMemoryLocationRepo.SaveSnapshot.
+func (repo *InMemoryCheckInRepo) SaveSnapshot(path string, env *Envelope) error {
+	repo.mu.RLock()
+	var checkIns []domain.CheckIn
+	for _, userCheckIns := range repo.byUser {
+		checkIns = append(checkIns, userCheckIns...)
+	}
+	repo.mu.RUnlock()
+
+	for i := range checkIns {
+		comment, err := env.Seal(checkIns[i].Comment)
+		if err != nil {
+			return fmt.Errorf("saving check-ins: %w", err)
+		}
+		checkIns[i].Comment = comment
+	}
+	data, err := json.Marshal(checkIns)
+	if err != nil {
+		return err
+	}
+	if err := writeFileAtomic(path, data); err != nil {
+		return fmt.Errorf("saving check-ins: %w", err)
+	}
+	return nil
+}
+
+// LoadSnapshot adds the check-ins in the file SaveSnapshot wrote at path to
+// the repository, which should be empty. A missing file is not an error.
+func (repo *InMemoryCheckInRepo) LoadSnapshot(path string, env *Envelope) error {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("reading check-ins: %w", err)
+	}
+	var checkIns []domain.CheckIn
+	if err := json.Unmarshal(data, &checkIns); err != nil {
+		return fmt.Errorf("reading check-ins: %w", err)
+	}
+	repo.mu.Lock()
+	defer repo.mu.Unlock()
+	// Check-ins of one user were saved oldest first and are appended in the
+	// same order.
+	for _, checkIn := range checkIns {
+		if checkIn.Comment, err = env.Open(checkIn.Comment); err != nil {
+			return fmt.Errorf("reading check-in %s: %w", checkIn.Id, err)
+		}
+		repo.byUser[checkIn.UserID] = append(repo.byUser[checkIn.UserID], checkIn)
+		repo.owners[checkIn.LocationID] = checkIn.UserID
+	}
+	return nil
+}
+
+// RunSnapshots calls save every interval until ctx is done, and once more
+// then, so that at most one interval of changes is lost on shutdown. Errors
+// are passed to onError; the next call tries again.
+func RunSnapshots(ctx context.Context, interval time.Duration, save func() error, onError func(error)) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			if err := save(); err != nil {
+				onError(err)
+			}
+			return
+		case <-ticker.C:
+			if err := save(); err != nil {
+				onError(err)
+			}
+		}
+	}
+}
+
+// writeFileAtomic writes data to a temporary file next to path and renames
+// it over path, so that a crash never leaves a partial file. The file is
+// readable by its owner only.
+func writeFileAtomic(path string, data []byte) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0o750); err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+"-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
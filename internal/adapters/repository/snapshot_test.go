@@ -0,0 +1,164 @@
+package repository
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/lafetz/weavo/internal/core/domain"
+	"github.com/lafetz/weavo/internal/core/service/location"
+)
+
+func TestSnapshotEncryptsNotes(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+	locationsFile := filepath.Join(dir, "locations.json")
+	historyFile := filepath.Join(dir, "history.json")
+	old, current := NewMasterKey(), NewMasterKey()
+	env, _ := NewEnvelope(old)
+
+	repo := NewInMemoryLocationRepo(domain.RetentionPolicy{})
+	history := NewInMemoryHistoryRepo()
+	svc := location.NewService(repo, location.WithHistory(history))
+	home, _ := svc.CreateLocation(ctx, domain.Location{UserID: "user1", Nickname: "Home", Notes: "door code 1234"}, true)
+	home.Notes = "door code 5678"
+	if _, err := svc.UpdateLocation(ctx, home); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	trashed, _ := svc.CreateLocation(ctx, domain.Location{UserID: "user1", City: "Elsewhere", Notes: "spare key under the mat"}, true)
+	if err := svc.DeleteLocation(ctx, trashed.Id, "user1", 0); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	collection, _ := repo.CreateCollection(ctx, domain.Collection{UserID: "user1", Name: "Favourites"})
+	repo.AddToCollection(ctx, collection.Id, home.Id)
+
+	if err := repo.SaveSnapshot(locationsFile, env); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if err := history.SaveSnapshot(historyFile, env); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	for _, file := range []string{locationsFile, historyFile} {
+		data, err := os.ReadFile(file)
+		if err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+		for _, plaintext := range []string{"door code", "spare key"} {
+			if bytes.Contains(data, []byte(plaintext)) {
+				t.Errorf("%s: expected notes to be encrypted, found %q", filepath.Base(file), plaintext)
+			}
+		}
+		if !bytes.Contains(data, []byte(old.ID)) {
+			t.Errorf("%s: expected notes sealed with key %s", filepath.Base(file), old.ID)
+		}
+	}
+
+	// After a rotation the old snapshots still load, and the next save
+	// re-encrypts them with the new key.
+	rotated, _ := NewEnvelope(current, old)
+	restored := NewInMemoryLocationRepo(domain.RetentionPolicy{})
+	restoredHistory := NewInMemoryHistoryRepo()
+	if err := restored.LoadSnapshot(locationsFile, rotated); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if err := restoredHistory.LoadSnapshot(historyFile, rotated); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	loc, err := restored.GetLocation(ctx, home.Id)
+	if err != nil || loc.Notes != "door code 5678" || len(loc.Collections) != 1 {
+		t.Errorf("expected the location with its notes and collection, got %+v, %v", loc, err)
+	}
+	if trash, _ := restored.GetTrashedLocations(ctx, "user1"); len(trash) != 1 || trash[0].Notes != "spare key under the mat" {
+		t.Errorf("expected the trashed location with its notes, got %+v", trash)
+	}
+	if _, err := restored.GetCollection(ctx, collection.Id); err != nil {
+		t.Errorf("expected the collection, got %v", err)
+	}
+	entries, _ := restoredHistory.GetHistory(ctx, home.Id)
+	if len(entries) != 2 || entries[1].Snapshot.Notes != "door code 5678" {
+		t.Fatalf("expected both history entries with their notes, got %+v", entries)
+	}
+	if change := entries[1].Changes[0]; change.Field != "notes" || change.Old != "door code 1234" || change.New != "door code 5678" {
+		t.Errorf("expected the change to the notes, got %+v", change)
+	}
+
+	if err := restored.SaveSnapshot(locationsFile, rotated); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if err := restoredHistory.SaveSnapshot(historyFile, rotated); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	onlyCurrent, _ := NewEnvelope(current)
+	if err := NewInMemoryLocationRepo(domain.RetentionPolicy{}).LoadSnapshot(locationsFile, onlyCurrent); err != nil {
+		t.Errorf("expected the locations to be re-encrypted with the new key, got %v", err)
+	}
+	if err := NewInMemoryHistoryRepo().LoadSnapshot(historyFile, onlyCurrent); err != nil {
+		t.Errorf("expected the history to be re-encrypted with the new key, got %v", err)
+	}
+}
+
+func TestLoadSnapshotMissingFile(t *testing.T) {
+	env, _ := NewEnvelope(NewMasterKey())
+	path := filepath.Join(t.TempDir(), "locations.json")
+	if err := NewInMemoryLocationRepo(domain.RetentionPolicy{}).LoadSnapshot(path, env); err != nil {
+		t.Errorf("expected a missing snapshot to load as empty, got %v", err)
+	}
+}
+
+func TestAttachmentSnapshot(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "attachments.json")
+	repo := NewInMemoryAttachmentRepo()
+	for _, a := range []domain.Attachment{
+		{Id: "1", LocationID: "a", FileName: "door.jpg", HasThumbnail: true},
+		{Id: "2", LocationID: "a", FileName: "gate.jpg"},
+		{Id: "3", LocationID: "b", FileName: "map.pdf"},
+	} {
+		repo.CreateAttachment(ctx, a)
+	}
+	if err := repo.SaveSnapshot(path); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	restored := NewInMemoryAttachmentRepo()
+	if err := restored.LoadSnapshot(path); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	list, _ := restored.GetAttachments(ctx, "a")
+	if len(list) != 2 || list[0].Id != "1" || list[1].Id != "2" || !list[0].HasThumbnail {
+		t.Errorf("expected both attachments of a in order, got %+v", list)
+	}
+	if a, err := restored.GetAttachment(ctx, "3"); err != nil || a.FileName != "map.pdf" {
+		t.Errorf("expected attachment 3, got %+v, %v", a, err)
+	}
+}
+
+func TestCheckInSnapshot(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "checkins.json")
+	env, _ := NewEnvelope(NewMasterKey())
+	repo := NewInMemoryCheckInRepo()
+	first, _ := repo.CreateCheckIn(ctx, domain.CheckIn{LocationID: "a", UserID: "user1", Comment: "left the spare key", CreatedAt: time.Now().Add(-time.Hour)})
+	repo.CreateCheckIn(ctx, domain.CheckIn{LocationID: "a", UserID: "user1", Weather: &domain.Weather{}, CreatedAt: time.Now()})
+	if err := repo.SaveSnapshot(path, env); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if data, _ := os.ReadFile(path); bytes.Contains(data, []byte("spare key")) {
+		t.Errorf("expected comments to be encrypted, got %s", data)
+	}
+
+	restored := NewInMemoryCheckInRepo()
+	if err := restored.LoadSnapshot(path, env); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	checkIns, _ := restored.GetCheckIns(ctx, "a")
+	if len(checkIns) != 2 || checkIns[1].Id != first.Id || checkIns[1].Comment != "left the spare key" || checkIns[0].Weather == nil {
+		t.Errorf("expected both check-ins, newest first, with the comment and weather, got %+v", checkIns)
+	}
+	if timeline, _, _ := restored.GetTimeline(ctx, "user1", location.CheckInFilter{Page: 1, PageSize: 10}); len(timeline) != 2 {
+		t.Errorf("expected both check-ins on the timeline, got %+v", timeline)
+	}
+}
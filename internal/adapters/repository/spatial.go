@@ -0,0 +1,98 @@
+package repository
+
+import (
+	"math"
+
+	"github.com/lafetz/weavo/internal/core/domain"
+)
+
+const (
+	// cellDegrees is the side of a spatial index cell, roughly 111 km at
+	// the equator.
+	cellDegrees = 1.0
+	lonCells    = int(360 / cellDegrees)
+)
+
+type gridCell struct {
+	lat int
+	lon int
+}
+
+// spatialIndex buckets one user's locations into fixed-size
+// latitude/longitude cells so radius and box queries only visit nearby
+// cells.
+type spatialIndex map[gridCell]map[string]struct{}
+
+func cellOf(c domain.Coordinates) gridCell {
+	lon := int(math.Floor(c.Lon / cellDegrees))
+	if lon >= lonCells/2 {
+		lon = lonCells/2 - 1 // lon == 180 belongs with the cells just west of it
+	}
+	return gridCell{
+		lat: int(math.Floor(c.Lat / cellDegrees)),
+		lon: lon,
+	}
+}
+
+func (idx spatialIndex) add(id string, c domain.Coordinates) {
+	cell := cellOf(c)
+	ids, exists := idx[cell]
+	if !exists {
+		ids = make(map[string]struct{})
+		idx[cell] = ids
+	}
+	ids[id] = struct{}{}
+}
+
+func (idx spatialIndex) remove(id string, c domain.Coordinates) {
+	cell := cellOf(c)
+	delete(idx[cell], id)
+	if len(idx[cell]) == 0 {
+		delete(idx, cell)
+	}
+}
+
+// candidates returns the IDs in every cell overlapping box. When the box
+// covers more cells than the index holds it walks the occupied cells
+// instead.
+func (idx spatialIndex) candidates(box domain.BoundingBox) []string {
+	minCell := cellOf(domain.Coordinates{Lat: box.MinLat, Lon: box.MinLon})
+	maxCell := cellOf(domain.Coordinates{Lat: box.MaxLat, Lon: box.MaxLon})
+	lonSpan := maxCell.lon - minCell.lon + 1
+	if lonSpan <= 0 {
+		lonSpan += lonCells
+	}
+	latSpan := maxCell.lat - minCell.lat + 1
+
+	var ids []string
+	if latSpan*lonSpan > len(idx) {
+		for cell, cellIDs := range idx {
+			if cell.lat < minCell.lat || cell.lat > maxCell.lat || !lonInSpan(cell.lon, minCell.lon, lonSpan) {
+				continue
+			}
+			for id := range cellIDs {
+				ids = append(ids, id)
+			}
+		}
+		return ids
+	}
+	for lat := minCell.lat; lat <= maxCell.lat; lat++ {
+		for i := 0; i < lonSpan; i++ {
+			lon := wrapCell(minCell.lon + i)
+			for id := range idx[gridCell{lat: lat, lon: lon}] {
+				ids = append(ids, id)
+			}
+		}
+	}
+	return ids
+}
+
+func wrapCell(lon int) int {
+	half := lonCells / 2
+	return ((lon+half)%lonCells+lonCells)%lonCells - half
+}
+
+func lonInSpan(lon, start, span int) bool {
+	offset := ((lon-start)%lonCells + lonCells) % lonCells
+	return offset < span
+}
@@ -0,0 +1,140 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"maps"
+
+	"github.com/lafetz/weavo/internal/core/domain"
+)
+
+var errNestedTx = errors.New("transactions on different users cannot be nested")
+
+type txKey struct{}
+
+// tx marks a context as belonging to a transaction that holds the write lock
+// of shard.
+type tx struct {
+	shard *locationShard
+}
+
+// lock write-locks s for a call made with ctx and returns the matching
+// unlock. Inside a transaction on s the lock is already held, so both are
+// no-ops.
+func (s *locationShard) lock(ctx context.Context) func() {
+	if t, ok := ctx.Value(txKey{}).(*tx); ok && t.shard == s {
+		return func() {}
+	}
+	s.mu.Lock()
+	return s.mu.Unlock
+}
+
+// rlock is lock for readers.
+func (s *locationShard) rlock(ctx context.Context) func() {
+	if t, ok := ctx.Value(txKey{}).(*tx); ok && t.shard == s {
+		return func() {}
+	}
+	s.mu.RLock()
+	return s.mu.RUnlock
+}
+
+// userSnapshot is a copy of one user's records in a shard.
+type userSnapshot struct {
+	users       map[string]domain.Location
+	trash       map[string]domain.Location
+	collections map[string]domain.Collection
+	shares      map[string]domain.Share
+}
+
+// WithinTx holds the write lock of userID's shard while fn runs, so other
+// callers see none of fn's writes until it returns, and puts back the
+// user's records as they were if fn returns an error or panics. Only the
+// writes fn makes to userID's records through the context it is given are
+// covered.
+func (repo *InMemoryLocationRepo) WithinTx(ctx context.Context, userID string, fn func(ctx context.Context) error) error {
+	s := repo.userShard(userID)
+	if t, ok := ctx.Value(txKey{}).(*tx); ok {
+		if t.shard != s {
+			return errNestedTx
+		}
+		return fn(ctx)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	before := userSnapshot{
+		users:       maps.Clone(s.users[userID]),
+		trash:       maps.Clone(s.trash[userID]),
+		collections: maps.Clone(s.collections[userID]),
+		shares:      maps.Clone(s.shares[userID]),
+	}
+	committed := false
+	defer func() {
+		if !committed {
+			repo.rollback(s, userID, before)
+		}
+	}()
+
+	if err := fn(context.WithValue(ctx, txKey{}, &tx{shard: s})); err != nil {
+		return err
+	}
+	committed = true
+	return nil
+}
+
+// rollback puts the user's records in s back to before and brings the owner
+// index in line with them. The caller holds s.mu. Heap entries left behind
+// by the undone writes no longer match any record and are discarded by the
+// cleanup loop.
+func (repo *InMemoryLocationRepo) rollback(s *locationShard, userID string, before userSnapshot) {
+	after := map[string]bool{}
+	for id := range s.users[userID] {
+		after[id] = true
+	}
+	for id := range s.trash[userID] {
+		after[id] = true
+	}
+	for id := range s.collections[userID] {
+		after[id] = true
+	}
+	for token := range s.shares[userID] {
+		after[token] = true
+	}
+
+	delete(s.users, userID)
+	delete(s.spatial, userID)
+	for _, loc := range before.users {
+		s.put(loc)
+	}
+	restore(s.trash, userID, before.trash)
+	restore(s.collections, userID, before.collections)
+	restore(s.shares, userID, before.shares)
+
+	for _, ids := range []map[string]bool{keys(before.users), keys(before.trash), keys(before.collections), keys(before.shares)} {
+		for id := range ids {
+			if !after[id] {
+				repo.setOwner(id, userID)
+			}
+			delete(after, id)
+		}
+	}
+	for id := range after {
+		repo.removeOwner(id)
+	}
+}
+
+func restore[V any](m map[string]map[string]V, userID string, before map[string]V) {
+	if len(before) == 0 {
+		delete(m, userID)
+		return
+	}
+	m[userID] = before
+}
+
+func keys[V any](m map[string]V) map[string]bool {
+	set := make(map[string]bool, len(m))
+	for k := range m {
+		set[k] = true
+	}
+	return set
+}
@@ -0,0 +1,59 @@
+package repository
+
+import (
+	"context"
+	"sync"
+
+	"github.com/lafetz/weavo/internal/core/domain"
+	"github.com/lafetz/weavo/internal/core/service/user"
+)
+
+// InMemoryUserRepo keeps accounts in memory for the life of the process.
+type InMemoryUserRepo struct {
+	mu      sync.RWMutex
+	users   map[string]domain.User // id -> user
+	byEmail map[string]string      // email -> id
+}
+
+func NewInMemoryUserRepo() *InMemoryUserRepo {
+	return &InMemoryUserRepo{
+		users:   make(map[string]domain.User),
+		byEmail: make(map[string]string),
+	}
+}
+
+func (repo *InMemoryUserRepo) CreateUser(ctx context.Context, u domain.User) (domain.User, error) {
+	repo.mu.Lock()
+	defer repo.mu.Unlock()
+	return repo.create(u)
+}
+
+// create adds u. The caller holds repo.mu.
+func (repo *InMemoryUserRepo) create(u domain.User) (domain.User, error) {
+	if _, exists := repo.byEmail[u.Email]; exists {
+		return domain.User{}, user.ErrEmailTaken
+	}
+	repo.users[u.Id] = u
+	repo.byEmail[u.Email] = u.Id
+	return u, nil
+}
+
+func (repo *InMemoryUserRepo) GetUser(ctx context.Context, id string) (domain.User, error) {
+	repo.mu.RLock()
+	defer repo.mu.RUnlock()
+	u, exists := repo.users[id]
+	if !exists {
+		return domain.User{}, user.ErrUserNotFound
+	}
+	return u, nil
+}
+
+func (repo *InMemoryUserRepo) GetUserByEmail(ctx context.Context, email string) (domain.User, error) {
+	repo.mu.RLock()
+	defer repo.mu.RUnlock()
+	id, exists := repo.byEmail[email]
+	if !exists {
+		return domain.User{}, user.ErrUserNotFound
+	}
+	return repo.users[id], nil
+}
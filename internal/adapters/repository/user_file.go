@@ -0,0 +1,85 @@
+package repository
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/lafetz/weavo/internal/core/domain"
+)
+
+// FileUserRepo keeps accounts in memory and writes all of them to a JSON
+// file after every change, so that they survive restarts. It suits a single
+// instance with a modest number of accounts.
+type FileUserRepo struct {
+	*InMemoryUserRepo
+	path string
+}
+
+type userRecord struct {
+	Id           string    `json:"id"`
+	Email        string    `json:"email"`
+	PasswordHash string    `json:"password_hash"`
+	CreatedAt    time.Time `json:"created_at"`
+}
+
+// NewFileUserRepo loads the accounts stored at path, which need not exist
+// yet; its directory is created if needed.
+func NewFileUserRepo(path string) (*FileUserRepo, error) {
+	repo := &FileUserRepo{InMemoryUserRepo: NewInMemoryUserRepo(), path: path}
+	if err := os.MkdirAll(filepath.Dir(path), 0o750); err != nil {
+		return nil, fmt.Errorf("creating user store directory: %w", err)
+	}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return repo, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("reading users: %w", err)
+	}
+	var records []userRecord
+	if err := json.Unmarshal(data, &records); err != nil {
+		return nil, fmt.Errorf("reading users: %w", err)
+	}
+	for _, r := range records {
+		repo.create(domain.User{Id: r.Id, Email: r.Email, PasswordHash: r.PasswordHash, CreatedAt: r.CreatedAt})
+	}
+	return repo, nil
+}
+
+func (repo *FileUserRepo) CreateUser(ctx context.Context, u domain.User) (domain.User, error) {
+	repo.mu.Lock()
+	defer repo.mu.Unlock()
+	created, err := repo.create(u)
+	if err != nil {
+		return domain.User{}, err
+	}
+	if err := repo.save(); err != nil {
+		delete(repo.users, u.Id)
+		delete(repo.byEmail, u.Email)
+		return domain.User{}, err
+	}
+	return created, nil
+}
+
+// save writes every account to the file, replacing it atomically. The
+// caller holds repo.mu.
+func (repo *FileUserRepo) save() error {
+	records := make([]userRecord, 0, len(repo.users))
+	for _, u := range repo.users {
+		records = append(records, userRecord{Id: u.Id, Email: u.Email, PasswordHash: u.PasswordHash, CreatedAt: u.CreatedAt})
+	}
+	data, err := json.Marshal(records)
+	if err != nil {
+		return err
+	}
+	if err := writeFileAtomic(repo.path, data); err != nil {
+		return fmt.Errorf("saving users: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,38 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"testing"
+
+	"github.com/lafetz/weavo/internal/core/domain"
+	"github.com/lafetz/weavo/internal/core/service/user"
+)
+
+func TestFileUserRepo(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "users.json")
+	repo, err := NewFileUserRepo(path)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if _, err := repo.CreateUser(ctx, domain.User{Id: "1", Email: "ada@example.com", PasswordHash: "hash"}); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if _, err := repo.CreateUser(ctx, domain.User{Id: "2", Email: "ada@example.com"}); !errors.Is(err, user.ErrEmailTaken) {
+		t.Fatalf("expected ErrEmailTaken, got %v", err)
+	}
+
+	reopened, err := NewFileUserRepo(path)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	u, err := reopened.GetUserByEmail(ctx, "ada@example.com")
+	if err != nil || u.Id != "1" || u.PasswordHash != "hash" {
+		t.Fatalf("expected the account to survive a restart, got %+v, %v", u, err)
+	}
+	if _, err := reopened.GetUser(ctx, "2"); !errors.Is(err, user.ErrUserNotFound) {
+		t.Errorf("expected ErrUserNotFound, got %v", err)
+	}
+}
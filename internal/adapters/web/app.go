@@ -9,12 +9,14 @@ import (
 	"os"
 	"os/signal"
 	"strconv"
+	"sync"
 	"syscall"
 	"time"
 
 	"github.com/gorilla/sessions"
 	"github.com/lafetz/weavo/internal/adapters/web/webutils"
 	"github.com/lafetz/weavo/internal/core/service/location"
+	"github.com/lafetz/weavo/internal/core/service/user"
 	"github.com/lafetz/weavo/internal/core/service/weather"
 )
 
@@ -25,15 +27,23 @@ type App struct {
 	validator   *webutils.CustomValidator
 	locationSvc location.ServiceApi
 	weatherSvc  weather.ServiceApi
+	userSvc     user.ServiceApi
 	store       *sessions.CookieStore
+	idempotency *webutils.IdempotencyStore
+	// maxAttachmentBytes is the size limit of uploaded files, which sets
+	// how large an upload request may be.
+	maxAttachmentBytes int64
 }
 
 func NewApp(
 	port int, logger *slog.Logger,
 	store *sessions.CookieStore,
+	idempotency *webutils.IdempotencyStore,
 	validator *webutils.CustomValidator,
 	locationSvc *location.Service,
 	weatherSvc weather.ServiceApi,
+	userSvc user.ServiceApi,
+	maxAttachmentBytes int64,
 ) *App {
 
 	a := &App{
@@ -43,16 +53,36 @@ func NewApp(
 		validator:   validator,
 		locationSvc: locationSvc,
 		weatherSvc:  weatherSvc,
+		userSvc:     userSvc,
 		store:       store,
+		idempotency: idempotency,
+
+		maxAttachmentBytes: maxAttachmentBytes,
 	}
 	a.initAppRoutes()
 	return a
 }
-func (a *App) Run() error {
+
+// Run serves HTTP until SIGINT or SIGTERM, then shuts down gracefully.
+// Each worker runs in its own goroutine for as long as the server; its
+// context is cancelled when shutdown begins, and Run waits for all workers
+// to return before it does.
+func (a *App) Run(workers ...func(ctx context.Context)) error {
+	workerCtx, stopWorkers := context.WithCancel(context.Background())
+	var wg sync.WaitGroup
+	for _, worker := range workers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			worker(workerCtx)
+		}()
+	}
+	defer wg.Wait()
+	defer stopWorkers()
 
 	srv := &http.Server{
 		Addr:         fmt.Sprintf(":%s", strconv.Itoa(a.port)),
-		Handler:      a.enableCORS(a.Router),
+		Handler:      a.requestID(a.enableCORS(a.Router)),
 		IdleTimeout:  time.Minute,
 		ReadTimeout:  10 * time.Second,
 		WriteTimeout: 30 * time.Second,
@@ -66,6 +96,7 @@ func (a *App) Run() error {
 		<-quit
 
 		a.logger.Info("shutting down server")
+		stopWorkers()
 		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 		defer cancel()
 
@@ -81,6 +112,7 @@ func (a *App) Run() error {
 	if err != nil {
 		return err
 	}
+	wg.Wait()
 	a.logger.Info("server stopped")
 	return nil
 }
@@ -8,13 +8,16 @@ import (
 
 	"io"
 	"log/slog"
+	"mime/multipart"
 	"net/http"
 	"net/http/httptest"
+	"strings"
 	"testing"
 	"time"
 
 	"github.com/go-playground/validator/v10"
 
+	localstorage "github.com/lafetz/weavo/internal/adapters/local_storage"
 	mockcache "github.com/lafetz/weavo/internal/adapters/mock_cache"
 	"github.com/lafetz/weavo/internal/adapters/repository"
 
@@ -22,6 +25,7 @@ import (
 	"github.com/lafetz/weavo/internal/adapters/web/webutils"
 	"github.com/lafetz/weavo/internal/core/domain"
 	"github.com/lafetz/weavo/internal/core/service/location"
+	"github.com/lafetz/weavo/internal/core/service/user"
 	"github.com/lafetz/weavo/internal/core/service/weather"
 	customlogger "github.com/lafetz/weavo/internal/logger"
 )
@@ -36,18 +40,26 @@ type opmock struct {
 func (o *opmock) GetWeather(ctx context.Context, city string) (domain.Weather, error) {
 	return domain.Weather{}, nil
 }
-func setupServer() *App {
+func setupServer(opts ...location.Option) *App {
+	return setupServerWithUploads(0, opts...)
+}
+
+// setupServerWithUploads is setupServer with a size limit for uploaded
+// files.
+func setupServerWithUploads(maxAttachmentBytes int64, opts ...location.Option) *App {
 	ow := &opmock{}
 	logger := customlogger.NewLogger(slog.LevelDebug, "development")
-	store := repository.NewInMemoryLocationRepo(dataRetention)
+	store := repository.NewInMemoryLocationRepo(domain.RetentionPolicy{Period: dataRetention})
 	locationID = seedDatabase(store)
-	locationSvc := location.NewService(store)
+	opts = append([]location.Option{location.WithHistory(repository.NewInMemoryHistoryRepo())}, opts...)
+	locationSvc := location.NewService(store, opts...)
 	mc := mockcache.NewMockCache()
 	weatherSvc := weather.NewService(ow, mc)
 	val := validator.New()
 	custonmVal := webutils.NewCustomValidator(val)
-	cookieStore := webutils.CookieStore(dataRetention)
-	app := NewApp(8080, logger, cookieStore, custonmVal, locationSvc, weatherSvc)
+	cookieStore := webutils.CookieStore(dataRetention, false)
+	userSvc := user.NewService(repository.NewInMemoryUserRepo(), user.WithHashParams(user.HashParams{Memory: 64, Time: 1, Threads: 1}))
+	app := NewApp(8080, logger, cookieStore, webutils.NewIdempotencyStore(time.Hour), custonmVal, locationSvc, weatherSvc, userSvc, maxAttachmentBytes)
 
 	return app
 }
@@ -121,7 +133,7 @@ func TestCreateLocation(t *testing.T) {
 		createLocation := dto.LocationReq{
 			Notes:    "Test Notes",
 			Nickname: "Test Nickname",
-			City:     "Test City",
+			City:     "Other City",
 			Coordinates: dto.Coordinates{
 				Lat: 1.0,
 				Lon: 1.0,
@@ -146,6 +158,73 @@ func TestCreateLocation(t *testing.T) {
 	})
 }
 
+func TestDuplicateLocations(t *testing.T) {
+	app := setupServer()
+	server := httptest.NewServer(app.Router)
+	defer server.Close()
+
+	// The seeded location is in Test City at (1, 1).
+	duplicate := dto.LocationReq{
+		Notes:    "Test Notes",
+		Nickname: "Same place",
+		City:     "test city",
+		Coordinates: dto.Coordinates{
+			Lat: 1.0001,
+			Lon: 1.0001,
+		},
+	}
+	create := func(query string) *http.Response {
+		reqBody, _ := json.Marshal(duplicate)
+		req, err := http.NewRequest(http.MethodPost, server.URL+"/api/v1/locations"+query, bytes.NewBuffer(reqBody))
+		if err != nil {
+			t.Fatalf("Failed to create request: %v", err)
+		}
+		addcookie(app, req)
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatalf("Failed to send request: %v", err)
+		}
+		return resp
+	}
+
+	resp := create("")
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusConflict {
+		t.Fatalf("Expected status code %d, got %d", http.StatusConflict, resp.StatusCode)
+	}
+	var conflict struct {
+		Data []dto.LocationRes `json:"data"`
+	}
+	json.NewDecoder(resp.Body).Decode(&conflict)
+	if len(conflict.Data) != 1 || conflict.Data[0].Id != locationID {
+		t.Errorf("Expected the seeded location as the only match, got %+v", conflict.Data)
+	}
+
+	forced := create("?force=true")
+	defer forced.Body.Close()
+	if forced.StatusCode != http.StatusCreated {
+		t.Fatalf("Expected status code %d, got %d", http.StatusCreated, forced.StatusCode)
+	}
+
+	req, err := http.NewRequest(http.MethodGet, server.URL+"/api/v1/locations/duplicates", nil)
+	if err != nil {
+		t.Fatalf("Failed to create request: %v", err)
+	}
+	addcookie(app, req)
+	listed, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("Failed to send request: %v", err)
+	}
+	defer listed.Body.Close()
+	var groups struct {
+		Data [][]dto.LocationRes `json:"data"`
+	}
+	json.NewDecoder(listed.Body).Decode(&groups)
+	if len(groups.Data) != 1 || len(groups.Data[0]) != 2 {
+		t.Errorf("Expected one group of two locations, got %+v", groups.Data)
+	}
+}
+
 func TestGetLocation(t *testing.T) {
 	app := setupServer()
 	server := httptest.NewServer(app.Router)
@@ -352,6 +431,69 @@ func TestUpdateLocation(t *testing.T) {
 	})
 }
 
+func TestPatchLocation(t *testing.T) {
+	app := setupServer()
+	server := httptest.NewServer(app.Router)
+	defer server.Close()
+
+	reqBody := bytes.NewBufferString(`{"city": "Patched City", "coordinates": {"lat": 3.0}}`)
+	req, err := http.NewRequest(http.MethodPatch, server.URL+"/api/v1/locations/"+locationID, reqBody)
+	if err != nil {
+		t.Fatalf("Failed to create request: %v", err)
+	}
+	req.Header.Set("Content-Type", "application/merge-patch+json")
+	addcookie(app, req)
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("Failed to send request: %v", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("Expected status code %d, got %d", http.StatusOK, resp.StatusCode)
+	}
+	var response struct {
+		Data dto.LocationRes `json:"data"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&response); err != nil {
+		t.Fatalf("Failed to decode response body: %v", err)
+	}
+	if response.Data.City != "Patched City" || response.Data.Notes != "Test Notes" {
+		t.Errorf("Expected patched city and untouched notes, got %+v", response.Data)
+	}
+	if response.Data.Coordinates.Lat != 3.0 || response.Data.Coordinates.Lon != 1.0 {
+		t.Errorf("Expected merged coordinates, got %+v", response.Data.Coordinates)
+	}
+
+	etag := resp.Header.Get("ETag")
+	if etag == "" {
+		t.Fatalf("Expected an ETag header")
+	}
+	for _, tc := range []struct {
+		ifMatch string
+		status  int
+	}{
+		{ifMatch: `"1"`, status: http.StatusPreconditionFailed},
+		{ifMatch: etag, status: http.StatusOK},
+	} {
+		req, err := http.NewRequest(http.MethodPatch, server.URL+"/api/v1/locations/"+locationID, bytes.NewBufferString(`{"notes": "Conditional"}`))
+		if err != nil {
+			t.Fatalf("Failed to create request: %v", err)
+		}
+		req.Header.Set("Content-Type", "application/merge-patch+json")
+		req.Header.Set("If-Match", tc.ifMatch)
+		addcookie(app, req)
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatalf("Failed to send request: %v", err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != tc.status {
+			t.Errorf("If-Match %s: expected status code %d, got %d", tc.ifMatch, tc.status, resp.StatusCode)
+		}
+	}
+}
+
 func TestDeleteLocation(t *testing.T) {
 	app := setupServer()
 	server := httptest.NewServer(app.Router)
@@ -390,7 +532,319 @@ func TestDeleteLocation(t *testing.T) {
 			t.Errorf("Expected status code %d, got %d", http.StatusOK, resp.StatusCode)
 		}
 	})
+
+	t.Run("deleted location moves to trash", func(t *testing.T) {
+		do := func(method, path string) *http.Response {
+			req, err := http.NewRequest(method, server.URL+path, nil)
+			if err != nil {
+				t.Fatalf("Failed to create request: %v", err)
+			}
+			addcookie(app, req)
+			resp, err := http.DefaultClient.Do(req)
+			if err != nil {
+				t.Fatalf("Failed to send request: %v", err)
+			}
+			return resp
+		}
+
+		resp := do(http.MethodGet, "/api/v1/locations/"+locationID)
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusNotFound {
+			t.Errorf("Expected deleted location to be hidden, got status %d", resp.StatusCode)
+		}
+
+		resp = do(http.MethodGet, "/api/v1/locations/trash")
+		var trash struct {
+			Data []dto.LocationRes `json:"data"`
+		}
+		err := json.NewDecoder(resp.Body).Decode(&trash)
+		resp.Body.Close()
+		if err != nil {
+			t.Fatalf("Failed to decode response body: %v", err)
+		}
+		if len(trash.Data) != 1 || trash.Data[0].Id != locationID {
+			t.Fatalf("Expected the deleted location in the trash, got %+v", trash.Data)
+		}
+
+		resp = do(http.MethodPost, "/api/v1/locations/"+locationID+"/restore")
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusOK {
+			t.Fatalf("Expected status code %d, got %d", http.StatusOK, resp.StatusCode)
+		}
+		resp = do(http.MethodGet, "/api/v1/locations/"+locationID)
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusOK {
+			t.Errorf("Expected restored location to be readable, got status %d", resp.StatusCode)
+		}
+	})
+}
+func TestExportImportRoundTrip(t *testing.T) {
+	app := setupServer()
+	server := httptest.NewServer(app.Router)
+	defer server.Close()
+
+	for _, format := range []string{"json", "csv", "geojson"} {
+		t.Run(format, func(t *testing.T) {
+			req, _ := http.NewRequest(http.MethodGet, server.URL+"/api/v1/locations/export?format="+format, nil)
+			addcookie(app, req)
+			resp, err := http.DefaultClient.Do(req)
+			if err != nil {
+				t.Fatalf("Failed to send request: %v", err)
+			}
+			exported, _ := io.ReadAll(resp.Body)
+			resp.Body.Close()
+			if resp.StatusCode != http.StatusOK {
+				t.Fatalf("Expected status code %d, got %d", http.StatusOK, resp.StatusCode)
+			}
+
+			// Importing an export again finds every location already there.
+			req, _ = http.NewRequest(http.MethodPost, server.URL+"/api/v1/locations/import?format="+format, bytes.NewReader(exported))
+			addcookie(app, req)
+			resp, err = http.DefaultClient.Do(req)
+			if err != nil {
+				t.Fatalf("Failed to send request: %v", err)
+			}
+			defer resp.Body.Close()
+			var res struct {
+				Data dto.ImportReportRes `json:"data"`
+			}
+			if err := json.NewDecoder(resp.Body).Decode(&res); err != nil {
+				t.Fatalf("Failed to decode response body: %v", err)
+			}
+			if res.Data.Total != 1 || res.Data.Duplicates != 1 || res.Data.Rows[0].DuplicateOf != locationID {
+				t.Fatalf("Expected the seeded location to be reported as a duplicate, got %+v", res.Data)
+			}
+		})
+	}
+}
+func TestLocationHistory(t *testing.T) {
+	app := setupServer()
+	server := httptest.NewServer(app.Router)
+	defer server.Close()
+
+	do := func(method, path, body string) *http.Response {
+		req, _ := http.NewRequest(method, server.URL+path, bytes.NewBufferString(body))
+		addcookie(app, req)
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatalf("Failed to send request: %v", err)
+		}
+		return resp
+	}
+
+	resp := do(http.MethodPost, "/api/v1/locations", `{"notes": "first", "nickname": "Home", "city": "Paris", "coordinates": {"lat": 1, "lon": 1}}`)
+	var created struct {
+		Data dto.LocationRes `json:"data"`
+	}
+	json.NewDecoder(resp.Body).Decode(&created)
+	resp.Body.Close()
+	id := created.Data.Id
+
+	resp = do(http.MethodPut, "/api/v1/locations/"+id, `{"notes": "second", "nickname": "Home", "city": "Paris", "coordinates": {"lat": 1, "lon": 1}}`)
+	resp.Body.Close()
+
+	resp = do(http.MethodGet, "/api/v1/locations/"+id+"/history", "")
+	var history struct {
+		Data []dto.HistoryEntryRes `json:"data"`
+	}
+	err := json.NewDecoder(resp.Body).Decode(&history)
+	resp.Body.Close()
+	if err != nil {
+		t.Fatalf("Failed to decode response body: %v", err)
+	}
+	if len(history.Data) != 2 || history.Data[1].Version != 2 || history.Data[1].ActorID != "test-user-id" {
+		t.Fatalf("Expected a create and an update entry, got %+v", history.Data)
+	}
+
+	resp = do(http.MethodPost, "/api/v1/locations/"+id+"/history/1/revert", "")
+	var reverted struct {
+		Data dto.LocationRes `json:"data"`
+	}
+	json.NewDecoder(resp.Body).Decode(&reverted)
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK || reverted.Data.Notes != "first" || reverted.Data.Version != 3 {
+		t.Fatalf("Expected version 3 with the original notes, got %d %+v", resp.StatusCode, reverted.Data)
+	}
+}
+
+func TestShareLink(t *testing.T) {
+	app := setupServer()
+	server := httptest.NewServer(app.Router)
+	defer server.Close()
+
+	req, _ := http.NewRequest(http.MethodPost, server.URL+"/api/v1/shares", bytes.NewBufferString(`{"location_id": "`+locationID+`", "precision": 1}`))
+	addcookie(app, req)
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("Failed to send request: %v", err)
+	}
+	var created struct {
+		Data dto.ShareRes `json:"data"`
+	}
+	json.NewDecoder(resp.Body).Decode(&created)
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusCreated {
+		t.Fatalf("Expected status code %d, got %d", http.StatusCreated, resp.StatusCode)
+	}
+
+	// Opened without the owner's cookie.
+	resp, err = http.Get(server.URL + created.Data.Path)
+	if err != nil {
+		t.Fatalf("Failed to send request: %v", err)
+	}
+	var shared struct {
+		Data dto.SharedRes `json:"data"`
+	}
+	json.NewDecoder(resp.Body).Decode(&shared)
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK || shared.Data.Location == nil || shared.Data.Location.Nickname != "Test Nickname" {
+		t.Fatalf("Expected the shared location, got %d %+v", resp.StatusCode, shared.Data)
+	}
+	if len(resp.Header.Values("Set-Cookie")) != 0 {
+		t.Errorf("Expected opening a share not to start a session")
+	}
+
+	req, _ = http.NewRequest(http.MethodDelete, server.URL+"/api/v1/shares/"+created.Data.Token, nil)
+	addcookie(app, req)
+	resp, _ = http.DefaultClient.Do(req)
+	resp.Body.Close()
+	resp, _ = http.Get(server.URL + created.Data.Path)
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusNotFound {
+		t.Errorf("Expected a revoked share to be gone, got %d", resp.StatusCode)
+	}
+}
+
+func TestLocationOwnership(t *testing.T) {
+	app := setupServer()
+	server := httptest.NewServer(app.Router)
+	defer server.Close()
+
+	// Without the owner's cookie the request runs in a fresh session.
+	resp, err := http.Get(server.URL + "/api/v1/locations/" + locationID)
+	if err != nil {
+		t.Fatalf("Failed to send request: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusNotFound {
+		t.Fatalf("Expected status code %d for another user, got %d", http.StatusNotFound, resp.StatusCode)
+	}
+
+	req, _ := http.NewRequest(http.MethodDelete, server.URL+"/api/v1/locations/"+locationID, nil)
+	resp, _ = http.DefaultClient.Do(req)
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusNotFound {
+		t.Fatalf("Expected status code %d deleting as another user, got %d", http.StatusNotFound, resp.StatusCode)
+	}
+
+	req, _ = http.NewRequest(http.MethodPost, server.URL+"/api/v1/shares", bytes.NewBufferString(`{"location_id": "`+locationID+`"}`))
+	addcookie(app, req)
+	resp, _ = http.DefaultClient.Do(req)
+	var created struct {
+		Data dto.ShareRes `json:"data"`
+	}
+	json.NewDecoder(resp.Body).Decode(&created)
+	resp.Body.Close()
+
+	resp, _ = http.Get(server.URL + "/api/v1/locations/" + locationID + "?share=" + created.Data.Token)
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Errorf("Expected status code %d with a share token, got %d", http.StatusOK, resp.StatusCode)
+	}
+}
+
+func TestCreateLocationIdempotencyKey(t *testing.T) {
+	app := setupServer()
+	server := httptest.NewServer(app.Router)
+	defer server.Close()
+
+	create := func(body string) (int, dto.LocationRes) {
+		req, _ := http.NewRequest(http.MethodPost, server.URL+"/api/v1/locations", bytes.NewBufferString(body))
+		req.Header.Set("Idempotency-Key", "retry-me")
+		addcookie(app, req)
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatalf("Failed to send request: %v", err)
+		}
+		defer resp.Body.Close()
+		var res struct {
+			Data dto.LocationRes `json:"data"`
+		}
+		json.NewDecoder(resp.Body).Decode(&res)
+		return resp.StatusCode, res.Data
+	}
+
+	body := `{"notes": "n", "nickname": "Cabin", "city": "Oslo", "coordinates": {"lat": 59.9, "lon": 10.7}}`
+	status, first := create(body)
+	if status != http.StatusCreated {
+		t.Fatalf("Expected status code %d, got %d", http.StatusCreated, status)
+	}
+	status, retry := create(body)
+	if status != http.StatusCreated || retry.Id != first.Id {
+		t.Fatalf("Expected the retry to return location %s, got %d %s", first.Id, status, retry.Id)
+	}
+	if status, _ := create(`{"notes": "n", "nickname": "Other", "city": "Oslo", "coordinates": {"lat": 59.9, "lon": 10.7}}`); status != http.StatusUnprocessableEntity {
+		t.Errorf("Expected status code %d for a different body, got %d", http.StatusUnprocessableEntity, status)
+	}
+
+	locations, _, _ := app.locationSvc.GetLocations(context.Background(), "test-user-id", location.Filter{Page: 1, PageSize: 10, City: "Oslo"})
+	if len(locations) != 1 {
+		t.Errorf("Expected a single location to be created, got %d", len(locations))
+	}
+}
+
+func TestBatchLocations(t *testing.T) {
+	app := setupServer()
+	server := httptest.NewServer(app.Router)
+	defer server.Close()
+
+	send := func(query, body string) dto.BatchRes {
+		req, _ := http.NewRequest(http.MethodPost, server.URL+"/api/v1/locations:batch"+query, bytes.NewBufferString(body))
+		addcookie(app, req)
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatalf("Failed to send request: %v", err)
+		}
+		defer resp.Body.Close()
+		if resp.StatusCode != http.StatusOK {
+			t.Fatalf("Expected status code %d, got %d", http.StatusOK, resp.StatusCode)
+		}
+		var res struct {
+			Data dto.BatchRes `json:"data"`
+		}
+		json.NewDecoder(resp.Body).Decode(&res)
+		return res.Data
+	}
+	count := func() int {
+		locations, _, _ := app.locationSvc.GetLocations(context.Background(), "test-user-id", location.Filter{Page: 1, PageSize: 100})
+		return len(locations)
+	}
+	before := count()
+
+	res := send("?atomic=true", `{"operations": [
+		{"op": "create", "location": {"notes": "n", "nickname": "A", "city": "Oslo"}},
+		{"op": "delete", "id": "`+locationID+`"},
+		{"op": "update", "id": "missing", "location": {"notes": "n", "nickname": "B", "city": "Oslo"}}
+	]}`)
+	if res.Succeeded != 0 || res.Results[2].Status != http.StatusNotFound {
+		t.Fatalf("Expected the batch to fail on the missing location, got %+v", res)
+	}
+	if count() != before {
+		t.Fatalf("Expected the atomic batch to be rolled back, got %d locations instead of %d", count(), before)
+	}
+	if _, err := app.locationSvc.GetLocation(context.Background(), locationID, "test-user-id"); err != nil {
+		t.Fatalf("Expected the deleted location to be back, got %v", err)
+	}
+
+	res = send("", `{"operations": [
+		{"op": "create", "location": {"notes": "n", "nickname": "A", "city": "Oslo"}},
+		{"op": "update", "id": "missing", "location": {"notes": "n", "nickname": "B", "city": "Oslo"}}
+	]}`)
+	if res.Succeeded != 1 || count() != before+1 {
+		t.Errorf("Expected the create to be applied on its own, got %+v", res)
+	}
 }
+
 func addcookie(app *App, req *http.Request) {
 	session, _ := app.store.Get(req, "user-session")
 	userId := "test-user-id"
@@ -402,3 +856,305 @@ func addcookie(app *App, req *http.Request) {
 		req.AddCookie(cookie)
 	}
 }
+
+func TestUsage(t *testing.T) {
+	app := setupServer()
+	server := httptest.NewServer(app.Router)
+	defer server.Close()
+
+	req, err := http.NewRequest(http.MethodGet, server.URL+"/api/v1/me/usage", nil)
+	if err != nil {
+		t.Fatalf("Failed to create request: %v", err)
+	}
+	addcookie(app, req)
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("Failed to send request: %v", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("Expected status code %d, got %d", http.StatusOK, resp.StatusCode)
+	}
+	var res struct {
+		Data dto.UsageRes `json:"data"`
+	}
+	json.NewDecoder(resp.Body).Decode(&res)
+	if res.Data != (dto.UsageRes{Locations: 1}) {
+		t.Errorf("Expected the seeded location and no limits, got %+v", res.Data)
+	}
+}
+
+func TestLocationExpiry(t *testing.T) {
+	app := setupServer()
+	server := httptest.NewServer(app.Router)
+	defer server.Close()
+
+	req, err := http.NewRequest(http.MethodGet, server.URL+"/api/v1/locations/"+locationID, nil)
+	if err != nil {
+		t.Fatalf("Failed to create request: %v", err)
+	}
+	addcookie(app, req)
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("Failed to send request: %v", err)
+	}
+	defer resp.Body.Close()
+
+	var res struct {
+		Data dto.LocationRes `json:"data"`
+	}
+	json.NewDecoder(resp.Body).Decode(&res)
+	if res.Data.ExpiresAt == "" {
+		t.Errorf("Expected the location to carry its expiry, got %+v", res.Data)
+	}
+}
+
+func TestAttachments(t *testing.T) {
+	blobs, err := localstorage.NewLocalStore(t.TempDir())
+	if err != nil {
+		t.Fatalf("Failed to create store: %v", err)
+	}
+	app := setupServer(location.WithAttachments(repository.NewInMemoryAttachmentRepo(), blobs))
+	server := httptest.NewServer(app.Router)
+	defer server.Close()
+	base := server.URL + "/api/v1/locations/" + locationID + "/attachments"
+
+	do := func(method, url string, body io.Reader, contentType string) *http.Response {
+		t.Helper()
+		req, err := http.NewRequest(method, url, body)
+		if err != nil {
+			t.Fatalf("Failed to create request: %v", err)
+		}
+		if contentType != "" {
+			req.Header.Set("Content-Type", contentType)
+		}
+		addcookie(app, req)
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatalf("Failed to send request: %v", err)
+		}
+		return resp
+	}
+
+	var body bytes.Buffer
+	mw := multipart.NewWriter(&body)
+	fw, _ := mw.CreateFormFile("file", "readme.txt")
+	fw.Write([]byte("hello attachments"))
+	mw.Close()
+	resp := do(http.MethodPost, base, &body, mw.FormDataContentType())
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusCreated {
+		t.Fatalf("Expected status code %d, got %d", http.StatusCreated, resp.StatusCode)
+	}
+	var created struct {
+		Data dto.AttachmentRes `json:"data"`
+	}
+	json.NewDecoder(resp.Body).Decode(&created)
+	if created.Data.FileName != "readme.txt" || created.Data.Size != 17 || !strings.HasPrefix(created.Data.ContentType, "text/plain") {
+		t.Fatalf("Unexpected attachment %+v", created.Data)
+	}
+
+	resp = do(http.MethodGet, base+"/"+created.Data.ID, nil, "")
+	defer resp.Body.Close()
+	content, _ := io.ReadAll(resp.Body)
+	if resp.StatusCode != http.StatusOK || string(content) != "hello attachments" {
+		t.Fatalf("Expected the uploaded content, got %d %q", resp.StatusCode, content)
+	}
+
+	resp = do(http.MethodDelete, base+"/"+created.Data.ID, nil, "")
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("Expected status code %d, got %d", http.StatusOK, resp.StatusCode)
+	}
+	resp = do(http.MethodGet, base+"/"+created.Data.ID, nil, "")
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusNotFound {
+		t.Errorf("Expected status code %d after delete, got %d", http.StatusNotFound, resp.StatusCode)
+	}
+}
+
+func TestCheckIns(t *testing.T) {
+	weatherSvc := weather.NewService(&opmock{}, mockcache.NewMockCache())
+	app := setupServer(location.WithCheckIns(repository.NewInMemoryCheckInRepo(), weatherSvc))
+	server := httptest.NewServer(app.Router)
+	defer server.Close()
+
+	for _, comment := range []string{"first visit", "second visit"} {
+		req, err := http.NewRequest(http.MethodPost, server.URL+"/api/v1/locations/"+locationID+"/checkins", strings.NewReader(`{"comment": "`+comment+`"}`))
+		if err != nil {
+			t.Fatalf("Failed to create request: %v", err)
+		}
+		addcookie(app, req)
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatalf("Failed to send request: %v", err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusCreated {
+			t.Fatalf("Expected status code %d, got %d", http.StatusCreated, resp.StatusCode)
+		}
+	}
+
+	req, err := http.NewRequest(http.MethodGet, server.URL+"/api/v1/checkins?after="+time.Now().Add(-time.Hour).Format(time.RFC3339), nil)
+	if err != nil {
+		t.Fatalf("Failed to create request: %v", err)
+	}
+	addcookie(app, req)
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("Failed to send request: %v", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("Expected status code %d, got %d", http.StatusOK, resp.StatusCode)
+	}
+	var res struct {
+		Data []dto.CheckInRes `json:"data"`
+		Meta dto.JSONMetadata `json:"meta"`
+	}
+	json.NewDecoder(resp.Body).Decode(&res)
+	if len(res.Data) != 2 || res.Data[0].Comment != "second visit" || res.Data[0].City != "Test City" || res.Data[0].Weather == nil {
+		t.Errorf("Expected both check-ins, newest first, with weather, got %+v", res.Data)
+	}
+}
+
+func TestAccounts(t *testing.T) {
+	app := setupServer()
+	server := httptest.NewServer(app.Router)
+	defer server.Close()
+
+	post := func(path, body string, cookies []*http.Cookie) *http.Response {
+		t.Helper()
+		req, err := http.NewRequest(http.MethodPost, server.URL+path, strings.NewReader(body))
+		if err != nil {
+			t.Fatalf("Failed to create request: %v", err)
+		}
+		for _, c := range cookies {
+			req.AddCookie(c)
+		}
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatalf("Failed to send request: %v", err)
+		}
+		resp.Body.Close()
+		return resp
+	}
+	creds := `{"email": "ada@example.com", "password": "correct horse"}`
+
+	resp := post("/api/v1/auth/register", creds, nil)
+	if resp.StatusCode != http.StatusCreated {
+		t.Fatalf("Expected status code %d, got %d", http.StatusCreated, resp.StatusCode)
+	}
+	session := resp.Cookies()
+
+	// The account's session is used like any other by the location routes.
+	resp = post("/api/v1/locations", `{"notes": "n", "nickname": "Home", "city": "Oslo"}`, session)
+	if resp.StatusCode != http.StatusCreated {
+		t.Fatalf("Expected status code %d, got %d", http.StatusCreated, resp.StatusCode)
+	}
+
+	if resp := post("/api/v1/auth/logout", "", session); resp.StatusCode != http.StatusOK {
+		t.Fatalf("Expected status code %d, got %d", http.StatusOK, resp.StatusCode)
+	}
+	if resp := post("/api/v1/auth/login", `{"email": "ada@example.com", "password": "wrong password"}`, nil); resp.StatusCode != http.StatusUnauthorized {
+		t.Fatalf("Expected status code %d, got %d", http.StatusUnauthorized, resp.StatusCode)
+	}
+	resp = post("/api/v1/auth/login", creds, nil)
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("Expected status code %d, got %d", http.StatusOK, resp.StatusCode)
+	}
+
+	req, _ := http.NewRequest(http.MethodGet, server.URL+"/api/v1/me/usage", nil)
+	for _, c := range resp.Cookies() {
+		req.AddCookie(c)
+	}
+	usageResp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("Failed to send request: %v", err)
+	}
+	defer usageResp.Body.Close()
+	var res struct {
+		Data dto.UsageRes `json:"data"`
+	}
+	json.NewDecoder(usageResp.Body).Decode(&res)
+	if res.Data.Locations != 1 {
+		t.Errorf("Expected the location saved before logging out, got %d", res.Data.Locations)
+	}
+}
+
+func TestRegisterAdoptsAnonymousLocations(t *testing.T) {
+	app := setupServer()
+	server := httptest.NewServer(app.Router)
+	defer server.Close()
+
+	// The anonymous test session owns the seeded location.
+	req, _ := http.NewRequest(http.MethodPost, server.URL+"/api/v1/auth/register", strings.NewReader(`{"email": "ada@example.com", "password": "correct horse"}`))
+	addcookie(app, req)
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("Failed to send request: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusCreated {
+		t.Fatalf("Expected status code %d, got %d", http.StatusCreated, resp.StatusCode)
+	}
+
+	req, _ = http.NewRequest(http.MethodGet, server.URL+"/api/v1/locations", nil)
+	for _, c := range resp.Cookies() {
+		req.AddCookie(c)
+	}
+	listResp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("Failed to send request: %v", err)
+	}
+	defer listResp.Body.Close()
+	body, _ := io.ReadAll(listResp.Body)
+	if !strings.Contains(string(body), `"city": "Test City"`) {
+		t.Errorf("Expected the anonymous session's location in the account, got %s", body)
+	}
+}
+
+func TestUploadLimitFollowsConfig(t *testing.T) {
+	blobs, err := localstorage.NewLocalStore(t.TempDir())
+	if err != nil {
+		t.Fatalf("Failed to create store: %v", err)
+	}
+	const limit = 9 << 20
+	app := setupServerWithUploads(limit,
+		location.WithAttachments(repository.NewInMemoryAttachmentRepo(), blobs),
+		location.WithQuota(location.Quota{MaxAttachmentBytes: limit}),
+	)
+	server := httptest.NewServer(app.Router)
+	defer server.Close()
+
+	upload := func(size int) (int, string) {
+		t.Helper()
+		var body bytes.Buffer
+		mw := multipart.NewWriter(&body)
+		fw, _ := mw.CreateFormFile("file", "big.bin")
+		fw.Write(bytes.Repeat([]byte("x"), size))
+		mw.Close()
+		req, _ := http.NewRequest(http.MethodPost, server.URL+"/api/v1/locations/"+locationID+"/attachments", &body)
+		req.Header.Set("Content-Type", mw.FormDataContentType())
+		// Idempotency buffers the whole body, so it must allow uploads
+		// too.
+		req.Header.Set("Idempotency-Key", fmt.Sprintf("upload-%d", size))
+		addcookie(app, req)
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatalf("Failed to send request: %v", err)
+		}
+		defer resp.Body.Close()
+		respBody, _ := io.ReadAll(resp.Body)
+		return resp.StatusCode, string(respBody)
+	}
+
+	if status, body := upload(limit - 1); status != http.StatusCreated {
+		t.Fatalf("Expected a file under the configured limit to be accepted, got %d: %s", status, body)
+	}
+	status, body := upload(limit + 1)
+	if status != http.StatusRequestEntityTooLarge || !strings.Contains(body, fmt.Sprintf("the maximum is %d bytes", limit)) {
+		t.Errorf("Expected 413 naming the configured limit, got %d: %s", status, body)
+	}
+}
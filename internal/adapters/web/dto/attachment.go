@@ -0,0 +1,31 @@
+package dto
+
+import "github.com/lafetz/weavo/internal/core/domain"
+
+type AttachmentRes struct {
+	ID           string `json:"id"`
+	FileName     string `json:"file_name"`
+	ContentType  string `json:"content_type"`
+	Size         int64  `json:"size"`
+	HasThumbnail bool   `json:"has_thumbnail"`
+	CreatedAt    string `json:"created_at"`
+}
+
+func GetAttachmentRes(a domain.Attachment) AttachmentRes {
+	return AttachmentRes{
+		ID:           a.Id,
+		FileName:     a.FileName,
+		ContentType:  a.ContentType,
+		Size:         a.Size,
+		HasThumbnail: a.HasThumbnail,
+		CreatedAt:    a.CreatedAt.String(),
+	}
+}
+
+func GetAttachmentsRes(attachments []domain.Attachment) []AttachmentRes {
+	res := make([]AttachmentRes, 0, len(attachments))
+	for _, a := range attachments {
+		res = append(res, GetAttachmentRes(a))
+	}
+	return res
+}
@@ -0,0 +1,81 @@
+package dto
+
+import (
+	"net/http"
+
+	"github.com/lafetz/weavo/internal/core/service/location"
+)
+
+type BatchReq struct {
+	Operations []BatchOpReq `json:"operations" validate:"required,min=1"`
+}
+
+// BatchOpReq is one operation of a batch. Updates and deletes name their
+// target with id; a non-zero version makes them conditional like If-Match.
+// Force lets a create go through even if it looks like a duplicate.
+type BatchOpReq struct {
+	Op       string       `json:"op" validate:"oneof=create update delete"`
+	Id       string       `json:"id" validate:"required_unless=Op create"`
+	Version  int64        `json:"version" validate:"gte=0"`
+	Force    bool         `json:"force"`
+	Location *LocationReq `json:"location" validate:"required_unless=Op delete"`
+}
+
+func (o *BatchOpReq) ToDomain() location.BatchOperation {
+	op := location.BatchOperation{Op: location.BatchOp(o.Op), Force: o.Force}
+	if o.Location != nil {
+		op.Location = o.Location.ToDomain()
+	}
+	op.Location.Id = o.Id
+	op.Location.Version = o.Version
+	return op
+}
+
+type BatchResultRes struct {
+	Index int    `json:"index"`
+	Op    string `json:"op"`
+	// Status is the HTTP status the operation would have had as a request
+	// of its own.
+	Status   int               `json:"status"`
+	Error    string            `json:"error,omitempty"`
+	Errors   map[string]string `json:"errors,omitempty"`
+	Location *LocationRes      `json:"location,omitempty"`
+}
+
+type BatchRes struct {
+	Atomic    bool             `json:"atomic"`
+	Total     int              `json:"total"`
+	Succeeded int              `json:"succeeded"`
+	Failed    int              `json:"failed"`
+	Results   []BatchResultRes `json:"results"`
+}
+
+// GetBatchRes merges the operations rejected by validation with the results
+// of the others. rejected[i] decides whether operation i was rejected;
+// results holds one entry per remaining operation, in order, and status
+// maps each to its HTTP status and error message.
+func GetBatchRes(atomic bool, ops []BatchOpReq, rejected []map[string]string, results []location.BatchResult, status func(op string, err error) (int, string)) BatchRes {
+	res := BatchRes{Atomic: atomic, Total: len(ops), Results: make([]BatchResultRes, 0, len(ops))}
+	next := 0
+	for i, op := range ops {
+		r := BatchResultRes{Index: i, Op: op.Op}
+		if len(rejected[i]) > 0 {
+			r.Status, r.Error, r.Errors = http.StatusUnprocessableEntity, "validation error", rejected[i]
+		} else {
+			result := results[next]
+			next++
+			r.Status, r.Error = status(op.Op, result.Err)
+			if result.Err == nil && op.Op != string(location.BatchDelete) {
+				loc := GetLocationRes(result.Location)
+				r.Location = &loc
+			}
+		}
+		if r.Status < http.StatusMultipleChoices {
+			res.Succeeded++
+		} else {
+			res.Failed++
+		}
+		res.Results = append(res.Results, r)
+	}
+	return res
+}
@@ -0,0 +1,66 @@
+package dto
+
+import (
+	"time"
+
+	"github.com/lafetz/weavo/internal/core/domain"
+	"github.com/lafetz/weavo/internal/core/service/location"
+)
+
+type CheckInReq struct {
+	Comment string `json:"comment" validate:"max=1000"`
+}
+
+type CheckInRes struct {
+	ID         string      `json:"id"`
+	LocationID string      `json:"location_id"`
+	Comment    string      `json:"comment,omitempty"`
+	City       string      `json:"city"`
+	Nickname   string      `json:"nickname"`
+	Weather    *WeatherRes `json:"weather"`
+	CreatedAt  string      `json:"created_at"`
+}
+
+func GetCheckInRes(c domain.CheckIn) CheckInRes {
+	res := CheckInRes{
+		ID:         c.Id,
+		LocationID: c.LocationID,
+		Comment:    c.Comment,
+		City:       c.City,
+		Nickname:   c.Nickname,
+		CreatedAt:  c.CreatedAt.String(),
+	}
+	if c.Weather != nil {
+		weather := GetWeatherRes(*c.Weather)
+		res.Weather = &weather
+	}
+	return res
+}
+
+func GetCheckInsRes(checkIns []domain.CheckIn) []CheckInRes {
+	res := make([]CheckInRes, 0, len(checkIns))
+	for _, c := range checkIns {
+		res = append(res, GetCheckInRes(c))
+	}
+	return res
+}
+
+// TimelineQuery holds the timeline query parameters before they are
+// validated and converted to a location.CheckInFilter.
+type TimelineQuery struct {
+	Page     int    `validate:"gte=1"`
+	PageSize int    `validate:"gte=1,lte=100"`
+	After    string `validate:"omitempty,datetime=2006-01-02T15:04:05Z07:00"`
+	Before   string `validate:"omitempty,datetime=2006-01-02T15:04:05Z07:00"`
+}
+
+func (q *TimelineQuery) ToFilter() location.CheckInFilter {
+	filter := location.CheckInFilter{Page: q.Page, PageSize: q.PageSize}
+	if q.After != "" {
+		filter.After, _ = time.Parse(time.RFC3339, q.After)
+	}
+	if q.Before != "" {
+		filter.Before, _ = time.Parse(time.RFC3339, q.Before)
+	}
+	return filter
+}
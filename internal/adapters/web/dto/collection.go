@@ -0,0 +1,37 @@
+package dto
+
+import "github.com/lafetz/weavo/internal/core/domain"
+
+// request
+type CollectionReq struct {
+	Name string `json:"name" validate:"required,min=1,max=64"`
+}
+
+func (c *CollectionReq) ToDomain() domain.Collection {
+	return domain.Collection{
+		Name: c.Name,
+	}
+}
+
+// response
+type CollectionRes struct {
+	Id        string `json:"id"`
+	Name      string `json:"name"`
+	CreatedAt string `json:"created_at"`
+}
+
+func GetCollectionRes(c domain.Collection) CollectionRes {
+	return CollectionRes{
+		Id:        c.Id,
+		Name:      c.Name,
+		CreatedAt: c.CreatedAt.String(),
+	}
+}
+
+func GetCollectionsRes(collections []domain.Collection) []CollectionRes {
+	res := make([]CollectionRes, 0, len(collections))
+	for _, c := range collections {
+		res = append(res, GetCollectionRes(c))
+	}
+	return res
+}
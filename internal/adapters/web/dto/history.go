@@ -0,0 +1,37 @@
+package dto
+
+import "github.com/lafetz/weavo/internal/core/domain"
+
+type FieldChangeRes struct {
+	Field string `json:"field"`
+	Old   any    `json:"old"`
+	New   any    `json:"new"`
+}
+
+type HistoryEntryRes struct {
+	Version   int64            `json:"version"`
+	Action    string           `json:"action"`
+	ActorID   string           `json:"actor_id"`
+	RequestID string           `json:"request_id,omitempty"`
+	At        string           `json:"at"`
+	Changes   []FieldChangeRes `json:"changes"`
+}
+
+func GetHistoryRes(entries []domain.HistoryEntry) []HistoryEntryRes {
+	res := make([]HistoryEntryRes, 0, len(entries))
+	for _, e := range entries {
+		changes := make([]FieldChangeRes, 0, len(e.Changes))
+		for _, c := range e.Changes {
+			changes = append(changes, FieldChangeRes{Field: c.Field, Old: c.Old, New: c.New})
+		}
+		res = append(res, HistoryEntryRes{
+			Version:   e.Version,
+			Action:    string(e.Action),
+			ActorID:   e.ActorID,
+			RequestID: e.RequestID,
+			At:        e.At.String(),
+			Changes:   changes,
+		})
+	}
+	return res
+}
@@ -1,10 +1,18 @@
 package dto
 
-import "github.com/lafetz/weavo/internal/core/domain"
+import (
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/lafetz/weavo/internal/core/domain"
+	"github.com/lafetz/weavo/internal/core/service/location"
+)
 
 type Coordinates struct {
-	Lat float64 `json:"lat" `
-	Lon float64 `json:"lon"`
+	Lat float64 `json:"lat" validate:"gte=-90,lte=90"`
+	Lon float64 `json:"lon" validate:"gte=-180,lte=180"`
 }
 
 // request
@@ -14,6 +22,8 @@ type LocationReq struct {
 	Nickname    string      `json:"nickname" validate:"required,min=1"`
 	City        string      `json:"city" validate:"required,min=1"`
 	Coordinates Coordinates `json:"coordinates" `
+	Tags        []string    `json:"tags" validate:"omitempty,max=20,dive,min=1,max=32"`
+	Pinned      bool        `json:"pinned"`
 }
 
 func (l *LocationReq) ToDomain() domain.Location {
@@ -26,9 +36,118 @@ func (l *LocationReq) ToDomain() domain.Location {
 			Lat: l.Coordinates.Lat,
 			Lon: l.Coordinates.Lon,
 		},
+		Tags:   l.Tags,
+		Pinned: l.Pinned,
 	}
 }
 
+// NewLocationReq returns the request body that would recreate l. It is the
+// document a merge patch is applied to.
+func NewLocationReq(l domain.Location) LocationReq {
+	return LocationReq{
+		Notes:    l.Notes,
+		Nickname: l.Nickname,
+		City:     l.City,
+		Coordinates: Coordinates{
+			Lat: l.Coordinates.Lat,
+			Lon: l.Coordinates.Lon,
+		},
+		Tags:   l.Tags,
+		Pinned: l.Pinned,
+	}
+}
+
+// LocationsQuery holds the listing query parameters before they are
+// validated and converted to a location.Filter.
+type LocationsQuery struct {
+	Page          int `validate:"gte=1"`
+	PageSize      int `validate:"gte=1,lte=100"`
+	Cursor        string
+	Limit         int    `validate:"gte=1,lte=100"`
+	Sort          string `validate:"oneof=position created_at nickname city"`
+	Order         string `validate:"oneof=asc desc"`
+	City          string
+	Q             string
+	Tag           string
+	Collection    string
+	CreatedAfter  string `validate:"omitempty,datetime=2006-01-02T15:04:05Z07:00"`
+	CreatedBefore string `validate:"omitempty,datetime=2006-01-02T15:04:05Z07:00"`
+	// UseCursor selects cursor pagination even without a cursor, which is
+	// how a client asks for the first page.
+	UseCursor bool
+}
+
+func (q *LocationsQuery) ToFilter() location.Filter {
+	filter := location.Filter{
+		Page:       q.Page,
+		PageSize:   q.PageSize,
+		SortBy:     location.SortField(q.Sort),
+		SortDir:    location.SortDirection(q.Order),
+		City:       q.City,
+		Query:      q.Q,
+		Tag:        q.Tag,
+		Collection: q.Collection,
+	}
+	if q.Cursor != "" || q.UseCursor {
+		filter.Cursor = q.Cursor
+		filter.Limit = q.Limit
+	}
+	if q.CreatedAfter != "" {
+		filter.CreatedAfter, _ = time.Parse(time.RFC3339, q.CreatedAfter)
+	}
+	if q.CreatedBefore != "" {
+		filter.CreatedBefore, _ = time.Parse(time.RFC3339, q.CreatedBefore)
+	}
+	return filter
+}
+
+// ReorderReq lists location IDs in the order the user wants them shown.
+type ReorderReq struct {
+	Ids []string `json:"ids" validate:"required,min=1,max=1000,dive,required"`
+}
+
+// NearbyQuery holds the parameters of a radius search.
+type NearbyQuery struct {
+	Lat      *float64 `validate:"required,gte=-90,lte=90"`
+	Lon      *float64 `validate:"required,gte=-180,lte=180"`
+	RadiusKm *float64 `validate:"required,gt=0,lte=20000"`
+	Limit    int      `validate:"gte=1,lte=100"`
+}
+
+func (q *NearbyQuery) ToDomain() location.NearbyQuery {
+	return location.NearbyQuery{
+		Center:   domain.Coordinates{Lat: *q.Lat, Lon: *q.Lon},
+		RadiusKm: *q.RadiusKm,
+		Limit:    q.Limit,
+	}
+}
+
+var ErrInvalidBoundingBox = errors.New("bbox must be minLon,minLat,maxLon,maxLat")
+
+// ParseBoundingBox parses a "minLon,minLat,maxLon,maxLat" box, the order
+// used by GeoJSON. minLon may exceed maxLon for boxes that cross the
+// antimeridian.
+func ParseBoundingBox(s string) (*domain.BoundingBox, error) {
+	parts := strings.Split(s, ",")
+	if len(parts) != 4 {
+		return nil, ErrInvalidBoundingBox
+	}
+	var v [4]float64
+	for i, p := range parts {
+		f, err := strconv.ParseFloat(strings.TrimSpace(p), 64)
+		if err != nil {
+			return nil, ErrInvalidBoundingBox
+		}
+		v[i] = f
+	}
+	box := domain.BoundingBox{MinLon: v[0], MinLat: v[1], MaxLon: v[2], MaxLat: v[3]}
+	if box.MinLat < -90 || box.MaxLat > 90 || box.MinLat > box.MaxLat ||
+		box.MinLon < -180 || box.MinLon > 180 || box.MaxLon < -180 || box.MaxLon > 180 {
+		return nil, ErrInvalidBoundingBox
+	}
+	return &box, nil
+}
+
 // response
 // get location
 type LocationRes struct {
@@ -37,10 +156,26 @@ type LocationRes struct {
 	Nickname    string      `json:"nickname"`
 	City        string      `json:"city"`
 	Coordinates Coordinates `json:"coordinates"`
+	Tags        []string    `json:"tags"`
+	Collections []string    `json:"collections"`
+	Pinned      bool        `json:"pinned"`
+	Position    int         `json:"position"`
+	Version     int64       `json:"version"`
 	CreatedAt   string      `json:"created_at"`
+	DeletedAt   string      `json:"deleted_at,omitempty"`
+	// ExpiresAt warns when the location will be removed under the retention
+	// policy. It is omitted for locations that are kept forever.
+	ExpiresAt string `json:"expires_at,omitempty"`
 }
 
 func GetLocationRes(l domain.Location) LocationRes {
+	var deletedAt, expiresAt string
+	if !l.DeletedAt.IsZero() {
+		deletedAt = l.DeletedAt.String()
+	}
+	if !l.ExpiresAt.IsZero() {
+		expiresAt = l.ExpiresAt.String()
+	}
 	return LocationRes{
 		Id:       l.Id,
 		Notes:    l.Notes,
@@ -50,17 +185,66 @@ func GetLocationRes(l domain.Location) LocationRes {
 			Lat: l.Coordinates.Lat,
 			Lon: l.Coordinates.Lon,
 		},
-		CreatedAt: l.CreatedAt.String(),
+		Tags:        nonNil(l.Tags),
+		Collections: nonNil(l.Collections),
+		Pinned:      l.Pinned,
+		Position:    l.Position,
+		Version:     l.Version,
+		CreatedAt:   l.CreatedAt.String(),
+		DeletedAt:   deletedAt,
+		ExpiresAt:   expiresAt,
+	}
+}
+
+func GetLocationsListRes(locations []domain.Location) []LocationRes {
+	res := make([]LocationRes, 0, len(locations))
+	for _, l := range locations {
+		res = append(res, GetLocationRes(l))
+	}
+	return res
+}
+
+func GetDuplicatesRes(groups [][]domain.Location) [][]LocationRes {
+	res := make([][]LocationRes, 0, len(groups))
+	for _, group := range groups {
+		res = append(res, GetLocationsListRes(group))
+	}
+	return res
+}
+
+// nonNil makes empty lists encode as [] rather than null.
+func nonNil(s []string) []string {
+	if s == nil {
+		return []string{}
+	}
+	return s
+}
+
+type NearbyLocationRes struct {
+	LocationRes
+	DistanceKm float64 `json:"distance_km"`
+}
+
+func GetNearbyLocationsRes(nearby []location.NearbyLocation) []NearbyLocationRes {
+	res := make([]NearbyLocationRes, 0, len(nearby))
+	for _, n := range nearby {
+		res = append(res, NearbyLocationRes{
+			LocationRes: GetLocationRes(n.Location),
+			DistanceKm:  n.DistanceKm,
+		})
 	}
+	return res
 }
 
 // get locations
 type JSONMetadata struct {
-	CurrentPage  int32 `json:"currentPage"`
-	PageSize     int32 `json:"pageSize"`
-	FirstPage    int32 `json:"firstPage"`
-	LastPage     int32 `json:"lastPage"`
-	TotalRecords int32 `json:"totalRecords"`
+	CurrentPage  int32  `json:"currentPage"`
+	PageSize     int32  `json:"pageSize"`
+	FirstPage    int32  `json:"firstPage"`
+	LastPage     int32  `json:"lastPage"`
+	TotalRecords int32  `json:"totalRecords"`
+	NextCursor   string `json:"nextCursor,omitempty"`
+	PrevCursor   string `json:"prevCursor,omitempty"`
 }
 
 func ConvertToJSONMetadata(meta domain.Metadata) JSONMetadata {
@@ -70,6 +254,8 @@ func ConvertToJSONMetadata(meta domain.Metadata) JSONMetadata {
 		FirstPage:    meta.FirstPage,
 		LastPage:     meta.LastPage,
 		TotalRecords: meta.TotalRecords,
+		NextCursor:   meta.NextCursor,
+		PrevCursor:   meta.PrevCursor,
 	}
 }
 
@@ -0,0 +1,126 @@
+package dto
+
+import (
+	"time"
+
+	"github.com/lafetz/weavo/internal/core/domain"
+	"github.com/lafetz/weavo/internal/core/service/location"
+)
+
+// request
+type ShareReq struct {
+	LocationID   string `json:"location_id"`
+	CollectionID string `json:"collection_id"`
+	ExpiresAt    string `json:"expires_at" validate:"omitempty,datetime=2006-01-02T15:04:05Z07:00"`
+	// Precision is the number of decimal places coordinates are rounded to
+	// for whoever opens the share; 0, the default, shares the exact
+	// coordinates.
+	Precision int `json:"precision" validate:"gte=0,lte=6"`
+}
+
+func (s *ShareReq) ToDomain() domain.Share {
+	share := domain.Share{
+		LocationID:   s.LocationID,
+		CollectionID: s.CollectionID,
+		Precision:    s.Precision,
+	}
+	if s.ExpiresAt != "" {
+		share.ExpiresAt, _ = time.Parse(time.RFC3339, s.ExpiresAt)
+	}
+	return share
+}
+
+// response
+type ShareRes struct {
+	Token        string `json:"token"`
+	Path         string `json:"path"`
+	LocationID   string `json:"location_id,omitempty"`
+	CollectionID string `json:"collection_id,omitempty"`
+	Precision    int    `json:"precision"`
+	ExpiresAt    string `json:"expires_at,omitempty"`
+	CreatedAt    string `json:"created_at"`
+}
+
+func GetShareRes(s domain.Share) ShareRes {
+	var expiresAt string
+	if !s.ExpiresAt.IsZero() {
+		expiresAt = s.ExpiresAt.Format(time.RFC3339)
+	}
+	return ShareRes{
+		Token:        s.Token,
+		Path:         "/api/v1/shared/" + s.Token,
+		LocationID:   s.LocationID,
+		CollectionID: s.CollectionID,
+		Precision:    s.Precision,
+		ExpiresAt:    expiresAt,
+		CreatedAt:    s.CreatedAt.String(),
+	}
+}
+
+func GetSharesRes(shares []domain.Share) []ShareRes {
+	res := make([]ShareRes, 0, len(shares))
+	for _, s := range shares {
+		res = append(res, GetShareRes(s))
+	}
+	return res
+}
+
+// SharedLocationRes is the public view of a shared location. It leaves out
+// everything that identifies the owner or their other data.
+type SharedLocationRes struct {
+	Nickname    string      `json:"nickname"`
+	Notes       string      `json:"notes"`
+	City        string      `json:"city"`
+	Coordinates Coordinates `json:"coordinates"`
+	Tags        []string    `json:"tags"`
+	Weather     *WeatherRes `json:"weather"`
+}
+
+type SharedCollectionRes struct {
+	Name      string              `json:"name"`
+	Locations []SharedLocationRes `json:"locations"`
+}
+
+type SharedRes struct {
+	Location   *SharedLocationRes   `json:"location,omitempty"`
+	Collection *SharedCollectionRes `json:"collection,omitempty"`
+	ExpiresAt  string               `json:"expires_at,omitempty"`
+}
+
+// GetSharedRes builds the public view of shared content. weather returns
+// the current weather in a city, or nil.
+func GetSharedRes(content location.SharedContent, weather func(city string) *domain.Weather) SharedRes {
+	sharedLocation := func(l domain.Location) SharedLocationRes {
+		res := SharedLocationRes{
+			Nickname:    l.Nickname,
+			Notes:       l.Notes,
+			City:        l.City,
+			Coordinates: Coordinates{Lat: l.Coordinates.Lat, Lon: l.Coordinates.Lon},
+			Tags:        nonNil(l.Tags),
+		}
+		if w := weather(l.City); w != nil {
+			wr := GetWeatherRes(*w)
+			res.Weather = &wr
+		}
+		return res
+	}
+
+	var res SharedRes
+	if !content.Share.ExpiresAt.IsZero() {
+		res.ExpiresAt = content.Share.ExpiresAt.Format(time.RFC3339)
+	}
+	if content.Location != nil {
+		l := sharedLocation(*content.Location)
+		res.Location = &l
+	}
+	if content.Collection != nil {
+		res.Collection = &SharedCollectionRes{
+			Name:      content.Collection.Name,
+			Locations: make([]SharedLocationRes, 0, len(content.Locations)),
+		}
+		for _, l := range content.Locations {
+			res.Collection.Locations = append(res.Collection.Locations, sharedLocation(l))
+		}
+	}
+	return res
+}
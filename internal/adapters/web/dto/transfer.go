@@ -0,0 +1,433 @@
+package dto
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/lafetz/weavo/internal/core/domain"
+	"github.com/lafetz/weavo/internal/core/service/location"
+)
+
+const (
+	FormatJSON    = "json"
+	FormatCSV     = "csv"
+	FormatGeoJSON = "geojson"
+	FormatGPX     = "gpx"
+	FormatKML     = "kml"
+)
+
+var (
+	ErrInvalidCSVHeader = errors.New("csv header must contain lat and lon columns")
+	ErrInvalidGeoJSON   = errors.New("body must be a GeoJSON FeatureCollection")
+)
+
+// csvHeader is the column order of CSV exports. Imports match columns by
+// name, so they may come in any order and unknown columns are ignored.
+var csvHeader = []string{"id", "nickname", "city", "lat", "lon", "notes", "tags", "pinned", "created_at"}
+
+// csvTagSeparator joins a location's tags in its single CSV cell.
+const csvTagSeparator = ";"
+
+// ExportContentType returns the media type of an export in format.
+func ExportContentType(format string) string {
+	switch format {
+	case FormatCSV:
+		return "text/csv; charset=utf-8"
+	case FormatGeoJSON:
+		return "application/geo+json"
+	case FormatGPX:
+		return "application/gpx+xml"
+	case FormatKML:
+		return "application/vnd.google-earth.kml+xml"
+	}
+	return "application/json"
+}
+
+// ExportQuery holds the parameters of an export.
+type ExportQuery struct {
+	Format     string `validate:"oneof=json csv geojson gpx kml"`
+	Tag        string
+	Collection string
+	// Weather adds the current weather to the description of GPX and KML
+	// exports.
+	Weather bool
+}
+
+func (q *ExportQuery) ToFilter() location.Filter {
+	return location.Filter{Tag: q.Tag, Collection: q.Collection}
+}
+
+// LocationEncoder writes locations to an export one at a time.
+type LocationEncoder interface {
+	// Encode writes l. weather is the current weather at l, or nil; only
+	// formats with a free-text description use it.
+	Encode(l domain.Location, weather *domain.Weather) error
+	// Close writes whatever the format needs after the last location.
+	Close() error
+}
+
+// NewLocationEncoder returns an encoder writing format to w. The format
+// must have been validated with ExportQuery.
+func NewLocationEncoder(format string, w io.Writer) LocationEncoder {
+	switch format {
+	case FormatCSV:
+		return &csvEncoder{w: csv.NewWriter(w)}
+	case FormatGeoJSON:
+		return &jsonListEncoder{w: w, open: `{"type":"FeatureCollection","features":[`, close: "]}\n", item: toFeature}
+	case FormatGPX:
+		return newXMLListEncoder(w, gpxOpen, gpxClose, toWaypoint)
+	case FormatKML:
+		return newXMLListEncoder(w, kmlOpen, kmlClose, toPlacemark)
+	}
+	return &jsonListEncoder{w: w, open: "[", close: "]\n", item: func(l domain.Location) any { return GetLocationRes(l) }}
+}
+
+// jsonListEncoder streams a JSON array, wrapped in open and close.
+type jsonListEncoder struct {
+	w           io.Writer
+	open, close string
+	item        func(domain.Location) any
+	started     bool
+}
+
+func (e *jsonListEncoder) Encode(l domain.Location, _ *domain.Weather) error {
+	js, err := json.Marshal(e.item(l))
+	if err != nil {
+		return err
+	}
+	sep := ",\n"
+	if !e.started {
+		sep = e.open + "\n"
+		e.started = true
+	}
+	_, err = io.WriteString(e.w, sep+string(js))
+	return err
+}
+
+func (e *jsonListEncoder) Close() error {
+	prefix := "\n"
+	if !e.started {
+		prefix = e.open
+	}
+	_, err := io.WriteString(e.w, prefix+e.close)
+	return err
+}
+
+type csvEncoder struct {
+	w       *csv.Writer
+	started bool
+}
+
+func (e *csvEncoder) Encode(l domain.Location, _ *domain.Weather) error {
+	if !e.started {
+		e.started = true
+		if err := e.w.Write(csvHeader); err != nil {
+			return err
+		}
+	}
+	return e.w.Write([]string{
+		l.Id,
+		l.Nickname,
+		l.City,
+		strconv.FormatFloat(l.Coordinates.Lat, 'f', -1, 64),
+		strconv.FormatFloat(l.Coordinates.Lon, 'f', -1, 64),
+		l.Notes,
+		strings.Join(l.Tags, csvTagSeparator),
+		strconv.FormatBool(l.Pinned),
+		l.CreatedAt.Format(time.RFC3339),
+	})
+}
+
+func (e *csvEncoder) Close() error {
+	if !e.started {
+		e.started = true
+		if err := e.w.Write(csvHeader); err != nil {
+			return err
+		}
+	}
+	e.w.Flush()
+	return e.w.Error()
+}
+
+type geoJSONPoint struct {
+	Type        string    `json:"type"`
+	Coordinates []float64 `json:"coordinates"`
+}
+
+type featureProperties struct {
+	Nickname    string   `json:"nickname"`
+	City        string   `json:"city"`
+	Notes       string   `json:"notes"`
+	Tags        []string `json:"tags"`
+	Collections []string `json:"collections,omitempty"`
+	Pinned      bool     `json:"pinned"`
+	CreatedAt   string   `json:"created_at,omitempty"`
+}
+
+type geoJSONFeature struct {
+	Type       string            `json:"type"`
+	Id         string            `json:"id,omitempty"`
+	Geometry   *geoJSONPoint     `json:"geometry"`
+	Properties featureProperties `json:"properties"`
+}
+
+func toFeature(l domain.Location) any {
+	return geoJSONFeature{
+		Type: "Feature",
+		Id:   l.Id,
+		// GeoJSON positions are longitude first.
+		Geometry: &geoJSONPoint{Type: "Point", Coordinates: []float64{l.Coordinates.Lon, l.Coordinates.Lat}},
+		Properties: featureProperties{
+			Nickname:    l.Nickname,
+			City:        l.City,
+			Notes:       l.Notes,
+			Tags:        nonNil(l.Tags),
+			Collections: l.Collections,
+			Pinned:      l.Pinned,
+			CreatedAt:   l.CreatedAt.Format(time.RFC3339),
+		},
+	}
+}
+
+// ImportQuery holds the options of an import.
+type ImportQuery struct {
+	Format      string `validate:"oneof=json csv geojson"`
+	OnDuplicate string `validate:"oneof=skip create"`
+	DryRun      bool
+}
+
+func (q *ImportQuery) ToOptions() location.ImportOptions {
+	return location.ImportOptions{
+		DryRun:      q.DryRun,
+		OnDuplicate: location.DuplicatePolicy(q.OnDuplicate),
+	}
+}
+
+// ImportRow is one decoded row of an import. Errors holds the problems
+// found while decoding it, keyed by field; the row still has to be
+// validated.
+type ImportRow struct {
+	Req    LocationReq
+	Errors map[string]string
+}
+
+func (r *ImportRow) addError(field, msg string) {
+	if r.Errors == nil {
+		r.Errors = make(map[string]string)
+	}
+	r.Errors[field] = msg
+}
+
+// checkCoordinates reports coordinates outside the valid range.
+func (r *ImportRow) checkCoordinates() {
+	if lat := r.Req.Coordinates.Lat; lat < -90 || lat > 90 {
+		r.addError("lat", "must be between -90 and 90")
+	}
+	if lon := r.Req.Coordinates.Lon; lon < -180 || lon > 180 {
+		r.addError("lon", "must be between -180 and 180")
+	}
+}
+
+// ParseImport decodes an import in format, which must have been validated
+// with ImportQuery. Each of the formats produced by an export can be read
+// back. Problems with a single row are recorded on that row; the error is
+// only set when the input as a whole cannot be read.
+func ParseImport(format string, r io.Reader) ([]ImportRow, error) {
+	switch format {
+	case FormatCSV:
+		return parseCSV(r)
+	case FormatGeoJSON:
+		return parseGeoJSON(r)
+	}
+	return parseJSON(r)
+}
+
+func parseJSON(r io.Reader) ([]ImportRow, error) {
+	var raw []json.RawMessage
+	if err := json.NewDecoder(r).Decode(&raw); err != nil {
+		return nil, fmt.Errorf("body must be a JSON array of locations: %w", err)
+	}
+	rows := make([]ImportRow, len(raw))
+	for i, item := range raw {
+		if err := json.Unmarshal(item, &rows[i].Req); err != nil {
+			rows[i].addError("row", "must be a location object")
+			continue
+		}
+		rows[i].checkCoordinates()
+	}
+	return rows, nil
+}
+
+func parseCSV(r io.Reader) ([]ImportRow, error) {
+	cr := csv.NewReader(r)
+	cr.FieldsPerRecord = -1
+	cr.TrimLeadingSpace = true
+	header, err := cr.Read()
+	if err != nil {
+		return nil, fmt.Errorf("reading csv header: %w", err)
+	}
+	columns := make(map[string]int, len(header))
+	for i, name := range header {
+		columns[strings.ToLower(strings.TrimSpace(name))] = i
+	}
+	if _, ok := columns["lat"]; !ok {
+		return nil, ErrInvalidCSVHeader
+	}
+	if _, ok := columns["lon"]; !ok {
+		return nil, ErrInvalidCSVHeader
+	}
+
+	var rows []ImportRow
+	for {
+		record, err := cr.Read()
+		if err == io.EOF {
+			return rows, nil
+		}
+		if err != nil {
+			return nil, fmt.Errorf("reading csv: %w", err)
+		}
+		cell := func(name string) string {
+			if i, ok := columns[name]; ok && i < len(record) {
+				return strings.TrimSpace(record[i])
+			}
+			return ""
+		}
+
+		var row ImportRow
+		row.Req.Nickname = cell("nickname")
+		row.Req.City = cell("city")
+		row.Req.Notes = cell("notes")
+		for _, tag := range strings.Split(cell("tags"), csvTagSeparator) {
+			if tag = strings.TrimSpace(tag); tag != "" {
+				row.Req.Tags = append(row.Req.Tags, tag)
+			}
+		}
+		if v := cell("pinned"); v != "" {
+			pinned, err := strconv.ParseBool(v)
+			if err != nil {
+				row.addError("pinned", "must be true or false")
+			}
+			row.Req.Pinned = pinned
+		}
+		for _, c := range []struct {
+			name string
+			dst  *float64
+		}{{"lat", &row.Req.Coordinates.Lat}, {"lon", &row.Req.Coordinates.Lon}} {
+			v, err := strconv.ParseFloat(cell(c.name), 64)
+			if err != nil {
+				row.addError(c.name, "must be a number")
+				continue
+			}
+			*c.dst = v
+		}
+		row.checkCoordinates()
+		rows = append(rows, row)
+	}
+}
+
+func parseGeoJSON(r io.Reader) ([]ImportRow, error) {
+	var collection struct {
+		Type     string            `json:"type"`
+		Features []json.RawMessage `json:"features"`
+	}
+	if err := json.NewDecoder(r).Decode(&collection); err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidGeoJSON, err)
+	}
+	if collection.Type != "FeatureCollection" {
+		return nil, ErrInvalidGeoJSON
+	}
+	rows := make([]ImportRow, len(collection.Features))
+	for i, item := range collection.Features {
+		var feature geoJSONFeature
+		row := &rows[i]
+		if err := json.Unmarshal(item, &feature); err != nil || feature.Type != "Feature" {
+			row.addError("row", "must be a GeoJSON Feature")
+			continue
+		}
+		if feature.Geometry == nil || feature.Geometry.Type != "Point" || len(feature.Geometry.Coordinates) < 2 {
+			row.addError("geometry", "must be a Point")
+			continue
+		}
+		p := feature.Properties
+		row.Req = LocationReq{
+			Nickname:    p.Nickname,
+			City:        p.City,
+			Notes:       p.Notes,
+			Tags:        p.Tags,
+			Pinned:      p.Pinned,
+			Coordinates: Coordinates{Lat: feature.Geometry.Coordinates[1], Lon: feature.Geometry.Coordinates[0]},
+		}
+		row.checkCoordinates()
+	}
+	return rows, nil
+}
+
+// ImportRowRes reports what happened to one row of an import. Row numbers
+// start at 1 and do not count a CSV header.
+type ImportRowRes struct {
+	Row         int               `json:"row"`
+	Status      string            `json:"status"`
+	Location    *LocationRes      `json:"location,omitempty"`
+	DuplicateOf string            `json:"duplicate_of,omitempty"`
+	Errors      map[string]string `json:"errors,omitempty"`
+}
+
+// ImportStatusRejected marks a row that failed validation, here or in the
+// service, and was not imported.
+const ImportStatusRejected = "rejected"
+
+type ImportReportRes struct {
+	DryRun     bool           `json:"dry_run"`
+	Total      int            `json:"total"`
+	Accepted   int            `json:"accepted"`
+	Duplicates int            `json:"duplicates"`
+	Rejected   int            `json:"rejected"`
+	Rows       []ImportRowRes `json:"rows"`
+}
+
+// GetImportReport merges the rejected rows with the outcomes of the rows
+// that were passed to the service. rows[i].Errors decides whether row i was rejected;
+// outcomes holds one entry per remaining row, in order.
+func GetImportReport(dryRun bool, rows []ImportRow, outcomes []location.ImportOutcome) ImportReportRes {
+	report := ImportReportRes{DryRun: dryRun, Total: len(rows), Rows: make([]ImportRowRes, 0, len(rows))}
+	next := 0
+	for i, row := range rows {
+		res := ImportRowRes{Row: i + 1}
+		if len(row.Errors) > 0 {
+			res.Status = ImportStatusRejected
+			res.Errors = row.Errors
+			report.Rejected++
+			report.Rows = append(report.Rows, res)
+			continue
+		}
+		outcome := outcomes[next]
+		next++
+		if outcome.Status == location.ImportRejected {
+			res.Status = ImportStatusRejected
+			res.Errors = map[string]string{"row": outcome.Err.Error()}
+			if errors.Is(outcome.Err, location.ErrNotesTooLong) {
+				res.Errors = map[string]string{"notes": outcome.Err.Error()}
+			}
+			report.Rejected++
+			report.Rows = append(report.Rows, res)
+			continue
+		}
+		res.Status = string(outcome.Status)
+		res.DuplicateOf = outcome.DuplicateOf
+		loc := GetLocationRes(outcome.Location)
+		res.Location = &loc
+		if outcome.Status == location.ImportDuplicate {
+			report.Duplicates++
+		} else {
+			report.Accepted++
+		}
+		report.Rows = append(report.Rows, res)
+	}
+	return report
+}
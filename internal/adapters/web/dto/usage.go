@@ -0,0 +1,21 @@
+package dto
+
+import "github.com/lafetz/weavo/internal/core/service/location"
+
+// UsageRes reports what a user has stored against their quota. A limit of
+// 0 means there is none.
+type UsageRes struct {
+	Locations          int   `json:"locations"`
+	MaxLocations       int   `json:"max_locations"`
+	MaxNotesLength     int   `json:"max_notes_length"`
+	MaxAttachmentBytes int64 `json:"max_attachment_bytes"`
+}
+
+func GetUsageRes(u location.Usage) UsageRes {
+	return UsageRes{
+		Locations:          u.Locations,
+		MaxLocations:       u.Quota.MaxLocations,
+		MaxNotesLength:     u.Quota.MaxNotesLength,
+		MaxAttachmentBytes: u.Quota.MaxAttachmentBytes,
+	}
+}
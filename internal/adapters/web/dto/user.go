@@ -0,0 +1,27 @@
+package dto
+
+import "github.com/lafetz/weavo/internal/core/domain"
+
+type RegisterReq struct {
+	Email    string `json:"email" validate:"required,email,max=254"`
+	Password string `json:"password" validate:"required,min=8,max=128"`
+}
+
+type LoginReq struct {
+	Email    string `json:"email" validate:"required,max=254"`
+	Password string `json:"password" validate:"required,max=128"`
+}
+
+type UserRes struct {
+	ID        string `json:"id"`
+	Email     string `json:"email"`
+	CreatedAt string `json:"created_at"`
+}
+
+func GetUserRes(u domain.User) UserRes {
+	return UserRes{
+		ID:        u.Id,
+		Email:     u.Email,
+		CreatedAt: u.CreatedAt.String(),
+	}
+}
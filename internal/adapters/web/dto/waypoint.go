@@ -0,0 +1,137 @@
+package dto
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/lafetz/weavo/internal/core/domain"
+)
+
+const (
+	gpxOpen = xml.Header + `<gpx version="1.1" creator="weavo" xmlns="http://www.topografix.com/GPX/1/1">`
+	// The XML encoder puts a newline between elements but not after the
+	// last one, so the closing tags start with one.
+	gpxClose = "\n</gpx>\n"
+	kmlOpen  = xml.Header + `<kml xmlns="http://www.opengis.net/kml/2.2"><Document><name>Weavo locations</name>`
+	kmlClose = "\n</Document></kml>\n"
+)
+
+// xmlListEncoder writes one XML element per location between open and
+// close.
+type xmlListEncoder struct {
+	w           io.Writer
+	enc         *xml.Encoder
+	open, close string
+	item        func(domain.Location, *domain.Weather) any
+	started     bool
+}
+
+func newXMLListEncoder(w io.Writer, open, close string, item func(domain.Location, *domain.Weather) any) *xmlListEncoder {
+	enc := xml.NewEncoder(w)
+	enc.Indent("", "  ")
+	return &xmlListEncoder{w: w, enc: enc, open: open, close: close, item: item}
+}
+
+func (e *xmlListEncoder) start() error {
+	if e.started {
+		return nil
+	}
+	e.started = true
+	_, err := io.WriteString(e.w, e.open+"\n")
+	return err
+}
+
+func (e *xmlListEncoder) Encode(l domain.Location, weather *domain.Weather) error {
+	if err := e.start(); err != nil {
+		return err
+	}
+	return e.enc.Encode(e.item(l, weather))
+}
+
+func (e *xmlListEncoder) Close() error {
+	if !e.started {
+		_, err := io.WriteString(e.w, e.open+e.close)
+		return err
+	}
+	_, err := io.WriteString(e.w, e.close)
+	return err
+}
+
+// gpxWaypoint is a GPX 1.1 wpt. The schema fixes the order of its children.
+type gpxWaypoint struct {
+	XMLName xml.Name `xml:"wpt"`
+	Lat     float64  `xml:"lat,attr"`
+	Lon     float64  `xml:"lon,attr"`
+	Time    string   `xml:"time,omitempty"`
+	Name    string   `xml:"name"`
+	Desc    string   `xml:"desc,omitempty"`
+	Type    string   `xml:"type,omitempty"`
+}
+
+func toWaypoint(l domain.Location, weather *domain.Weather) any {
+	wpt := gpxWaypoint{
+		Lat:  l.Coordinates.Lat,
+		Lon:  l.Coordinates.Lon,
+		Name: l.Nickname,
+		Desc: describe(l, weather),
+		Type: l.City,
+	}
+	if !l.CreatedAt.IsZero() {
+		wpt.Time = l.CreatedAt.UTC().Format(time.RFC3339)
+	}
+	return wpt
+}
+
+type kmlPoint struct {
+	// Coordinates is "lon,lat".
+	Coordinates string `xml:"coordinates"`
+}
+
+type kmlPlacemark struct {
+	XMLName     xml.Name `xml:"Placemark"`
+	Id          string   `xml:"id,attr,omitempty"`
+	Name        string   `xml:"name"`
+	Address     string   `xml:"address,omitempty"`
+	Description string   `xml:"description,omitempty"`
+	Point       kmlPoint `xml:"Point"`
+}
+
+func toPlacemark(l domain.Location, weather *domain.Weather) any {
+	return kmlPlacemark{
+		Id:          l.Id,
+		Name:        l.Nickname,
+		Address:     l.City,
+		Description: describe(l, weather),
+		Point: kmlPoint{
+			Coordinates: strconv.FormatFloat(l.Coordinates.Lon, 'f', -1, 64) + "," +
+				strconv.FormatFloat(l.Coordinates.Lat, 'f', -1, 64),
+		},
+	}
+}
+
+// describe returns the notes of l followed by a summary of the weather, if
+// any.
+func describe(l domain.Location, weather *domain.Weather) string {
+	if weather == nil {
+		return l.Notes
+	}
+	summary := fmt.Sprintf("Weather: %s%s", strconv.FormatFloat(weather.Temperature, 'f', 1, 64), temperatureUnit(weather.Units))
+	if weather.Description != "" {
+		summary += ", " + weather.Description
+	}
+	return strings.TrimSpace(l.Notes + "\n\n" + summary)
+}
+
+func temperatureUnit(units string) string {
+	switch units {
+	case "metric":
+		return "°C"
+	case "imperial":
+		return "°F"
+	}
+	return " K"
+}
@@ -0,0 +1,249 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/lafetz/weavo/internal/adapters/web/dto"
+	"github.com/lafetz/weavo/internal/adapters/web/webutils"
+	"github.com/lafetz/weavo/internal/core/domain"
+	"github.com/lafetz/weavo/internal/core/service/location"
+)
+
+const (
+	// defaultUploadBytes bounds an upload request when attachments have no
+	// size limit of their own.
+	defaultUploadBytes = 8 << 20
+	// multipartOverhead leaves room for the multipart framing and headers
+	// around the file.
+	multipartOverhead = 64 << 10
+)
+
+// MaxUploadBytes returns the largest upload request accepted for files of at
+// most maxAttachmentBytes, multipart framing included. The file itself is
+// held to that limit by the service; 0 means no limit, and requests are
+// held to 8 MiB.
+func MaxUploadBytes(maxAttachmentBytes int64) int64 {
+	if maxAttachmentBytes <= 0 {
+		return defaultUploadBytes
+	}
+	return maxAttachmentBytes + multipartOverhead
+}
+
+// writeAttachmentError maps attachment service errors to responses and logs
+// anything unexpected.
+func writeAttachmentError(w http.ResponseWriter, logger *slog.Logger, err error, action string) {
+	var maxBytesError *http.MaxBytesError
+	switch {
+	case errors.As(err, &maxBytesError):
+		webutils.WriteJSON(w, http.StatusRequestEntityTooLarge, fmt.Sprintf("upload must not be larger than %d bytes", maxBytesError.Limit), nil, nil)
+	case errors.Is(err, location.ErrAttachmentTooLarge):
+		webutils.WriteJSON(w, http.StatusRequestEntityTooLarge, err.Error(), nil, nil)
+	case errors.Is(err, location.ErrEmptyAttachment):
+		webutils.WriteJSON(w, http.StatusBadRequest, err.Error(), nil, nil)
+	case errors.Is(err, location.ErrAttachmentLimit):
+		webutils.WriteJSON(w, http.StatusForbidden, err.Error(), nil, nil)
+	case errors.Is(err, location.ErrLocationNotFound):
+		webutils.WriteJSON(w, http.StatusNotFound, "location not found", nil, nil)
+	case errors.Is(err, location.ErrAttachmentNotFound):
+		webutils.WriteJSON(w, http.StatusNotFound, "attachment not found", nil, nil)
+	case errors.Is(err, location.ErrNoThumbnail):
+		webutils.WriteJSON(w, http.StatusNotFound, err.Error(), nil, nil)
+	case errors.Is(err, location.ErrUnAuthorized):
+		webutils.WriteJSON(w, http.StatusForbidden, "forbidden", nil, nil)
+	case errors.Is(err, location.ErrAttachmentsOff):
+		webutils.WriteJSON(w, http.StatusNotImplemented, err.Error(), nil, nil)
+	default:
+		webutils.WriteJSON(w, http.StatusInternalServerError, "internal server error", nil, nil)
+		logger.Error("error on "+action, "error", err.Error())
+	}
+}
+
+// UploadAttachment handles the HTTP request to attach a file to a location.
+//
+// @Summary Upload an attachment
+// @Description Attaches the "file" field of a multipart form to one of the user's locations. The content type is sniffed from the file rather than taken from the request, and GIF, JPEG and PNG images get a thumbnail.
+// @Description A location may have at most 20 attachments, and each file is held to the user's size limit.
+// @Tags attachments
+// @Accept multipart/form-data
+// @Produce json
+// @Param id path string true "Location ID"
+// @Param file formData file true "File to attach"
+// @Param Idempotency-Key header string false "Retries with the same key replay the first response instead of running again"
+// @Success 201 {object} dto.AttachmentRes "attachment uploaded successfully"
+// @Failure 400 {string} string "invalid multipart body"
+// @Failure 403 {string} string "forbidden or attachment limit reached"
+// @Failure 404 {string} string "location not found"
+// @Failure 409 {string} string "a request with this Idempotency-Key is still in progress"
+// @Failure 413 {string} string "attachment is too large"
+// @Failure 500 {string} string "internal server error"
+// @Router /api/v1/locations/{id}/attachments [post]
+func UploadAttachment(locationSvc location.ServiceApi, logger *slog.Logger, maxAttachmentBytes int64) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		r.Body = http.MaxBytesReader(w, r.Body, MaxUploadBytes(maxAttachmentBytes))
+		part, err := attachmentPart(r)
+		if err != nil {
+			var maxBytesError *http.MaxBytesError
+			if errors.As(err, &maxBytesError) {
+				writeAttachmentError(w, logger, uploadError(err, maxAttachmentBytes), "uploading attachment")
+				return
+			}
+			webutils.WriteJSON(w, http.StatusBadRequest, err.Error(), nil, nil)
+			return
+		}
+
+		userId := r.Context().Value("userId").(string)
+		attachment, err := locationSvc.AddAttachment(r.Context(), r.PathValue("id"), userId, part.FileName(), part)
+		if err != nil {
+			writeAttachmentError(w, logger, uploadError(err, maxAttachmentBytes), "uploading attachment")
+			return
+		}
+		webutils.WriteJSON(w, http.StatusCreated, "attachment uploaded successfully", dto.GetAttachmentRes(attachment), nil)
+	}
+}
+
+// uploadError reports a request cut off by the upload limit as a file over
+// maxAttachmentBytes, the limit the client is told about.
+func uploadError(err error, maxAttachmentBytes int64) error {
+	var maxBytesError *http.MaxBytesError
+	if maxAttachmentBytes > 0 && errors.As(err, &maxBytesError) {
+		return fmt.Errorf("%w: the maximum is %d bytes", location.ErrAttachmentTooLarge, maxAttachmentBytes)
+	}
+	return err
+}
+
+// attachmentPart returns the "file" part of a multipart upload.
+func attachmentPart(r *http.Request) (*multipart.Part, error) {
+	mr, err := r.MultipartReader()
+	if err != nil {
+		return nil, errors.New("request must be a multipart form")
+	}
+	for {
+		part, err := mr.NextPart()
+		if err == io.EOF {
+			return nil, errors.New(`multipart body must contain a "file" field`)
+		}
+		var maxBytesError *http.MaxBytesError
+		if errors.As(err, &maxBytesError) {
+			return nil, err
+		}
+		if err != nil {
+			return nil, errors.New("invalid multipart body")
+		}
+		if part.FormName() == "file" {
+			return part, nil
+		}
+	}
+}
+
+// GetAttachments handles the HTTP request to list a location's attachments.
+//
+// @Summary List attachments
+// @Description Lists the files attached to a location, oldest first. Anyone who can read the location, including through a share link passed as share, can list them.
+// @Tags attachments
+// @Produce json
+// @Param id path string true "Location ID"
+// @Param share query string false "Token of a share link covering the location"
+// @Success 200 {array} dto.AttachmentRes "attachments retrieved successfully"
+// @Failure 403 {string} string "forbidden"
+// @Failure 404 {string} string "location not found"
+// @Failure 500 {string} string "internal server error"
+// @Router /api/v1/locations/{id}/attachments [get]
+func GetAttachments(locationSvc location.ServiceApi, logger *slog.Logger) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+		if token := r.URL.Query().Get("share"); token != "" {
+			ctx = domain.ContextWithShareToken(ctx, token)
+		}
+		userId := r.Context().Value("userId").(string)
+		attachments, err := locationSvc.GetAttachments(ctx, r.PathValue("id"), userId)
+		if err != nil {
+			writeAttachmentError(w, logger, err, "getting attachments")
+			return
+		}
+		webutils.WriteJSON(w, http.StatusOK, "attachments retrieved successfully", dto.GetAttachmentsRes(attachments), nil)
+	}
+}
+
+// DownloadAttachment handles the HTTP request for the content of an
+// attachment or its thumbnail.
+//
+// @Summary Download an attachment
+// @Description Returns the attached file with its sniffed content type. Images are shown inline; anything else is sent as a download. With thumbnail=true a scaled-down JPEG or PNG of an image is returned instead.
+// @Tags attachments
+// @Produce octet-stream
+// @Param id path string true "Location ID"
+// @Param attachmentId path string true "Attachment ID"
+// @Param thumbnail query bool false "Return the thumbnail instead of the file" default(false)
+// @Param share query string false "Token of a share link covering the location"
+// @Success 200 {file} file "the attachment's content"
+// @Failure 403 {string} string "forbidden"
+// @Failure 404 {string} string "location, attachment or thumbnail not found"
+// @Failure 500 {string} string "internal server error"
+// @Router /api/v1/locations/{id}/attachments/{attachmentId} [get]
+func DownloadAttachment(locationSvc location.ServiceApi, logger *slog.Logger) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+		if token := r.URL.Query().Get("share"); token != "" {
+			ctx = domain.ContextWithShareToken(ctx, token)
+		}
+		thumbnail := webutils.GetQueryBool(r, "thumbnail", false)
+		userId := r.Context().Value("userId").(string)
+		attachment, content, err := locationSvc.OpenAttachment(ctx, r.PathValue("id"), r.PathValue("attachmentId"), userId, thumbnail)
+		if err != nil {
+			writeAttachmentError(w, logger, err, "downloading attachment")
+			return
+		}
+		defer content.Close()
+
+		contentType, disposition := attachment.ContentType, "attachment"
+		if thumbnail {
+			contentType = attachment.ThumbnailContentType()
+		} else {
+			w.Header().Set("Content-Length", strconv.FormatInt(attachment.Size, 10))
+		}
+		if strings.HasPrefix(contentType, "image/") {
+			disposition = "inline"
+		}
+		w.Header().Set("Content-Type", contentType)
+		w.Header().Set("Content-Disposition", mime.FormatMediaType(disposition, map[string]string{"filename": attachment.FileName}))
+		// The sniffed type is authoritative; browsers must not second-guess
+		// it and render an uploaded file as something more dangerous.
+		w.Header().Set("X-Content-Type-Options", "nosniff")
+		if _, err := io.Copy(w, content); err != nil {
+			logger.Error("error on downloading attachment", "error", err.Error())
+		}
+	}
+}
+
+// DeleteAttachment handles the HTTP request to remove an attachment.
+//
+// @Summary Delete an attachment
+// @Description Removes a file attached to one of the user's locations. Attachments are also removed when their location is purged from the trash or expires.
+// @Tags attachments
+// @Produce json
+// @Param id path string true "Location ID"
+// @Param attachmentId path string true "Attachment ID"
+// @Success 200 {string} string "attachment deleted successfully"
+// @Failure 403 {string} string "forbidden"
+// @Failure 404 {string} string "location or attachment not found"
+// @Failure 500 {string} string "internal server error"
+// @Router /api/v1/locations/{id}/attachments/{attachmentId} [delete]
+func DeleteAttachment(locationSvc location.ServiceApi, logger *slog.Logger) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userId := r.Context().Value("userId").(string)
+		err := locationSvc.DeleteAttachment(r.Context(), r.PathValue("id"), r.PathValue("attachmentId"), userId)
+		if err != nil {
+			writeAttachmentError(w, logger, err, "deleting attachment")
+			return
+		}
+		webutils.WriteJSON(w, http.StatusOK, "attachment deleted successfully", nil, nil)
+	}
+}
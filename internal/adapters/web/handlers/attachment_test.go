@@ -0,0 +1,166 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"log/slog"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// multipartUpload builds a multipart body with content as its field part.
+func multipartUpload(t *testing.T, field, fileName, content string) (*bytes.Buffer, string) {
+	t.Helper()
+	var body bytes.Buffer
+	mw := multipart.NewWriter(&body)
+	fw, err := mw.CreateFormFile(field, fileName)
+	if err != nil {
+		t.Fatalf("Failed to create form file: %v", err)
+	}
+	fw.Write([]byte(content))
+	mw.Close()
+	return &body, mw.FormDataContentType()
+}
+
+func TestUploadAttachment(t *testing.T) {
+	mockSvc := NewMockLocationService()
+	router := http.NewServeMux()
+	router.HandleFunc("POST /api/v1/locations/{id}/attachments", UploadAttachment(mockSvc, slog.Default(), 0))
+	ctx := context.WithValue(context.Background(), "userId", "1")
+
+	tests := []struct {
+		name       string
+		path       string
+		field      string
+		content    string
+		wantStatus int
+		wantBody   string
+	}{
+		{"success", "/api/v1/locations/a/attachments", "file", "hello", http.StatusCreated, `"file_name": "notes.txt"`},
+		{"missing file field", "/api/v1/locations/a/attachments", "other", "hello", http.StatusBadRequest, `must contain a \"file\" field`},
+		{"too large", "/api/v1/locations/a/attachments", "file", "hello, world!", http.StatusRequestEntityTooLarge, "too large"},
+		{"location not found", "/api/v1/locations/notfound/attachments", "file", "hello", http.StatusNotFound, "location not found"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			body, contentType := multipartUpload(t, tt.field, "notes.txt", tt.content)
+			req := httptest.NewRequest(http.MethodPost, tt.path, body).WithContext(ctx)
+			req.Header.Set("Content-Type", contentType)
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+			if w.Code != tt.wantStatus {
+				t.Fatalf("Expected status code %d, got %d: %s", tt.wantStatus, w.Code, w.Body.String())
+			}
+			if !strings.Contains(w.Body.String(), tt.wantBody) {
+				t.Errorf("Expected response body to contain %q, got %s", tt.wantBody, w.Body.String())
+			}
+		})
+	}
+
+	t.Run("not multipart", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodPost, "/api/v1/locations/a/attachments", strings.NewReader("hello")).WithContext(ctx)
+		req.Header.Set("Content-Type", "text/plain")
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		if w.Code != http.StatusBadRequest {
+			t.Errorf("Expected status code %d, got %d", http.StatusBadRequest, w.Code)
+		}
+	})
+
+	upload := func(maxAttachmentBytes int64, size int) *httptest.ResponseRecorder {
+		body, contentType := multipartUpload(t, "file", "big.bin", strings.Repeat("x", size))
+		req := httptest.NewRequest(http.MethodPost, "/api/v1/locations/a/attachments", body).WithContext(ctx)
+		req.Header.Set("Content-Type", contentType)
+		w := httptest.NewRecorder()
+		UploadAttachment(mockSvc, slog.Default(), maxAttachmentBytes)(w, req)
+		return w
+	}
+
+	t.Run("upload over the default request cap", func(t *testing.T) {
+		if w := upload(0, defaultUploadBytes+1); w.Code != http.StatusRequestEntityTooLarge {
+			t.Errorf("Expected status code %d, got %d", http.StatusRequestEntityTooLarge, w.Code)
+		}
+	})
+
+	t.Run("upload over the configured limit", func(t *testing.T) {
+		w := upload(1024, 1024+multipartOverhead+1)
+		if w.Code != http.StatusRequestEntityTooLarge {
+			t.Fatalf("Expected status code %d, got %d", http.StatusRequestEntityTooLarge, w.Code)
+		}
+		if !strings.Contains(w.Body.String(), "the maximum is 1024 bytes") {
+			t.Errorf("Expected the configured limit in the response, got %s", w.Body.String())
+		}
+	})
+}
+
+func TestDownloadAttachment(t *testing.T) {
+	mockSvc := NewMockLocationService()
+	router := http.NewServeMux()
+	router.HandleFunc("GET /api/v1/locations/{id}/attachments/{attachmentId}", DownloadAttachment(mockSvc, slog.Default()))
+	ctx := context.WithValue(context.Background(), "userId", "1")
+
+	t.Run("success", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodGet, "/api/v1/locations/a/attachments/att-1", nil).WithContext(ctx)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		if w.Code != http.StatusOK {
+			t.Fatalf("Expected status code %d, got %d", http.StatusOK, w.Code)
+		}
+		if w.Body.String() != "hello" {
+			t.Errorf("Expected body %q, got %q", "hello", w.Body.String())
+		}
+		for header, want := range map[string]string{
+			"Content-Type":           "text/plain; charset=utf-8",
+			"Content-Disposition":    `attachment; filename=notes.txt`,
+			"X-Content-Type-Options": "nosniff",
+			"Content-Length":         "5",
+		} {
+			if got := w.Header().Get(header); got != want {
+				t.Errorf("Expected %s %q, got %q", header, want, got)
+			}
+		}
+	})
+
+	for _, path := range []string{"/api/v1/locations/a/attachments/missing", "/api/v1/locations/a/attachments/att-1?thumbnail=true"} {
+		t.Run("not found "+path, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, path, nil).WithContext(ctx)
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+			if w.Code != http.StatusNotFound {
+				t.Errorf("Expected status code %d, got %d", http.StatusNotFound, w.Code)
+			}
+		})
+	}
+}
+
+func TestGetAndDeleteAttachments(t *testing.T) {
+	mockSvc := NewMockLocationService()
+	router := http.NewServeMux()
+	router.HandleFunc("GET /api/v1/locations/{id}/attachments", GetAttachments(mockSvc, slog.Default()))
+	router.HandleFunc("DELETE /api/v1/locations/{id}/attachments/{attachmentId}", DeleteAttachment(mockSvc, slog.Default()))
+	ctx := context.WithValue(context.Background(), "userId", "1")
+
+	tests := []struct {
+		method     string
+		path       string
+		wantStatus int
+	}{
+		{http.MethodGet, "/api/v1/locations/a/attachments", http.StatusOK},
+		{http.MethodGet, "/api/v1/locations/notfound/attachments", http.StatusNotFound},
+		{http.MethodDelete, "/api/v1/locations/a/attachments/att-1", http.StatusOK},
+		{http.MethodDelete, "/api/v1/locations/forbidden/attachments/att-1", http.StatusForbidden},
+	}
+	for _, tt := range tests {
+		t.Run(tt.method+" "+tt.path, func(t *testing.T) {
+			req := httptest.NewRequest(tt.method, tt.path, nil).WithContext(ctx)
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+			if w.Code != tt.wantStatus {
+				t.Errorf("Expected status code %d, got %d", tt.wantStatus, w.Code)
+			}
+		})
+	}
+}
@@ -0,0 +1,171 @@
+package handlers
+
+import (
+	"errors"
+	"log/slog"
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gorilla/sessions"
+	"github.com/lafetz/weavo/internal/adapters/web/dto"
+	"github.com/lafetz/weavo/internal/adapters/web/webutils"
+	"github.com/lafetz/weavo/internal/core/domain"
+	"github.com/lafetz/weavo/internal/core/service/location"
+	"github.com/lafetz/weavo/internal/core/service/user"
+)
+
+// startSession makes u the user of the session cookie, replacing whoever
+// it belonged to.
+func startSession(w http.ResponseWriter, r *http.Request, store sessions.Store, u domain.User) error {
+	session, _ := store.Get(r, webutils.SessionName)
+	session.Values["userId"] = u.Id
+	return session.Save(r, w)
+}
+
+// adoptAnonymous moves whatever the session saved while anonymous to u. It
+// must run before startSession replaces the session's user. Sessions that
+// already belong to an account are left alone: logging in to a second
+// account does not merge the two.
+func adoptAnonymous(r *http.Request, store sessions.Store, userSvc user.ServiceApi, locationSvc location.ServiceApi, u domain.User) error {
+	session, _ := store.Get(r, webutils.SessionName)
+	anonymousID, _ := session.Values["userId"].(string)
+	if anonymousID == "" || anonymousID == u.Id || userSvc.IsRegistered(anonymousID) {
+		return nil
+	}
+	_, err := locationSvc.MergeUser(r.Context(), anonymousID, u.Id)
+	return err
+}
+
+// Register handles the HTTP request to create an account.
+//
+// @Summary Register
+// @Description Creates an account and logs the session in to it. Locations saved in the session while it was anonymous move to the account.
+// @Tags users
+// @Accept json
+// @Produce json
+// @Param user body dto.RegisterReq true "Email and password"
+// @Success 201 {object} dto.UserRes "registered successfully"
+// @Failure 400 {string} string "Invalid input format"
+// @Failure 409 {string} string "email is already registered"
+// @Failure 422 {string} string "validation error"
+// @Failure 500 {string} string "internal server error"
+// @Router /api/v1/auth/register [post]
+func Register(userSvc user.ServiceApi, locationSvc location.ServiceApi, store sessions.Store, logger *slog.Logger, validator *webutils.CustomValidator) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var req dto.RegisterReq
+		if err := webutils.ReadJSON(w, r, &req); err != nil {
+			webutils.WriteJSON(w, http.StatusBadRequest, "Invalid input format", nil, nil)
+			return
+		}
+		if validator.ValidateAndRespond(w, req) {
+			return
+		}
+
+		u, err := userSvc.Register(r.Context(), req.Email, req.Password)
+		if err != nil {
+			if errors.Is(err, user.ErrEmailTaken) {
+				webutils.WriteJSON(w, http.StatusConflict, err.Error(), nil, nil)
+				return
+			}
+			webutils.WriteJSON(w, http.StatusInternalServerError, "internal server error", nil, nil)
+			logger.Error("error on registering", "error", err.Error())
+			return
+		}
+		// The session stays anonymous if its data cannot be moved, so that
+		// logging in again retries the move.
+		if err := adoptAnonymous(r, store, userSvc, locationSvc, u); err != nil {
+			webutils.WriteJSON(w, http.StatusInternalServerError, "internal server error", nil, nil)
+			logger.Error("error on merging anonymous data", "error", err.Error())
+			return
+		}
+		if err := startSession(w, r, store, u); err != nil {
+			webutils.WriteJSON(w, http.StatusInternalServerError, "internal server error", nil, nil)
+			logger.Error("error on saving session", "error", err.Error())
+			return
+		}
+		webutils.WriteJSON(w, http.StatusCreated, "registered successfully", dto.GetUserRes(u), nil)
+	}
+}
+
+// Login handles the HTTP request to log in with a password.
+//
+// @Summary Log in
+// @Description Logs the session in to an account. Locations saved in the session while it was anonymous move to the account; likely duplicates of the account's own locations, and those over its location limit, go to its trash. After 5 wrong passwords in a row the email is locked for 15 minutes, and the response says when to retry.
+// @Tags users
+// @Accept json
+// @Produce json
+// @Param credentials body dto.LoginReq true "Email and password"
+// @Success 200 {object} dto.UserRes "logged in successfully"
+// @Failure 400 {string} string "Invalid input format"
+// @Failure 401 {string} string "invalid email or password"
+// @Failure 422 {string} string "validation error"
+// @Failure 429 {string} string "too many failed login attempts"
+// @Failure 500 {string} string "internal server error"
+// @Router /api/v1/auth/login [post]
+func Login(userSvc user.ServiceApi, locationSvc location.ServiceApi, store sessions.Store, logger *slog.Logger, validator *webutils.CustomValidator) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var req dto.LoginReq
+		if err := webutils.ReadJSON(w, r, &req); err != nil {
+			webutils.WriteJSON(w, http.StatusBadRequest, "Invalid input format", nil, nil)
+			return
+		}
+		if validator.ValidateAndRespond(w, req) {
+			return
+		}
+
+		u, err := userSvc.Login(r.Context(), req.Email, req.Password)
+		if err != nil {
+			var locked *user.LockedError
+			switch {
+			case errors.Is(err, user.ErrInvalidCredentials):
+				webutils.WriteJSON(w, http.StatusUnauthorized, err.Error(), nil, nil)
+			case errors.As(err, &locked):
+				retryAfter := math.Ceil(time.Until(locked.Until).Seconds())
+				w.Header().Set("Retry-After", strconv.Itoa(max(1, int(retryAfter))))
+				webutils.WriteJSON(w, http.StatusTooManyRequests, err.Error(), nil, nil)
+			default:
+				webutils.WriteJSON(w, http.StatusInternalServerError, "internal server error", nil, nil)
+				logger.Error("error on logging in", "error", err.Error())
+			}
+			return
+		}
+		// The session stays anonymous if its data cannot be moved, so that
+		// logging in again retries the move.
+		if err := adoptAnonymous(r, store, userSvc, locationSvc, u); err != nil {
+			webutils.WriteJSON(w, http.StatusInternalServerError, "internal server error", nil, nil)
+			logger.Error("error on merging anonymous data", "error", err.Error())
+			return
+		}
+		if err := startSession(w, r, store, u); err != nil {
+			webutils.WriteJSON(w, http.StatusInternalServerError, "internal server error", nil, nil)
+			logger.Error("error on saving session", "error", err.Error())
+			return
+		}
+		webutils.WriteJSON(w, http.StatusOK, "logged in successfully", dto.GetUserRes(u), nil)
+	}
+}
+
+// Logout handles the HTTP request to log out.
+//
+// @Summary Log out
+// @Description Ends the session. The next request starts a new anonymous one.
+// @Tags users
+// @Produce json
+// @Success 200 {string} string "logged out successfully"
+// @Failure 500 {string} string "internal server error"
+// @Router /api/v1/auth/logout [post]
+func Logout(store sessions.Store, logger *slog.Logger) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		session, _ := store.Get(r, webutils.SessionName)
+		delete(session.Values, "userId")
+		session.Options.MaxAge = -1
+		if err := session.Save(r, w); err != nil {
+			webutils.WriteJSON(w, http.StatusInternalServerError, "internal server error", nil, nil)
+			logger.Error("error on saving session", "error", err.Error())
+			return
+		}
+		webutils.WriteJSON(w, http.StatusOK, "logged out successfully", nil, nil)
+	}
+}
@@ -0,0 +1,174 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/lafetz/weavo/internal/adapters/web/webutils"
+	"github.com/lafetz/weavo/internal/core/domain"
+	"github.com/lafetz/weavo/internal/core/service/location"
+	"github.com/lafetz/weavo/internal/core/service/user"
+)
+
+type mockUserService struct{}
+
+func (m *mockUserService) Register(ctx context.Context, email, password string) (domain.User, error) {
+	if email == "taken@example.com" {
+		return domain.User{}, user.ErrEmailTaken
+	}
+	return domain.User{Id: "new-user", Email: email}, nil
+}
+
+func (m *mockUserService) Login(ctx context.Context, email, password string) (domain.User, error) {
+	switch {
+	case email == "locked@example.com":
+		return domain.User{}, &user.LockedError{Until: time.Now().Add(90 * time.Second)}
+	case password != "correct horse":
+		return domain.User{}, user.ErrInvalidCredentials
+	}
+	return domain.User{Id: "user-1", Email: email}, nil
+}
+
+func (m *mockUserService) IsRegistered(userID string) bool {
+	return userID == "user-1" || userID == "new-user"
+}
+
+// mergingLocationService records the merges it is asked to make.
+type mergingLocationService struct {
+	*MockLocationService
+	merged []string
+	err    error
+}
+
+func (m *mergingLocationService) MergeUser(ctx context.Context, fromUserID, toUserID string) (location.MergeResult, error) {
+	if m.err != nil {
+		return location.MergeResult{}, m.err
+	}
+	m.merged = append(m.merged, fromUserID+" -> "+toUserID)
+	return location.MergeResult{Locations: 1}, nil
+}
+
+func TestAuth(t *testing.T) {
+	store := webutils.CookieStore(time.Hour, false)
+	val := webutils.NewCustomValidator(validator.New())
+	router := http.NewServeMux()
+	router.HandleFunc("POST /register", Register(&mockUserService{}, NewMockLocationService(), store, slog.Default(), val))
+	router.HandleFunc("POST /login", Login(&mockUserService{}, NewMockLocationService(), store, slog.Default(), val))
+	router.HandleFunc("POST /logout", Logout(store, slog.Default()))
+
+	tests := []struct {
+		name       string
+		path       string
+		body       string
+		wantStatus int
+		wantUserID string
+	}{
+		{"register", "/register", `{"email": "ada@example.com", "password": "correct horse"}`, http.StatusCreated, "new-user"},
+		{"register taken email", "/register", `{"email": "taken@example.com", "password": "correct horse"}`, http.StatusConflict, ""},
+		{"register short password", "/register", `{"email": "ada@example.com", "password": "short"}`, http.StatusUnprocessableEntity, ""},
+		{"register invalid email", "/register", `{"email": "ada", "password": "correct horse"}`, http.StatusUnprocessableEntity, ""},
+		{"login", "/login", `{"email": "ada@example.com", "password": "correct horse"}`, http.StatusOK, "user-1"},
+		{"login wrong password", "/login", `{"email": "ada@example.com", "password": "wrong"}`, http.StatusUnauthorized, ""},
+		{"login locked", "/login", `{"email": "locked@example.com", "password": "correct horse"}`, http.StatusTooManyRequests, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, tt.path, strings.NewReader(tt.body))
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+			if w.Code != tt.wantStatus {
+				t.Fatalf("Expected status code %d, got %d: %s", tt.wantStatus, w.Code, w.Body.String())
+			}
+			if tt.wantStatus == http.StatusTooManyRequests && w.Header().Get("Retry-After") != "90" {
+				t.Errorf("Expected Retry-After 90, got %q", w.Header().Get("Retry-After"))
+			}
+
+			// The session cookie now carries the account's user ID.
+			check := httptest.NewRequest(http.MethodGet, "/", nil)
+			for _, c := range w.Result().Cookies() {
+				check.AddCookie(c)
+			}
+			session, _ := store.Get(check, webutils.SessionName)
+			if got, _ := session.Values["userId"].(string); got != tt.wantUserID {
+				t.Errorf("Expected session user %q, got %q", tt.wantUserID, got)
+			}
+		})
+	}
+
+	t.Run("logout", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodPost, "/logout", nil)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		if w.Code != http.StatusOK {
+			t.Fatalf("Expected status code %d, got %d", http.StatusOK, w.Code)
+		}
+		cookies := w.Result().Cookies()
+		if len(cookies) != 1 || cookies[0].MaxAge >= 0 {
+			t.Errorf("Expected the session cookie to be deleted, got %+v", cookies)
+		}
+	})
+}
+
+func TestLoginMergesAnonymousSession(t *testing.T) {
+	store := webutils.CookieStore(time.Hour, false)
+	val := webutils.NewCustomValidator(validator.New())
+	sessionOf := func(userID string) []*http.Cookie {
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		w := httptest.NewRecorder()
+		session, _ := store.Get(req, webutils.SessionName)
+		session.Values["userId"] = userID
+		session.Save(req, w)
+		return w.Result().Cookies()
+	}
+	login := func(locationSvc location.ServiceApi, cookies []*http.Cookie) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/login", strings.NewReader(`{"email": "ada@example.com", "password": "correct horse"}`))
+		for _, c := range cookies {
+			req.AddCookie(c)
+		}
+		w := httptest.NewRecorder()
+		Login(&mockUserService{}, locationSvc, store, slog.Default(), val)(w, req)
+		return w
+	}
+
+	tests := []struct {
+		name       string
+		cookies    []*http.Cookie
+		wantMerged []string
+	}{
+		{"anonymous session", sessionOf("anon-id"), []string{"anon-id -> user-1"}},
+		{"no session", nil, nil},
+		{"same account", sessionOf("user-1"), nil},
+		{"another account", sessionOf("new-user"), nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			svc := &mergingLocationService{MockLocationService: NewMockLocationService()}
+			w := login(svc, tt.cookies)
+			if w.Code != http.StatusOK {
+				t.Fatalf("Expected status code %d, got %d", http.StatusOK, w.Code)
+			}
+			if fmt.Sprint(svc.merged) != fmt.Sprint(tt.wantMerged) {
+				t.Errorf("Expected merges %v, got %v", tt.wantMerged, svc.merged)
+			}
+		})
+	}
+
+	t.Run("merge fails", func(t *testing.T) {
+		svc := &mergingLocationService{MockLocationService: NewMockLocationService(), err: errors.New("boom")}
+		w := login(svc, sessionOf("anon-id"))
+		if w.Code != http.StatusInternalServerError {
+			t.Fatalf("Expected status code %d, got %d", http.StatusInternalServerError, w.Code)
+		}
+		if cookies := w.Result().Cookies(); len(cookies) != 0 {
+			t.Errorf("Expected the anonymous session to be kept, got %+v", cookies)
+		}
+	})
+}
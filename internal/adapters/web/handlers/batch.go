@@ -0,0 +1,101 @@
+package handlers
+
+import (
+	"errors"
+	"log/slog"
+	"net/http"
+
+	"github.com/lafetz/weavo/internal/adapters/web/dto"
+	"github.com/lafetz/weavo/internal/adapters/web/webutils"
+	"github.com/lafetz/weavo/internal/core/service/location"
+)
+
+// BatchLocations handles the HTTP request for applying several location
+// writes at once.
+//
+// @Summary Create, update and delete locations in one request
+// @Description Applies up to 100 operations in order and reports the outcome of each with the status it would have had as a request of its own. With atomic=true any failure, including a validation error, leaves every location as it was; the other operations then report 424. Atomic batches can only change the user's own locations. Creates that look like duplicates of an existing location report 409 unless the operation sets force.
+// @Tags locations
+// @Accept json
+// @Produce json
+// @Param batch body dto.BatchReq true "Operations"
+// @Param atomic query bool false "Apply all operations or none" default(false)
+// @Param Idempotency-Key header string false "Retries with the same key replay the first response instead of running again"
+// @Success 200 {object} dto.BatchRes "batch processed"
+// @Failure 400 {string} string "Invalid input format"
+// @Failure 409 {string} string "a request with this Idempotency-Key is still in progress"
+// @Failure 413 {string} string "too many operations"
+// @Failure 422 {string} string "validation error"
+// @Failure 500 {string} string "internal server error"
+// @Router /api/v1/locations:batch [post]
+func BatchLocations(locationSvc location.ServiceApi, logger *slog.Logger, validator *webutils.CustomValidator) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var req dto.BatchReq
+		if err := webutils.ReadJSON(w, r, &req); err != nil {
+			webutils.WriteJSON(w, http.StatusBadRequest, "Invalid input format", nil, nil)
+			return
+		}
+		if len(req.Operations) > location.MaxBatchOperations {
+			webutils.WriteJSON(w, http.StatusRequestEntityTooLarge, location.ErrBatchTooLarge.Error(), nil, nil)
+			return
+		}
+		if validator.ValidateAndRespond(w, req) {
+			return
+		}
+		atomic := webutils.GetQueryBool(r, "atomic", false)
+
+		rejected := make([]map[string]string, len(req.Operations))
+		var ops []location.BatchOperation
+		for i := range req.Operations {
+			if errs := validator.Validate(req.Operations[i]); errs != nil {
+				rejected[i] = errs
+				continue
+			}
+			ops = append(ops, req.Operations[i].ToDomain())
+		}
+
+		var results []location.BatchResult
+		if atomic && len(ops) < len(req.Operations) {
+			results = make([]location.BatchResult, len(ops))
+			for i := range results {
+				results[i].Err = location.ErrBatchAborted
+			}
+		} else {
+			userId := r.Context().Value("userId").(string)
+			var err error
+			results, err = locationSvc.Batch(r.Context(), userId, ops, atomic)
+			if err != nil {
+				webutils.WriteJSON(w, http.StatusInternalServerError, "internal server error", nil, nil)
+				logger.Error("error on applying batch", "error", err.Error())
+				return
+			}
+		}
+
+		status := func(op string, err error) (int, string) {
+			switch {
+			case err == nil && op == string(location.BatchCreate):
+				return http.StatusCreated, ""
+			case err == nil:
+				return http.StatusOK, ""
+			case errors.Is(err, location.ErrLocationNotFound):
+				return http.StatusNotFound, "location not found"
+			case errors.Is(err, location.ErrUnAuthorized):
+				return http.StatusForbidden, "forbidden"
+			case errors.Is(err, location.ErrVersionMismatch):
+				return http.StatusPreconditionFailed, err.Error()
+			case errors.Is(err, location.ErrDuplicateLocation):
+				return http.StatusConflict, err.Error()
+			case errors.Is(err, location.ErrLocationLimit):
+				return http.StatusForbidden, err.Error()
+			case errors.Is(err, location.ErrNotesTooLong):
+				return http.StatusUnprocessableEntity, err.Error()
+			case errors.Is(err, location.ErrBatchAborted):
+				return http.StatusFailedDependency, err.Error()
+			}
+			logger.Error("error on applying batch operation", "op", op, "error", err.Error())
+			return http.StatusInternalServerError, "internal server error"
+		}
+		res := dto.GetBatchRes(atomic, req.Operations, rejected, results, status)
+		webutils.WriteJSON(w, http.StatusOK, "batch processed", res, nil)
+	}
+}
@@ -0,0 +1,103 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/lafetz/weavo/internal/adapters/web/dto"
+	"github.com/lafetz/weavo/internal/adapters/web/webutils"
+)
+
+func TestBatchLocations(t *testing.T) {
+	mockSvc := NewMockLocationService()
+	handler := BatchLocations(mockSvc, slog.Default(), webutils.NewCustomValidator(validator.New()))
+	ctx := context.WithValue(context.Background(), "userId", "1")
+
+	send := func(query, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/api/v1/locations:batch"+query, strings.NewReader(body)).WithContext(ctx)
+		w := httptest.NewRecorder()
+		handler(w, req)
+		return w
+	}
+	batch := func(t *testing.T, w *httptest.ResponseRecorder) dto.BatchRes {
+		t.Helper()
+		if w.Code != http.StatusOK {
+			t.Fatalf("Expected status code %d, got %d: %s", http.StatusOK, w.Code, w.Body.String())
+		}
+		var res struct {
+			Data dto.BatchRes `json:"data"`
+		}
+		if err := json.NewDecoder(w.Body).Decode(&res); err != nil {
+			t.Fatalf("Failed to decode response: %v", err)
+		}
+		return res.Data
+	}
+	body := `{"operations": [
+		{"op": "create", "location": {"notes": "n", "nickname": "Home", "city": "Paris", "coordinates": {"lat": 48.85, "lon": 2.35}}},
+		{"op": "update", "id": "a", "version": 1, "location": {"notes": "n", "nickname": "Work", "city": "Paris"}},
+		{"op": "delete", "id": "notfound"},
+		{"op": "update", "location": {"notes": "", "nickname": "x", "city": "y"}}
+	]}`
+
+	t.Run("operations fail on their own", func(t *testing.T) {
+		res := batch(t, send("", body))
+		if res.Total != 4 || res.Succeeded != 2 || res.Failed != 2 {
+			t.Fatalf("Unexpected counts: %+v", res)
+		}
+		want := []int{http.StatusCreated, http.StatusOK, http.StatusNotFound, http.StatusUnprocessableEntity}
+		for i, status := range want {
+			if res.Results[i].Status != status {
+				t.Errorf("Expected operation %d to have status %d, got %+v", i, status, res.Results[i])
+			}
+		}
+		if res.Results[0].Location == nil || res.Results[0].Location.Id == "" {
+			t.Errorf("Expected the created location, got %+v", res.Results[0])
+		}
+		if errs := res.Results[3].Errors; errs["id"] == "" || errs["notes"] == "" {
+			t.Errorf("Expected the missing id and the empty notes to be reported, got %v", errs)
+		}
+	})
+
+	t.Run("atomic batch with an invalid operation", func(t *testing.T) {
+		res := batch(t, send("?atomic=true", body))
+		if !res.Atomic || res.Succeeded != 0 {
+			t.Fatalf("Expected nothing to be applied, got %+v", res)
+		}
+		if res.Results[0].Status != http.StatusFailedDependency || res.Results[3].Status != http.StatusUnprocessableEntity {
+			t.Errorf("Expected the valid operations to be aborted, got %+v", res.Results)
+		}
+	})
+
+	t.Run("atomic batch with a failing operation", func(t *testing.T) {
+		res := batch(t, send("?atomic=true", `{"operations": [{"op": "delete", "id": "a"}, {"op": "delete", "id": "notfound"}]}`))
+		if res.Results[0].Status != http.StatusFailedDependency || res.Results[1].Status != http.StatusNotFound {
+			t.Errorf("Expected the failing operation to abort the other, got %+v", res.Results)
+		}
+	})
+
+	t.Run("unknown op", func(t *testing.T) {
+		res := batch(t, send("", `{"operations": [{"op": "upsert", "id": "a"}]}`))
+		if res.Results[0].Errors["op"] == "" {
+			t.Errorf("Expected the op to be rejected, got %+v", res.Results[0])
+		}
+	})
+
+	t.Run("empty batch", func(t *testing.T) {
+		if w := send("", `{"operations": []}`); w.Code != http.StatusUnprocessableEntity {
+			t.Errorf("Expected status code %d, got %d", http.StatusUnprocessableEntity, w.Code)
+		}
+	})
+
+	t.Run("too many operations", func(t *testing.T) {
+		ops := strings.Repeat(`{"op": "delete", "id": "a"},`, 101)
+		if w := send("", `{"operations": [`+strings.TrimSuffix(ops, ",")+`]}`); w.Code != http.StatusRequestEntityTooLarge {
+			t.Errorf("Expected status code %d, got %d", http.StatusRequestEntityTooLarge, w.Code)
+		}
+	})
+}
@@ -0,0 +1,138 @@
+package handlers
+
+import (
+	"errors"
+	"log/slog"
+	"net/http"
+
+	"github.com/lafetz/weavo/internal/adapters/web/dto"
+	"github.com/lafetz/weavo/internal/adapters/web/webutils"
+	"github.com/lafetz/weavo/internal/core/domain"
+	"github.com/lafetz/weavo/internal/core/service/location"
+)
+
+// writeCheckInError maps check-in service errors to responses and logs
+// anything unexpected.
+func writeCheckInError(w http.ResponseWriter, logger *slog.Logger, err error, action string) {
+	switch {
+	case errors.Is(err, location.ErrInvalidPage), errors.Is(err, location.ErrInvalidDateSpan):
+		webutils.WriteJSON(w, http.StatusBadRequest, err.Error(), nil, nil)
+	case errors.Is(err, location.ErrLocationNotFound):
+		webutils.WriteJSON(w, http.StatusNotFound, "location not found", nil, nil)
+	case errors.Is(err, location.ErrUnAuthorized):
+		webutils.WriteJSON(w, http.StatusForbidden, "forbidden", nil, nil)
+	case errors.Is(err, location.ErrCheckInsOff):
+		webutils.WriteJSON(w, http.StatusNotImplemented, err.Error(), nil, nil)
+	default:
+		webutils.WriteJSON(w, http.StatusInternalServerError, "internal server error", nil, nil)
+		logger.Error("error on "+action, "error", err.Error())
+	}
+}
+
+// CreateCheckIn handles the HTTP request to check in at a location.
+//
+// @Summary Check in at a location
+// @Description Records a visit to one of the user's locations with an optional comment. The current weather in the location's city is stored with the check-in; if it cannot be looked up, weather is null.
+// @Tags checkins
+// @Accept json
+// @Produce json
+// @Param id path string true "Location ID"
+// @Param checkin body dto.CheckInReq false "Check-in comment"
+// @Param Idempotency-Key header string false "Retries with the same key replay the first response instead of running again"
+// @Success 201 {object} dto.CheckInRes "checked in successfully"
+// @Failure 400 {string} string "Invalid input format"
+// @Failure 403 {string} string "forbidden"
+// @Failure 404 {string} string "location not found"
+// @Failure 409 {string} string "a request with this Idempotency-Key is still in progress"
+// @Failure 422 {string} string "validation error"
+// @Failure 500 {string} string "internal server error"
+// @Router /api/v1/locations/{id}/checkins [post]
+func CreateCheckIn(locationSvc location.ServiceApi, logger *slog.Logger, validator *webutils.CustomValidator) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var req dto.CheckInReq
+		// The body is optional: a check-in without a comment needs none.
+		if r.ContentLength != 0 {
+			if err := webutils.ReadJSON(w, r, &req); err != nil {
+				webutils.WriteJSON(w, http.StatusBadRequest, "Invalid input format", nil, nil)
+				return
+			}
+		}
+		if validator.ValidateAndRespond(w, req) {
+			return
+		}
+
+		userId := r.Context().Value("userId").(string)
+		checkIn, err := locationSvc.CheckIn(r.Context(), r.PathValue("id"), userId, req.Comment)
+		if err != nil {
+			writeCheckInError(w, logger, err, "checking in")
+			return
+		}
+		webutils.WriteJSON(w, http.StatusCreated, "checked in successfully", dto.GetCheckInRes(checkIn), nil)
+	}
+}
+
+// GetCheckIns handles the HTTP request to list the check-ins at a location.
+//
+// @Summary List check-ins at a location
+// @Description Lists the visits recorded at a location, newest first, each with the weather at the time. Anyone who can read the location, including through a share link passed as share, can list them.
+// @Tags checkins
+// @Produce json
+// @Param id path string true "Location ID"
+// @Param share query string false "Token of a share link covering the location"
+// @Success 200 {array} dto.CheckInRes "check-ins retrieved successfully"
+// @Failure 403 {string} string "forbidden"
+// @Failure 404 {string} string "location not found"
+// @Failure 500 {string} string "internal server error"
+// @Router /api/v1/locations/{id}/checkins [get]
+func GetCheckIns(locationSvc location.ServiceApi, logger *slog.Logger) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+		if token := r.URL.Query().Get("share"); token != "" {
+			ctx = domain.ContextWithShareToken(ctx, token)
+		}
+		userId := r.Context().Value("userId").(string)
+		checkIns, err := locationSvc.GetCheckIns(ctx, r.PathValue("id"), userId)
+		if err != nil {
+			writeCheckInError(w, logger, err, "getting check-ins")
+			return
+		}
+		webutils.WriteJSON(w, http.StatusOK, "check-ins retrieved successfully", dto.GetCheckInsRes(checkIns), nil)
+	}
+}
+
+// GetTimeline handles the HTTP request for the user's travel journal.
+//
+// @Summary Get the check-in timeline
+// @Description Lists the user's check-ins at all of their locations, newest first, optionally only those between after and before.
+// @Tags checkins
+// @Produce json
+// @Param page query int false "Page number" default(1)
+// @Param pageSize query int false "Number of items per page" default(5)
+// @Param after query string false "Only check-ins after this RFC 3339 time"
+// @Param before query string false "Only check-ins before this RFC 3339 time"
+// @Success 200 {array} dto.CheckInRes "timeline retrieved successfully"
+// @Failure 400 {string} string "invalid page or date span"
+// @Failure 422 {string} string "validation error"
+// @Failure 500 {string} string "internal server error"
+// @Router /api/v1/checkins [get]
+func GetTimeline(locationSvc location.ServiceApi, logger *slog.Logger, validator *webutils.CustomValidator) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		query := dto.TimelineQuery{
+			Page:     webutils.GetQueryInt(r, "page", 1),
+			PageSize: webutils.GetQueryInt(r, "pageSize", defaultPageSize),
+			After:    webutils.GetQueryString(r, "after", ""),
+			Before:   webutils.GetQueryString(r, "before", ""),
+		}
+		if validator.ValidateAndRespond(w, query) {
+			return
+		}
+
+		userId := r.Context().Value("userId").(string)
+		checkIns, metadata, err := locationSvc.GetTimeline(r.Context(), userId, query.ToFilter())
+		if err != nil {
+			writeCheckInError(w, logger, err, "getting timeline")
+			return
+		}
+		webutils.WriteJSON(w, http.StatusOK, "timeline retrieved successfully", dto.GetCheckInsRes(checkIns), dto.ConvertToJSONMetadata(metadata))
+	}
+}
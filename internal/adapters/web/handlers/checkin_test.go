@@ -0,0 +1,84 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/lafetz/weavo/internal/adapters/web/dto"
+	"github.com/lafetz/weavo/internal/adapters/web/webutils"
+)
+
+func TestCreateCheckIn(t *testing.T) {
+	mockSvc := NewMockLocationService()
+	router := http.NewServeMux()
+	router.HandleFunc("POST /api/v1/locations/{id}/checkins", CreateCheckIn(mockSvc, slog.Default(), webutils.NewCustomValidator(validator.New())))
+	ctx := context.WithValue(context.Background(), "userId", "1")
+
+	tests := []struct {
+		name       string
+		path       string
+		body       string
+		wantStatus int
+	}{
+		{"with comment", "/api/v1/locations/a/checkins", `{"comment": "Sunny walk"}`, http.StatusCreated},
+		{"without body", "/api/v1/locations/a/checkins", "", http.StatusCreated},
+		{"comment too long", "/api/v1/locations/a/checkins", `{"comment": "` + strings.Repeat("x", 1001) + `"}`, http.StatusUnprocessableEntity},
+		{"invalid json", "/api/v1/locations/a/checkins", `{`, http.StatusBadRequest},
+		{"location not found", "/api/v1/locations/notfound/checkins", "", http.StatusNotFound},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, tt.path, strings.NewReader(tt.body)).WithContext(ctx)
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+			if w.Code != tt.wantStatus {
+				t.Fatalf("Expected status code %d, got %d: %s", tt.wantStatus, w.Code, w.Body.String())
+			}
+			if tt.wantStatus != http.StatusCreated {
+				return
+			}
+			var res struct {
+				Data dto.CheckInRes `json:"data"`
+			}
+			if err := json.NewDecoder(w.Body).Decode(&res); err != nil {
+				t.Fatalf("Failed to decode response: %v", err)
+			}
+			if res.Data.Weather == nil || res.Data.Weather.Temperature != 21.5 {
+				t.Errorf("Expected the weather snapshot in the response, got %+v", res.Data)
+			}
+		})
+	}
+}
+
+func TestGetTimeline(t *testing.T) {
+	mockSvc := NewMockLocationService()
+	router := http.NewServeMux()
+	router.HandleFunc("GET /api/v1/checkins", GetTimeline(mockSvc, slog.Default(), webutils.NewCustomValidator(validator.New())))
+	ctx := context.WithValue(context.Background(), "userId", "1")
+
+	tests := []struct {
+		name       string
+		query      string
+		wantStatus int
+	}{
+		{"success", "?after=2024-01-01T00:00:00Z&before=2024-02-01T00:00:00Z", http.StatusOK},
+		{"invalid date", "?after=yesterday", http.StatusUnprocessableEntity},
+		{"after not before before", "?after=2024-02-01T00:00:00Z&before=2024-01-01T00:00:00Z", http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/api/v1/checkins"+tt.query, nil).WithContext(ctx)
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+			if w.Code != tt.wantStatus {
+				t.Fatalf("Expected status code %d, got %d: %s", tt.wantStatus, w.Code, w.Body.String())
+			}
+		})
+	}
+}
@@ -0,0 +1,227 @@
+package handlers
+
+import (
+	"errors"
+	"log/slog"
+	"net/http"
+
+	"github.com/lafetz/weavo/internal/adapters/web/dto"
+	"github.com/lafetz/weavo/internal/adapters/web/webutils"
+	"github.com/lafetz/weavo/internal/core/service/location"
+)
+
+// writeCollectionError maps collection service errors to responses and logs
+// anything unexpected.
+func writeCollectionError(w http.ResponseWriter, logger *slog.Logger, err error, action string) {
+	switch {
+	case errors.Is(err, location.ErrCollectionNotFound):
+		webutils.WriteJSON(w, http.StatusNotFound, "collection not found", nil, nil)
+	case errors.Is(err, location.ErrLocationNotFound):
+		webutils.WriteJSON(w, http.StatusNotFound, "location not found", nil, nil)
+	case errors.Is(err, location.ErrUnAuthorized):
+		webutils.WriteJSON(w, http.StatusForbidden, "forbidden", nil, nil)
+	default:
+		webutils.WriteJSON(w, http.StatusInternalServerError, "internal server error", nil, nil)
+		logger.Error("error on "+action, "error", err.Error())
+	}
+}
+
+// CreateCollection handles the creation of a new collection.
+//
+// @Summary Create a collection
+// @Description Create a named collection for grouping locations
+// @Tags collections
+// @Accept json
+// @Produce json
+// @Param collection body dto.CollectionReq true "Collection request body"
+// @Param Idempotency-Key header string false "Retries with the same key replay the first response instead of running again"
+// @Success 201 {object} dto.CollectionRes "collection created successfully"
+// @Failure 400 {string} string "Invalid input format"
+// @Failure 409 {string} string "a request with this Idempotency-Key is still in progress"
+// @Failure 422 {string} string "validation error"
+// @Failure 500 {string} string "internal server error"
+// @Router /api/v1/collections [post]
+func CreateCollection(locationSvc location.ServiceApi, logger *slog.Logger, validator *webutils.CustomValidator) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var req dto.CollectionReq
+		if err := webutils.ReadJSON(w, r, &req); err != nil {
+			webutils.WriteJSON(w, http.StatusBadRequest, "Invalid input format", nil, nil)
+			return
+		}
+		if validator.ValidateAndRespond(w, req) {
+			return
+		}
+
+		collection := req.ToDomain()
+		collection.UserID = r.Context().Value("userId").(string)
+		collection, err := locationSvc.CreateCollection(r.Context(), collection)
+		if err != nil {
+			writeCollectionError(w, logger, err, "creating collection")
+			return
+		}
+
+		webutils.WriteJSON(w, http.StatusCreated, "collection created successfully", dto.GetCollectionRes(collection), nil)
+	}
+}
+
+// GetCollections handles the HTTP request to list the user's collections.
+//
+// @Summary List collections
+// @Description Retrieves the user's collections ordered by name
+// @Tags collections
+// @Produce json
+// @Success 200 {array} dto.CollectionRes "collections retrieved successfully"
+// @Failure 500 {string} string "internal server error"
+// @Router /api/v1/collections [get]
+func GetCollections(locationSvc location.ServiceApi, logger *slog.Logger) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userId := r.Context().Value("userId").(string)
+		collections, err := locationSvc.GetCollections(r.Context(), userId)
+		if err != nil {
+			writeCollectionError(w, logger, err, "getting collections")
+			return
+		}
+
+		webutils.WriteJSON(w, http.StatusOK, "collections retrieved successfully", dto.GetCollectionsRes(collections), nil)
+	}
+}
+
+// GetCollection handles the HTTP request to retrieve a collection by its ID.
+//
+// @Summary Retrieve a collection by ID
+// @Tags collections
+// @Produce json
+// @Param id path string true "Collection ID"
+// @Success 200 {object} dto.CollectionRes "collection retrieved successfully"
+// @Failure 403 {string} string "forbidden"
+// @Failure 404 {string} string "collection not found"
+// @Failure 500 {string} string "internal server error"
+// @Router /api/v1/collections/{id} [get]
+func GetCollection(locationSvc location.ServiceApi, logger *slog.Logger) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userId := r.Context().Value("userId").(string)
+		collection, err := locationSvc.GetCollection(r.Context(), r.PathValue("id"), userId)
+		if err != nil {
+			writeCollectionError(w, logger, err, "getting collection")
+			return
+		}
+
+		webutils.WriteJSON(w, http.StatusOK, "collection retrieved successfully", dto.GetCollectionRes(collection), nil)
+	}
+}
+
+// UpdateCollection handles the HTTP request to rename a collection.
+//
+// @Summary Rename a collection
+// @Tags collections
+// @Accept json
+// @Produce json
+// @Param id path string true "Collection ID"
+// @Param collection body dto.CollectionReq true "Collection request body"
+// @Success 200 {object} dto.CollectionRes "collection updated successfully"
+// @Failure 400 {string} string "Invalid input format"
+// @Failure 403 {string} string "forbidden"
+// @Failure 404 {string} string "collection not found"
+// @Failure 422 {string} string "validation error"
+// @Failure 500 {string} string "internal server error"
+// @Router /api/v1/collections/{id} [put]
+func UpdateCollection(locationSvc location.ServiceApi, logger *slog.Logger, validator *webutils.CustomValidator) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var req dto.CollectionReq
+		if err := webutils.ReadJSON(w, r, &req); err != nil {
+			webutils.WriteJSON(w, http.StatusBadRequest, "Invalid input format", nil, nil)
+			return
+		}
+		if validator.ValidateAndRespond(w, req) {
+			return
+		}
+
+		collection := req.ToDomain()
+		collection.Id = r.PathValue("id")
+		collection.UserID = r.Context().Value("userId").(string)
+		collection, err := locationSvc.UpdateCollection(r.Context(), collection)
+		if err != nil {
+			writeCollectionError(w, logger, err, "updating collection")
+			return
+		}
+
+		webutils.WriteJSON(w, http.StatusOK, "collection updated successfully", dto.GetCollectionRes(collection), nil)
+	}
+}
+
+// DeleteCollection handles the HTTP request to delete a collection. Its
+// locations are kept.
+//
+// @Summary Delete a collection
+// @Description Deletes a collection; the locations in it are not deleted
+// @Tags collections
+// @Produce json
+// @Param id path string true "Collection ID"
+// @Success 200 {string} string "collection deleted successfully"
+// @Failure 403 {string} string "forbidden"
+// @Failure 404 {string} string "collection not found"
+// @Failure 500 {string} string "internal server error"
+// @Router /api/v1/collections/{id} [delete]
+func DeleteCollection(locationSvc location.ServiceApi, logger *slog.Logger) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userId := r.Context().Value("userId").(string)
+		if err := locationSvc.DeleteCollection(r.Context(), r.PathValue("id"), userId); err != nil {
+			writeCollectionError(w, logger, err, "deleting collection")
+			return
+		}
+
+		webutils.WriteJSON(w, http.StatusOK, "collection deleted successfully", nil, nil)
+	}
+}
+
+// AddToCollection handles the HTTP request to add a location to a
+// collection.
+//
+// @Summary Add a location to a collection
+// @Tags collections
+// @Produce json
+// @Param id path string true "Collection ID"
+// @Param locationId path string true "Location ID"
+// @Success 200 {string} string "location added to collection"
+// @Failure 403 {string} string "forbidden"
+// @Failure 404 {string} string "collection or location not found"
+// @Failure 500 {string} string "internal server error"
+// @Router /api/v1/collections/{id}/locations/{locationId} [put]
+func AddToCollection(locationSvc location.ServiceApi, logger *slog.Logger) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userId := r.Context().Value("userId").(string)
+		err := locationSvc.AddToCollection(r.Context(), r.PathValue("id"), r.PathValue("locationId"), userId)
+		if err != nil {
+			writeCollectionError(w, logger, err, "adding location to collection")
+			return
+		}
+
+		webutils.WriteJSON(w, http.StatusOK, "location added to collection", nil, nil)
+	}
+}
+
+// RemoveFromCollection handles the HTTP request to remove a location from a
+// collection.
+//
+// @Summary Remove a location from a collection
+// @Tags collections
+// @Produce json
+// @Param id path string true "Collection ID"
+// @Param locationId path string true "Location ID"
+// @Success 200 {string} string "location removed from collection"
+// @Failure 403 {string} string "forbidden"
+// @Failure 404 {string} string "collection or location not found"
+// @Failure 500 {string} string "internal server error"
+// @Router /api/v1/collections/{id}/locations/{locationId} [delete]
+func RemoveFromCollection(locationSvc location.ServiceApi, logger *slog.Logger) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userId := r.Context().Value("userId").(string)
+		err := locationSvc.RemoveFromCollection(r.Context(), r.PathValue("id"), r.PathValue("locationId"), userId)
+		if err != nil {
+			writeCollectionError(w, logger, err, "removing location from collection")
+			return
+		}
+
+		webutils.WriteJSON(w, http.StatusOK, "location removed from collection", nil, nil)
+	}
+}
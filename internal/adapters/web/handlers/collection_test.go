@@ -0,0 +1,107 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/lafetz/weavo/internal/adapters/web/webutils"
+)
+
+func TestCreateCollection(t *testing.T) {
+	mockSvc := NewMockLocationService()
+	handler := CreateCollection(mockSvc, slog.Default(), webutils.NewCustomValidator(validator.New()))
+	ctx := context.WithValue(context.Background(), "userId", "1")
+
+	tests := []struct {
+		name     string
+		body     string
+		expected int
+	}{
+		{"invalid input format", `invalid json`, http.StatusBadRequest},
+		{"missing name", `{"name": ""}`, http.StatusUnprocessableEntity},
+		{"successful creation", `{"name": "Ski trips"}`, http.StatusCreated},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, "/api/v1/collections", bytes.NewBufferString(tt.body)).WithContext(ctx)
+			w := httptest.NewRecorder()
+
+			router := http.NewServeMux()
+			router.HandleFunc("/api/v1/collections", handler)
+			router.ServeHTTP(w, req)
+
+			if w.Code != tt.expected {
+				t.Errorf("Expected status code %d, got %d", tt.expected, w.Code)
+			}
+		})
+	}
+}
+
+func TestDeleteCollection(t *testing.T) {
+	mockSvc := NewMockLocationService()
+	handler := DeleteCollection(mockSvc, slog.Default())
+	ctx := context.WithValue(context.Background(), "userId", "1")
+
+	tests := []struct {
+		name     string
+		id       string
+		expected int
+	}{
+		{"not found", "notfound", http.StatusNotFound},
+		{"another user's collection", "forbidden", http.StatusForbidden},
+		{"successful deletion", "c1", http.StatusOK},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodDelete, "/api/v1/collections/"+tt.id, nil).WithContext(ctx)
+			w := httptest.NewRecorder()
+
+			router := http.NewServeMux()
+			router.HandleFunc("/api/v1/collections/{id}", handler)
+			router.ServeHTTP(w, req)
+
+			if w.Code != tt.expected {
+				t.Errorf("Expected status code %d, got %d", tt.expected, w.Code)
+			}
+		})
+	}
+}
+
+func TestAddToCollection(t *testing.T) {
+	mockSvc := NewMockLocationService()
+	handler := AddToCollection(mockSvc, slog.Default())
+	ctx := context.WithValue(context.Background(), "userId", "1")
+
+	tests := []struct {
+		name       string
+		collection string
+		location   string
+		expected   int
+	}{
+		{"collection not found", "notfound", "l1", http.StatusNotFound},
+		{"location not found", "c1", "notfound", http.StatusNotFound},
+		{"successful add", "c1", "l1", http.StatusOK},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPut, "/api/v1/collections/"+tt.collection+"/locations/"+tt.location, nil).WithContext(ctx)
+			w := httptest.NewRecorder()
+
+			router := http.NewServeMux()
+			router.HandleFunc("PUT /api/v1/collections/{id}/locations/{locationId}", handler)
+			router.ServeHTTP(w, req)
+
+			if w.Code != tt.expected {
+				t.Errorf("Expected status code %d, got %d", tt.expected, w.Code)
+			}
+		})
+	}
+}
@@ -0,0 +1,105 @@
+package handlers
+
+import (
+	"errors"
+	"log/slog"
+	"net/http"
+	"strconv"
+
+	"github.com/lafetz/weavo/internal/adapters/web/dto"
+	"github.com/lafetz/weavo/internal/adapters/web/webutils"
+	"github.com/lafetz/weavo/internal/core/service/location"
+)
+
+// GetLocationHistory handles the HTTP request for a location's change
+// history.
+//
+// @Summary Get a location's history
+// @Description Lists every change made to a location, oldest first, with who made it, when, in which request and which fields changed. History remains available after the location is deleted.
+// @Tags locations
+// @Produce json
+// @Param id path string true "Location ID"
+// @Success 200 {array} dto.HistoryEntryRes "history retrieved successfully"
+// @Failure 403 {string} string "forbidden"
+// @Failure 404 {string} string "location not found"
+// @Failure 500 {string} string "internal server error"
+// @Router /api/v1/locations/{id}/history [get]
+func GetLocationHistory(locationSvc location.ServiceApi, logger *slog.Logger) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id := r.PathValue("id")
+		userId := r.Context().Value("userId").(string)
+		entries, err := locationSvc.GetHistory(r.Context(), id, userId)
+		if err != nil {
+			switch {
+			case errors.Is(err, location.ErrLocationNotFound):
+				webutils.WriteJSON(w, http.StatusNotFound, "location not found", nil, nil)
+			case errors.Is(err, location.ErrUnAuthorized):
+				webutils.WriteJSON(w, http.StatusForbidden, "forbidden", nil, nil)
+			default:
+				webutils.WriteJSON(w, http.StatusInternalServerError, "internal server error", nil, nil)
+				logger.Error("error on getting location history", "error", err.Error())
+			}
+			return
+		}
+
+		webutils.WriteJSON(w, http.StatusOK, "history retrieved successfully", dto.GetHistoryRes(entries), nil)
+	}
+}
+
+// RevertLocation handles the HTTP request for reverting a location to an
+// earlier version.
+//
+// @Summary Revert a location
+// @Description Sets the location's editable fields back to how they were at the given version. The revert is recorded as a new version.
+// @Tags locations
+// @Produce json
+// @Param id path string true "Location ID"
+// @Param version path int true "Version to revert to"
+// @Param If-Match header string false "ETag of the version being replaced"
+// @Success 200 {object} dto.LocationRes "location reverted successfully"
+// @Failure 400 {string} string "invalid version"
+// @Failure 403 {string} string "forbidden"
+// @Failure 404 {string} string "location or version not found"
+// @Failure 412 {string} string "location has been modified"
+// @Failure 422 {string} string "the version's notes are longer than the user's limit"
+// @Failure 500 {string} string "internal server error"
+// @Router /api/v1/locations/{id}/history/{version}/revert [post]
+func RevertLocation(locationSvc location.ServiceApi, logger *slog.Logger) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id := r.PathValue("id")
+		toVersion, err := strconv.ParseInt(r.PathValue("version"), 10, 64)
+		if err != nil || toVersion < 1 {
+			webutils.WriteJSON(w, http.StatusBadRequest, "invalid version", nil, nil)
+			return
+		}
+		ifVersion, err := webutils.ParseIfMatch(r)
+		if err != nil {
+			webutils.WriteJSON(w, http.StatusBadRequest, err.Error(), nil, nil)
+			return
+		}
+
+		userId := r.Context().Value("userId").(string)
+		loc, err := locationSvc.RevertLocation(r.Context(), id, userId, toVersion, ifVersion)
+		if err != nil {
+			switch {
+			case errors.Is(err, location.ErrLocationNotFound):
+				webutils.WriteJSON(w, http.StatusNotFound, "location not found", nil, nil)
+			case errors.Is(err, location.ErrVersionNotFound):
+				webutils.WriteJSON(w, http.StatusNotFound, err.Error(), nil, nil)
+			case errors.Is(err, location.ErrUnAuthorized):
+				webutils.WriteJSON(w, http.StatusForbidden, "forbidden", nil, nil)
+			case errors.Is(err, location.ErrVersionMismatch):
+				webutils.WriteJSON(w, http.StatusPreconditionFailed, err.Error(), nil, nil)
+			case errors.Is(err, location.ErrNotesTooLong):
+				webutils.WriteJSON(w, http.StatusUnprocessableEntity, "validation error", map[string]string{"notes": err.Error()}, nil)
+			default:
+				webutils.WriteJSON(w, http.StatusInternalServerError, "internal server error", nil, nil)
+				logger.Error("error on reverting location", "error", err.Error())
+			}
+			return
+		}
+
+		webutils.SetETag(w, loc.Version)
+		webutils.WriteJSON(w, http.StatusOK, "location reverted successfully", dto.GetLocationRes(loc), nil)
+	}
+}
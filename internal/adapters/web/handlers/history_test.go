@@ -0,0 +1,80 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/lafetz/weavo/internal/adapters/web/dto"
+	"github.com/lafetz/weavo/internal/adapters/web/webutils"
+)
+
+func TestGetLocationHistory(t *testing.T) {
+	mockSvc := NewMockLocationService()
+	router := http.NewServeMux()
+	router.HandleFunc("/api/v1/locations/{id}/history", GetLocationHistory(mockSvc, slog.Default()))
+	ctx := context.WithValue(context.Background(), "userId", "1")
+
+	t.Run("not found", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodGet, "/api/v1/locations/notfound/history", nil).WithContext(ctx)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		if w.Code != http.StatusNotFound {
+			t.Errorf("Expected status code %d, got %d", http.StatusNotFound, w.Code)
+		}
+	})
+
+	t.Run("success", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodGet, "/api/v1/locations/a/history", nil).WithContext(ctx)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		if w.Code != http.StatusOK {
+			t.Fatalf("Expected status code %d, got %d", http.StatusOK, w.Code)
+		}
+		var res struct {
+			Data []dto.HistoryEntryRes `json:"data"`
+		}
+		if err := json.NewDecoder(w.Body).Decode(&res); err != nil {
+			t.Fatalf("Failed to decode response: %v", err)
+		}
+		if len(res.Data) != 1 || res.Data[0].Action != "created" || res.Data[0].Changes[0].Field != "city" {
+			t.Errorf("Unexpected history: %+v", res.Data)
+		}
+	})
+}
+
+func TestRevertLocation(t *testing.T) {
+	mockSvc := NewMockLocationService()
+	router := http.NewServeMux()
+	router.HandleFunc("/api/v1/locations/{id}/history/{version}/revert", RevertLocation(mockSvc, slog.Default()))
+	ctx := context.WithValue(context.Background(), "userId", "1")
+
+	tests := []struct {
+		name    string
+		path    string
+		ifMatch string
+		status  int
+	}{
+		{"invalid version", "/api/v1/locations/a/history/zero/revert", "", http.StatusBadRequest},
+		{"unknown version", "/api/v1/locations/a/history/9/revert", "", http.StatusNotFound},
+		{"location not found", "/api/v1/locations/notfound/history/1/revert", "", http.StatusNotFound},
+		{"stale If-Match", "/api/v1/locations/a/history/1/revert", webutils.FormatETag(mockVersion + 1), http.StatusPreconditionFailed},
+		{"success", "/api/v1/locations/a/history/1/revert", webutils.FormatETag(mockVersion), http.StatusOK},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, tt.path, nil).WithContext(ctx)
+			if tt.ifMatch != "" {
+				req.Header.Set("If-Match", tt.ifMatch)
+			}
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+			if w.Code != tt.status {
+				t.Errorf("Expected status code %d, got %d", tt.status, w.Code)
+			}
+		})
+	}
+}
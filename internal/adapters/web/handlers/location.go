@@ -1,27 +1,38 @@
 package handlers
 
 import (
+	"encoding/json"
 	"errors"
 	"log/slog"
+	"mime"
 	"net/http"
 
 	"github.com/lafetz/weavo/internal/adapters/web/dto"
 	"github.com/lafetz/weavo/internal/adapters/web/webutils"
+	"github.com/lafetz/weavo/internal/core/domain"
 	"github.com/lafetz/weavo/internal/core/service/location"
 )
 
-const hello = ""
+const (
+	defaultPageSize    = 5
+	defaultNearbyLimit = 50
+)
 
 // CreateLocation handles the creation of a new location.
 //
 // @Summary Create a new location
-// @Description Create a new location with the provided details
+// @Description Create a new location with the provided details. A location in the same city and close to one the user already has is rejected as a likely duplicate unless force is true; the response then lists the existing matches.
 // @Tags locations
 // @Accept json
 // @Produce json
 // @Param location body dto.LocationReq true "Location request body"
+// @Param force query bool false "Create the location even if it looks like a duplicate" default(false)
+// @Param Idempotency-Key header string false "Retries with the same key replay the first response instead of running again"
 // @Success 201 {object} dto.LocationRes "Location created successfully"
 // @Failure 400 {string} string "Invalid input format"
+// @Failure 403 {string} string "location limit reached"
+// @Failure 409 {array} dto.LocationRes "location looks like a duplicate of an existing location, or a request with this Idempotency-Key is still in progress"
+// @Failure 422 {string} string "validation error, notes longer than the user's limit, or Idempotency-Key reused for a different request"
 // @Failure 500 {string} string "Internal server error"
 // @Router /api/v1/locations [post]
 func CreateLocation(locationSvc location.ServiceApi, logger *slog.Logger, validator *webutils.CustomValidator) http.HandlerFunc {
@@ -36,16 +47,28 @@ func CreateLocation(locationSvc location.ServiceApi, logger *slog.Logger, valida
 			return
 		}
 
-		location := req.ToDomain()
-		location.UserID = r.Context().Value("userId").(string)
-		loc, err := locationSvc.CreateLocation(r.Context(), location)
+		newLoc := req.ToDomain()
+		newLoc.UserID = r.Context().Value("userId").(string)
+		force := webutils.GetQueryBool(r, "force", false)
+		loc, err := locationSvc.CreateLocation(r.Context(), newLoc, force)
 
 		if err != nil {
-			webutils.WriteJSON(w, http.StatusInternalServerError, "internal server error", nil, nil)
-			logger.Error("error on creating location", "error", err.Error())
+			var dupErr *location.DuplicateError
+			switch {
+			case errors.As(err, &dupErr):
+				webutils.WriteJSON(w, http.StatusConflict, "location looks like a duplicate of an existing location", dto.GetLocationsListRes(dupErr.Matches), nil)
+			case errors.Is(err, location.ErrLocationLimit):
+				webutils.WriteJSON(w, http.StatusForbidden, err.Error(), nil, nil)
+			case errors.Is(err, location.ErrNotesTooLong):
+				webutils.WriteJSON(w, http.StatusUnprocessableEntity, "validation error", map[string]string{"notes": err.Error()}, nil)
+			default:
+				webutils.WriteJSON(w, http.StatusInternalServerError, "internal server error", nil, nil)
+				logger.Error("error on creating location", "error", err.Error())
+			}
 			return
 		}
 
+		webutils.SetETag(w, loc.Version)
 		webutils.WriteJSON(w, http.StatusCreated, "location created successfully", dto.GetLocationRes(loc), nil)
 	}
 }
@@ -53,13 +76,15 @@ func CreateLocation(locationSvc location.ServiceApi, logger *slog.Logger, valida
 // GetLocation handles the HTTP request to retrieve a location by its ID.
 //
 // @Summary Retrieve a location by ID
-// @Description Retrieves a location from the service using the provided ID.
+// @Description Retrieves one of the user's locations. Admins can retrieve any location; anyone else needs the token of a share link covering it, passed as share.
 // @Tags locations
 // @Accept json
 // @Produce json
 // @Param id path string true "Location ID"
+// @Param share query string false "Token of a share link covering the location"
 // @Success 200 {object} dto.LocationRes "location retrieved successfully"
 // @Failure 400 {string} string "invalid id"
+// @Failure 403 {string} string "forbidden"
 // @Failure 404 {string} string "location not found"
 // @Failure 500 {string} string "internal server error"
 // @Router /api/v1/locations/{id} [get]
@@ -71,33 +96,46 @@ func GetLocation(locationSvc location.ServiceApi, logger *slog.Logger) http.Hand
 			return
 		}
 
-		loc, err := locationSvc.GetLocation(r.Context(), id)
+		ctx := r.Context()
+		if token := r.URL.Query().Get("share"); token != "" {
+			ctx = domain.ContextWithShareToken(ctx, token)
+		}
+		userId := r.Context().Value("userId").(string)
+		loc, err := locationSvc.GetLocation(ctx, id, userId)
 
 		if err != nil {
-			if errors.Is(err, location.ErrLocationNotFound) {
+			switch {
+			case errors.Is(err, location.ErrLocationNotFound):
 				webutils.WriteJSON(w, http.StatusNotFound, "location not found", nil, nil)
-				return
+			case errors.Is(err, location.ErrUnAuthorized):
+				webutils.WriteJSON(w, http.StatusForbidden, "forbidden", nil, nil)
+			default:
+				webutils.WriteJSON(w, http.StatusInternalServerError, "internal server error", nil, nil)
+				logger.Error("error on getting location by id", "error", err.Error())
 			}
-			webutils.WriteJSON(w, http.StatusInternalServerError, "internal server error", nil, nil)
-			logger.Error("error on getting location by id", "error", err.Error())
 			return
 		}
 
+		webutils.SetETag(w, loc.Version)
 		webutils.WriteJSON(w, http.StatusOK, "location retrieved successfully", dto.GetLocationRes(loc), nil)
 	}
 }
 
-// UpdateLocation handles the HTTP request for updating a location.
-// @Summary Update a location
-// @Description Update an existing location with the provided details
+// UpdateLocation handles the HTTP request for replacing a location.
+// @Summary Replace a location
+// @Description Replace every editable field of an existing location and return the stored result. Send the ETag from a previous response as If-Match to reject the update when the location changed in the meantime.
 // @Tags locations
 // @Accept json
 // @Produce json
 // @Param id path string true "Location ID"
+// @Param If-Match header string false "ETag of the version being replaced"
 // @Param LocationReq body dto.LocationReq true "Location request body"
 // @Success 200 {object} dto.LocationRes "location updated successfully"
 // @Failure 400 {string} string "Invalid input format or invalid id"
+// @Failure 403 {string} string "forbidden"
 // @Failure 404 {string} string "location not found"
+// @Failure 412 {string} string "location has been modified"
+// @Failure 422 {string} string "validation error"
 // @Failure 500 {string} string "internal server error"
 // @Router /api/v1/locations/{id} [put]
 func UpdateLocation(locationSvc location.ServiceApi, logger *slog.Logger, validator *webutils.CustomValidator) http.HandlerFunc {
@@ -115,21 +153,146 @@ func UpdateLocation(locationSvc location.ServiceApi, logger *slog.Logger, valida
 			webutils.WriteJSON(w, http.StatusBadRequest, "invalid id", nil, nil)
 			return
 		}
+		version, err := webutils.ParseIfMatch(r)
+		if err != nil {
+			webutils.WriteJSON(w, http.StatusBadRequest, err.Error(), nil, nil)
+			return
+		}
 
 		loc := req.ToDomain()
 		loc.Id = id
 		loc.UserID = r.Context().Value("userId").(string)
-		loc, err := locationSvc.UpdateLocation(r.Context(), loc)
+		loc.Version = version
+		loc, err = locationSvc.UpdateLocation(r.Context(), loc)
 		if err != nil {
-			if errors.Is(err, location.ErrLocationNotFound) {
+			switch {
+			case errors.Is(err, location.ErrLocationNotFound):
 				webutils.WriteJSON(w, http.StatusNotFound, "location not found", nil, nil)
-				return
+			case errors.Is(err, location.ErrUnAuthorized):
+				webutils.WriteJSON(w, http.StatusForbidden, "forbidden", nil, nil)
+			case errors.Is(err, location.ErrVersionMismatch):
+				webutils.WriteJSON(w, http.StatusPreconditionFailed, err.Error(), nil, nil)
+			case errors.Is(err, location.ErrNotesTooLong):
+				webutils.WriteJSON(w, http.StatusUnprocessableEntity, "validation error", map[string]string{"notes": err.Error()}, nil)
+			default:
+				webutils.WriteJSON(w, http.StatusInternalServerError, "internal server error", nil, nil)
+				logger.Error("error on updating location", "error", err.Error())
+			}
+			return
+		}
+
+		webutils.SetETag(w, loc.Version)
+		webutils.WriteJSON(w, http.StatusOK, "location updated successfully", dto.GetLocationRes(loc), nil)
+	}
+}
+
+// PatchLocation handles the HTTP request for partially updating a location.
+//
+// @Summary Patch a location
+// @Description Apply an RFC 7396 JSON merge patch to a location. Members set to null are cleared; the merged result is validated like a full update.
+// @Description Without If-Match the patch applies to the version it was merged against and fails with 409 if the location changes concurrently.
+// @Tags locations
+// @Accept application/merge-patch+json
+// @Produce json
+// @Param id path string true "Location ID"
+// @Param If-Match header string false "ETag of the version being patched"
+// @Param patch body object true "Merge patch"
+// @Success 200 {object} dto.LocationRes "location updated successfully"
+// @Failure 400 {string} string "Invalid input format"
+// @Failure 403 {string} string "forbidden"
+// @Failure 404 {string} string "location not found"
+// @Failure 409 {string} string "location has been modified concurrently"
+// @Failure 412 {string} string "location has been modified"
+// @Failure 415 {string} string "unsupported media type"
+// @Failure 422 {string} string "validation error"
+// @Failure 500 {string} string "internal server error"
+// @Router /api/v1/locations/{id} [patch]
+func PatchLocation(locationSvc location.ServiceApi, logger *slog.Logger, validator *webutils.CustomValidator) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+		if mediaType != "application/merge-patch+json" && mediaType != "application/json" {
+			webutils.WriteJSON(w, http.StatusUnsupportedMediaType, "unsupported media type, use application/merge-patch+json", nil, nil)
+			return
+		}
+		var patch json.RawMessage
+		if err := webutils.ReadJSON(w, r, &patch); err != nil {
+			webutils.WriteJSON(w, http.StatusBadRequest, "Invalid input format", nil, nil)
+			return
+		}
+		id := r.PathValue("id")
+		userId := r.Context().Value("userId").(string)
+		version, err := webutils.ParseIfMatch(r)
+		if err != nil {
+			webutils.WriteJSON(w, http.StatusBadRequest, err.Error(), nil, nil)
+			return
+		}
+
+		current, err := locationSvc.GetLocation(r.Context(), id, userId)
+		if err != nil {
+			switch {
+			case errors.Is(err, location.ErrLocationNotFound):
+				webutils.WriteJSON(w, http.StatusNotFound, "location not found", nil, nil)
+			case errors.Is(err, location.ErrUnAuthorized):
+				webutils.WriteJSON(w, http.StatusForbidden, "forbidden", nil, nil)
+			default:
+				webutils.WriteJSON(w, http.StatusInternalServerError, "internal server error", nil, nil)
+				logger.Error("error on patching location", "error", err.Error())
 			}
+			return
+		}
+
+		if version != 0 && version != current.Version {
+			webutils.WriteJSON(w, http.StatusPreconditionFailed, location.ErrVersionMismatch.Error(), nil, nil)
+			return
+		}
+
+		doc, err := json.Marshal(dto.NewLocationReq(current))
+		if err != nil {
 			webutils.WriteJSON(w, http.StatusInternalServerError, "internal server error", nil, nil)
-			logger.Error("error on updating location", "error", err.Error())
+			logger.Error("error on patching location", "error", err.Error())
+			return
+		}
+		merged, err := webutils.MergePatch(doc, patch)
+		if err != nil {
+			webutils.WriteJSON(w, http.StatusBadRequest, "Invalid input format", nil, nil)
+			return
+		}
+		var req dto.LocationReq
+		if err := webutils.UnmarshalStrict(merged, &req); err != nil {
+			webutils.WriteJSON(w, http.StatusBadRequest, err.Error(), nil, nil)
+			return
+		}
+		if validator.ValidateAndRespond(w, req) {
+			return
+		}
+
+		loc := req.ToDomain()
+		loc.Id = id
+		loc.UserID = userId
+		// The patch was merged against current, so the write is always
+		// conditional on it even when the client did not send If-Match.
+		loc.Version = current.Version
+		loc, err = locationSvc.UpdateLocation(r.Context(), loc)
+		if err != nil {
+			switch {
+			case errors.Is(err, location.ErrLocationNotFound):
+				webutils.WriteJSON(w, http.StatusNotFound, "location not found", nil, nil)
+			case errors.Is(err, location.ErrUnAuthorized):
+				webutils.WriteJSON(w, http.StatusForbidden, "forbidden", nil, nil)
+			case errors.Is(err, location.ErrVersionMismatch) && version != 0:
+				webutils.WriteJSON(w, http.StatusPreconditionFailed, err.Error(), nil, nil)
+			case errors.Is(err, location.ErrVersionMismatch):
+				webutils.WriteJSON(w, http.StatusConflict, "location has been modified concurrently", nil, nil)
+			case errors.Is(err, location.ErrNotesTooLong):
+				webutils.WriteJSON(w, http.StatusUnprocessableEntity, "validation error", map[string]string{"notes": err.Error()}, nil)
+			default:
+				webutils.WriteJSON(w, http.StatusInternalServerError, "internal server error", nil, nil)
+				logger.Error("error on patching location", "error", err.Error())
+			}
 			return
 		}
 
+		webutils.SetETag(w, loc.Version)
 		webutils.WriteJSON(w, http.StatusOK, "location updated successfully", dto.GetLocationRes(loc), nil)
 	}
 }
@@ -137,14 +300,17 @@ func UpdateLocation(locationSvc location.ServiceApi, logger *slog.Logger, valida
 // DeleteLocation handles the HTTP request for deleting a location.
 //
 // @Summary Delete a location
-// @Description Deletes a location by its ID
+// @Description Moves a location to the trash, from where it can be restored until the purge window passes. With If-Match the delete only succeeds if the location is still at that version.
 // @Tags locations
 // @Accept json
 // @Produce json
 // @Param id path string true "Location ID"
+// @Param If-Match header string false "ETag of the version being deleted"
 // @Success 200 {string} string "location deleted successfully"
 // @Failure 400 {string} string "invalid id"
+// @Failure 403 {string} string "forbidden"
 // @Failure 404 {string} string "location not found"
+// @Failure 412 {string} string "location has been modified"
 // @Failure 500 {string} string "internal server error"
 // @Router /api/v1/locations/{id} [delete]
 func DeleteLocation(locationSvc location.ServiceApi, logger *slog.Logger) http.HandlerFunc {
@@ -155,15 +321,25 @@ func DeleteLocation(locationSvc location.ServiceApi, logger *slog.Logger) http.H
 			webutils.WriteJSON(w, http.StatusBadRequest, "invalid id", nil, nil)
 			return
 		}
+		version, err := webutils.ParseIfMatch(r)
+		if err != nil {
+			webutils.WriteJSON(w, http.StatusBadRequest, err.Error(), nil, nil)
+			return
+		}
 		userId := r.Context().Value("userId").(string)
-		err := locationSvc.DeleteLocation(r.Context(), id, userId)
+		err = locationSvc.DeleteLocation(r.Context(), id, userId, version)
 		if err != nil {
-			if errors.Is(err, location.ErrLocationNotFound) {
+			switch {
+			case errors.Is(err, location.ErrLocationNotFound):
 				webutils.WriteJSON(w, http.StatusNotFound, "location not found", nil, nil)
-				return
+			case errors.Is(err, location.ErrUnAuthorized):
+				webutils.WriteJSON(w, http.StatusForbidden, "forbidden", nil, nil)
+			case errors.Is(err, location.ErrVersionMismatch):
+				webutils.WriteJSON(w, http.StatusPreconditionFailed, err.Error(), nil, nil)
+			default:
+				webutils.WriteJSON(w, http.StatusInternalServerError, "internal server error", nil, nil)
+				logger.Error("error on deleting location", "error", err.Error())
 			}
-			webutils.WriteJSON(w, http.StatusInternalServerError, "internal server error", nil, nil)
-			logger.Error("error on deleting location", "error", err.Error())
 			return
 		}
 
@@ -180,21 +356,61 @@ func DeleteLocation(locationSvc location.ServiceApi, logger *slog.Logger) http.H
 // @Produce json
 // @Param page query int false "Page number" default(1)
 // @Param pageSize query int false "Number of items per page" default(5)
-// @Success 200 {object} dto.LocationsRes "locations retrieved successfully"
-// @Failure 500 {object} webutils.ErrorResponse "internal server error"
+// @Param cursor query string false "Opaque cursor from a previous response's nextCursor or prevCursor"
+// @Param limit query int false "Number of items per page when paginating by cursor" default(5)
+// @Param sort query string false "Sort field; position lists pinned locations first" Enums(position, created_at, nickname, city) default(position)
+// @Param order query string false "Sort direction" Enums(asc, desc) default(asc)
+// @Param city query string false "Only locations in this city (case-insensitive)"
+// @Param q query string false "Substring to search for in nickname or notes"
+// @Param created_after query string false "Only locations created after this RFC 3339 time"
+// @Param created_before query string false "Only locations created before this RFC 3339 time"
+// @Param bbox query string false "Only locations inside minLon,minLat,maxLon,maxLat"
+// @Param tag query string false "Only locations with this tag"
+// @Param collection query string false "Only locations in this collection"
+// @Success 200 {array} dto.LocationRes "locations retrieved successfully"
+// @Failure 400 {string} string "invalid cursor or bbox"
+// @Failure 422 {string} string "validation error"
+// @Failure 500 {string} string "internal server error"
 // @Router /api/v1/locations [get]
-
-func GetAllLocations(locationSvc location.ServiceApi, logger *slog.Logger) http.HandlerFunc {
+func GetAllLocations(locationSvc location.ServiceApi, logger *slog.Logger, validator *webutils.CustomValidator) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		userId := r.Context().Value("userId").(string)
 
-		filters := location.Filter{
-			Page:     webutils.GetQueryInt(r, "page", 1),
-			PageSize: webutils.GetQueryInt(r, "pageSize", 5),
+		query := dto.LocationsQuery{
+			Page:          webutils.GetQueryInt(r, "page", 1),
+			PageSize:      webutils.GetQueryInt(r, "pageSize", defaultPageSize),
+			Cursor:        webutils.GetQueryString(r, "cursor", ""),
+			Limit:         webutils.GetQueryInt(r, "limit", defaultPageSize),
+			UseCursor:     r.URL.Query().Has("limit"),
+			Sort:          webutils.GetQueryString(r, "sort", string(location.SortByPosition)),
+			Order:         webutils.GetQueryString(r, "order", string(location.SortAsc)),
+			City:          webutils.GetQueryString(r, "city", ""),
+			Q:             webutils.GetQueryString(r, "q", ""),
+			Tag:           webutils.GetQueryString(r, "tag", ""),
+			Collection:    webutils.GetQueryString(r, "collection", ""),
+			CreatedAfter:  webutils.GetQueryString(r, "created_after", ""),
+			CreatedBefore: webutils.GetQueryString(r, "created_before", ""),
+		}
+		if validator.ValidateAndRespond(w, query) {
+			return
+		}
+
+		filter := query.ToFilter()
+		if bbox := r.URL.Query().Get("bbox"); bbox != "" {
+			box, err := dto.ParseBoundingBox(bbox)
+			if err != nil {
+				webutils.WriteJSON(w, http.StatusBadRequest, err.Error(), nil, nil)
+				return
+			}
+			filter.Box = box
 		}
 
-		locations, metadata, err := locationSvc.GetLocations(r.Context(), userId, filters)
+		locations, metadata, err := locationSvc.GetLocations(r.Context(), userId, filter)
 		if err != nil {
+			if errors.Is(err, location.ErrInvalidCursor) || errors.Is(err, location.ErrInvalidPage) {
+				webutils.WriteJSON(w, http.StatusBadRequest, err.Error(), nil, nil)
+				return
+			}
 			webutils.WriteJSON(w, http.StatusInternalServerError, "internal server error", nil, nil)
 			logger.Error("error on getting all locations", "error", err.Error())
 			return
@@ -203,3 +419,181 @@ func GetAllLocations(locationSvc location.ServiceApi, logger *slog.Logger) http.
 		webutils.WriteJSON(w, http.StatusOK, "locations retrieved successfully", locationsRes.Locations, locationsRes.Meta)
 	}
 }
+
+// GetNearbyLocations handles the HTTP request to find a user's locations
+// around a point.
+//
+// @Summary Find nearby locations
+// @Description Retrieves the user's locations within radius_km of lat/lon, closest first, with their distance.
+// @Tags locations
+// @Accept json
+// @Produce json
+// @Param lat query number true "Latitude of the search center"
+// @Param lon query number true "Longitude of the search center"
+// @Param radius_km query number true "Search radius in kilometres"
+// @Param bbox query string false "Only locations inside minLon,minLat,maxLon,maxLat"
+// @Param limit query int false "Maximum number of results" default(50)
+// @Success 200 {array} dto.NearbyLocationRes "nearby locations retrieved successfully"
+// @Failure 400 {string} string "invalid bbox"
+// @Failure 422 {string} string "validation error"
+// @Failure 500 {string} string "internal server error"
+// @Router /api/v1/locations/nearby [get]
+func GetNearbyLocations(locationSvc location.ServiceApi, logger *slog.Logger, validator *webutils.CustomValidator) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userId := r.Context().Value("userId").(string)
+
+		query := dto.NearbyQuery{
+			Lat:      webutils.GetQueryFloat(r, "lat"),
+			Lon:      webutils.GetQueryFloat(r, "lon"),
+			RadiusKm: webutils.GetQueryFloat(r, "radius_km"),
+			Limit:    webutils.GetQueryInt(r, "limit", defaultNearbyLimit),
+		}
+		if validator.ValidateAndRespond(w, query) {
+			return
+		}
+
+		nearbyQuery := query.ToDomain()
+		if bbox := r.URL.Query().Get("bbox"); bbox != "" {
+			box, err := dto.ParseBoundingBox(bbox)
+			if err != nil {
+				webutils.WriteJSON(w, http.StatusBadRequest, err.Error(), nil, nil)
+				return
+			}
+			nearbyQuery.Box = box
+		}
+
+		nearby, err := locationSvc.FindNearby(r.Context(), userId, nearbyQuery)
+		if err != nil {
+			webutils.WriteJSON(w, http.StatusInternalServerError, "internal server error", nil, nil)
+			logger.Error("error on finding nearby locations", "error", err.Error())
+			return
+		}
+		webutils.WriteJSON(w, http.StatusOK, "nearby locations retrieved successfully", dto.GetNearbyLocationsRes(nearby), nil)
+	}
+}
+
+// GetDuplicateLocations handles the HTTP request to list groups of the user's
+// locations that look like duplicates of each other.
+//
+// @Summary Find duplicate locations
+// @Description Groups the user's locations that share a city and lie close to each other, in list order, so that all but one of each group can be removed.
+// @Tags locations
+// @Produce json
+// @Success 200 {array} []dto.LocationRes "duplicate locations retrieved successfully"
+// @Failure 500 {string} string "internal server error"
+// @Router /api/v1/locations/duplicates [get]
+func GetDuplicateLocations(locationSvc location.ServiceApi, logger *slog.Logger) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userId := r.Context().Value("userId").(string)
+
+		groups, err := locationSvc.FindDuplicates(r.Context(), userId)
+		if err != nil {
+			webutils.WriteJSON(w, http.StatusInternalServerError, "internal server error", nil, nil)
+			logger.Error("error on finding duplicate locations", "error", err.Error())
+			return
+		}
+		webutils.WriteJSON(w, http.StatusOK, "duplicate locations retrieved successfully", dto.GetDuplicatesRes(groups), nil)
+	}
+}
+
+// ReorderLocations handles the HTTP request to set the user's location order.
+//
+// @Summary Reorder locations
+// @Description Moves the listed locations, in order, to the front of the user's list. The change is applied atomically.
+// @Tags locations
+// @Accept json
+// @Produce json
+// @Param order body dto.ReorderReq true "Ordered location IDs"
+// @Success 200 {string} string "locations reordered successfully"
+// @Failure 400 {string} string "Invalid input format or duplicate ids"
+// @Failure 404 {string} string "location not found"
+// @Failure 422 {string} string "validation error"
+// @Failure 500 {string} string "internal server error"
+// @Router /api/v1/locations/reorder [post]
+func ReorderLocations(locationSvc location.ServiceApi, logger *slog.Logger, validator *webutils.CustomValidator) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var req dto.ReorderReq
+		if err := webutils.ReadJSON(w, r, &req); err != nil {
+			webutils.WriteJSON(w, http.StatusBadRequest, "Invalid input format", nil, nil)
+			return
+		}
+		if validator.ValidateAndRespond(w, req) {
+			return
+		}
+
+		userId := r.Context().Value("userId").(string)
+		err := locationSvc.ReorderLocations(r.Context(), userId, req.Ids)
+		if err != nil {
+			switch {
+			case errors.Is(err, location.ErrInvalidOrder):
+				webutils.WriteJSON(w, http.StatusBadRequest, err.Error(), nil, nil)
+			case errors.Is(err, location.ErrLocationNotFound):
+				webutils.WriteJSON(w, http.StatusNotFound, "location not found", nil, nil)
+			default:
+				webutils.WriteJSON(w, http.StatusInternalServerError, "internal server error", nil, nil)
+				logger.Error("error on reordering locations", "error", err.Error())
+			}
+			return
+		}
+
+		webutils.WriteJSON(w, http.StatusOK, "locations reordered successfully", nil, nil)
+	}
+}
+
+// GetTrash handles the HTTP request for listing the user's deleted locations.
+//
+// @Summary List deleted locations
+// @Description Retrieves the user's deleted locations, most recently deleted first. They can be restored until the purge window passes.
+// @Tags locations
+// @Produce json
+// @Success 200 {array} dto.LocationRes "trash retrieved successfully"
+// @Failure 500 {string} string "internal server error"
+// @Router /api/v1/locations/trash [get]
+func GetTrash(locationSvc location.ServiceApi, logger *slog.Logger) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userId := r.Context().Value("userId").(string)
+		locations, err := locationSvc.GetTrash(r.Context(), userId)
+		if err != nil {
+			webutils.WriteJSON(w, http.StatusInternalServerError, "internal server error", nil, nil)
+			logger.Error("error on getting trash", "error", err.Error())
+			return
+		}
+
+		webutils.WriteJSON(w, http.StatusOK, "trash retrieved successfully", dto.GetLocationsListRes(locations), nil)
+	}
+}
+
+// RestoreLocation handles the HTTP request for restoring a deleted location.
+//
+// @Summary Restore a deleted location
+// @Description Moves a location out of the trash and back to the end of the user's list
+// @Tags locations
+// @Produce json
+// @Param id path string true "Location ID"
+// @Success 200 {object} dto.LocationRes "location restored successfully"
+// @Failure 403 {string} string "location limit reached"
+// @Failure 404 {string} string "location not found"
+// @Failure 500 {string} string "internal server error"
+// @Router /api/v1/locations/{id}/restore [post]
+func RestoreLocation(locationSvc location.ServiceApi, logger *slog.Logger) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id := r.PathValue("id")
+		userId := r.Context().Value("userId").(string)
+		loc, err := locationSvc.RestoreLocation(r.Context(), id, userId)
+		if err != nil {
+			switch {
+			case errors.Is(err, location.ErrLocationNotFound):
+				webutils.WriteJSON(w, http.StatusNotFound, "location not found", nil, nil)
+			case errors.Is(err, location.ErrLocationLimit):
+				webutils.WriteJSON(w, http.StatusForbidden, err.Error(), nil, nil)
+			default:
+				webutils.WriteJSON(w, http.StatusInternalServerError, "internal server error", nil, nil)
+				logger.Error("error on restoring location", "error", err.Error())
+			}
+			return
+		}
+
+		webutils.SetETag(w, loc.Version)
+		webutils.WriteJSON(w, http.StatusOK, "location restored successfully", dto.GetLocationRes(loc), nil)
+	}
+}
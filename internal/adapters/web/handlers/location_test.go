@@ -4,10 +4,13 @@ import (
 	"bytes"
 	"context"
 	"encoding/json"
+	"io"
 	"log/slog"
 	"net/http"
 	"net/http/httptest"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/go-playground/validator/v10"
 	"github.com/google/uuid"
@@ -23,15 +26,31 @@ func NewMockLocationService() *MockLocationService {
 	return &MockLocationService{}
 }
 
-func (m *MockLocationService) CreateLocation(ctx context.Context, loc domain.Location) (domain.Location, error) {
+func (m *MockLocationService) CreateLocation(ctx context.Context, loc domain.Location, force bool) (domain.Location, error) {
+	if loc.City == "Duplicate City" && !force {
+		return domain.Location{}, &location.DuplicateError{Matches: []domain.Location{{Id: "existing", City: "Duplicate City"}}}
+	}
+	if loc.City == "Full City" {
+		return domain.Location{}, location.ErrLocationLimit
+	}
+	if loc.Notes == "too long" {
+		return domain.Location{}, location.ErrNotesTooLong
+	}
 	loc.Id = uuid.New().String()
 	return loc, nil
 }
 
-func (m *MockLocationService) GetLocation(ctx context.Context, id string) (domain.Location, error) {
+func (m *MockLocationService) FindDuplicates(ctx context.Context, userID string) ([][]domain.Location, error) {
+	return [][]domain.Location{{{Id: "1", City: "Test City"}, {Id: "2", City: "test city"}}}, nil
+}
+
+func (m *MockLocationService) GetLocation(ctx context.Context, id string, userID string) (domain.Location, error) {
 	if id == "notfound" {
 		return domain.Location{}, location.ErrLocationNotFound
 	}
+	if id == "forbidden" {
+		return domain.Location{}, location.ErrUnAuthorized
+	}
 	return domain.Location{
 		Id:       id,
 		UserID:   "1",
@@ -42,23 +61,290 @@ func (m *MockLocationService) GetLocation(ctx context.Context, id string) (domai
 			Lat: 1.0,
 			Lon: 1.0,
 		},
+		Version: mockVersion,
 	}, nil
 }
 
+// mockVersion is the version every location returned by the mock is at.
+const mockVersion = 1
+
 func (m *MockLocationService) UpdateLocation(ctx context.Context, loc domain.Location) (domain.Location, error) {
 	if loc.Id == "notfound" {
 		return domain.Location{}, location.ErrLocationNotFound
 	}
+	if loc.Id == "forbidden" {
+		return domain.Location{}, location.ErrUnAuthorized
+	}
+	if loc.Version != 0 && loc.Version != mockVersion {
+		return domain.Location{}, location.ErrVersionMismatch
+	}
+	loc.Version = mockVersion + 1
 	return loc, nil
 }
 
-func (m *MockLocationService) DeleteLocation(ctx context.Context, id string, userId string) error {
+func (m *MockLocationService) DeleteLocation(ctx context.Context, id string, userId string, version int64) error {
 	if id == "notfound" {
 		return location.ErrLocationNotFound
 	}
+	if id == "forbidden" {
+		return location.ErrUnAuthorized
+	}
+	if version != 0 && version != mockVersion {
+		return location.ErrVersionMismatch
+	}
+	return nil
+}
+
+func (m *MockLocationService) GetTrash(ctx context.Context, userID string) ([]domain.Location, error) {
+	return []domain.Location{
+		{Id: uuid.New().String(), UserID: userID, City: "Test City", Version: mockVersion, DeletedAt: time.Now()},
+	}, nil
+}
+
+func (m *MockLocationService) RestoreLocation(ctx context.Context, id string, userID string) (domain.Location, error) {
+	if id == "notfound" {
+		return domain.Location{}, location.ErrLocationNotFound
+	}
+	return domain.Location{Id: id, UserID: userID, City: "Test City", Version: mockVersion}, nil
+}
+
+func (m *MockLocationService) GetHistory(ctx context.Context, id string, userID string) ([]domain.HistoryEntry, error) {
+	if id == "notfound" {
+		return nil, location.ErrLocationNotFound
+	}
+	return []domain.HistoryEntry{
+		{LocationID: id, UserID: userID, ActorID: userID, Action: domain.HistoryCreated, Version: 1, At: time.Now(),
+			Changes: []domain.FieldChange{{Field: "city", New: "Test City"}}},
+	}, nil
+}
+
+func (m *MockLocationService) RevertLocation(ctx context.Context, id string, userID string, toVersion int64, ifVersion int64) (domain.Location, error) {
+	if id == "notfound" {
+		return domain.Location{}, location.ErrLocationNotFound
+	}
+	if toVersion > mockVersion {
+		return domain.Location{}, location.ErrVersionNotFound
+	}
+	if ifVersion != 0 && ifVersion != mockVersion {
+		return domain.Location{}, location.ErrVersionMismatch
+	}
+	return domain.Location{Id: id, UserID: userID, City: "Test City", Version: mockVersion + 1}, nil
+}
+
+func (m *MockLocationService) FindNearby(ctx context.Context, userID string, query location.NearbyQuery) ([]location.NearbyLocation, error) {
+	return []location.NearbyLocation{
+		{Location: domain.Location{Id: uuid.New().String(), UserID: "1", City: "Test City"}, DistanceKm: 1.5},
+	}, nil
+}
+
+func (m *MockLocationService) ExportLocations(ctx context.Context, userID string, filter location.Filter, fn func(domain.Location) error) error {
+	filter.Page, filter.PageSize = 1, 100
+	locations, _, _ := m.GetLocations(ctx, userID, filter)
+	for _, loc := range locations {
+		if err := fn(loc); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ImportLocations treats locations in the city "Duplicate City" as
+// duplicates and accepts everything else.
+func (m *MockLocationService) ImportLocations(ctx context.Context, userID string, locations []domain.Location, opts location.ImportOptions) ([]location.ImportOutcome, error) {
+	if len(locations) > location.MaxImportRows {
+		return nil, location.ErrImportTooLarge
+	}
+	outcomes := make([]location.ImportOutcome, len(locations))
+	for i, loc := range locations {
+		loc.UserID = userID
+		if loc.City == "Duplicate City" && opts.OnDuplicate != location.DuplicateCreate {
+			outcomes[i] = location.ImportOutcome{Status: location.ImportDuplicate, Location: loc, DuplicateOf: "existing"}
+			continue
+		}
+		if !opts.DryRun {
+			loc.Id = uuid.New().String()
+		}
+		outcomes[i] = location.ImportOutcome{Status: location.ImportAccepted, Location: loc}
+	}
+	return outcomes, nil
+}
+
+func (m *MockLocationService) Usage(ctx context.Context, userID string) (location.Usage, error) {
+	return location.Usage{Locations: 3, Quota: location.Quota{MaxLocations: 100, MaxNotesLength: 500}}, nil
+}
+
+func (m *MockLocationService) MergeUser(ctx context.Context, fromUserID, toUserID string) (location.MergeResult, error) {
+	return location.MergeResult{}, nil
+}
+
+func (m *MockLocationService) AddAttachment(ctx context.Context, locationID, userID, fileName string, r io.Reader) (domain.Attachment, error) {
+	if locationID == "notfound" {
+		return domain.Attachment{}, location.ErrLocationNotFound
+	}
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return domain.Attachment{}, err
+	}
+	if len(data) > 10 {
+		return domain.Attachment{}, location.ErrAttachmentTooLarge
+	}
+	return domain.Attachment{Id: "att-1", LocationID: locationID, FileName: fileName, ContentType: "text/plain; charset=utf-8", Size: int64(len(data))}, nil
+}
+
+func (m *MockLocationService) GetAttachments(ctx context.Context, locationID, userID string) ([]domain.Attachment, error) {
+	if locationID == "notfound" {
+		return nil, location.ErrLocationNotFound
+	}
+	return []domain.Attachment{{Id: "att-1", LocationID: locationID, FileName: "notes.txt", ContentType: "text/plain; charset=utf-8", Size: 5}}, nil
+}
+
+func (m *MockLocationService) OpenAttachment(ctx context.Context, locationID, id, userID string, thumbnail bool) (domain.Attachment, io.ReadCloser, error) {
+	if id == "missing" {
+		return domain.Attachment{}, nil, location.ErrAttachmentNotFound
+	}
+	if thumbnail {
+		return domain.Attachment{}, nil, location.ErrNoThumbnail
+	}
+	attachment := domain.Attachment{Id: id, LocationID: locationID, FileName: "notes.txt", ContentType: "text/plain; charset=utf-8", Size: 5}
+	return attachment, io.NopCloser(strings.NewReader("hello")), nil
+}
+
+func (m *MockLocationService) DeleteAttachment(ctx context.Context, locationID, id, userID string) error {
+	if locationID == "forbidden" {
+		return location.ErrUnAuthorized
+	}
+	return nil
+}
+
+func (m *MockLocationService) CheckIn(ctx context.Context, locationID, userID, comment string) (domain.CheckIn, error) {
+	if locationID == "notfound" {
+		return domain.CheckIn{}, location.ErrLocationNotFound
+	}
+	return domain.CheckIn{Id: "c1", LocationID: locationID, Comment: comment, City: "Paris", Weather: &domain.Weather{Temperature: 21.5}}, nil
+}
+
+func (m *MockLocationService) GetCheckIns(ctx context.Context, locationID, userID string) ([]domain.CheckIn, error) {
+	if locationID == "notfound" {
+		return nil, location.ErrLocationNotFound
+	}
+	return []domain.CheckIn{{Id: "c1", LocationID: locationID, City: "Paris"}}, nil
+}
+
+func (m *MockLocationService) GetTimeline(ctx context.Context, userID string, filter location.CheckInFilter) ([]domain.CheckIn, domain.Metadata, error) {
+	if !filter.After.IsZero() && !filter.Before.IsZero() && !filter.After.Before(filter.Before) {
+		return nil, domain.Metadata{}, location.ErrInvalidDateSpan
+	}
+	checkIns := []domain.CheckIn{{Id: "c2", LocationID: "b"}, {Id: "c1", LocationID: "a"}}
+	return checkIns, domain.CalculateMetadata(2, 0, int32(filter.PageSize)), nil
+}
+
+func (m *MockLocationService) Batch(ctx context.Context, userID string, ops []location.BatchOperation, atomic bool) ([]location.BatchResult, error) {
+	results := make([]location.BatchResult, len(ops))
+	for i, op := range ops {
+		switch {
+		case op.Location.Id == "notfound":
+			results[i].Err = location.ErrLocationNotFound
+		case op.Op == location.BatchCreate:
+			results[i].Location = op.Location
+			results[i].Location.Id = uuid.New().String()
+		default:
+			results[i].Location = op.Location
+		}
+		if atomic && results[i].Err != nil {
+			for j := range results {
+				if j != i {
+					results[j] = location.BatchResult{Err: location.ErrBatchAborted}
+				}
+			}
+			return results, nil
+		}
+	}
+	return results, nil
+}
+
+func (m *MockLocationService) CreateCollection(ctx context.Context, c domain.Collection) (domain.Collection, error) {
+	c.Id = uuid.New().String()
+	return c, nil
+}
+
+func (m *MockLocationService) GetCollection(ctx context.Context, id string, userID string) (domain.Collection, error) {
+	switch id {
+	case "notfound":
+		return domain.Collection{}, location.ErrCollectionNotFound
+	case "forbidden":
+		return domain.Collection{}, location.ErrUnAuthorized
+	}
+	return domain.Collection{Id: id, UserID: userID, Name: "Ski trips"}, nil
+}
+
+func (m *MockLocationService) GetCollections(ctx context.Context, userID string) ([]domain.Collection, error) {
+	return []domain.Collection{{Id: uuid.New().String(), UserID: userID, Name: "Ski trips"}}, nil
+}
+
+func (m *MockLocationService) UpdateCollection(ctx context.Context, c domain.Collection) (domain.Collection, error) {
+	_, err := m.GetCollection(ctx, c.Id, c.UserID)
+	return c, err
+}
+
+func (m *MockLocationService) DeleteCollection(ctx context.Context, id string, userID string) error {
+	_, err := m.GetCollection(ctx, id, userID)
+	return err
+}
+
+func (m *MockLocationService) AddToCollection(ctx context.Context, collectionID, locationID, userID string) error {
+	if locationID == "notfound" {
+		return location.ErrLocationNotFound
+	}
+	_, err := m.GetCollection(ctx, collectionID, userID)
+	return err
+}
+
+func (m *MockLocationService) RemoveFromCollection(ctx context.Context, collectionID, locationID, userID string) error {
+	return m.AddToCollection(ctx, collectionID, locationID, userID)
+}
+
+func (m *MockLocationService) ReorderLocations(ctx context.Context, userID string, ids []string) error {
+	for _, id := range ids {
+		if id == "notfound" {
+			return location.ErrLocationNotFound
+		}
+	}
 	return nil
 }
 
+func (m *MockLocationService) CreateShare(ctx context.Context, share domain.Share) (domain.Share, error) {
+	if share.LocationID == "notfound" {
+		return domain.Share{}, location.ErrLocationNotFound
+	}
+	if (share.LocationID == "") == (share.CollectionID == "") {
+		return domain.Share{}, location.ErrInvalidShare
+	}
+	share.Token = "token"
+	return share, nil
+}
+
+func (m *MockLocationService) GetShares(ctx context.Context, userID string) ([]domain.Share, error) {
+	return []domain.Share{{Token: "token", UserID: userID, LocationID: "a"}}, nil
+}
+
+func (m *MockLocationService) RevokeShare(ctx context.Context, token string, userID string) error {
+	_, err := m.GetShared(ctx, token)
+	return err
+}
+
+// GetShared knows the tokens "token", which shares a location, and
+// "expired".
+func (m *MockLocationService) GetShared(ctx context.Context, token string) (location.SharedContent, error) {
+	switch token {
+	case "token":
+		loc := domain.Location{Id: "a", UserID: "1", Nickname: "Home", City: "Test City", Coordinates: domain.Coordinates{Lat: 1.23, Lon: 4.56}}
+		return location.SharedContent{Share: domain.Share{Token: token, LocationID: "a"}, Location: &loc}, nil
+	case "expired":
+		return location.SharedContent{}, location.ErrShareExpired
+	}
+	return location.SharedContent{}, location.ErrShareNotFound
+}
+
 func (m *MockLocationService) GetLocations(ctx context.Context, userID string, filter location.Filter) ([]domain.Location, domain.Metadata, error) {
 
 	locations := []domain.Location{
@@ -97,6 +383,20 @@ func TestCreateLocation(t *testing.T) {
 		}
 	})
 
+	t.Run("coordinates out of range", func(t *testing.T) {
+		for _, body := range []string{
+			`{"notes": "n", "nickname": "Home", "city": "Oslo", "coordinates": {"lat": -90.5, "lon": 10}}`,
+			`{"notes": "n", "nickname": "Home", "city": "Oslo", "coordinates": {"lat": 59.9, "lon": 180.1}}`,
+		} {
+			req := httptest.NewRequest(http.MethodPost, "/api/v1/locations", bytes.NewBufferString(body)).WithContext(ctx)
+			w := httptest.NewRecorder()
+			handler(w, req)
+			if w.Code != http.StatusUnprocessableEntity {
+				t.Errorf("Expected status code %d for %s, got %d", http.StatusUnprocessableEntity, body, w.Code)
+			}
+		}
+	})
+
 	t.Run("missing required fields", func(t *testing.T) {
 		createLocation := dto.LocationReq{
 			Notes:    "",
@@ -152,6 +452,82 @@ func TestCreateLocation(t *testing.T) {
 	if err != nil {
 		t.Errorf("Failed to decode response: %v", err)
 	}
+
+	t.Run("over quota", func(t *testing.T) {
+		for _, tt := range []struct {
+			name     string
+			city     string
+			notes    string
+			expected int
+		}{
+			{"location limit", "Full City", "Test Notes", http.StatusForbidden},
+			{"notes length", "Test City", "too long", http.StatusUnprocessableEntity},
+		} {
+			over := createLocation
+			over.City, over.Notes = tt.city, tt.notes
+			reqBody, _ := json.Marshal(over)
+			req := httptest.NewRequest(http.MethodPost, "/api/v1/locations", bytes.NewBuffer(reqBody)).WithContext(ctx)
+			w := httptest.NewRecorder()
+
+			router := http.NewServeMux()
+			router.HandleFunc("/api/v1/locations", handler)
+			router.ServeHTTP(w, req)
+
+			if w.Code != tt.expected {
+				t.Errorf("%s: expected status code %d, got %d", tt.name, tt.expected, w.Code)
+			}
+		}
+	})
+
+	t.Run("likely duplicate", func(t *testing.T) {
+		duplicate := createLocation
+		duplicate.City = "Duplicate City"
+		for _, tt := range []struct {
+			query    string
+			expected int
+		}{
+			{"", http.StatusConflict},
+			{"?force=true", http.StatusCreated},
+		} {
+			reqBody, _ := json.Marshal(duplicate)
+			req := httptest.NewRequest(http.MethodPost, "/api/v1/locations"+tt.query, bytes.NewBuffer(reqBody)).WithContext(ctx)
+			w := httptest.NewRecorder()
+
+			router := http.NewServeMux()
+			router.HandleFunc("/api/v1/locations", handler)
+			router.ServeHTTP(w, req)
+
+			if w.Code != tt.expected {
+				t.Errorf("Expected status code %d, got %d", tt.expected, w.Code)
+			}
+			if tt.expected == http.StatusConflict && !bytes.Contains(w.Body.Bytes(), []byte(`"id": "existing"`)) {
+				t.Errorf("Expected response body to list the existing match, got %s", w.Body.String())
+			}
+		}
+	})
+}
+
+func TestGetDuplicateLocations(t *testing.T) {
+	mockSvc := NewMockLocationService()
+	handler := GetDuplicateLocations(mockSvc, slog.Default())
+	ctx := context.WithValue(context.Background(), "userId", "1")
+
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/locations/duplicates", nil).WithContext(ctx)
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status code %d, got %d", http.StatusOK, w.Code)
+	}
+	var response struct {
+		Data [][]dto.LocationRes `json:"data"`
+	}
+	if err := json.NewDecoder(w.Body).Decode(&response); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+	if len(response.Data) != 1 || len(response.Data[0]) != 2 {
+		t.Errorf("Expected one group of two locations, got %+v", response.Data)
+	}
 }
 
 func TestGetLocation(t *testing.T) {
@@ -191,6 +567,19 @@ func TestGetLocation(t *testing.T) {
 		}
 	})
 
+	t.Run("another user's location", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodGet, "/api/v1/locations/forbidden", nil).WithContext(ctx)
+		w := httptest.NewRecorder()
+
+		router := http.NewServeMux()
+		router.HandleFunc("/api/v1/locations/{id}", handler)
+		router.ServeHTTP(w, req)
+
+		if w.Code != http.StatusForbidden {
+			t.Errorf("Expected status code %d, got %d", http.StatusForbidden, w.Code)
+		}
+	})
+
 	t.Run("successful retrieval", func(t *testing.T) {
 
 		req := httptest.NewRequest(http.MethodGet, "/api/v1/locations/o", nil).WithContext(ctx)
@@ -203,6 +592,9 @@ func TestGetLocation(t *testing.T) {
 		if w.Code != http.StatusOK {
 			t.Errorf("Expected status code %d, got %d", http.StatusOK, w.Code)
 		}
+		if etag := w.Header().Get("ETag"); etag != webutils.FormatETag(mockVersion) {
+			t.Errorf("Expected ETag %s, got %s", webutils.FormatETag(mockVersion), etag)
+		}
 
 		var response dto.LocationRes
 		err := json.NewDecoder(w.Body).Decode(&response)
@@ -215,7 +607,7 @@ func TestGetLocation(t *testing.T) {
 
 func TestGetAllLocations(t *testing.T) {
 	mockSvc := NewMockLocationService()
-	handler := GetAllLocations(mockSvc, slog.Default())
+	handler := GetAllLocations(mockSvc, slog.Default(), webutils.NewCustomValidator(validator.New()))
 	ctx := context.WithValue(context.Background(), "userId", "1")
 
 	req := httptest.NewRequest(http.MethodGet, "/api/v1/locations?page=1&pageSize=10", nil).WithContext(ctx)
@@ -239,6 +631,39 @@ func TestGetAllLocations(t *testing.T) {
 	}
 }
 
+func TestGetAllLocationsInvalidQuery(t *testing.T) {
+	mockSvc := NewMockLocationService()
+	handler := GetAllLocations(mockSvc, slog.Default(), webutils.NewCustomValidator(validator.New()))
+	ctx := context.WithValue(context.Background(), "userId", "1")
+
+	tests := []struct {
+		name  string
+		query string
+	}{
+		{"unknown sort field", "sort=notes"},
+		{"unknown order", "order=sideways"},
+		{"malformed created_after", "created_after=yesterday"},
+		{"zero page size", "pageSize=0"},
+		{"zero page", "page=0"},
+		{"limit too large", "limit=500"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/api/v1/locations?"+tt.query, nil).WithContext(ctx)
+			w := httptest.NewRecorder()
+
+			router := http.NewServeMux()
+			router.HandleFunc("/api/v1/locations", handler)
+			router.ServeHTTP(w, req)
+
+			if w.Code != http.StatusUnprocessableEntity {
+				t.Errorf("Expected status code %d, got %d", http.StatusUnprocessableEntity, w.Code)
+			}
+		})
+	}
+}
+
 func TestUpdateLocation(t *testing.T) {
 	mockSvc := NewMockLocationService()
 	handler := UpdateLocation(mockSvc, slog.Default(), webutils.NewCustomValidator(validator.New()))
@@ -302,6 +727,20 @@ func TestUpdateLocation(t *testing.T) {
 		}
 	})
 
+	t.Run("another user's location", func(t *testing.T) {
+		reqBody := `{"notes": "n", "nickname": "n", "city": "c", "coordinates": {"lat": 2.0, "lon": 2.0}}`
+		req := httptest.NewRequest(http.MethodPut, "/api/v1/locations/forbidden", bytes.NewBufferString(reqBody)).WithContext(ctx)
+		w := httptest.NewRecorder()
+
+		router := http.NewServeMux()
+		router.HandleFunc("/api/v1/locations/{id}", handler)
+		router.ServeHTTP(w, req)
+
+		if w.Code != http.StatusForbidden {
+			t.Errorf("Expected status code %d, got %d", http.StatusForbidden, w.Code)
+		}
+	})
+
 	t.Run("successful update", func(t *testing.T) {
 		updateLocation := dto.LocationReq{
 			UserID:   "1",
@@ -332,6 +771,39 @@ func TestUpdateLocation(t *testing.T) {
 			t.Errorf("Failed to decode response: %v", err)
 		}
 	})
+
+	t.Run("stale If-Match", func(t *testing.T) {
+		reqBody := bytes.NewBufferString(`{"notes": "n", "nickname": "nick", "city": "city", "coordinates": {"lat": 1.0, "lon": 1.0}}`)
+		req := httptest.NewRequest(http.MethodPut, "/api/v1/locations/12", reqBody).WithContext(ctx)
+		req.Header.Set("If-Match", webutils.FormatETag(mockVersion+1))
+		w := httptest.NewRecorder()
+
+		router := http.NewServeMux()
+		router.HandleFunc("/api/v1/locations/{id}", handler)
+		router.ServeHTTP(w, req)
+
+		if w.Code != http.StatusPreconditionFailed {
+			t.Errorf("Expected status code %d, got %d", http.StatusPreconditionFailed, w.Code)
+		}
+	})
+
+	t.Run("matching If-Match", func(t *testing.T) {
+		reqBody := bytes.NewBufferString(`{"notes": "n", "nickname": "nick", "city": "city", "coordinates": {"lat": 1.0, "lon": 1.0}}`)
+		req := httptest.NewRequest(http.MethodPut, "/api/v1/locations/12", reqBody).WithContext(ctx)
+		req.Header.Set("If-Match", webutils.FormatETag(mockVersion))
+		w := httptest.NewRecorder()
+
+		router := http.NewServeMux()
+		router.HandleFunc("/api/v1/locations/{id}", handler)
+		router.ServeHTTP(w, req)
+
+		if w.Code != http.StatusOK {
+			t.Errorf("Expected status code %d, got %d", http.StatusOK, w.Code)
+		}
+		if etag := w.Header().Get("ETag"); etag != webutils.FormatETag(mockVersion+1) {
+			t.Errorf("Expected ETag %s, got %s", webutils.FormatETag(mockVersion+1), etag)
+		}
+	})
 }
 
 func TestDeleteLocation(t *testing.T) {
@@ -369,6 +841,19 @@ func TestDeleteLocation(t *testing.T) {
 		}
 	})
 
+	t.Run("another user's location", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodDelete, "/api/v1/locations/forbidden", nil).WithContext(ctx)
+		w := httptest.NewRecorder()
+
+		router := http.NewServeMux()
+		router.HandleFunc("/api/v1/locations/{id}", handler)
+		router.ServeHTTP(w, req)
+
+		if w.Code != http.StatusForbidden {
+			t.Errorf("Expected status code %d, got %d", http.StatusForbidden, w.Code)
+		}
+	})
+
 	t.Run("successful deletion", func(t *testing.T) {
 
 		req := httptest.NewRequest(http.MethodDelete, "/api/v1/locations/s", nil).WithContext(ctx)
@@ -385,4 +870,195 @@ func TestDeleteLocation(t *testing.T) {
 			t.Errorf("Expected response body to contain 'location deleted successfully', got %s", w.Body.String())
 		}
 	})
+
+	t.Run("stale If-Match", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodDelete, "/api/v1/locations/s", nil).WithContext(ctx)
+		req.Header.Set("If-Match", webutils.FormatETag(mockVersion+1))
+		w := httptest.NewRecorder()
+
+		router := http.NewServeMux()
+		router.HandleFunc("/api/v1/locations/{id}", handler)
+		router.ServeHTTP(w, req)
+
+		if w.Code != http.StatusPreconditionFailed {
+			t.Errorf("Expected status code %d, got %d", http.StatusPreconditionFailed, w.Code)
+		}
+	})
+
+	t.Run("malformed If-Match", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodDelete, "/api/v1/locations/s", nil).WithContext(ctx)
+		req.Header.Set("If-Match", "1")
+		w := httptest.NewRecorder()
+
+		router := http.NewServeMux()
+		router.HandleFunc("/api/v1/locations/{id}", handler)
+		router.ServeHTTP(w, req)
+
+		if w.Code != http.StatusBadRequest {
+			t.Errorf("Expected status code %d, got %d", http.StatusBadRequest, w.Code)
+		}
+	})
+}
+
+func TestGetNearbyLocations(t *testing.T) {
+	mockSvc := NewMockLocationService()
+	handler := GetNearbyLocations(mockSvc, slog.Default(), webutils.NewCustomValidator(validator.New()))
+	ctx := context.WithValue(context.Background(), "userId", "1")
+
+	tests := []struct {
+		name     string
+		query    string
+		expected int
+	}{
+		{"valid query", "lat=51.5&lon=-0.12&radius_km=10", http.StatusOK},
+		{"missing radius", "lat=51.5&lon=-0.12", http.StatusUnprocessableEntity},
+		{"latitude out of range", "lat=91&lon=0&radius_km=10", http.StatusUnprocessableEntity},
+		{"non-positive radius", "lat=0&lon=0&radius_km=0", http.StatusUnprocessableEntity},
+		{"malformed bbox", "lat=0&lon=0&radius_km=10&bbox=1,2,3", http.StatusBadRequest},
+		{"valid bbox", "lat=0&lon=0&radius_km=10&bbox=-1,-1,1,1", http.StatusOK},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/api/v1/locations/nearby?"+tt.query, nil).WithContext(ctx)
+			w := httptest.NewRecorder()
+
+			router := http.NewServeMux()
+			router.HandleFunc("/api/v1/locations/nearby", handler)
+			router.ServeHTTP(w, req)
+
+			if w.Code != tt.expected {
+				t.Errorf("Expected status code %d, got %d: %s", tt.expected, w.Code, w.Body.String())
+			}
+		})
+	}
+}
+
+func TestReorderLocations(t *testing.T) {
+	mockSvc := NewMockLocationService()
+	handler := ReorderLocations(mockSvc, slog.Default(), webutils.NewCustomValidator(validator.New()))
+	ctx := context.WithValue(context.Background(), "userId", "1")
+
+	tests := []struct {
+		name     string
+		body     string
+		expected int
+	}{
+		{"invalid input format", `invalid json`, http.StatusBadRequest},
+		{"empty list", `{"ids": []}`, http.StatusUnprocessableEntity},
+		{"unknown location", `{"ids": ["a", "notfound"]}`, http.StatusNotFound},
+		{"successful reorder", `{"ids": ["b", "a"]}`, http.StatusOK},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, "/api/v1/locations/reorder", bytes.NewBufferString(tt.body)).WithContext(ctx)
+			w := httptest.NewRecorder()
+
+			router := http.NewServeMux()
+			router.HandleFunc("/api/v1/locations/reorder", handler)
+			router.ServeHTTP(w, req)
+
+			if w.Code != tt.expected {
+				t.Errorf("Expected status code %d, got %d", tt.expected, w.Code)
+			}
+		})
+	}
+}
+
+func TestPatchLocation(t *testing.T) {
+	mockSvc := NewMockLocationService()
+	handler := PatchLocation(mockSvc, slog.Default(), webutils.NewCustomValidator(validator.New()))
+	ctx := context.WithValue(context.Background(), "userId", "1")
+
+	tests := []struct {
+		name        string
+		id          string
+		contentType string
+		body        string
+		expected    int
+		contains    string
+	}{
+		{"wrong content type", "1", "text/plain", `{"notes": "x"}`, http.StatusUnsupportedMediaType, ""},
+		{"invalid json", "1", "application/merge-patch+json", `{"notes":`, http.StatusBadRequest, ""},
+		{"not found", "notfound", "application/merge-patch+json", `{"notes": "x"}`, http.StatusNotFound, ""},
+		{"another user's location", "forbidden", "application/merge-patch+json", `{"notes": "x"}`, http.StatusForbidden, ""},
+		{"wrong type", "1", "application/merge-patch+json", `{"nickname": 5}`, http.StatusBadRequest, ""},
+		{"unknown field", "1", "application/merge-patch+json", `{"colour": "red"}`, http.StatusBadRequest, ""},
+		{"clearing a required field", "1", "application/merge-patch+json", `{"city": null}`, http.StatusUnprocessableEntity, "This field is required"},
+		{"partial update", "1", "application/merge-patch+json", `{"notes": "Patched"}`, http.StatusOK, "Test Nickname"},
+		{"nested update", "1", "application/json", `{"coordinates": {"lat": 5}}`, http.StatusOK, `"lon": 1`},
+		{"latitude out of range", "1", "application/merge-patch+json", `{"coordinates": {"lat": 91}}`, http.StatusUnprocessableEntity, "can not be greater than 90"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPatch, "/api/v1/locations/"+tt.id, bytes.NewBufferString(tt.body)).WithContext(ctx)
+			req.Header.Set("Content-Type", tt.contentType)
+			w := httptest.NewRecorder()
+
+			router := http.NewServeMux()
+			router.HandleFunc("/api/v1/locations/{id}", handler)
+			router.ServeHTTP(w, req)
+
+			if w.Code != tt.expected {
+				t.Fatalf("Expected status code %d, got %d: %s", tt.expected, w.Code, w.Body.String())
+			}
+			if tt.contains != "" && !bytes.Contains(w.Body.Bytes(), []byte(tt.contains)) {
+				t.Errorf("Expected response body to contain %q, got %s", tt.contains, w.Body.String())
+			}
+		})
+	}
+}
+
+func TestGetTrash(t *testing.T) {
+	mockSvc := NewMockLocationService()
+	handler := GetTrash(mockSvc, slog.Default())
+	ctx := context.WithValue(context.Background(), "userId", "1")
+
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/locations/trash", nil).WithContext(ctx)
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status code %d, got %d", http.StatusOK, w.Code)
+	}
+	var response struct {
+		Data []dto.LocationRes `json:"data"`
+	}
+	if err := json.NewDecoder(w.Body).Decode(&response); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+	if len(response.Data) != 1 || response.Data[0].DeletedAt == "" {
+		t.Errorf("Expected one trashed location with deleted_at, got %+v", response.Data)
+	}
+}
+
+func TestRestoreLocation(t *testing.T) {
+	mockSvc := NewMockLocationService()
+	handler := RestoreLocation(mockSvc, slog.Default())
+	ctx := context.WithValue(context.Background(), "userId", "1")
+
+	tests := []struct {
+		name   string
+		id     string
+		status int
+	}{
+		{name: "not in trash", id: "notfound", status: http.StatusNotFound},
+		{name: "restored", id: "12", status: http.StatusOK},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, "/api/v1/locations/"+tt.id+"/restore", nil).WithContext(ctx)
+			w := httptest.NewRecorder()
+
+			router := http.NewServeMux()
+			router.HandleFunc("POST /api/v1/locations/{id}/restore", handler)
+			router.ServeHTTP(w, req)
+
+			if w.Code != tt.status {
+				t.Errorf("Expected status code %d, got %d", tt.status, w.Code)
+			}
+		})
+	}
 }
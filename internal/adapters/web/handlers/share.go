@@ -0,0 +1,147 @@
+package handlers
+
+import (
+	"errors"
+	"log/slog"
+	"net/http"
+
+	"github.com/lafetz/weavo/internal/adapters/web/dto"
+	"github.com/lafetz/weavo/internal/adapters/web/webutils"
+	"github.com/lafetz/weavo/internal/core/service/location"
+	"github.com/lafetz/weavo/internal/core/service/weather"
+)
+
+// writeShareError maps share service errors to responses and logs anything
+// unexpected.
+func writeShareError(w http.ResponseWriter, logger *slog.Logger, err error, action string) {
+	switch {
+	case errors.Is(err, location.ErrInvalidShare), errors.Is(err, location.ErrInvalidExpiry):
+		webutils.WriteJSON(w, http.StatusBadRequest, err.Error(), nil, nil)
+	case errors.Is(err, location.ErrShareNotFound):
+		webutils.WriteJSON(w, http.StatusNotFound, "share not found", nil, nil)
+	case errors.Is(err, location.ErrShareExpired):
+		webutils.WriteJSON(w, http.StatusGone, err.Error(), nil, nil)
+	case errors.Is(err, location.ErrLocationNotFound):
+		webutils.WriteJSON(w, http.StatusNotFound, "location not found", nil, nil)
+	case errors.Is(err, location.ErrCollectionNotFound):
+		webutils.WriteJSON(w, http.StatusNotFound, "collection not found", nil, nil)
+	case errors.Is(err, location.ErrUnAuthorized):
+		webutils.WriteJSON(w, http.StatusForbidden, "forbidden", nil, nil)
+	default:
+		webutils.WriteJSON(w, http.StatusInternalServerError, "internal server error", nil, nil)
+		logger.Error("error on "+action, "error", err.Error())
+	}
+}
+
+// CreateShare handles the creation of a share link.
+//
+// @Summary Create a share link
+// @Description Creates a revocable token that gives anyone holding it read access to one location or one collection, with its live weather. Set exactly one of location_id and collection_id. precision rounds the shared coordinates to that many decimal places; without it, or with 0, the exact coordinates are shared.
+// @Tags shares
+// @Accept json
+// @Produce json
+// @Param share body dto.ShareReq true "Share request body"
+// @Param Idempotency-Key header string false "Retries with the same key replay the first response instead of running again"
+// @Success 201 {object} dto.ShareRes "share created successfully"
+// @Failure 400 {string} string "Invalid input format or scope"
+// @Failure 403 {string} string "forbidden"
+// @Failure 404 {string} string "location or collection not found"
+// @Failure 409 {string} string "a request with this Idempotency-Key is still in progress"
+// @Failure 422 {string} string "validation error"
+// @Failure 500 {string} string "internal server error"
+// @Router /api/v1/shares [post]
+func CreateShare(locationSvc location.ServiceApi, logger *slog.Logger, validator *webutils.CustomValidator) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var req dto.ShareReq
+		if err := webutils.ReadJSON(w, r, &req); err != nil {
+			webutils.WriteJSON(w, http.StatusBadRequest, "Invalid input format", nil, nil)
+			return
+		}
+		if validator.ValidateAndRespond(w, req) {
+			return
+		}
+
+		share := req.ToDomain()
+		share.UserID = r.Context().Value("userId").(string)
+		share, err := locationSvc.CreateShare(r.Context(), share)
+		if err != nil {
+			writeShareError(w, logger, err, "creating share")
+			return
+		}
+
+		webutils.WriteJSON(w, http.StatusCreated, "share created successfully", dto.GetShareRes(share), nil)
+	}
+}
+
+// GetShares handles the HTTP request to list the user's share links.
+//
+// @Summary List share links
+// @Description Retrieves the user's share links, newest first
+// @Tags shares
+// @Produce json
+// @Success 200 {array} dto.ShareRes "shares retrieved successfully"
+// @Failure 500 {string} string "internal server error"
+// @Router /api/v1/shares [get]
+func GetShares(locationSvc location.ServiceApi, logger *slog.Logger) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userId := r.Context().Value("userId").(string)
+		shares, err := locationSvc.GetShares(r.Context(), userId)
+		if err != nil {
+			writeShareError(w, logger, err, "getting shares")
+			return
+		}
+
+		webutils.WriteJSON(w, http.StatusOK, "shares retrieved successfully", dto.GetSharesRes(shares), nil)
+	}
+}
+
+// RevokeShare handles the HTTP request for revoking a share link.
+//
+// @Summary Revoke a share link
+// @Description Deletes a share link; its token stops working immediately
+// @Tags shares
+// @Produce json
+// @Param token path string true "Share token"
+// @Success 200 {string} string "share revoked successfully"
+// @Failure 403 {string} string "forbidden"
+// @Failure 404 {string} string "share not found"
+// @Failure 500 {string} string "internal server error"
+// @Router /api/v1/shares/{token} [delete]
+func RevokeShare(locationSvc location.ServiceApi, logger *slog.Logger) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userId := r.Context().Value("userId").(string)
+		if err := locationSvc.RevokeShare(r.Context(), r.PathValue("token"), userId); err != nil {
+			writeShareError(w, logger, err, "revoking share")
+			return
+		}
+
+		webutils.WriteJSON(w, http.StatusOK, "share revoked successfully", nil, nil)
+	}
+}
+
+// GetShared handles the public HTTP request for opening a share link. It
+// needs no session: the token alone decides what can be read.
+//
+// @Summary Open a share link
+// @Description Retrieves the shared location or collection with the current weather in each city. No session is needed, and opening a share does not keep the shared locations from expiring.
+// @Tags shares
+// @Produce json
+// @Param token path string true "Share token"
+// @Success 200 {object} dto.SharedRes "shared content retrieved successfully"
+// @Failure 404 {string} string "share not found"
+// @Failure 410 {string} string "share has expired"
+// @Failure 500 {string} string "internal server error"
+// @Router /api/v1/shared/{token} [get]
+func GetShared(locationSvc location.ServiceApi, weatherSvc weather.ServiceApi, logger *slog.Logger) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		content, err := locationSvc.GetShared(r.Context(), r.PathValue("token"))
+		if err != nil {
+			writeShareError(w, logger, err, "opening share")
+			return
+		}
+
+		w.Header().Set("Cache-Control", "no-store")
+		res := dto.GetSharedRes(content, weatherLookup(r.Context(), weatherSvc, logger))
+		webutils.WriteJSON(w, http.StatusOK, "shared content retrieved successfully", res, nil)
+	}
+}
@@ -0,0 +1,88 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/lafetz/weavo/internal/adapters/web/dto"
+	"github.com/lafetz/weavo/internal/adapters/web/webutils"
+)
+
+func TestCreateShare(t *testing.T) {
+	mockSvc := NewMockLocationService()
+	handler := CreateShare(mockSvc, slog.Default(), webutils.NewCustomValidator(validator.New()))
+	ctx := context.WithValue(context.Background(), "userId", "1")
+
+	tests := []struct {
+		name   string
+		body   string
+		status int
+	}{
+		{"invalid json", `nope`, http.StatusBadRequest},
+		{"no scope", `{}`, http.StatusBadRequest},
+		{"bad expiry", `{"location_id": "a", "expires_at": "tomorrow"}`, http.StatusUnprocessableEntity},
+		{"precision too fine", `{"location_id": "a", "precision": 9}`, http.StatusUnprocessableEntity},
+		{"location not found", `{"location_id": "notfound"}`, http.StatusNotFound},
+		{"success", `{"location_id": "a", "precision": 2, "expires_at": "2099-01-01T00:00:00Z"}`, http.StatusCreated},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, "/api/v1/shares", bytes.NewBufferString(tt.body)).WithContext(ctx)
+			w := httptest.NewRecorder()
+			handler(w, req)
+			if w.Code != tt.status {
+				t.Errorf("Expected status code %d, got %d: %s", tt.status, w.Code, w.Body.String())
+			}
+		})
+	}
+}
+
+func TestGetShared(t *testing.T) {
+	mockSvc := NewMockLocationService()
+	router := http.NewServeMux()
+	router.HandleFunc("/api/v1/shared/{token}", GetShared(mockSvc, &MockWeatherService{}, slog.Default()))
+
+	// No userId in the context: share links work without a session.
+	get := func(token string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/v1/shared/"+token, nil))
+		return w
+	}
+
+	t.Run("success", func(t *testing.T) {
+		w := get("token")
+		if w.Code != http.StatusOK {
+			t.Fatalf("Expected status code %d, got %d", http.StatusOK, w.Code)
+		}
+		var res struct {
+			Data dto.SharedRes `json:"data"`
+		}
+		if err := json.NewDecoder(w.Body).Decode(&res); err != nil {
+			t.Fatalf("Failed to decode response: %v", err)
+		}
+		if res.Data.Location == nil || res.Data.Location.Nickname != "Home" {
+			t.Fatalf("Expected the shared location, got %+v", res.Data)
+		}
+		if res.Data.Location.Weather == nil || res.Data.Location.Weather.Location != "Test City" {
+			t.Errorf("Expected the weather in the location's city, got %+v", res.Data.Location.Weather)
+		}
+	})
+
+	t.Run("expired", func(t *testing.T) {
+		if w := get("expired"); w.Code != http.StatusGone {
+			t.Errorf("Expected status code %d, got %d", http.StatusGone, w.Code)
+		}
+	})
+
+	t.Run("unknown", func(t *testing.T) {
+		if w := get("nope"); w.Code != http.StatusNotFound {
+			t.Errorf("Expected status code %d, got %d", http.StatusNotFound, w.Code)
+		}
+	})
+}
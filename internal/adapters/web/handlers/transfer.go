@@ -0,0 +1,233 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"mime"
+	"net/http"
+	"path/filepath"
+	"strings"
+
+	"github.com/lafetz/weavo/internal/adapters/web/dto"
+	"github.com/lafetz/weavo/internal/adapters/web/webutils"
+	"github.com/lafetz/weavo/internal/core/domain"
+	"github.com/lafetz/weavo/internal/core/service/location"
+	"github.com/lafetz/weavo/internal/core/service/weather"
+)
+
+// maxImportBytes bounds the size of an import upload.
+const maxImportBytes = 8 << 20
+
+// ExportLocations handles the HTTP request to download a user's locations.
+//
+// @Summary Export locations
+// @Description Streams the user's locations as a JSON array, a CSV file or a GeoJSON FeatureCollection, which can all be imported again, or as GPX waypoints or KML placemarks for GPS units and Google Earth.
+// @Description In GPX and KML the nickname becomes the name and the notes the description; with weather=true the current weather is appended to the description.
+// @Tags locations
+// @Produce json
+// @Produce text/csv
+// @Produce application/geo+json
+// @Produce application/gpx+xml
+// @Produce application/vnd.google-earth.kml+xml
+// @Param format query string false "Export format" Enums(json, csv, geojson, gpx, kml) default(json)
+// @Param tag query string false "Only locations with this tag"
+// @Param collection query string false "Only locations in this collection"
+// @Param weather query bool false "Add the current weather to GPX and KML descriptions" default(false)
+// @Success 200 {array} dto.LocationRes "the exported locations"
+// @Failure 422 {string} string "validation error"
+// @Failure 500 {string} string "internal server error"
+// @Router /api/v1/locations/export [get]
+func ExportLocations(locationSvc location.ServiceApi, weatherSvc weather.ServiceApi, logger *slog.Logger, validator *webutils.CustomValidator) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		query := dto.ExportQuery{
+			Format:     webutils.GetQueryString(r, "format", dto.FormatJSON),
+			Tag:        webutils.GetQueryString(r, "tag", ""),
+			Collection: webutils.GetQueryString(r, "collection", ""),
+			Weather:    webutils.GetQueryBool(r, "weather", false),
+		}
+		if validator.ValidateAndRespond(w, query) {
+			return
+		}
+		withWeather := query.Weather && (query.Format == dto.FormatGPX || query.Format == dto.FormatKML)
+		currentWeather := weatherLookup(r.Context(), weatherSvc, logger)
+
+		userId := r.Context().Value("userId").(string)
+		enc := dto.NewLocationEncoder(query.Format, w)
+		started := false
+		start := func() {
+			started = true
+			w.Header().Set("Content-Type", dto.ExportContentType(query.Format))
+			w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="locations.%s"`, query.Format))
+		}
+		err := locationSvc.ExportLocations(r.Context(), userId, query.ToFilter(), func(loc domain.Location) error {
+			var cw *domain.Weather
+			if withWeather {
+				cw = currentWeather(loc.City)
+			}
+			if !started {
+				start()
+			}
+			return enc.Encode(loc, cw)
+		})
+		if err != nil {
+			// Once the body has started there is no way to report the
+			// error to the client other than cutting the export short.
+			if !started {
+				webutils.WriteJSON(w, http.StatusInternalServerError, "internal server error", nil, nil)
+			}
+			logger.Error("error on exporting locations", "error", err.Error())
+			return
+		}
+		if !started {
+			start()
+		}
+		if err := enc.Close(); err != nil {
+			logger.Error("error on exporting locations", "error", err.Error())
+		}
+	}
+}
+
+// ImportLocations handles the HTTP request to import locations.
+//
+// @Summary Import locations
+// @Description Imports locations from a JSON array, a CSV file or a GeoJSON FeatureCollection, sent either as the request body or as the "file" field of a multipart form.
+// @Description Without format it is taken from the uploaded file's extension or the Content-Type. Every row is validated on its own and the report lists which rows were accepted, rejected or skipped as duplicates.
+// @Description A location is a duplicate when its city and coordinates match one the user already has or an earlier row.
+// @Description Rows with notes longer than the user's limit are rejected. An import that would take the user over their location limit is refused as a whole, and if any accepted row fails to save none of them are kept.
+// @Tags locations
+// @Accept json
+// @Accept text/csv
+// @Accept application/geo+json
+// @Accept multipart/form-data
+// @Produce json
+// @Param format query string false "Import format" Enums(json, csv, geojson)
+// @Param dry_run query bool false "Validate and report without saving anything" default(false)
+// @Param on_duplicate query string false "What to do with duplicates" Enums(skip, create) default(skip)
+// @Param file formData file false "File to import"
+// @Param Idempotency-Key header string false "Retries with the same key replay the first response instead of running again"
+// @Success 200 {object} dto.ImportReportRes "import completed"
+// @Failure 400 {string} string "Invalid input format"
+// @Failure 403 {string} string "location limit reached"
+// @Failure 409 {string} string "a request with this Idempotency-Key is still in progress"
+// @Failure 413 {string} string "import too large"
+// @Failure 422 {string} string "validation error"
+// @Failure 500 {string} string "internal server error"
+// @Router /api/v1/locations/import [post]
+func ImportLocations(locationSvc location.ServiceApi, logger *slog.Logger, validator *webutils.CustomValidator) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		r.Body = http.MaxBytesReader(w, r.Body, maxImportBytes)
+		body, format, err := importBody(r)
+		if err != nil {
+			webutils.WriteJSON(w, http.StatusBadRequest, err.Error(), nil, nil)
+			return
+		}
+		query := dto.ImportQuery{
+			Format:      webutils.GetQueryString(r, "format", format),
+			OnDuplicate: webutils.GetQueryString(r, "on_duplicate", string(location.DuplicateSkip)),
+			DryRun:      webutils.GetQueryBool(r, "dry_run", false),
+		}
+		if validator.ValidateAndRespond(w, query) {
+			return
+		}
+
+		rows, err := dto.ParseImport(query.Format, body)
+		if err != nil {
+			var maxBytesError *http.MaxBytesError
+			if errors.As(err, &maxBytesError) {
+				webutils.WriteJSON(w, http.StatusRequestEntityTooLarge, fmt.Sprintf("import must not be larger than %d bytes", maxImportBytes), nil, nil)
+				return
+			}
+			webutils.WriteJSON(w, http.StatusBadRequest, err.Error(), nil, nil)
+			return
+		}
+		if len(rows) > location.MaxImportRows {
+			webutils.WriteJSON(w, http.StatusRequestEntityTooLarge, location.ErrImportTooLarge.Error(), nil, nil)
+			return
+		}
+
+		var locations []domain.Location
+		for i := range rows {
+			if errs := validator.Validate(rows[i].Req); errs != nil {
+				if rows[i].Errors == nil {
+					rows[i].Errors = errs
+				} else {
+					for field, msg := range errs {
+						rows[i].Errors[field] = msg
+					}
+				}
+			}
+			if len(rows[i].Errors) == 0 {
+				locations = append(locations, rows[i].Req.ToDomain())
+			}
+		}
+
+		userId := r.Context().Value("userId").(string)
+		outcomes, err := locationSvc.ImportLocations(r.Context(), userId, locations, query.ToOptions())
+		if err != nil {
+			switch {
+			case errors.Is(err, location.ErrImportTooLarge):
+				webutils.WriteJSON(w, http.StatusRequestEntityTooLarge, err.Error(), nil, nil)
+			case errors.Is(err, location.ErrLocationLimit):
+				webutils.WriteJSON(w, http.StatusForbidden, err.Error(), nil, nil)
+			default:
+				webutils.WriteJSON(w, http.StatusInternalServerError, "internal server error", nil, nil)
+				logger.Error("error on importing locations", "error", err.Error())
+			}
+			return
+		}
+
+		message := "import completed"
+		if query.DryRun {
+			message = "import dry run completed, nothing was saved"
+		}
+		webutils.WriteJSON(w, http.StatusOK, message, dto.GetImportReport(query.DryRun, rows, outcomes), nil)
+	}
+}
+
+// importBody returns the data to import and the format implied by the
+// upload: the extension of a multipart file, or the request's Content-Type.
+func importBody(r *http.Request) (io.Reader, string, error) {
+	mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	if mediaType != "multipart/form-data" {
+		return r.Body, formatOf(mediaType, ""), nil
+	}
+	mr, err := r.MultipartReader()
+	if err != nil {
+		return nil, "", errors.New("invalid multipart body")
+	}
+	for {
+		part, err := mr.NextPart()
+		if err == io.EOF {
+			return nil, "", errors.New(`multipart body must contain a "file" field`)
+		}
+		if err != nil {
+			return nil, "", errors.New("invalid multipart body")
+		}
+		if part.FormName() == "file" {
+			partType, _, _ := mime.ParseMediaType(part.Header.Get("Content-Type"))
+			return part, formatOf(partType, part.FileName()), nil
+		}
+	}
+}
+
+// formatOf guesses an import format from a file name, falling back to its
+// media type and then to JSON.
+func formatOf(mediaType, fileName string) string {
+	switch strings.ToLower(filepath.Ext(fileName)) {
+	case ".csv":
+		return dto.FormatCSV
+	case ".geojson":
+		return dto.FormatGeoJSON
+	case ".json":
+		return dto.FormatJSON
+	}
+	switch mediaType {
+	case "text/csv":
+		return dto.FormatCSV
+	case "application/geo+json":
+		return dto.FormatGeoJSON
+	}
+	return dto.FormatJSON
+}
@@ -0,0 +1,241 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"encoding/xml"
+	"log/slog"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/lafetz/weavo/internal/adapters/web/dto"
+	"github.com/lafetz/weavo/internal/adapters/web/webutils"
+)
+
+func TestExportLocations(t *testing.T) {
+	mockSvc := NewMockLocationService()
+	handler := ExportLocations(mockSvc, &MockWeatherService{}, slog.Default(), webutils.NewCustomValidator(validator.New()))
+	ctx := context.WithValue(context.Background(), "userId", "1")
+
+	export := func(format string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, "/api/v1/locations/export?format="+format, nil).WithContext(ctx)
+		w := httptest.NewRecorder()
+		handler(w, req)
+		return w
+	}
+
+	t.Run("json", func(t *testing.T) {
+		w := export("json")
+		if w.Code != http.StatusOK {
+			t.Fatalf("Expected status code %d, got %d", http.StatusOK, w.Code)
+		}
+		var locations []dto.LocationRes
+		if err := json.NewDecoder(w.Body).Decode(&locations); err != nil {
+			t.Fatalf("Failed to decode response: %v", err)
+		}
+		if len(locations) != 2 {
+			t.Errorf("Expected 2 locations, got %d", len(locations))
+		}
+	})
+
+	t.Run("csv", func(t *testing.T) {
+		w := export("csv")
+		if ct := w.Header().Get("Content-Type"); !strings.HasPrefix(ct, "text/csv") {
+			t.Errorf("Expected a csv content type, got %s", ct)
+		}
+		records, err := csv.NewReader(w.Body).ReadAll()
+		if err != nil {
+			t.Fatalf("Failed to read csv: %v", err)
+		}
+		if len(records) != 3 || records[0][0] != "id" {
+			t.Errorf("Expected a header and 2 rows, got %v", records)
+		}
+	})
+
+	t.Run("geojson", func(t *testing.T) {
+		w := export("geojson")
+		var collection struct {
+			Type     string `json:"type"`
+			Features []struct {
+				Geometry struct {
+					Coordinates []float64 `json:"coordinates"`
+				} `json:"geometry"`
+			} `json:"features"`
+		}
+		if err := json.NewDecoder(w.Body).Decode(&collection); err != nil {
+			t.Fatalf("Failed to decode response: %v", err)
+		}
+		if collection.Type != "FeatureCollection" || len(collection.Features) != 2 {
+			t.Errorf("Expected a FeatureCollection with 2 features, got %+v", collection)
+		}
+	})
+
+	t.Run("gpx", func(t *testing.T) {
+		w := export("gpx&weather=true")
+		var gpx struct {
+			Waypoints []struct {
+				Lat  float64 `xml:"lat,attr"`
+				Name string  `xml:"name"`
+				Desc string  `xml:"desc"`
+			} `xml:"wpt"`
+		}
+		if err := xml.NewDecoder(w.Body).Decode(&gpx); err != nil {
+			t.Fatalf("Failed to decode response: %v", err)
+		}
+		if len(gpx.Waypoints) != 2 {
+			t.Fatalf("Expected 2 waypoints, got %d", len(gpx.Waypoints))
+		}
+		wpt := gpx.Waypoints[0]
+		if wpt.Name != "Test Nickname" || wpt.Lat != 1 {
+			t.Errorf("Expected the nickname and coordinates of the location, got %+v", wpt)
+		}
+		if !strings.HasPrefix(wpt.Desc, "Test Notes") || !strings.Contains(wpt.Desc, "Clear") {
+			t.Errorf("Expected the notes followed by the weather, got %q", wpt.Desc)
+		}
+	})
+
+	t.Run("kml", func(t *testing.T) {
+		w := export("kml")
+		if ct := w.Header().Get("Content-Type"); ct != "application/vnd.google-earth.kml+xml" {
+			t.Errorf("Expected a KML content type, got %s", ct)
+		}
+		var kml struct {
+			Placemarks []struct {
+				Description string `xml:"description"`
+				Coordinates string `xml:"Point>coordinates"`
+			} `xml:"Document>Placemark"`
+		}
+		if err := xml.NewDecoder(w.Body).Decode(&kml); err != nil {
+			t.Fatalf("Failed to decode response: %v", err)
+		}
+		if len(kml.Placemarks) != 2 || kml.Placemarks[1].Coordinates != "2,2" {
+			t.Fatalf("Expected 2 placemarks, got %+v", kml.Placemarks)
+		}
+		if kml.Placemarks[0].Description != "Test Notes" {
+			t.Errorf("Expected only the notes without weather=true, got %q", kml.Placemarks[0].Description)
+		}
+	})
+
+	t.Run("unknown format", func(t *testing.T) {
+		if w := export("xml"); w.Code != http.StatusUnprocessableEntity {
+			t.Errorf("Expected status code %d, got %d", http.StatusUnprocessableEntity, w.Code)
+		}
+	})
+}
+
+func TestImportLocations(t *testing.T) {
+	mockSvc := NewMockLocationService()
+	handler := ImportLocations(mockSvc, slog.Default(), webutils.NewCustomValidator(validator.New()))
+	ctx := context.WithValue(context.Background(), "userId", "1")
+
+	importReport := func(t *testing.T, w *httptest.ResponseRecorder) dto.ImportReportRes {
+		t.Helper()
+		if w.Code != http.StatusOK {
+			t.Fatalf("Expected status code %d, got %d: %s", http.StatusOK, w.Code, w.Body.String())
+		}
+		var res struct {
+			Data dto.ImportReportRes `json:"data"`
+		}
+		if err := json.NewDecoder(w.Body).Decode(&res); err != nil {
+			t.Fatalf("Failed to decode response: %v", err)
+		}
+		return res.Data
+	}
+
+	t.Run("json rows are validated one by one", func(t *testing.T) {
+		body := `[
+			{"notes": "n", "nickname": "Home", "city": "Paris", "coordinates": {"lat": 48.85, "lon": 2.35}},
+			{"notes": "", "nickname": "Work", "city": "Paris", "coordinates": {"lat": 48.86, "lon": 2.34}},
+			{"notes": "n", "nickname": "Cabin", "city": "Duplicate City", "coordinates": {"lat": 1, "lon": 1}},
+			{"notes": "n", "nickname": "Nowhere", "city": "Paris", "coordinates": {"lat": 91, "lon": 0}}
+		]`
+		req := httptest.NewRequest(http.MethodPost, "/api/v1/locations/import", strings.NewReader(body)).WithContext(ctx)
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		handler(w, req)
+
+		report := importReport(t, w)
+		if report.Total != 4 || report.Accepted != 1 || report.Rejected != 2 || report.Duplicates != 1 {
+			t.Fatalf("Unexpected report counts: %+v", report)
+		}
+		if report.Rows[1].Status != dto.ImportStatusRejected || report.Rows[1].Errors["notes"] == "" {
+			t.Errorf("Expected row 2 to be rejected for its notes, got %+v", report.Rows[1])
+		}
+		if report.Rows[2].DuplicateOf != "existing" {
+			t.Errorf("Expected row 3 to be a duplicate, got %+v", report.Rows[2])
+		}
+		if report.Rows[3].Errors["lat"] == "" {
+			t.Errorf("Expected row 4 to be rejected for its latitude, got %+v", report.Rows[3])
+		}
+	})
+
+	t.Run("multipart csv dry run", func(t *testing.T) {
+		var buf bytes.Buffer
+		mw := multipart.NewWriter(&buf)
+		part, _ := mw.CreateFormFile("file", "places.csv")
+		part.Write([]byte("nickname,city,lat,lon,notes,tags\nHome,Paris,48.85,2.35,n,home;family\nWork,Paris,north,2.34,n,\n"))
+		mw.Close()
+
+		req := httptest.NewRequest(http.MethodPost, "/api/v1/locations/import?dry_run=true", &buf).WithContext(ctx)
+		req.Header.Set("Content-Type", mw.FormDataContentType())
+		w := httptest.NewRecorder()
+		handler(w, req)
+
+		report := importReport(t, w)
+		if !report.DryRun || report.Accepted != 1 || report.Rejected != 1 {
+			t.Fatalf("Unexpected report: %+v", report)
+		}
+		if report.Rows[0].Location.Id != "" {
+			t.Errorf("Expected a dry run not to save, got id %s", report.Rows[0].Location.Id)
+		}
+		if tags := report.Rows[0].Location.Tags; len(tags) != 2 {
+			t.Errorf("Expected 2 tags, got %v", tags)
+		}
+		if report.Rows[1].Errors["lat"] != "must be a number" {
+			t.Errorf("Expected row 2 to be rejected for its latitude, got %+v", report.Rows[1])
+		}
+	})
+
+	t.Run("geojson", func(t *testing.T) {
+		body := `{"type": "FeatureCollection", "features": [
+			{"type": "Feature", "geometry": {"type": "Point", "coordinates": [2.35, 48.85]}, "properties": {"nickname": "Home", "city": "Paris", "notes": "n"}},
+			{"type": "Feature", "geometry": {"type": "LineString", "coordinates": [[0, 0], [1, 1]]}, "properties": {}}
+		]}`
+		req := httptest.NewRequest(http.MethodPost, "/api/v1/locations/import", strings.NewReader(body)).WithContext(ctx)
+		req.Header.Set("Content-Type", "application/geo+json")
+		w := httptest.NewRecorder()
+		handler(w, req)
+
+		report := importReport(t, w)
+		if report.Accepted != 1 || report.Rejected != 1 {
+			t.Fatalf("Unexpected report: %+v", report)
+		}
+		if c := report.Rows[0].Location.Coordinates; c.Lat != 48.85 || c.Lon != 2.35 {
+			t.Errorf("Expected coordinates to be read longitude first, got %+v", c)
+		}
+	})
+
+	t.Run("malformed body", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodPost, "/api/v1/locations/import", strings.NewReader(`{"not": "a list"}`)).WithContext(ctx)
+		w := httptest.NewRecorder()
+		handler(w, req)
+		if w.Code != http.StatusBadRequest {
+			t.Errorf("Expected status code %d, got %d", http.StatusBadRequest, w.Code)
+		}
+	})
+
+	t.Run("unknown duplicate policy", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodPost, "/api/v1/locations/import?on_duplicate=merge", strings.NewReader(`[]`)).WithContext(ctx)
+		w := httptest.NewRecorder()
+		handler(w, req)
+		if w.Code != http.StatusUnprocessableEntity {
+			t.Errorf("Expected status code %d, got %d", http.StatusUnprocessableEntity, w.Code)
+		}
+	})
+}
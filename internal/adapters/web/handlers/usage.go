@@ -0,0 +1,32 @@
+package handlers
+
+import (
+	"log/slog"
+	"net/http"
+
+	"github.com/lafetz/weavo/internal/adapters/web/dto"
+	"github.com/lafetz/weavo/internal/adapters/web/webutils"
+	"github.com/lafetz/weavo/internal/core/service/location"
+)
+
+// GetUsage handles the HTTP request for the user's usage of their quota.
+//
+// @Summary Get the user's usage
+// @Description Reports how many locations the user has, not counting the trash, and the limits they are held to. A limit of 0 means there is none.
+// @Tags users
+// @Produce json
+// @Success 200 {object} dto.UsageRes "usage retrieved successfully"
+// @Failure 500 {string} string "internal server error"
+// @Router /api/v1/me/usage [get]
+func GetUsage(locationSvc location.ServiceApi, logger *slog.Logger) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userId := r.Context().Value("userId").(string)
+		usage, err := locationSvc.Usage(r.Context(), userId)
+		if err != nil {
+			webutils.WriteJSON(w, http.StatusInternalServerError, "internal server error", nil, nil)
+			logger.Error("error on getting usage", "error", err.Error())
+			return
+		}
+		webutils.WriteJSON(w, http.StatusOK, "usage retrieved successfully", dto.GetUsageRes(usage), nil)
+	}
+}
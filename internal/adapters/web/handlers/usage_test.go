@@ -0,0 +1,36 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/lafetz/weavo/internal/adapters/web/dto"
+)
+
+func TestGetUsage(t *testing.T) {
+	mockSvc := NewMockLocationService()
+	handler := GetUsage(mockSvc, slog.Default())
+	ctx := context.WithValue(context.Background(), "userId", "1")
+
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/me/usage", nil).WithContext(ctx)
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status code %d, got %d", http.StatusOK, w.Code)
+	}
+	var response struct {
+		Data dto.UsageRes `json:"data"`
+	}
+	if err := json.NewDecoder(w.Body).Decode(&response); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+	expected := dto.UsageRes{Locations: 3, MaxLocations: 100, MaxNotesLength: 500}
+	if response.Data != expected {
+		t.Errorf("Expected %+v, got %+v", expected, response.Data)
+	}
+}
@@ -1,13 +1,16 @@
 package handlers
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"log/slog"
 	"net/http"
+	"strings"
 
 	"github.com/lafetz/weavo/internal/adapters/web/dto"
 	"github.com/lafetz/weavo/internal/adapters/web/webutils"
+	"github.com/lafetz/weavo/internal/core/domain"
 	"github.com/lafetz/weavo/internal/core/service/weather"
 )
 
@@ -47,3 +50,24 @@ func GetWeather(weatherSvc weather.ServiceApi, logger *slog.Logger) http.Handler
 		webutils.WriteJSON(w, http.StatusOK, "weather retrieved successfully", dto.GetWeatherRes(weatherData), nil)
 	}
 }
+
+// weatherLookup returns a function that gives the current weather in a city,
+// asking weatherSvc at most once per city. It is best effort: a city the
+// provider does not know, or a failing provider, just gets no weather.
+func weatherLookup(ctx context.Context, weatherSvc weather.ServiceApi, logger *slog.Logger) func(city string) *domain.Weather {
+	byCity := make(map[string]*domain.Weather)
+	return func(city string) *domain.Weather {
+		key := strings.ToLower(city)
+		if cw, ok := byCity[key]; ok {
+			return cw
+		}
+		var cw *domain.Weather
+		if data, err := weatherSvc.GetWeather(ctx, city); err == nil {
+			cw = &data
+		} else if !errors.Is(err, weather.ErrCityNotFound) {
+			logger.Warn("error on getting weather", "city", city, "error", err.Error())
+		}
+		byCity[key] = cw
+		return cw
+	}
+}
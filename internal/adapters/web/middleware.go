@@ -1,12 +1,20 @@
 package web
 
 import (
+	"bytes"
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
 	"fmt"
+	"io"
 	"net/http"
 	"strings"
 
 	"github.com/google/uuid"
+	"github.com/lafetz/weavo/internal/adapters/web/handlers"
+	"github.com/lafetz/weavo/internal/adapters/web/webutils"
+	"github.com/lafetz/weavo/internal/core/domain"
 )
 
 func (app *App) recoverPanic(next http.Handler) http.HandlerFunc {
@@ -32,7 +40,7 @@ func (app *App) recoverPanic(next http.Handler) http.HandlerFunc {
 }
 func (app *App) UserContext(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		session, err := app.store.Get(r, "user-session")
+		session, err := app.store.Get(r, webutils.SessionName)
 		if err != nil {
 			if !strings.Contains(err.Error(), "the value is not valid") {
 				app.logger.Error("error getting session", "error", err)
@@ -57,6 +65,135 @@ func (app *App) UserContext(next http.Handler) http.Handler {
 		next.ServeHTTP(w, r.WithContext(ctx))
 	})
 }
+
+// maxRequestIDLength bounds client-supplied request IDs; longer ones are
+// replaced.
+const maxRequestIDLength = 128
+
+// requestID tags every request with an ID, taken from the X-Request-ID
+// header when the client sent one, and echoes it in the response.
+func (app *App) requestID(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id := r.Header.Get("X-Request-ID")
+		if id == "" || len(id) > maxRequestIDLength {
+			id = uuid.New().String()
+		}
+		w.Header().Set("X-Request-ID", id)
+		next.ServeHTTP(w, r.WithContext(domain.ContextWithRequestID(r.Context(), id)))
+	})
+}
+
+const (
+	maxIdempotencyKeyLength = 255
+	// maxIdempotentBodySize bounds the request bodies buffered for
+	// fingerprinting; it matches the largest JSON body any route accepts.
+	// Uploads may be larger, see maxBodySize.
+	maxIdempotentBodySize = 8 << 20
+)
+
+// maxBodySize is the largest request body idempotent buffers: the largest
+// of JSON bodies and attachment uploads.
+func (app *App) maxBodySize() int64 {
+	return max(maxIdempotentBodySize, handlers.MaxUploadBytes(app.maxAttachmentBytes))
+}
+
+// idempotent makes a mutating route safe to retry. The first request with a
+// given Idempotency-Key runs normally and its response is stored; retries
+// with the same key and body get that response replayed instead of running
+// again. Keys are scoped to the user and route, so idempotent must be
+// applied inside UserContext.
+func (app *App) idempotent(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		key := r.Header.Get("Idempotency-Key")
+		if key == "" {
+			next.ServeHTTP(w, r)
+			return
+		}
+		if len(key) > maxIdempotencyKeyLength {
+			webutils.WriteJSON(w, http.StatusBadRequest, "Idempotency-Key is too long", nil, nil)
+			return
+		}
+		body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, app.maxBodySize()))
+		if err != nil {
+			var maxBytesErr *http.MaxBytesError
+			if errors.As(err, &maxBytesErr) {
+				webutils.WriteJSON(w, http.StatusRequestEntityTooLarge, "request body too large", nil, nil)
+				return
+			}
+			webutils.WriteJSON(w, http.StatusBadRequest, "Invalid input format", nil, nil)
+			return
+		}
+		r.Body = io.NopCloser(bytes.NewReader(body))
+
+		userId, _ := r.Context().Value("userId").(string)
+		scoped := strings.Join([]string{userId, r.Method, r.URL.Path, key}, " ")
+		sum := sha256.New()
+		sum.Write([]byte(r.URL.RawQuery + "\n" + r.Header.Get("Content-Type") + "\n"))
+		sum.Write(body)
+		fingerprint := hex.EncodeToString(sum.Sum(nil))
+
+		stored, fresh := app.idempotency.Begin(scoped, fingerprint)
+		if !fresh {
+			switch {
+			case stored.Fingerprint != fingerprint:
+				webutils.WriteJSON(w, http.StatusUnprocessableEntity, "Idempotency-Key was already used for a different request", nil, nil)
+			case !stored.Done:
+				webutils.WriteJSON(w, http.StatusConflict, "a request with this Idempotency-Key is still in progress", nil, nil)
+			default:
+				for name, values := range stored.Header {
+					w.Header()[name] = values
+				}
+				w.Header().Set("Idempotent-Replayed", "true")
+				w.WriteHeader(stored.Status)
+				w.Write(stored.Body)
+			}
+			return
+		}
+
+		// Until the response is stored the key stays reserved; release it
+		// if the handler fails or panics so the client can retry.
+		completed := false
+		defer func() {
+			if !completed {
+				app.idempotency.Release(scoped)
+			}
+		}()
+		rec := &recordingWriter{ResponseWriter: w, status: http.StatusOK}
+		next.ServeHTTP(rec, r)
+		if rec.status >= http.StatusInternalServerError {
+			return
+		}
+		header := w.Header().Clone()
+		// The session cookie and request ID belong to the original request.
+		header.Del("Set-Cookie")
+		header.Del("X-Request-ID")
+		app.idempotency.Complete(scoped, rec.status, header, rec.body.Bytes())
+		completed = true
+	})
+}
+
+// recordingWriter passes a response through while keeping a copy of it.
+type recordingWriter struct {
+	http.ResponseWriter
+	status      int
+	wroteHeader bool
+	body        bytes.Buffer
+}
+
+func (rw *recordingWriter) WriteHeader(status int) {
+	if !rw.wroteHeader {
+		rw.status = status
+		rw.wroteHeader = true
+	}
+	rw.ResponseWriter.WriteHeader(status)
+}
+
+func (rw *recordingWriter) Write(b []byte) (int, error) {
+	rw.wroteHeader = true
+	rw.body.Write(b)
+	return rw.ResponseWriter.Write(b)
+}
+
 func (app *App) enableCORS(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		origin := r.Header.Get("Origin")
@@ -65,8 +202,9 @@ func (app *App) enableCORS(next http.Handler) http.Handler {
 		}
 
 		w.Header().Set("Vary", "Origin")
-		w.Header().Set("Access-Control-Allow-Methods", "GET, POST, PUT, DELETE, OPTIONS")
-		w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Content-Length, Accept-Encoding, X-CSRF-Token, Authorization, accept, origin, Cache-Control, X-Requested-With")
+		w.Header().Set("Access-Control-Allow-Methods", "GET, POST, PUT, PATCH, DELETE, OPTIONS")
+		w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Content-Length, Accept-Encoding, X-CSRF-Token, Authorization, accept, origin, Cache-Control, X-Requested-With, If-Match, X-Request-ID, Idempotency-Key")
+		w.Header().Set("Access-Control-Expose-Headers", "ETag, X-Request-ID, Idempotent-Replayed")
 		w.Header().Set("Access-Control-Max-Age", "3600")
 		w.Header().Set("Access-Control-Allow-Credentials", "true")
 
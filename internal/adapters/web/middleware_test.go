@@ -1,14 +1,21 @@
 package web
 
 import (
+	"context"
+	"fmt"
+	"io"
 	"log/slog"
 	"net/http"
 	"net/http/httptest"
 	"os"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/google/uuid"
 	"github.com/gorilla/sessions"
+	"github.com/lafetz/weavo/internal/adapters/web/webutils"
+	"github.com/lafetz/weavo/internal/core/domain"
 )
 
 func TestRecoverPanic(t *testing.T) {
@@ -114,12 +121,15 @@ func TestEnableCORS(t *testing.T) {
 		if resp.Header.Get("Vary") != "Origin" {
 			t.Errorf("Expected Vary header to be set to 'Origin'")
 		}
-		if resp.Header.Get("Access-Control-Allow-Methods") != "GET, POST, PUT, DELETE, OPTIONS" {
+		if resp.Header.Get("Access-Control-Allow-Methods") != "GET, POST, PUT, PATCH, DELETE, OPTIONS" {
 			t.Errorf("Expected Access-Control-Allow-Methods header to be set")
 		}
-		if resp.Header.Get("Access-Control-Allow-Headers") != "Content-Type, Content-Length, Accept-Encoding, X-CSRF-Token, Authorization, accept, origin, Cache-Control, X-Requested-With" {
+		if resp.Header.Get("Access-Control-Allow-Headers") != "Content-Type, Content-Length, Accept-Encoding, X-CSRF-Token, Authorization, accept, origin, Cache-Control, X-Requested-With, If-Match, X-Request-ID, Idempotency-Key" {
 			t.Errorf("Expected Access-Control-Allow-Headers header to be set")
 		}
+		if resp.Header.Get("Access-Control-Expose-Headers") != "ETag, X-Request-ID, Idempotent-Replayed" {
+			t.Errorf("Expected Access-Control-Expose-Headers header to expose ETag, X-Request-ID and Idempotent-Replayed")
+		}
 		if resp.Header.Get("Access-Control-Max-Age") != "3600" {
 			t.Errorf("Expected Access-Control-Max-Age header to be set")
 		}
@@ -140,3 +150,112 @@ func TestEnableCORS(t *testing.T) {
 func contains(s, substr string) bool {
 	return len(s) >= len(substr) && s[:len(substr)] == substr
 }
+
+func TestRequestID(t *testing.T) {
+	app := &App{}
+	var seen string
+	handler := app.requestID(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		seen = domain.RequestIDFromContext(r.Context())
+	}))
+
+	t.Run("generated", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/", nil))
+		if _, err := uuid.Parse(seen); err != nil {
+			t.Errorf("Expected a generated request ID, got %q", seen)
+		}
+		if got := w.Header().Get("X-Request-ID"); got != seen {
+			t.Errorf("Expected the response to carry request ID %q, got %q", seen, got)
+		}
+	})
+
+	t.Run("from client", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		req.Header.Set("X-Request-ID", "abc-123")
+		handler.ServeHTTP(httptest.NewRecorder(), req)
+		if seen != "abc-123" {
+			t.Errorf("Expected the client's request ID, got %q", seen)
+		}
+	})
+}
+
+func TestIdempotent(t *testing.T) {
+	app := &App{
+		logger:      slog.New(slog.NewTextHandler(os.Stdout, nil)),
+		idempotency: webutils.NewIdempotencyStore(time.Hour),
+	}
+	calls := 0
+	status := http.StatusCreated
+	handler := app.idempotent(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		body, _ := io.ReadAll(r.Body)
+		w.Header().Set("ETag", `"1"`)
+		w.WriteHeader(status)
+		fmt.Fprintf(w, "call %d: %s", calls, body)
+	}))
+
+	send := func(user, key, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/api/v1/locations", strings.NewReader(body))
+		req = req.WithContext(context.WithValue(req.Context(), "userId", user))
+		if key != "" {
+			req.Header.Set("Idempotency-Key", key)
+		}
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, req)
+		return w
+	}
+
+	first := send("u1", "key-1", `{"a": 1}`)
+	if first.Code != http.StatusCreated || first.Body.String() != `call 1: {"a": 1}` {
+		t.Fatalf("Expected the first request to run, got %d %s", first.Code, first.Body.String())
+	}
+
+	t.Run("retry is replayed", func(t *testing.T) {
+		w := send("u1", "key-1", `{"a": 1}`)
+		if calls != 1 {
+			t.Fatalf("Expected the handler to run once, ran %d times", calls)
+		}
+		if w.Code != http.StatusCreated || w.Body.String() != first.Body.String() {
+			t.Errorf("Expected the first response, got %d %s", w.Code, w.Body.String())
+		}
+		if w.Header().Get("ETag") != `"1"` || w.Header().Get("Idempotent-Replayed") != "true" {
+			t.Errorf("Expected the stored headers and the replay marker, got %v", w.Header())
+		}
+	})
+
+	t.Run("different body", func(t *testing.T) {
+		if w := send("u1", "key-1", `{"a": 2}`); w.Code != http.StatusUnprocessableEntity {
+			t.Errorf("Expected status code %d, got %d", http.StatusUnprocessableEntity, w.Code)
+		}
+	})
+
+	t.Run("keys are per user", func(t *testing.T) {
+		if w := send("u2", "key-1", `{"a": 1}`); w.Code != http.StatusCreated || calls != 2 {
+			t.Errorf("Expected another user's request to run, got %d after %d calls", w.Code, calls)
+		}
+	})
+
+	t.Run("no key", func(t *testing.T) {
+		before := calls
+		send("u1", "", `{"a": 1}`)
+		send("u1", "", `{"a": 1}`)
+		if calls != before+2 {
+			t.Errorf("Expected requests without a key to always run")
+		}
+	})
+
+	t.Run("server errors can be retried", func(t *testing.T) {
+		status = http.StatusInternalServerError
+		send("u1", "key-2", `{}`)
+		status = http.StatusCreated
+		if w := send("u1", "key-2", `{}`); w.Code != http.StatusCreated || w.Header().Get("Idempotent-Replayed") != "" {
+			t.Errorf("Expected the retry to run again, got %d", w.Code)
+		}
+	})
+
+	t.Run("key too long", func(t *testing.T) {
+		if w := send("u1", strings.Repeat("k", 256), `{}`); w.Code != http.StatusBadRequest {
+			t.Errorf("Expected status code %d, got %d", http.StatusBadRequest, w.Code)
+		}
+	})
+}
@@ -9,11 +9,48 @@ import (
 func (a *App) initAppRoutes() {
 
 	a.Router.HandleFunc("/api/swagger/", httpSwagger.WrapHandler)
-	a.Router.HandleFunc("GET /api/v1/locations", a.recoverPanic(a.UserContext(handlers.GetAllLocations(a.locationSvc, a.logger))))
+	a.Router.HandleFunc("GET /api/v1/locations", a.recoverPanic(a.UserContext(handlers.GetAllLocations(a.locationSvc, a.logger, a.validator))))
+	a.Router.HandleFunc("GET /api/v1/locations/nearby", a.recoverPanic(a.UserContext(handlers.GetNearbyLocations(a.locationSvc, a.logger, a.validator))))
+	a.Router.HandleFunc("GET /api/v1/locations/duplicates", a.recoverPanic(a.UserContext(handlers.GetDuplicateLocations(a.locationSvc, a.logger))))
+	a.Router.HandleFunc("GET /api/v1/locations/export", a.recoverPanic(a.UserContext(handlers.ExportLocations(a.locationSvc, a.weatherSvc, a.logger, a.validator))))
+	a.Router.HandleFunc("GET /api/v1/locations/trash", a.recoverPanic(a.UserContext(handlers.GetTrash(a.locationSvc, a.logger))))
+	a.Router.HandleFunc("GET /api/v1/locations/{id}/history", a.recoverPanic(a.UserContext(handlers.GetLocationHistory(a.locationSvc, a.logger))))
+	a.Router.HandleFunc("GET /api/v1/locations/{id}/checkins", a.recoverPanic(a.UserContext(handlers.GetCheckIns(a.locationSvc, a.logger))))
+	a.Router.HandleFunc("GET /api/v1/locations/{id}/attachments", a.recoverPanic(a.UserContext(handlers.GetAttachments(a.locationSvc, a.logger))))
+	a.Router.HandleFunc("GET /api/v1/locations/{id}/attachments/{attachmentId}", a.recoverPanic(a.UserContext(handlers.DownloadAttachment(a.locationSvc, a.logger))))
 	a.Router.HandleFunc("GET /api/v1/locations/{id}", a.recoverPanic(a.UserContext(handlers.GetLocation(a.locationSvc, a.logger))))
-	a.Router.HandleFunc("POST /api/v1/locations", a.recoverPanic(a.UserContext(handlers.CreateLocation(a.locationSvc, a.logger, a.validator))))
+	a.Router.HandleFunc("POST /api/v1/locations", a.recoverPanic(a.UserContext(a.idempotent(handlers.CreateLocation(a.locationSvc, a.logger, a.validator)))))
+	a.Router.HandleFunc("POST /api/v1/locations:batch", a.recoverPanic(a.UserContext(a.idempotent(handlers.BatchLocations(a.locationSvc, a.logger, a.validator)))))
+	a.Router.HandleFunc("POST /api/v1/locations/import", a.recoverPanic(a.UserContext(a.idempotent(handlers.ImportLocations(a.locationSvc, a.logger, a.validator)))))
+	a.Router.HandleFunc("POST /api/v1/locations/{id}/attachments", a.recoverPanic(a.UserContext(a.idempotent(handlers.UploadAttachment(a.locationSvc, a.logger, a.maxAttachmentBytes)))))
+	a.Router.HandleFunc("POST /api/v1/locations/{id}/checkins", a.recoverPanic(a.UserContext(a.idempotent(handlers.CreateCheckIn(a.locationSvc, a.logger, a.validator)))))
+	a.Router.HandleFunc("POST /api/v1/locations/reorder", a.recoverPanic(a.UserContext(handlers.ReorderLocations(a.locationSvc, a.logger, a.validator))))
+	a.Router.HandleFunc("POST /api/v1/locations/{id}/restore", a.recoverPanic(a.UserContext(handlers.RestoreLocation(a.locationSvc, a.logger))))
+	a.Router.HandleFunc("POST /api/v1/locations/{id}/history/{version}/revert", a.recoverPanic(a.UserContext(handlers.RevertLocation(a.locationSvc, a.logger))))
 	a.Router.HandleFunc("PUT /api/v1/locations/{id}", a.recoverPanic(a.UserContext(handlers.UpdateLocation(a.locationSvc, a.logger, a.validator))))
+	a.Router.HandleFunc("PATCH /api/v1/locations/{id}", a.recoverPanic(a.UserContext(handlers.PatchLocation(a.locationSvc, a.logger, a.validator))))
 	a.Router.HandleFunc("DELETE /api/v1/locations/{id}", a.recoverPanic(a.UserContext(handlers.DeleteLocation(a.locationSvc, a.logger))))
+	a.Router.HandleFunc("DELETE /api/v1/locations/{id}/attachments/{attachmentId}", a.recoverPanic(a.UserContext(handlers.DeleteAttachment(a.locationSvc, a.logger))))
+	a.Router.HandleFunc("GET /api/v1/collections", a.recoverPanic(a.UserContext(handlers.GetCollections(a.locationSvc, a.logger))))
+	a.Router.HandleFunc("GET /api/v1/collections/{id}", a.recoverPanic(a.UserContext(handlers.GetCollection(a.locationSvc, a.logger))))
+	a.Router.HandleFunc("POST /api/v1/collections", a.recoverPanic(a.UserContext(a.idempotent(handlers.CreateCollection(a.locationSvc, a.logger, a.validator)))))
+	a.Router.HandleFunc("PUT /api/v1/collections/{id}", a.recoverPanic(a.UserContext(handlers.UpdateCollection(a.locationSvc, a.logger, a.validator))))
+	a.Router.HandleFunc("DELETE /api/v1/collections/{id}", a.recoverPanic(a.UserContext(handlers.DeleteCollection(a.locationSvc, a.logger))))
+	a.Router.HandleFunc("PUT /api/v1/collections/{id}/locations/{locationId}", a.recoverPanic(a.UserContext(handlers.AddToCollection(a.locationSvc, a.logger))))
+	a.Router.HandleFunc("DELETE /api/v1/collections/{id}/locations/{locationId}", a.recoverPanic(a.UserContext(handlers.RemoveFromCollection(a.locationSvc, a.logger))))
+	a.Router.HandleFunc("GET /api/v1/shares", a.recoverPanic(a.UserContext(handlers.GetShares(a.locationSvc, a.logger))))
+	a.Router.HandleFunc("POST /api/v1/shares", a.recoverPanic(a.UserContext(a.idempotent(handlers.CreateShare(a.locationSvc, a.logger, a.validator)))))
+	a.Router.HandleFunc("DELETE /api/v1/shares/{token}", a.recoverPanic(a.UserContext(handlers.RevokeShare(a.locationSvc, a.logger))))
+	// Share links are opened by people without a session, so the token is
+	// the only identity and UserContext is left out on purpose.
+	a.Router.HandleFunc("GET /api/v1/shared/{token}", a.recoverPanic(handlers.GetShared(a.locationSvc, a.weatherSvc, a.logger)))
+	a.Router.HandleFunc("GET /api/v1/checkins", a.recoverPanic(a.UserContext(handlers.GetTimeline(a.locationSvc, a.logger, a.validator))))
+	// Logging in replaces the session rather than using it, so these routes
+	// run without UserContext.
+	a.Router.HandleFunc("POST /api/v1/auth/register", a.recoverPanic(handlers.Register(a.userSvc, a.locationSvc, a.store, a.logger, a.validator)))
+	a.Router.HandleFunc("POST /api/v1/auth/login", a.recoverPanic(handlers.Login(a.userSvc, a.locationSvc, a.store, a.logger, a.validator)))
+	a.Router.HandleFunc("POST /api/v1/auth/logout", a.recoverPanic(handlers.Logout(a.store, a.logger)))
+	a.Router.HandleFunc("GET /api/v1/me/usage", a.recoverPanic(a.UserContext(handlers.GetUsage(a.locationSvc, a.logger))))
 	a.Router.HandleFunc("GET /api/v1/weather", a.recoverPanic(a.UserContext(handlers.GetWeather(a.weatherSvc, a.logger))))
 
 }
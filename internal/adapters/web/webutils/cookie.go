@@ -4,23 +4,36 @@ import (
 	"net/http"
 	"time"
 
-	"github.com/gorilla/securecookie"
 	"github.com/gorilla/sessions"
 )
 
-const (
-	keyLength = 16
-)
+// SessionName is the cookie that carries the user ID, whether of an
+// anonymous visitor or of a logged-in account.
+const SessionName = "user-session"
 
-func CookieStore(dataRetention time.Duration) *sessions.CookieStore {
-	cookieStore := sessions.NewCookieStore(securecookie.GenerateRandomKey(keyLength), securecookie.GenerateRandomKey(keyLength))
+// CookieStore returns a session store whose cookies last dataRetention.
+// Cookies are signed and encrypted with the first of keys and accepted with
+// any of them, so a new key can be put first while old ones are kept until
+// the cookies they signed have expired. Without keys a random pair is used,
+// and sessions do not survive a restart. secure limits the cookie to HTTPS.
+func CookieStore(dataRetention time.Duration, secure bool, keys ...SessionKey) *sessions.CookieStore {
+	if len(keys) == 0 {
+		keys = []SessionKey{NewSessionKey()}
+	}
+	pairs := make([][]byte, 0, 2*len(keys))
+	for _, key := range keys {
+		pairs = append(pairs, key.HashKey, key.EncryptionKey)
+	}
+	cookieStore := sessions.NewCookieStore(pairs...)
 
 	cookieStore.Options = &sessions.Options{
 		Path:     "/",
-		MaxAge:   int(dataRetention.Seconds()),
 		HttpOnly: true,
-		Secure:   false,
+		Secure:   secure,
 		SameSite: http.SameSiteLaxMode,
 	}
+	// MaxAge also bounds the age of the cookies the keys accept, which
+	// otherwise defaults to 30 days.
+	cookieStore.MaxAge(int(dataRetention.Seconds()))
 	return cookieStore
 }
@@ -1,14 +1,19 @@
 package webutils
 
 import (
+	"errors"
 	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
 	"testing"
 	"time"
 )
 
 func TestCookieStore(t *testing.T) {
 	dataRetention := 24 * time.Hour
-	store := CookieStore(dataRetention)
+	store := CookieStore(dataRetention, false)
 
 	if store == nil {
 		t.Fatalf("expected non-nil store, got nil")
@@ -39,3 +44,101 @@ func TestCookieStore(t *testing.T) {
 		t.Errorf("expected SameSite %v, got %v", http.SameSiteLaxMode, options.SameSite)
 	}
 }
+
+func TestCookieStoreKeyRotation(t *testing.T) {
+	oldKey, newKey := NewSessionKey(), NewSessionKey()
+	issue := func(keys ...SessionKey) []*http.Cookie {
+		store := CookieStore(time.Hour, true, keys...)
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		w := httptest.NewRecorder()
+		session, _ := store.Get(req, SessionName)
+		session.Values["userId"] = "user-1"
+		if err := session.Save(req, w); err != nil {
+			t.Fatalf("expected no error saving, got %v", err)
+		}
+		return w.Result().Cookies()
+	}
+	read := func(cookies []*http.Cookie, keys ...SessionKey) string {
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		for _, c := range cookies {
+			req.AddCookie(c)
+		}
+		session, _ := CookieStore(time.Hour, true, keys...).Get(req, SessionName)
+		userID, _ := session.Values["userId"].(string)
+		return userID
+	}
+
+	cookies := issue(oldKey)
+	if !cookies[0].Secure {
+		t.Errorf("expected a Secure cookie")
+	}
+	if got := read(cookies, newKey, oldKey); got != "user-1" {
+		t.Errorf("expected a cookie signed with the old key to still be accepted, got %q", got)
+	}
+	if got := read(issue(newKey, oldKey), newKey); got != "user-1" {
+		t.Errorf("expected new cookies to be signed with the first key, got %q", got)
+	}
+	if got := read(cookies, newKey); got != "" {
+		t.Errorf("expected a cookie signed with a retired key to be rejected, got %q", got)
+	}
+}
+
+func TestParseSessionKeys(t *testing.T) {
+	first, second := NewSessionKey(), NewSessionKey()
+
+	keys, err := ParseSessionKeys(first.String() + ", " + second.String())
+	if err != nil || len(keys) != 2 || keys[1].String() != second.String() {
+		t.Fatalf("expected both keys in order, got %d keys (%v)", len(keys), err)
+	}
+
+	path := filepath.Join(t.TempDir(), "session.keys")
+	file := "# newest first\n" + first.String() + "\n\n" + second.String() + "\n"
+	if err := os.WriteFile(path, []byte(file), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	keys, err = ReadSessionKeys(path)
+	if err != nil || len(keys) != 2 || keys[0].String() != first.String() {
+		t.Fatalf("expected both keys from the file, got %d keys (%v)", len(keys), err)
+	}
+
+	for _, invalid := range []string{"no-separator", "c2hvcnQ=:" + strings.Split(first.String(), ":")[1], strings.Split(first.String(), ":")[0] + ":c2hvcnQ=", "!!:!!"} {
+		if _, err := ParseSessionKeys(invalid); err == nil {
+			t.Errorf("expected %q to be rejected", invalid)
+		}
+	}
+}
+
+func TestLoadSessionKeys(t *testing.T) {
+	key := NewSessionKey()
+	dir := t.TempDir()
+	commentsOnly := filepath.Join(dir, "comments.keys")
+	os.WriteFile(commentsOnly, []byte("# rotated out\n\n"), 0o600)
+	withKey := filepath.Join(dir, "session.keys")
+	os.WriteFile(withKey, []byte(key.String()+"\n"), 0o600)
+
+	tests := []struct {
+		name     string
+		env      string
+		path     string
+		required bool
+		wantKeys int
+		wantErr  error
+	}{
+		{"from env", key.String(), "", true, 1, nil},
+		{"file wins over env", "", withKey, true, 1, nil},
+		{"empty env required", " , ", "", true, 0, ErrNoSessionKeys},
+		{"comments-only file required", "", commentsOnly, true, 0, ErrNoSessionKeys},
+		{"comments-only file optional", "", commentsOnly, false, 0, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			keys, err := LoadSessionKeys(tt.env, tt.path, tt.required)
+			if !errors.Is(err, tt.wantErr) || len(keys) != tt.wantKeys {
+				t.Errorf("expected %d keys and error %v, got %d keys and %v", tt.wantKeys, tt.wantErr, len(keys), err)
+			}
+		})
+	}
+	if _, err := LoadSessionKeys("", filepath.Join(dir, "missing.keys"), true); err == nil {
+		t.Errorf("expected an error for a missing key file")
+	}
+}
@@ -0,0 +1,38 @@
+package webutils
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+var ErrInvalidIfMatch = errors.New("invalid If-Match header")
+
+// FormatETag renders a resource version as a strong entity tag.
+func FormatETag(version int64) string {
+	return `"` + strconv.FormatInt(version, 10) + `"`
+}
+
+// SetETag writes the ETag header for a resource version.
+func SetETag(w http.ResponseWriter, version int64) {
+	w.Header().Set("ETag", FormatETag(version))
+}
+
+// ParseIfMatch returns the version a request is conditional on. It returns
+// 0 when the header is absent or "*", meaning any current version matches.
+// Only a single strong tag produced by FormatETag is accepted.
+func ParseIfMatch(r *http.Request) (int64, error) {
+	val := strings.TrimSpace(r.Header.Get("If-Match"))
+	if val == "" || val == "*" {
+		return 0, nil
+	}
+	if len(val) < 3 || val[0] != '"' || val[len(val)-1] != '"' {
+		return 0, ErrInvalidIfMatch
+	}
+	version, err := strconv.ParseInt(val[1:len(val)-1], 10, 64)
+	if err != nil || version < 1 {
+		return 0, ErrInvalidIfMatch
+	}
+	return version, nil
+}
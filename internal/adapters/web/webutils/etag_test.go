@@ -0,0 +1,56 @@
+package webutils
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestParseIfMatch(t *testing.T) {
+	tests := []struct {
+		name     string
+		header   string
+		expected int64
+		wantErr  bool
+	}{
+		{name: "absent", header: "", expected: 0},
+		{name: "wildcard", header: "*", expected: 0},
+		{name: "strong tag", header: `"7"`, expected: 7},
+		{name: "weak tag", header: `W/"7"`, wantErr: true},
+		{name: "unquoted", header: "7", wantErr: true},
+		{name: "list", header: `"1", "2"`, wantErr: true},
+		{name: "not a number", header: `"abc"`, wantErr: true},
+		{name: "zero", header: `"0"`, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodPut, "/", nil)
+			if tt.header != "" {
+				r.Header.Set("If-Match", tt.header)
+			}
+			version, err := ParseIfMatch(r)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("expected error for %q", tt.header)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if version != tt.expected {
+				t.Errorf("expected %d, got %d", tt.expected, version)
+			}
+		})
+	}
+}
+
+func TestFormatETagRoundTrip(t *testing.T) {
+	r := httptest.NewRequest(http.MethodPut, "/", nil)
+	r.Header.Set("If-Match", FormatETag(42))
+	version, err := ParseIfMatch(r)
+	if err != nil || version != 42 {
+		t.Fatalf("expected 42, got %d (%v)", version, err)
+	}
+}
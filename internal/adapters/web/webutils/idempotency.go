@@ -0,0 +1,89 @@
+package webutils
+
+import (
+	"net/http"
+	"sync"
+	"time"
+)
+
+// IdempotencyRecord is what is kept for an Idempotency-Key: a fingerprint of
+// the request that first used it and, once that request has finished, its
+// response.
+type IdempotencyRecord struct {
+	Fingerprint string
+	Done        bool
+	Status      int
+	Header      http.Header
+	Body        []byte
+	expiresAt   time.Time
+}
+
+// IdempotencyStore remembers idempotency keys for a fixed window. Keys are
+// opaque to it; callers scope them to a user and route.
+type IdempotencyStore struct {
+	mu        sync.Mutex
+	window    time.Duration
+	records   map[string]IdempotencyRecord
+	nextSweep time.Time
+	now       func() time.Time
+}
+
+func NewIdempotencyStore(window time.Duration) *IdempotencyStore {
+	return &IdempotencyStore{
+		window:  window,
+		records: make(map[string]IdempotencyRecord),
+		now:     time.Now,
+	}
+}
+
+// Begin reserves key for a request with the given fingerprint. If the key is
+// already known it returns the existing record and false, and the caller
+// must not run the request; otherwise it returns true and the caller must
+// later Complete or Release the key.
+func (s *IdempotencyStore) Begin(key, fingerprint string) (IdempotencyRecord, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	now := s.now()
+	s.sweep(now)
+	if rec, ok := s.records[key]; ok && now.Before(rec.expiresAt) {
+		return rec, false
+	}
+	s.records[key] = IdempotencyRecord{Fingerprint: fingerprint, expiresAt: now.Add(s.window)}
+	return IdempotencyRecord{}, true
+}
+
+// Complete stores the response of the request that reserved key.
+func (s *IdempotencyStore) Complete(key string, status int, header http.Header, body []byte) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	rec, ok := s.records[key]
+	if !ok {
+		return
+	}
+	rec.Done = true
+	rec.Status = status
+	rec.Header = header
+	rec.Body = body
+	s.records[key] = rec
+}
+
+// Release forgets key so that the request can be retried, for requests that
+// failed without a result worth replaying.
+func (s *IdempotencyStore) Release(key string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.records, key)
+}
+
+// sweep drops expired records, at most once a minute.
+func (s *IdempotencyStore) sweep(now time.Time) {
+	if now.Before(s.nextSweep) {
+		return
+	}
+	for key, rec := range s.records {
+		if !now.Before(rec.expiresAt) {
+			delete(s.records, key)
+		}
+	}
+	s.nextSweep = now.Add(time.Minute)
+}
@@ -0,0 +1,37 @@
+package webutils
+
+import (
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestIdempotencyStore(t *testing.T) {
+	now := time.Now()
+	store := NewIdempotencyStore(time.Hour)
+	store.now = func() time.Time { return now }
+
+	if _, fresh := store.Begin("k", "fp"); !fresh {
+		t.Fatalf("Expected a new key to be reserved")
+	}
+	rec, fresh := store.Begin("k", "fp")
+	if fresh || rec.Done {
+		t.Fatalf("Expected the key to be in progress, got %+v", rec)
+	}
+
+	store.Complete("k", http.StatusCreated, http.Header{"Etag": {`"1"`}}, []byte("body"))
+	rec, _ = store.Begin("k", "other")
+	if !rec.Done || rec.Status != http.StatusCreated || string(rec.Body) != "body" || rec.Fingerprint != "fp" {
+		t.Fatalf("Expected the stored response of the first request, got %+v", rec)
+	}
+
+	now = now.Add(time.Hour)
+	if _, fresh := store.Begin("k", "fp"); !fresh {
+		t.Errorf("Expected the key to be reusable after the window")
+	}
+
+	store.Release("k")
+	if _, fresh := store.Begin("k", "fp"); !fresh {
+		t.Errorf("Expected a released key to be reusable")
+	}
+}
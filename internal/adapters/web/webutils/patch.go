@@ -0,0 +1,39 @@
+package webutils
+
+import (
+	"encoding/json"
+)
+
+// MergePatch applies an RFC 7396 JSON merge patch to doc and returns the
+// resulting document. Members set to null in the patch are removed, objects
+// are merged recursively and any other value replaces the target outright.
+func MergePatch(doc, patch []byte) ([]byte, error) {
+	var patchValue interface{}
+	if err := json.Unmarshal(patch, &patchValue); err != nil {
+		return nil, err
+	}
+	var docValue interface{}
+	if err := json.Unmarshal(doc, &docValue); err != nil {
+		return nil, err
+	}
+	return json.Marshal(mergeValue(docValue, patchValue))
+}
+
+func mergeValue(target, patch interface{}) interface{} {
+	patchObj, ok := patch.(map[string]interface{})
+	if !ok {
+		return patch
+	}
+	targetObj, ok := target.(map[string]interface{})
+	if !ok {
+		targetObj = make(map[string]interface{})
+	}
+	for key, value := range patchObj {
+		if value == nil {
+			delete(targetObj, key)
+			continue
+		}
+		targetObj[key] = mergeValue(targetObj[key], value)
+	}
+	return targetObj
+}
@@ -24,3 +24,31 @@ func GetQueryString(r *http.Request, key, defaultValue string) string {
 	}
 	return val
 }
+
+// GetQueryFloat returns the parsed value of key, or nil when it is missing
+// or not a number.
+func GetQueryFloat(r *http.Request, key string) *float64 {
+	val := r.URL.Query().Get(key)
+	if val == "" {
+		return nil
+	}
+	parsed, err := strconv.ParseFloat(val, 64)
+	if err != nil {
+		return nil
+	}
+	return &parsed
+}
+
+// GetQueryBool returns the parsed value of key, or defaultValue when it is
+// missing or not a boolean.
+func GetQueryBool(r *http.Request, key string, defaultValue bool) bool {
+	val := r.URL.Query().Get(key)
+	if val == "" {
+		return defaultValue
+	}
+	parsed, err := strconv.ParseBool(val)
+	if err != nil {
+		return defaultValue
+	}
+	return parsed
+}
@@ -110,3 +110,27 @@ func TestGetQueryString(t *testing.T) {
 		})
 	}
 }
+
+func TestGetQueryBool(t *testing.T) {
+	tests := []struct {
+		name         string
+		query        string
+		defaultValue bool
+		expected     bool
+	}{
+		{"true", "dry_run=true", false, true},
+		{"numeric", "dry_run=1", false, true},
+		{"false", "dry_run=false", true, false},
+		{"missing", "", true, true},
+		{"not a boolean", "dry_run=maybe", false, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := &http.Request{URL: &url.URL{RawQuery: tt.query}}
+			if result := GetQueryBool(req, "dry_run", tt.defaultValue); result != tt.expected {
+				t.Errorf("expected %v, got %v", tt.expected, result)
+			}
+		})
+	}
+}
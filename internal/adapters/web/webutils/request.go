@@ -1,6 +1,7 @@
 package webutils
 
 import (
+	"bytes"
 	"encoding/json"
 	"errors"
 	"fmt"
@@ -16,7 +17,17 @@ var (
 func ReadJSON(w http.ResponseWriter, r *http.Request, dst interface{}) error {
 	maxBytes := 1_048_576
 	r.Body = http.MaxBytesReader(w, r.Body, int64(maxBytes))
-	dec := json.NewDecoder(r.Body)
+	return decodeJSON(r.Body, dst, maxBytes)
+}
+
+// UnmarshalStrict decodes data into dst with the same rules and error
+// messages as ReadJSON.
+func UnmarshalStrict(data []byte, dst interface{}) error {
+	return decodeJSON(bytes.NewReader(data), dst, len(data))
+}
+
+func decodeJSON(body io.Reader, dst interface{}, maxBytes int) error {
+	dec := json.NewDecoder(body)
 	dec.DisallowUnknownFields()
 	err := dec.Decode(dst)
 	if err != nil {
@@ -0,0 +1,120 @@
+package webutils
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+)
+
+const (
+	hashKeyLength       = 64
+	encryptionKeyLength = 32
+	// minHashKeyLength is the shortest hash key accepted, as recommended
+	// for HMAC-SHA256.
+	minHashKeyLength = 32
+)
+
+var ErrNoSessionKeys = errors.New("no session keys configured")
+
+var ErrInvalidSessionKey = errors.New("a session key must be a base64 hash key of at least 32 bytes and a base64 encryption key of 16, 24 or 32 bytes, separated by ':'")
+
+// SessionKey is a pair of keys for session cookies: HashKey authenticates
+// them and EncryptionKey encrypts them with AES.
+type SessionKey struct {
+	HashKey       []byte
+	EncryptionKey []byte
+}
+
+// NewSessionKey returns a random key pair.
+func NewSessionKey() SessionKey {
+	key := SessionKey{
+		HashKey:       make([]byte, hashKeyLength),
+		EncryptionKey: make([]byte, encryptionKeyLength),
+	}
+	rand.Read(key.HashKey)
+	rand.Read(key.EncryptionKey)
+	return key
+}
+
+// String encodes k in the form ParseSessionKeys reads.
+func (k SessionKey) String() string {
+	return base64.StdEncoding.EncodeToString(k.HashKey) + ":" + base64.StdEncoding.EncodeToString(k.EncryptionKey)
+}
+
+// ParseSessionKeys reads key pairs separated by commas or newlines, newest
+// first. Blank lines and lines starting with # are skipped, so the same
+// format serves for an environment variable and a key file.
+func ParseSessionKeys(s string) ([]SessionKey, error) {
+	var keys []SessionKey
+	for _, line := range strings.Split(s, "\n") {
+		if line = strings.TrimSpace(line); strings.HasPrefix(line, "#") {
+			continue
+		}
+		for _, field := range strings.Split(line, ",") {
+			if field = strings.TrimSpace(field); field == "" {
+				continue
+			}
+			key, err := parseSessionKey(field)
+			if err != nil {
+				return nil, fmt.Errorf("session key %d: %w", len(keys)+1, err)
+			}
+			keys = append(keys, key)
+		}
+	}
+	return keys, nil
+}
+
+// ReadSessionKeys reads key pairs from the file at path, in the format of
+// ParseSessionKeys.
+func ReadSessionKeys(path string) ([]SessionKey, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return ParseSessionKeys(string(data))
+}
+
+// LoadSessionKeys returns the keys in the file at path, or when path is
+// empty those in s. When required is set, finding no keys at all, as with an
+// empty file or one holding only comments, is an error rather than a reason
+// to fall back to random keys.
+func LoadSessionKeys(s, path string, required bool) ([]SessionKey, error) {
+	var keys []SessionKey
+	var err error
+	if path != "" {
+		keys, err = ReadSessionKeys(path)
+	} else {
+		keys, err = ParseSessionKeys(s)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if required && len(keys) == 0 {
+		return nil, ErrNoSessionKeys
+	}
+	return keys, nil
+}
+
+func parseSessionKey(s string) (SessionKey, error) {
+	hashStr, encryptionStr, found := strings.Cut(s, ":")
+	if !found {
+		return SessionKey{}, ErrInvalidSessionKey
+	}
+	hashKey, err := base64.StdEncoding.DecodeString(hashStr)
+	if err != nil || len(hashKey) < minHashKeyLength {
+		return SessionKey{}, ErrInvalidSessionKey
+	}
+	encryptionKey, err := base64.StdEncoding.DecodeString(encryptionStr)
+	if err != nil {
+		return SessionKey{}, ErrInvalidSessionKey
+	}
+	switch len(encryptionKey) {
+	case 16, 24, 32:
+	default:
+		return SessionKey{}, ErrInvalidSessionKey
+	}
+	return SessionKey{HashKey: hashKey, EncryptionKey: encryptionKey}, nil
+}
@@ -36,6 +36,17 @@ func (v *CustomValidator) ValidateAndRespond(w http.ResponseWriter, input interf
 	return false
 }
 
+// Validate returns the validation errors of input keyed by field, or nil
+// when it is valid. It is for inputs that are reported on without failing
+// the whole request, like the rows of an import.
+func (v *CustomValidator) Validate(input interface{}) map[string]string {
+	err := v.validate.Struct(input)
+	if validationErrors, ok := err.(validator.ValidationErrors); ok {
+		return ValidateModel(validationErrors)
+	}
+	return nil
+}
+
 type ValidationErrorResponse struct {
 	StatusCode int         `json:"statusCode"`
 	Errors     interface{} `json:"errors"`
@@ -53,7 +64,7 @@ func ValidateModel(err validator.ValidationErrors) map[string]string {
 
 func errorMsgs(tag string, value string) string {
 	switch tag {
-	case "required":
+	case "required", "required_unless":
 		return "This field is required"
 	case "numeric":
 		return "must be numeric " + value
@@ -61,8 +72,18 @@ func errorMsgs(tag string, value string) string {
 		return "can not be greater than " + value
 	case "gte":
 		return "can not be less than " + value
+	case "min":
+		return "must be at least " + value
+	case "max":
+		return "must be at most " + value
+	case "gt":
+		return "must be greater than " + value
 	case "len":
 		return "length should be equal to " + value
+	case "oneof":
+		return "must be one of " + value
+	case "datetime":
+		return "must be a date time in the format " + value
 
 	}
 	return ""
@@ -5,14 +5,42 @@ import (
 	"log/slog"
 	"os"
 	"strconv"
+	"strings"
+	"time"
+
+	"github.com/lafetz/weavo/internal/core/domain"
 )
 
 var (
 	ErrOpenKeyNotSet = fmt.Errorf("OPEN_KEY not set")
 	ErrOpenURLNotSet = fmt.Errorf("OPEN_URL not set")
+	// ErrSessionKeysNotSet is returned in production, where random session
+	// keys would log everyone out on every restart and differ between
+	// replicas.
+	ErrSessionKeysNotSet = fmt.Errorf("SESSION_KEYS or SESSION_KEYS_FILE must be set in production")
+	// ErrEncryptionKeysNotSet is returned when snapshots are enabled, as
+	// they must not hold notes in plaintext.
+	ErrEncryptionKeysNotSet = fmt.Errorf("ENCRYPTION_KEYS or ENCRYPTION_KEYS_FILE must be set with SNAPSHOT_DIR")
 )
 
-const defaultPort = 8080
+const (
+	defaultPort            = 8080
+	defaultPurgeWindow     = 7 * 24 * time.Hour
+	defaultRetentionPeriod = 24 * time.Hour
+	// defaultIdempotencyWindow is how long Idempotency-Key responses are
+	// kept for replay.
+	defaultIdempotencyWindow = 24 * time.Hour
+	// defaultDuplicateRadiusKm is how close two locations in the same city
+	// must be to count as likely duplicates.
+	defaultDuplicateRadiusKm = 0.1
+	defaultMaxLocations      = 1000
+	defaultMaxNotesLength    = 5000
+	defaultMaxAttachmentSize = 5 << 20
+	defaultAttachmentDir     = "data/attachments"
+	// defaultSnapshotInterval is how often locations and history are saved
+	// when SNAPSHOT_DIR is set.
+	defaultSnapshotInterval = time.Minute
+)
 
 var logLevels = map[string]slog.Level{
 	"debug": slog.LevelDebug,
@@ -27,6 +55,50 @@ type Config struct {
 	Env      string
 	Open_URL string
 	Open_Key string
+	// CursorKey signs pagination cursors. When empty a random key is used,
+	// which invalidates cursors on restart.
+	CursorKey string
+	// PurgeWindow is how long deleted locations can be restored before
+	// they are removed permanently.
+	PurgeWindow time.Duration
+	// Retention decides when locations expire and are removed.
+	Retention domain.RetentionPolicy
+	// IdempotencyWindow is how long a response is replayed for retries with
+	// the same Idempotency-Key.
+	IdempotencyWindow time.Duration
+	// AdminUserIDs can read and change every user's locations.
+	AdminUserIDs []string
+	// DuplicateRadiusKm is how close, in kilometres, a new location must be
+	// to an existing one in the same city to be rejected as a duplicate.
+	DuplicateRadiusKm float64
+	// MaxLocations and MaxNotesLength bound what each user can store. 0
+	// means no limit.
+	MaxLocations   int
+	MaxNotesLength int
+	// MaxAttachmentBytes bounds the size of each uploaded file. 0 means no
+	// limit other than the upload size.
+	MaxAttachmentBytes int64
+	// AttachmentDir is where uploaded files are stored.
+	AttachmentDir string
+	// UserStoreFile is where accounts are saved. When empty they are kept
+	// in memory and lost on restart.
+	UserStoreFile string
+	// SessionKeys and SessionKeysFile hold the keys of session cookies,
+	// newest first, in the format of webutils.ParseSessionKeys. The file
+	// takes precedence. When both are empty random keys are used, which
+	// ends every session on restart.
+	SessionKeys     string
+	SessionKeysFile string
+	// SnapshotDir is where locations, their history, the metadata of their
+	// attachments and check-ins are saved every SnapshotInterval and on
+	// shutdown. When empty they are kept in memory and lost on restart.
+	SnapshotDir      string
+	SnapshotInterval time.Duration
+	// EncryptionKeys and EncryptionKeysFile hold the keys that encrypt notes
+	// and check-in comments in snapshots, newest first, in the format of
+	// repository.ParseMasterKeys. The file takes precedence.
+	EncryptionKeys     string
+	EncryptionKeysFile string
 }
 
 func NewConfig() (Config, error) {
@@ -69,11 +141,175 @@ func NewConfig() (Config, error) {
 
 		return Config{}, ErrOpenKeyNotSet
 	}
+	cursorKey := os.Getenv("CURSOR_KEY")
+	if cursorKey == "" {
+		fmt.Printf("CURSOR_KEY not set, pagination cursors will not survive a restart\n")
+	}
+	purgeWindow := defaultPurgeWindow
+	if purgeStr := os.Getenv("PURGE_WINDOW"); purgeStr != "" {
+		if d, err := time.ParseDuration(purgeStr); err == nil && d > 0 {
+			purgeWindow = d
+		} else {
+			fmt.Printf("Invalid PURGE_WINDOW '%s', defaulting to %s\n", purgeStr, defaultPurgeWindow)
+		}
+	}
+	retention := retentionFromEnv()
+	idempotencyWindow := defaultIdempotencyWindow
+	if windowStr := os.Getenv("IDEMPOTENCY_WINDOW"); windowStr != "" {
+		if d, err := time.ParseDuration(windowStr); err == nil && d > 0 {
+			idempotencyWindow = d
+		} else {
+			fmt.Printf("Invalid IDEMPOTENCY_WINDOW '%s', defaulting to %s\n", windowStr, defaultIdempotencyWindow)
+		}
+	}
+	var adminUserIDs []string
+	for _, id := range strings.Split(os.Getenv("ADMIN_USER_IDS"), ",") {
+		if id = strings.TrimSpace(id); id != "" {
+			adminUserIDs = append(adminUserIDs, id)
+		}
+	}
+	duplicateRadiusKm := defaultDuplicateRadiusKm
+	if radiusStr := os.Getenv("DUPLICATE_RADIUS_KM"); radiusStr != "" {
+		if km, err := strconv.ParseFloat(radiusStr, 64); err == nil && km >= 0 {
+			duplicateRadiusKm = km
+		} else {
+			fmt.Printf("Invalid DUPLICATE_RADIUS_KM '%s', defaulting to %g\n", radiusStr, defaultDuplicateRadiusKm)
+		}
+	}
+	maxLocations := intFromEnv("MAX_LOCATIONS_PER_USER", defaultMaxLocations)
+	maxNotesLength := intFromEnv("MAX_NOTES_LENGTH", defaultMaxNotesLength)
+	maxAttachmentBytes := intFromEnv("MAX_ATTACHMENT_BYTES", defaultMaxAttachmentSize)
+	attachmentDir := os.Getenv("ATTACHMENT_DIR")
+	if attachmentDir == "" {
+		fmt.Printf("ATTACHMENT_DIR not set, defaulting to '%s'\n", defaultAttachmentDir)
+		attachmentDir = defaultAttachmentDir
+	}
+	userStoreFile := os.Getenv("USER_STORE_FILE")
+	if userStoreFile == "" {
+		fmt.Printf("USER_STORE_FILE not set, accounts will not survive a restart\n")
+	}
+	sessionKeys := os.Getenv("SESSION_KEYS")
+	sessionKeysFile := os.Getenv("SESSION_KEYS_FILE")
+	switch {
+	case sessionKeys == "" && sessionKeysFile == "":
+		if env == "production" {
+			return Config{}, ErrSessionKeysNotSet
+		}
+		fmt.Printf("SESSION_KEYS not set, sessions will not survive a restart\n")
+	case sessionKeys != "" && sessionKeysFile != "":
+		fmt.Printf("Both SESSION_KEYS and SESSION_KEYS_FILE set, using SESSION_KEYS_FILE\n")
+		sessionKeys = ""
+	}
+	snapshotDir := os.Getenv("SNAPSHOT_DIR")
+	if snapshotDir == "" {
+		fmt.Printf("SNAPSHOT_DIR not set, locations will not survive a restart\n")
+	}
+	snapshotInterval := defaultSnapshotInterval
+	if intervalStr := os.Getenv("SNAPSHOT_INTERVAL"); intervalStr != "" {
+		if d, err := time.ParseDuration(intervalStr); err == nil && d > 0 {
+			snapshotInterval = d
+		} else {
+			fmt.Printf("Invalid SNAPSHOT_INTERVAL '%s', defaulting to %s\n", intervalStr, defaultSnapshotInterval)
+		}
+	}
+	encryptionKeys := os.Getenv("ENCRYPTION_KEYS")
+	encryptionKeysFile := os.Getenv("ENCRYPTION_KEYS_FILE")
+	switch {
+	case encryptionKeys == "" && encryptionKeysFile == "":
+		if snapshotDir != "" {
+			return Config{}, ErrEncryptionKeysNotSet
+		}
+	case encryptionKeys != "" && encryptionKeysFile != "":
+		fmt.Printf("Both ENCRYPTION_KEYS and ENCRYPTION_KEYS_FILE set, using ENCRYPTION_KEYS_FILE\n")
+		encryptionKeys = ""
+	}
 	return Config{
-		Port:     port,
-		LogLevel: level,
-		Env:      env,
-		Open_URL: openURL,
-		Open_Key: openKey,
+		Port:               port,
+		LogLevel:           level,
+		Env:                env,
+		Open_URL:           openURL,
+		Open_Key:           openKey,
+		CursorKey:          cursorKey,
+		PurgeWindow:        purgeWindow,
+		Retention:          retention,
+		IdempotencyWindow:  idempotencyWindow,
+		AdminUserIDs:       adminUserIDs,
+		DuplicateRadiusKm:  duplicateRadiusKm,
+		MaxLocations:       maxLocations,
+		MaxNotesLength:     maxNotesLength,
+		MaxAttachmentBytes: int64(maxAttachmentBytes),
+		AttachmentDir:      attachmentDir,
+		UserStoreFile:      userStoreFile,
+		SessionKeys:        sessionKeys,
+		SessionKeysFile:    sessionKeysFile,
+		SnapshotDir:        snapshotDir,
+		SnapshotInterval:   snapshotInterval,
+		EncryptionKeys:     encryptionKeys,
+		EncryptionKeysFile: encryptionKeysFile,
 	}, nil
 }
+
+// intFromEnv reads a non-negative integer from the environment variable
+// key, falling back to defaultValue when it is unset or invalid.
+func intFromEnv(key string, defaultValue int) int {
+	str := os.Getenv(key)
+	if str == "" {
+		return defaultValue
+	}
+	n, err := strconv.Atoi(str)
+	if err != nil || n < 0 {
+		fmt.Printf("Invalid %s '%s', defaulting to %d\n", key, str, defaultValue)
+		return defaultValue
+	}
+	return n
+}
+
+// retentionFromEnv reads the retention policy:
+//   - RETENTION_PERIOD, how long locations are kept, 0 for forever
+//   - RETENTION_BASIS, "created" or "accessed"
+//   - RETENTION_KEEP_REGISTERED, whether registered users' locations are
+//     kept forever
+//   - RETENTION_OVERRIDES, per-user periods as userID=period pairs
+//     separated by commas
+func retentionFromEnv() domain.RetentionPolicy {
+	policy := domain.RetentionPolicy{
+		Period:         defaultRetentionPeriod,
+		Basis:          domain.RetainFromCreation,
+		KeepRegistered: true,
+		Overrides:      make(map[string]time.Duration),
+	}
+	if periodStr := os.Getenv("RETENTION_PERIOD"); periodStr != "" {
+		if d, err := time.ParseDuration(periodStr); err == nil && d >= 0 {
+			policy.Period = d
+		} else {
+			fmt.Printf("Invalid RETENTION_PERIOD '%s', defaulting to %s\n", periodStr, defaultRetentionPeriod)
+		}
+	}
+	switch basis := domain.RetentionBasis(os.Getenv("RETENTION_BASIS")); basis {
+	case "":
+	case domain.RetainFromCreation, domain.RetainFromAccess:
+		policy.Basis = basis
+	default:
+		fmt.Printf("Invalid RETENTION_BASIS '%s', defaulting to '%s'\n", basis, domain.RetainFromCreation)
+	}
+	if keepStr := os.Getenv("RETENTION_KEEP_REGISTERED"); keepStr != "" {
+		if keep, err := strconv.ParseBool(keepStr); err == nil {
+			policy.KeepRegistered = keep
+		} else {
+			fmt.Printf("Invalid RETENTION_KEEP_REGISTERED '%s', defaulting to true\n", keepStr)
+		}
+	}
+	for _, pair := range strings.Split(os.Getenv("RETENTION_OVERRIDES"), ",") {
+		if pair = strings.TrimSpace(pair); pair == "" {
+			continue
+		}
+		userID, periodStr, _ := strings.Cut(pair, "=")
+		d, err := time.ParseDuration(strings.TrimSpace(periodStr))
+		if userID = strings.TrimSpace(userID); userID == "" || err != nil || d < 0 {
+			fmt.Printf("Invalid RETENTION_OVERRIDES entry '%s', ignoring it\n", pair)
+			continue
+		}
+		policy.Overrides[userID] = d
+	}
+	return policy
+}
@@ -0,0 +1,26 @@
+package domain
+
+import "time"
+
+// Attachment is a file uploaded to a location. Its content lives in a blob
+// store under keys derived from its ID; only the metadata is kept here.
+type Attachment struct {
+	Id         string
+	LocationID string
+	UserID     string // owner of the location
+	FileName   string
+	// ContentType is sniffed from the content, not taken from the upload.
+	ContentType  string
+	Size         int64
+	HasThumbnail bool
+	CreatedAt    time.Time
+}
+
+// ThumbnailContentType is the type of the attachment's thumbnail: JPEG
+// for JPEG photos and PNG for every other image.
+func (a Attachment) ThumbnailContentType() string {
+	if a.ContentType == "image/jpeg" {
+		return "image/jpeg"
+	}
+	return "image/png"
+}
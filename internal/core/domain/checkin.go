@@ -0,0 +1,21 @@
+package domain
+
+import "time"
+
+// CheckIn records a visit to a location. The weather at the time is stored
+// with it, so that the journal shows what it was like then rather than
+// now.
+type CheckIn struct {
+	Id         string
+	LocationID string
+	UserID     string // owner of the location
+	Comment    string
+	// City and Nickname are copied from the location when checking in, so
+	// that the timeline still reads correctly after the location is
+	// renamed.
+	City     string
+	Nickname string
+	// Weather is nil if it could not be looked up at the time.
+	Weather   *Weather
+	CreatedAt time.Time
+}
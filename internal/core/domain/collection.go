@@ -0,0 +1,12 @@
+package domain
+
+import "time"
+
+// Collection is a named group of a user's locations. Membership is stored on
+// the location, so deleting a collection leaves its locations in place.
+type Collection struct {
+	Id        string
+	UserID    string
+	Name      string
+	CreatedAt time.Time
+}
@@ -0,0 +1,82 @@
+package domain
+
+import "math"
+
+const (
+	earthRadiusKm = 6371.0
+	kmPerDegree   = math.Pi * earthRadiusKm / 180
+)
+
+// DistanceKm returns the great-circle distance between two points using the
+// haversine formula.
+func DistanceKm(a, b Coordinates) float64 {
+	lat1, lat2 := a.Lat*math.Pi/180, b.Lat*math.Pi/180
+	dLat := lat2 - lat1
+	dLon := (b.Lon - a.Lon) * math.Pi / 180
+	h := math.Sin(dLat/2)*math.Sin(dLat/2) +
+		math.Cos(lat1)*math.Cos(lat2)*math.Sin(dLon/2)*math.Sin(dLon/2)
+	return 2 * earthRadiusKm * math.Asin(math.Min(1, math.Sqrt(h)))
+}
+
+// BoundingBox is a latitude/longitude rectangle. A box whose MinLon is
+// greater than its MaxLon crosses the antimeridian.
+type BoundingBox struct {
+	MinLat float64
+	MinLon float64
+	MaxLat float64
+	MaxLon float64
+}
+
+func (b BoundingBox) Contains(c Coordinates) bool {
+	if c.Lat < b.MinLat || c.Lat > b.MaxLat {
+		return false
+	}
+	if b.MinLon <= b.MaxLon {
+		return c.Lon >= b.MinLon && c.Lon <= b.MaxLon
+	}
+	return c.Lon >= b.MinLon || c.Lon <= b.MaxLon
+}
+
+// BoundingBoxAround returns a box that contains every point within radiusKm
+// of center. It is a cheap pre-filter; callers still check DistanceKm.
+func BoundingBoxAround(center Coordinates, radiusKm float64) BoundingBox {
+	dLat := radiusKm / kmPerDegree
+	box := BoundingBox{
+		MinLat: math.Max(-90, center.Lat-dLat),
+		MaxLat: math.Min(90, center.Lat+dLat),
+		MinLon: -180,
+		MaxLon: 180,
+	}
+	// Near the poles every longitude is within reach.
+	if box.MinLat == -90 || box.MaxLat == 90 {
+		return box
+	}
+	maxAbsLat := math.Max(math.Abs(box.MinLat), math.Abs(box.MaxLat))
+	dLon := radiusKm / (kmPerDegree * math.Cos(maxAbsLat*math.Pi/180))
+	if dLon >= 180 {
+		return box
+	}
+	box.MinLon = normalizeLon(center.Lon - dLon)
+	box.MaxLon = normalizeLon(center.Lon + dLon)
+	return box
+}
+
+func normalizeLon(lon float64) float64 {
+	for lon < -180 {
+		lon += 360
+	}
+	for lon > 180 {
+		lon -= 360
+	}
+	return lon
+}
+
+// Round returns c with both coordinates rounded to the given number of
+// decimal places. Two places is about a kilometre.
+func (c Coordinates) Round(places int) Coordinates {
+	scale := math.Pow(10, float64(places))
+	return Coordinates{
+		Lat: math.Round(c.Lat*scale) / scale,
+		Lon: math.Round(c.Lon*scale) / scale,
+	}
+}
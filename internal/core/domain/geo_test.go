@@ -0,0 +1,69 @@
+package domain
+
+import (
+	"math"
+	"testing"
+)
+
+func TestDistanceKm(t *testing.T) {
+	london := Coordinates{Lat: 51.5074, Lon: -0.1278}
+	paris := Coordinates{Lat: 48.8566, Lon: 2.3522}
+
+	got := DistanceKm(london, paris)
+	if math.Abs(got-343.5) > 1 {
+		t.Fatalf("expected about 343.5 km, got %f", got)
+	}
+	if DistanceKm(london, london) != 0 {
+		t.Fatalf("expected zero distance to self")
+	}
+}
+
+func TestBoundingBoxAround(t *testing.T) {
+	tests := []struct {
+		name    string
+		center  Coordinates
+		radius  float64
+		inside  Coordinates
+		outside Coordinates
+	}{
+		{
+			name:    "regular box",
+			center:  Coordinates{Lat: 10, Lon: 10},
+			radius:  100,
+			inside:  Coordinates{Lat: 10.5, Lon: 10.5},
+			outside: Coordinates{Lat: 12, Lon: 10},
+		},
+		{
+			name:    "crosses the antimeridian",
+			center:  Coordinates{Lat: 0, Lon: 179.9},
+			radius:  100,
+			inside:  Coordinates{Lat: 0, Lon: -179.8},
+			outside: Coordinates{Lat: 0, Lon: 0},
+		},
+		{
+			name:    "touches a pole",
+			center:  Coordinates{Lat: 89.5, Lon: 0},
+			radius:  100,
+			inside:  Coordinates{Lat: 89.9, Lon: 180},
+			outside: Coordinates{Lat: 80, Lon: 0},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			box := BoundingBoxAround(tt.center, tt.radius)
+			if !box.Contains(tt.inside) {
+				t.Errorf("expected %+v to contain %+v", box, tt.inside)
+			}
+			if box.Contains(tt.outside) {
+				t.Errorf("expected %+v not to contain %+v", box, tt.outside)
+			}
+		})
+	}
+}
+
+func TestCoordinatesRound(t *testing.T) {
+	got := Coordinates{Lat: 48.85661, Lon: -2.35229}.Round(2)
+	if got != (Coordinates{Lat: 48.86, Lon: -2.35}) {
+		t.Fatalf("expected 48.86,-2.35, got %+v", got)
+	}
+}
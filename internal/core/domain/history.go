@@ -0,0 +1,38 @@
+package domain
+
+import "time"
+
+type HistoryAction string
+
+const (
+	HistoryCreated  HistoryAction = "created"
+	HistoryUpdated  HistoryAction = "updated"
+	HistoryDeleted  HistoryAction = "deleted"
+	HistoryRestored HistoryAction = "restored"
+	HistoryReverted HistoryAction = "reverted"
+)
+
+// FieldChange is one field of a location that a change modified. Old is
+// nil for a newly created location.
+type FieldChange struct {
+	Field string
+	Old   any
+	New   any
+}
+
+// HistoryEntry records one change to a location. Entries are never
+// modified once written.
+type HistoryEntry struct {
+	LocationID string
+	UserID     string // owner of the location
+	ActorID    string // user who made the change
+	RequestID  string
+	Action     HistoryAction
+	// Version is the location's version after the change.
+	Version int64
+	At      time.Time
+	Changes []FieldChange
+	// Snapshot is the location as the change left it; reverting to Version
+	// restores its editable fields.
+	Snapshot Location
+}
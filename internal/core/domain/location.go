@@ -1,6 +1,9 @@
 package domain
 
-import "time"
+import (
+	"strings"
+	"time"
+)
 
 type Location struct {
 	Id          string
@@ -9,8 +12,42 @@ type Location struct {
 	Nickname    string
 	City        string
 	Coordinates Coordinates
-	CreatedAt   time.Time
+	Tags        []string
+	Collections []string // IDs of the collections the location belongs to
+	Pinned      bool
+	Position    int // user-defined order, lowest first
+	// Version starts at 1 and increases with every change. It backs
+	// optimistic concurrency: writers state the version they read.
+	Version   int64
+	CreatedAt time.Time
+	// DeletedAt is zero unless the location is in the trash.
+	DeletedAt time.Time
+	// AccessedAt is when the location was last read or changed.
+	AccessedAt time.Time
+	// ExpiresAt is when the location will be removed under the retention
+	// policy, or zero if it is kept forever. It is set on locations read
+	// from a repository and ignored on writes.
+	ExpiresAt time.Time
 }
+
+func (l Location) HasTag(tag string) bool {
+	for _, t := range l.Tags {
+		if strings.EqualFold(t, tag) {
+			return true
+		}
+	}
+	return false
+}
+
+func (l Location) InCollection(collectionID string) bool {
+	for _, c := range l.Collections {
+		if c == collectionID {
+			return true
+		}
+	}
+	return false
+}
+
 type Coordinates struct {
 	Lat float64
 	Lon float64
@@ -8,10 +8,12 @@ type Metadata struct {
 	FirstPage    int32
 	LastPage     int32
 	TotalRecords int32
+	NextCursor   string
+	PrevCursor   string
 }
 
 func CalculateMetadata(totalRecords int32, offset, limit int32) Metadata {
-	if totalRecords == 0 {
+	if totalRecords == 0 || limit <= 0 {
 		return Metadata{}
 	}
 	return Metadata{
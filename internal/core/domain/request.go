@@ -0,0 +1,47 @@
+package domain
+
+import "context"
+
+type requestIDKey struct{}
+
+// ContextWithRequestID returns a copy of ctx carrying the ID of the request
+// being served, so that changes can be traced back to it.
+func ContextWithRequestID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, requestIDKey{}, id)
+}
+
+// RequestIDFromContext returns the request ID stored in ctx, or "".
+func RequestIDFromContext(ctx context.Context) string {
+	id, _ := ctx.Value(requestIDKey{}).(string)
+	return id
+}
+
+type shareTokenKey struct{}
+
+// ContextWithShareToken returns a copy of ctx carrying a share token the
+// caller presented, which lets them read what the share covers.
+func ContextWithShareToken(ctx context.Context, token string) context.Context {
+	return context.WithValue(ctx, shareTokenKey{}, token)
+}
+
+// ShareTokenFromContext returns the share token stored in ctx, or "".
+func ShareTokenFromContext(ctx context.Context) string {
+	token, _ := ctx.Value(shareTokenKey{}).(string)
+	return token
+}
+
+type withoutAccessKey struct{}
+
+// ContextWithoutAccess returns a copy of ctx for reads that must not count
+// as an access to the locations they return, such as a visitor opening a
+// share link, so that they do not keep those locations from expiring.
+func ContextWithoutAccess(ctx context.Context) context.Context {
+	return context.WithValue(ctx, withoutAccessKey{}, true)
+}
+
+// CountsAsAccess reports whether reads with ctx count as an access to the
+// locations they return.
+func CountsAsAccess(ctx context.Context) bool {
+	without, _ := ctx.Value(withoutAccessKey{}).(bool)
+	return !without
+}
@@ -0,0 +1,46 @@
+package domain
+
+import "time"
+
+// RetentionBasis is the moment a location's retention period is measured
+// from.
+type RetentionBasis string
+
+const (
+	RetainFromCreation RetentionBasis = "created"
+	// RetainFromAccess measures from the last time the location was read
+	// or changed, so locations in use are kept.
+	RetainFromAccess RetentionBasis = "accessed"
+)
+
+// RetentionPolicy decides when locations expire and are removed.
+type RetentionPolicy struct {
+	// Period is how long locations are kept. Zero keeps them forever.
+	Period time.Duration
+	Basis  RetentionBasis
+	// KeepRegistered keeps the locations of registered users forever.
+	KeepRegistered bool
+	// Overrides replaces Period for individual users, by user ID.
+	Overrides map[string]time.Duration
+}
+
+// ExpiresAt returns when loc expires under p, or the zero time if it is kept
+// forever. registered tells whether the location's owner is a registered
+// user.
+func (p RetentionPolicy) ExpiresAt(loc Location, registered bool) time.Time {
+	if registered && p.KeepRegistered {
+		return time.Time{}
+	}
+	period := p.Period
+	if override, exists := p.Overrides[loc.UserID]; exists {
+		period = override
+	}
+	if period <= 0 {
+		return time.Time{}
+	}
+	from := loc.CreatedAt
+	if p.Basis == RetainFromAccess && loc.AccessedAt.After(from) {
+		from = loc.AccessedAt
+	}
+	return from.Add(period)
+}
@@ -0,0 +1,35 @@
+package domain
+
+import (
+	"testing"
+	"time"
+)
+
+func TestRetentionPolicyExpiresAt(t *testing.T) {
+	created := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	loc := Location{UserID: "user1", CreatedAt: created, AccessedAt: created.Add(time.Hour)}
+	day := 24 * time.Hour
+
+	tests := []struct {
+		name       string
+		policy     RetentionPolicy
+		registered bool
+		expected   time.Time
+	}{
+		{"from creation", RetentionPolicy{Period: day}, false, created.Add(day)},
+		{"from access", RetentionPolicy{Period: day, Basis: RetainFromAccess}, false, created.Add(time.Hour + day)},
+		{"kept forever", RetentionPolicy{}, false, time.Time{}},
+		{"registered kept", RetentionPolicy{Period: day, KeepRegistered: true}, true, time.Time{}},
+		{"registered not kept", RetentionPolicy{Period: day}, true, created.Add(day)},
+		{"override", RetentionPolicy{Period: day, Overrides: map[string]time.Duration{"user1": 2 * day}}, false, created.Add(2 * day)},
+		{"override keeps forever", RetentionPolicy{Period: day, Overrides: map[string]time.Duration{"user1": 0}}, false, time.Time{}},
+		{"override for someone else", RetentionPolicy{Period: day, Overrides: map[string]time.Duration{"user2": 0}}, false, created.Add(day)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.policy.ExpiresAt(loc, tt.registered); !got.Equal(tt.expected) {
+				t.Errorf("expected %v, got %v", tt.expected, got)
+			}
+		})
+	}
+}
@@ -0,0 +1,23 @@
+package domain
+
+import "time"
+
+// Share grants anyone holding Token read access to a single location or a
+// single collection of its owner. Exactly one of LocationID and
+// CollectionID is set.
+type Share struct {
+	Token        string
+	UserID       string
+	LocationID   string
+	CollectionID string
+	// Precision is the number of decimal places shared coordinates are
+	// rounded to; zero shares them exactly.
+	Precision int
+	// ExpiresAt is zero for a share that lasts until it is revoked.
+	ExpiresAt time.Time
+	CreatedAt time.Time
+}
+
+func (s Share) Expired(now time.Time) bool {
+	return !s.ExpiresAt.IsZero() && !now.Before(s.ExpiresAt)
+}
@@ -0,0 +1,13 @@
+package domain
+
+import "time"
+
+// User is a registered account. Anonymous visitors have a user ID in their
+// session but no User.
+type User struct {
+	Id    string
+	Email string // lower case
+	// PasswordHash is an encoded argon2id hash, never the password itself.
+	PasswordHash string
+	CreatedAt    time.Time
+}
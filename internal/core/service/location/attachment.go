@@ -0,0 +1,299 @@
+package location
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"path/filepath"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/lafetz/weavo/internal/core/domain"
+)
+
+// MaxAttachmentsPerLocation bounds how many files can be attached to a
+// single location.
+const MaxAttachmentsPerLocation = 20
+
+// maxFileNameLength bounds the stored name of an attachment, in bytes.
+const maxFileNameLength = 255
+
+var (
+	ErrAttachmentNotFound = errors.New("attachment not found")
+	ErrAttachmentsOff     = errors.New("attachments are not enabled")
+	ErrAttachmentTooLarge = errors.New("attachment is too large")
+	ErrAttachmentLimit    = fmt.Errorf("a location may have at most %d attachments", MaxAttachmentsPerLocation)
+	ErrEmptyAttachment    = errors.New("attachment is empty")
+	ErrBlobNotFound       = errors.New("blob not found")
+	ErrNoThumbnail        = errors.New("attachment has no thumbnail")
+)
+
+// WithAttachments lets users attach files to their locations, keeping the
+// metadata in repo and the content in blobs. Without it every attachment
+// call fails with ErrAttachmentsOff.
+func WithAttachments(repo AttachmentRepo, blobs BlobStore) Option {
+	return func(s *Service) {
+		s.attachments = repo
+		s.blobs = blobs
+	}
+}
+
+// newAttachmentID returns a random ID. Attachment IDs are chosen here rather
+// than by the repository because the content is stored under them first.
+func newAttachmentID() string {
+	id := make([]byte, 16)
+	rand.Read(id)
+	return hex.EncodeToString(id)
+}
+
+// blobKey returns where the content of an attachment, or its thumbnail, is
+// stored.
+func blobKey(attachmentID string, thumbnail bool) string {
+	if thumbnail {
+		return attachmentID + ".thumb"
+	}
+	return attachmentID
+}
+
+// AddAttachment reads a file from r and attaches it to the location. The
+// content type is sniffed from the first bytes of the file, and images get
+// a thumbnail. It fails with ErrAttachmentTooLarge when the file is over the
+// quota and ErrAttachmentLimit when the location has no room left.
+func (s *Service) AddAttachment(ctx context.Context, locationID, userID, fileName string, r io.Reader) (domain.Attachment, error) {
+	if s.attachments == nil {
+		return domain.Attachment{}, ErrAttachmentsOff
+	}
+	loc, err := s.authorizedLocation(ctx, locationID, userID, permWrite)
+	if err != nil {
+		return domain.Attachment{}, err
+	}
+	head, r, err := sniffAttachment(r)
+	if err != nil {
+		return domain.Attachment{}, err
+	}
+	if err := s.checkAttachmentLimit(ctx, locationID); err != nil {
+		return domain.Attachment{}, err
+	}
+
+	attachment := domain.Attachment{
+		Id:          newAttachmentID(),
+		LocationID:  loc.Id,
+		UserID:      loc.UserID,
+		FileName:    cleanFileName(fileName),
+		ContentType: http.DetectContentType(head),
+		CreatedAt:   time.Now(),
+	}
+	content := &attachmentReader{r: r, max: s.quota.MaxAttachmentBytes}
+	if err := s.blobs.Put(ctx, blobKey(attachment.Id, false), content); err != nil {
+		// Whatever the store kept of a failed upload is removed.
+		s.blobs.Delete(ctx, blobKey(attachment.Id, false))
+		if content.tooLarge() {
+			return domain.Attachment{}, fmt.Errorf("%w: the maximum is %d bytes", ErrAttachmentTooLarge, content.max)
+		}
+		return domain.Attachment{}, fmt.Errorf("storing attachment: %w", err)
+	}
+	attachment.Size = content.size
+	// An image that cannot be decoded is still stored, just without a
+	// thumbnail.
+	if strings.HasPrefix(attachment.ContentType, "image/") {
+		thumb, err := thumbnail(func() (io.ReadCloser, error) {
+			return s.blobs.Open(ctx, blobKey(attachment.Id, false))
+		})
+		if err == nil {
+			if err := s.blobs.Put(ctx, blobKey(attachment.Id, true), bytes.NewReader(thumb)); err == nil {
+				attachment.HasThumbnail = true
+			}
+		}
+	}
+
+	// The count is taken again now that the content is stored, as other
+	// uploads to the location may have finished in the meantime.
+	s.attachmentMu.Lock()
+	defer s.attachmentMu.Unlock()
+	created, err := s.createAttachment(ctx, attachment)
+	if err != nil {
+		s.deleteBlobs(ctx, attachment)
+		return domain.Attachment{}, err
+	}
+	return created, nil
+}
+
+func (s *Service) createAttachment(ctx context.Context, attachment domain.Attachment) (domain.Attachment, error) {
+	if err := s.checkAttachmentLimit(ctx, attachment.LocationID); err != nil {
+		return domain.Attachment{}, err
+	}
+	return s.attachments.CreateAttachment(ctx, attachment)
+}
+
+// GetAttachments lists the attachments of a location the user may read.
+func (s *Service) GetAttachments(ctx context.Context, locationID, userID string) ([]domain.Attachment, error) {
+	if s.attachments == nil {
+		return nil, ErrAttachmentsOff
+	}
+	if _, err := s.authorizedLocation(ctx, locationID, userID, permRead); err != nil {
+		return nil, err
+	}
+	return s.attachments.GetAttachments(ctx, locationID)
+}
+
+// OpenAttachment returns an attachment of a location the user may read and
+// its content, or its thumbnail if thumbnail is set. The caller closes the
+// content. A thumbnail is always a JPEG or PNG image.
+func (s *Service) OpenAttachment(ctx context.Context, locationID, id, userID string, thumbnail bool) (domain.Attachment, io.ReadCloser, error) {
+	attachment, err := s.attachmentOf(ctx, locationID, id, userID, permRead)
+	if err != nil {
+		return domain.Attachment{}, nil, err
+	}
+	if thumbnail && !attachment.HasThumbnail {
+		return domain.Attachment{}, nil, ErrNoThumbnail
+	}
+	content, err := s.blobs.Open(ctx, blobKey(attachment.Id, thumbnail))
+	if errors.Is(err, ErrBlobNotFound) {
+		return domain.Attachment{}, nil, ErrAttachmentNotFound
+	}
+	if err != nil {
+		return domain.Attachment{}, nil, err
+	}
+	return attachment, content, nil
+}
+
+// DeleteAttachment removes an attachment and its content.
+func (s *Service) DeleteAttachment(ctx context.Context, locationID, id, userID string) error {
+	attachment, err := s.attachmentOf(ctx, locationID, id, userID, permWrite)
+	if err != nil {
+		return err
+	}
+	if err := s.attachments.DeleteAttachment(ctx, attachment.Id); err != nil {
+		return err
+	}
+	return s.deleteBlobs(ctx, attachment)
+}
+
+// authorizedLocation returns the location if userID may perform perm on it.
+func (s *Service) authorizedLocation(ctx context.Context, locationID, userID string, perm permission) (domain.Location, error) {
+	loc, err := s.repo.GetLocation(ctx, locationID)
+	if err != nil {
+		return domain.Location{}, err
+	}
+	if _, err := s.authorize(ctx, userID, loc, perm); err != nil {
+		return domain.Location{}, err
+	}
+	return loc, nil
+}
+
+// attachmentOf returns the attachment with the given id if it belongs to the
+// location and userID may perform perm on the location.
+func (s *Service) attachmentOf(ctx context.Context, locationID, id, userID string, perm permission) (domain.Attachment, error) {
+	if s.attachments == nil {
+		return domain.Attachment{}, ErrAttachmentsOff
+	}
+	if _, err := s.authorizedLocation(ctx, locationID, userID, perm); err != nil {
+		return domain.Attachment{}, err
+	}
+	attachment, err := s.attachments.GetAttachment(ctx, id)
+	if err != nil {
+		return domain.Attachment{}, err
+	}
+	if attachment.LocationID != locationID {
+		return domain.Attachment{}, ErrAttachmentNotFound
+	}
+	return attachment, nil
+}
+
+// sniffAttachment reads the first bytes of an upload, enough to detect its
+// content type, and returns them with a reader of the whole upload.
+func sniffAttachment(r io.Reader) ([]byte, io.Reader, error) {
+	head := make([]byte, 512)
+	n, err := io.ReadFull(r, head)
+	if err != nil && !errors.Is(err, io.ErrUnexpectedEOF) && !errors.Is(err, io.EOF) {
+		return nil, nil, err
+	}
+	if n == 0 {
+		return nil, nil, ErrEmptyAttachment
+	}
+	head = head[:n]
+	return head, io.MultiReader(bytes.NewReader(head), r), nil
+}
+
+// attachmentReader streams an upload to the blob store, counting its size
+// and failing once it goes over max, so that the store discards it. A max
+// of 0 means no limit.
+type attachmentReader struct {
+	r    io.Reader
+	max  int64
+	size int64
+}
+
+func (a *attachmentReader) Read(p []byte) (int, error) {
+	n, err := a.r.Read(p)
+	a.size += int64(n)
+	if a.tooLarge() {
+		return n, ErrAttachmentTooLarge
+	}
+	return n, err
+}
+
+func (a *attachmentReader) tooLarge() bool {
+	return a.max > 0 && a.size > a.max
+}
+
+func (s *Service) checkAttachmentLimit(ctx context.Context, locationID string) error {
+	existing, err := s.attachments.GetAttachments(ctx, locationID)
+	if err != nil {
+		return err
+	}
+	if len(existing) >= MaxAttachmentsPerLocation {
+		return ErrAttachmentLimit
+	}
+	return nil
+}
+
+func (s *Service) deleteBlobs(ctx context.Context, attachment domain.Attachment) error {
+	err := s.blobs.Delete(ctx, blobKey(attachment.Id, false))
+	if attachment.HasThumbnail {
+		err = errors.Join(err, s.blobs.Delete(ctx, blobKey(attachment.Id, true)))
+	}
+	return err
+}
+
+// removeAttachments deletes the attachments of locations that have been
+// removed permanently. It is registered with the repository's OnRemove and
+// has nobody to report failures to, so content that cannot be deleted is
+// left behind.
+func (s *Service) removeAttachments(ids []string) {
+	ctx := context.Background()
+	for _, id := range ids {
+		removed, err := s.attachments.DeleteAttachments(ctx, id)
+		if err != nil {
+			continue
+		}
+		for _, attachment := range removed {
+			s.deleteBlobs(ctx, attachment)
+		}
+	}
+}
+
+// cleanFileName keeps the last element of an uploaded file's name, without
+// control characters, so that it is safe to hand back in a download.
+func cleanFileName(name string) string {
+	name = filepath.Base(strings.ReplaceAll(name, `\`, "/"))
+	name = strings.Map(func(r rune) rune {
+		if unicode.IsControl(r) || r == '"' {
+			return -1
+		}
+		return r
+	}, name)
+	if len(name) > maxFileNameLength {
+		name = strings.ToValidUTF8(name[:maxFileNameLength], "")
+	}
+	if name == "" || name == "." || name == "/" {
+		return "attachment"
+	}
+	return name
+}
@@ -0,0 +1,219 @@
+package location
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"errors"
+	"hash/crc32"
+	"image"
+	"image/color"
+	"image/png"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/lafetz/weavo/internal/core/domain"
+)
+
+type mockAttachments struct {
+	attachments []domain.Attachment
+}
+
+func (m *mockAttachments) CreateAttachment(ctx context.Context, a domain.Attachment) (domain.Attachment, error) {
+	m.attachments = append(m.attachments, a)
+	return a, nil
+}
+
+func (m *mockAttachments) GetAttachment(ctx context.Context, id string) (domain.Attachment, error) {
+	for _, a := range m.attachments {
+		if a.Id == id {
+			return a, nil
+		}
+	}
+	return domain.Attachment{}, ErrAttachmentNotFound
+}
+
+func (m *mockAttachments) GetAttachments(ctx context.Context, locationID string) ([]domain.Attachment, error) {
+	found := []domain.Attachment{}
+	for _, a := range m.attachments {
+		if a.LocationID == locationID {
+			found = append(found, a)
+		}
+	}
+	return found, nil
+}
+
+func (m *mockAttachments) DeleteAttachment(ctx context.Context, id string) error {
+	for i, a := range m.attachments {
+		if a.Id == id {
+			m.attachments = append(m.attachments[:i], m.attachments[i+1:]...)
+			return nil
+		}
+	}
+	return ErrAttachmentNotFound
+}
+
+func (m *mockAttachments) DeleteAttachments(ctx context.Context, locationID string) ([]domain.Attachment, error) {
+	removed, _ := m.GetAttachments(ctx, locationID)
+	for _, a := range removed {
+		m.DeleteAttachment(ctx, a.Id)
+	}
+	return removed, nil
+}
+
+type mockBlobs map[string][]byte
+
+func (m mockBlobs) Put(ctx context.Context, key string, r io.Reader) error {
+	data, err := io.ReadAll(r)
+	m[key] = data
+	return err
+}
+
+func (m mockBlobs) Open(ctx context.Context, key string) (io.ReadCloser, error) {
+	data, exists := m[key]
+	if !exists {
+		return nil, ErrBlobNotFound
+	}
+	return io.NopCloser(bytes.NewReader(data)), nil
+}
+
+func (m mockBlobs) Delete(ctx context.Context, key string) error {
+	delete(m, key)
+	return nil
+}
+
+func testPNG(t *testing.T, w, h int) []byte {
+	t.Helper()
+	img := image.NewNRGBA(image.Rect(0, 0, w, h))
+	for x := range w {
+		img.Set(x, 0, color.NRGBA{R: 255, A: 255})
+	}
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		t.Fatalf("encoding test image: %v", err)
+	}
+	return buf.Bytes()
+}
+
+func opener(data []byte) func() (io.ReadCloser, error) {
+	return func() (io.ReadCloser, error) {
+		return io.NopCloser(bytes.NewReader(data)), nil
+	}
+}
+
+func TestAttachments(t *testing.T) {
+	ctx := context.Background()
+	repo := newMockRepo()
+	repo.locations["a"] = domain.Location{Id: "a", UserID: "owner"}
+	attachments, blobs := &mockAttachments{}, mockBlobs{}
+	svc := NewService(repo, WithAttachments(attachments, blobs), WithQuota(Quota{MaxAttachmentBytes: 1 << 20}))
+
+	t.Run("image with thumbnail", func(t *testing.T) {
+		attachment, err := svc.AddAttachment(ctx, "a", "owner", `C:\photos\..\beach.png`, bytes.NewReader(testPNG(t, 600, 300)))
+		if err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+		if attachment.ContentType != "image/png" || !attachment.HasThumbnail || attachment.FileName != "beach.png" {
+			t.Errorf("unexpected attachment %+v", attachment)
+		}
+		_, content, err := svc.OpenAttachment(ctx, "a", attachment.Id, "owner", true)
+		if err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+		defer content.Close()
+		cfg, format, err := image.DecodeConfig(content)
+		if err != nil {
+			t.Fatalf("expected a decodable thumbnail, got %v", err)
+		}
+		if format != "png" || cfg.Width != thumbnailSize || cfg.Height != thumbnailSize/2 {
+			t.Errorf("expected a %dx%d png, got a %dx%d %s", thumbnailSize, thumbnailSize/2, cfg.Width, cfg.Height, format)
+		}
+	})
+
+	t.Run("other file", func(t *testing.T) {
+		attachment, err := svc.AddAttachment(ctx, "a", "owner", "notes.txt", strings.NewReader("hello"))
+		if err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+		if !strings.HasPrefix(attachment.ContentType, "text/plain") || attachment.HasThumbnail || attachment.Size != 5 {
+			t.Errorf("unexpected attachment %+v", attachment)
+		}
+		if _, _, err := svc.OpenAttachment(ctx, "a", attachment.Id, "owner", true); !errors.Is(err, ErrNoThumbnail) {
+			t.Errorf("expected ErrNoThumbnail, got %v", err)
+		}
+
+		if err := svc.DeleteAttachment(ctx, "a", attachment.Id, "owner"); err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+		if _, exists := blobs[attachment.Id]; exists {
+			t.Error("expected the content to be deleted")
+		}
+	})
+
+	t.Run("rejected uploads", func(t *testing.T) {
+		if _, err := svc.AddAttachment(ctx, "a", "owner", "big.bin", bytes.NewReader(make([]byte, 1<<20+1))); !errors.Is(err, ErrAttachmentTooLarge) {
+			t.Errorf("expected ErrAttachmentTooLarge, got %v", err)
+		}
+		if _, err := svc.AddAttachment(ctx, "a", "owner", "empty.txt", strings.NewReader("")); !errors.Is(err, ErrEmptyAttachment) {
+			t.Errorf("expected ErrEmptyAttachment, got %v", err)
+		}
+		if _, err := svc.AddAttachment(ctx, "a", "intruder", "x.txt", strings.NewReader("x")); !errors.Is(err, ErrLocationNotFound) {
+			t.Errorf("expected ErrLocationNotFound, got %v", err)
+		}
+	})
+
+	t.Run("shared location", func(t *testing.T) {
+		repo.shares["tok"] = domain.Share{Token: "tok", UserID: "owner", LocationID: "a", CreatedAt: time.Now()}
+		shared := domain.ContextWithShareToken(ctx, "tok")
+		list, err := svc.GetAttachments(shared, "a", "viewer")
+		if err != nil || len(list) != 1 {
+			t.Fatalf("expected the viewer to see 1 attachment, got %d, %v", len(list), err)
+		}
+		if err := svc.DeleteAttachment(shared, "a", list[0].Id, "viewer"); !errors.Is(err, ErrUnAuthorized) {
+			t.Errorf("expected ErrUnAuthorized, got %v", err)
+		}
+	})
+
+	t.Run("limit per location", func(t *testing.T) {
+		repo.locations["b"] = domain.Location{Id: "b", UserID: "owner"}
+		for range MaxAttachmentsPerLocation {
+			if _, err := svc.AddAttachment(ctx, "b", "owner", "x.txt", strings.NewReader("x")); err != nil {
+				t.Fatalf("expected no error, got %v", err)
+			}
+		}
+		if _, err := svc.AddAttachment(ctx, "b", "owner", "x.txt", strings.NewReader("x")); !errors.Is(err, ErrAttachmentLimit) {
+			t.Errorf("expected ErrAttachmentLimit, got %v", err)
+		}
+	})
+
+	t.Run("removed with the location", func(t *testing.T) {
+		svc.removeAttachments([]string{"a", "b"})
+		if len(attachments.attachments) != 0 || len(blobs) != 0 {
+			t.Errorf("expected everything to be removed, got %d attachments and %d blobs", len(attachments.attachments), len(blobs))
+		}
+	})
+
+	t.Run("disabled", func(t *testing.T) {
+		if _, err := NewService(repo).GetAttachments(ctx, "a", "owner"); !errors.Is(err, ErrAttachmentsOff) {
+			t.Errorf("expected ErrAttachmentsOff, got %v", err)
+		}
+	})
+}
+
+func TestThumbnailRejectsHugeImages(t *testing.T) {
+	// A PNG header claiming 100000x100000 pixels must not be decoded.
+	huge := testPNG(t, 1, 1)
+	copy(huge[16:24], []byte{0, 1, 0x86, 0xa0, 0, 1, 0x86, 0xa0})
+	binary.BigEndian.PutUint32(huge[29:33], crc32.ChecksumIEEE(huge[12:29]))
+	if cfg, err := png.DecodeConfig(bytes.NewReader(huge)); err != nil || cfg.Width != 100000 {
+		t.Fatalf("expected a valid header, got %+v, %v", cfg, err)
+	}
+	if _, err := thumbnail(opener(huge)); err == nil {
+		t.Error("expected an error for an image over the pixel limit")
+	}
+	if _, err := thumbnail(opener([]byte("not an image"))); err == nil {
+		t.Error("expected an error for a file that is not an image")
+	}
+}
@@ -0,0 +1,102 @@
+package location
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"time"
+
+	"github.com/lafetz/weavo/internal/core/domain"
+)
+
+// role is what a user is to a location.
+type role int
+
+const (
+	roleNone role = iota
+	// roleViewer reads a location through one of its owner's share links.
+	roleViewer
+	roleOwner
+	// roleAdmin reads and changes every user's locations, but cannot share
+	// them.
+	roleAdmin
+)
+
+type permission int
+
+const (
+	permRead permission = iota
+	permReadHistory
+	permWrite
+	permShare
+)
+
+var rolePermissions = map[role][]permission{
+	roleViewer: {permRead},
+	roleOwner:  {permRead, permReadHistory, permWrite, permShare},
+	roleAdmin:  {permRead, permReadHistory, permWrite},
+}
+
+// WithAdmins sets the users that may read and change every user's
+// locations.
+func WithAdmins(userIDs ...string) Option {
+	return func(s *Service) {
+		for _, id := range userIDs {
+			s.admins[id] = true
+		}
+	}
+}
+
+// authorize checks that userID may perform perm on loc. It returns the share
+// the access goes through for a viewer, so that its precision can be
+// applied, and nil otherwise. A user with no role on loc gets
+// ErrLocationNotFound, so that other users' IDs cannot be probed; one whose
+// role lacks perm gets ErrUnAuthorized.
+func (s *Service) authorize(ctx context.Context, userID string, loc domain.Location, perm permission) (*domain.Share, error) {
+	r, share, err := s.roleOf(ctx, userID, loc)
+	if err != nil {
+		return nil, err
+	}
+	if r == roleNone {
+		return nil, ErrLocationNotFound
+	}
+	if !slices.Contains(rolePermissions[r], perm) {
+		return nil, ErrUnAuthorized
+	}
+	return share, nil
+}
+
+func (s *Service) roleOf(ctx context.Context, userID string, loc domain.Location) (role, *domain.Share, error) {
+	switch {
+	case userID != "" && loc.UserID == userID:
+		return roleOwner, nil, nil
+	case s.admins[userID]:
+		return roleAdmin, nil, nil
+	}
+	token := domain.ShareTokenFromContext(ctx)
+	if token == "" {
+		return roleNone, nil, nil
+	}
+	share, err := s.repo.GetShare(ctx, token)
+	if errors.Is(err, ErrShareNotFound) {
+		return roleNone, nil, nil
+	}
+	if err != nil {
+		return roleNone, nil, err
+	}
+	if share.Expired(time.Now()) || !shareCovers(share, loc) {
+		return roleNone, nil, nil
+	}
+	return roleViewer, &share, nil
+}
+
+// shareCovers reports whether loc is what share grants access to.
+func shareCovers(share domain.Share, loc domain.Location) bool {
+	if share.UserID != loc.UserID {
+		return false
+	}
+	if share.LocationID != "" {
+		return share.LocationID == loc.Id
+	}
+	return slices.Contains(loc.Collections, share.CollectionID)
+}
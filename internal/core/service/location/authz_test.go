@@ -0,0 +1,101 @@
+package location
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/lafetz/weavo/internal/core/domain"
+)
+
+func TestAuthorization(t *testing.T) {
+	ctx := context.Background()
+	repo := newMockRepo()
+	repo.locations["a"] = domain.Location{Id: "a", UserID: "owner", Notes: "private", Coordinates: domain.Coordinates{Lat: 48.85661, Lon: 2.35222}}
+	repo.locations["b"] = domain.Location{Id: "b", UserID: "owner", Collections: []string{"col"}}
+	repo.shares["loc-share"] = domain.Share{Token: "loc-share", UserID: "owner", LocationID: "a", Precision: 1}
+	repo.shares["col-share"] = domain.Share{Token: "col-share", UserID: "owner", CollectionID: "col"}
+	repo.shares["expired"] = domain.Share{Token: "expired", UserID: "owner", LocationID: "a", ExpiresAt: time.Now().Add(-time.Minute)}
+	history := &mockHistory{entries: make(map[string][]domain.HistoryEntry)}
+	svc := NewService(repo, WithHistory(history), WithAdmins("admin"))
+
+	t.Run("reads", func(t *testing.T) {
+		tests := []struct {
+			name   string
+			userID string
+			token  string
+			id     string
+			err    error
+		}{
+			{"owner", "owner", "", "a", nil},
+			{"admin", "admin", "", "a", nil},
+			{"another user", "intruder", "", "a", ErrLocationNotFound},
+			{"shared location", "intruder", "loc-share", "a", nil},
+			{"location outside the share", "intruder", "loc-share", "b", ErrLocationNotFound},
+			{"member of a shared collection", "intruder", "col-share", "b", nil},
+			{"expired share", "intruder", "expired", "a", ErrLocationNotFound},
+			{"unknown share", "intruder", "nope", "a", ErrLocationNotFound},
+			{"missing location", "owner", "", "missing", ErrLocationNotFound},
+		}
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				ctx := ctx
+				if tt.token != "" {
+					ctx = domain.ContextWithShareToken(ctx, tt.token)
+				}
+				_, err := svc.GetLocation(ctx, tt.id, tt.userID)
+				if !errors.Is(err, tt.err) {
+					t.Fatalf("expected %v, got %v", tt.err, err)
+				}
+			})
+		}
+	})
+
+	t.Run("viewers see the share's precision", func(t *testing.T) {
+		loc, err := svc.GetLocation(domain.ContextWithShareToken(ctx, "loc-share"), "a", "intruder")
+		if err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+		if loc.Coordinates != (domain.Coordinates{Lat: 48.9, Lon: 2.4}) {
+			t.Fatalf("expected coordinates rounded to 1 place, got %+v", loc.Coordinates)
+		}
+	})
+
+	t.Run("viewers cannot write", func(t *testing.T) {
+		shared := domain.ContextWithShareToken(ctx, "loc-share")
+		if _, err := svc.UpdateLocation(shared, domain.Location{Id: "a", UserID: "intruder"}); !errors.Is(err, ErrUnAuthorized) {
+			t.Fatalf("expected ErrUnAuthorized updating, got %v", err)
+		}
+		if err := svc.DeleteLocation(shared, "a", "intruder", 0); !errors.Is(err, ErrUnAuthorized) {
+			t.Fatalf("expected ErrUnAuthorized deleting, got %v", err)
+		}
+	})
+
+	t.Run("admins write on the owner's behalf", func(t *testing.T) {
+		updated, err := svc.UpdateLocation(ctx, domain.Location{Id: "a", UserID: "admin", Notes: "moderated"})
+		if err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+		if updated.UserID != "owner" || repo.locations["a"].UserID != "owner" {
+			t.Fatalf("expected the location to stay the owner's, got %q", updated.UserID)
+		}
+		entries, err := svc.GetHistory(ctx, "a", "admin")
+		if err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+		if last := entries[len(entries)-1]; last.ActorID != "admin" || last.UserID != "owner" {
+			t.Fatalf("expected the admin recorded as actor, got %+v", last)
+		}
+		if _, err := svc.GetHistory(domain.ContextWithShareToken(ctx, "loc-share"), "a", "intruder"); !errors.Is(err, ErrUnAuthorized) {
+			t.Fatalf("expected viewers not to read history, got %v", err)
+		}
+	})
+
+	t.Run("admins cannot share", func(t *testing.T) {
+		_, err := svc.CreateShare(ctx, domain.Share{UserID: "admin", LocationID: "a"})
+		if !errors.Is(err, ErrUnAuthorized) {
+			t.Fatalf("expected ErrUnAuthorized, got %v", err)
+		}
+	})
+}
@@ -0,0 +1,118 @@
+package location
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/lafetz/weavo/internal/core/domain"
+)
+
+// MaxBatchOperations bounds the number of operations in a single batch.
+const MaxBatchOperations = 100
+
+var (
+	ErrBatchTooLarge = fmt.Errorf("a batch may contain at most %d operations", MaxBatchOperations)
+	ErrBatchAborted  = errors.New("not applied because another operation in the atomic batch failed")
+	ErrInvalidOp     = errors.New("unknown batch operation")
+)
+
+type BatchOp string
+
+const (
+	BatchCreate BatchOp = "create"
+	BatchUpdate BatchOp = "update"
+	BatchDelete BatchOp = "delete"
+)
+
+// BatchOperation is one write of a batch. Location is the location to
+// create or the replacement of the one to update; for updates and deletes
+// Location.Id selects the target and a non-zero Location.Version makes the
+// write conditional like in UpdateLocation. Force creates the location even
+// if it looks like a duplicate.
+type BatchOperation struct {
+	Op       BatchOp
+	Location domain.Location
+	Force    bool
+}
+
+// BatchResult is the outcome of one operation, in input order. Location is
+// the created or updated location.
+type BatchResult struct {
+	Location domain.Location
+	Err      error
+}
+
+// Batch applies ops for the user in order, each one authorized and applied
+// like its single-location counterpart. Without atomic every operation
+// succeeds or fails on its own. With atomic the first failure undoes the
+// operations before it and skips the ones after; every operation but the
+// failed one then reports ErrBatchAborted. An atomic batch can only change
+// the user's own locations, since the transaction covers only their records.
+func (s *Service) Batch(ctx context.Context, userID string, ops []BatchOperation, atomic bool) ([]BatchResult, error) {
+	if len(ops) > MaxBatchOperations {
+		return nil, ErrBatchTooLarge
+	}
+	results := make([]BatchResult, len(ops))
+	if !atomic {
+		for i, op := range ops {
+			loc, err := s.applyBatchOp(ctx, userID, op, false)
+			results[i] = BatchResult{Location: loc, Err: err}
+		}
+		return results, nil
+	}
+
+	// History is only written once the transaction has committed, so that
+	// undone operations leave no trace.
+	ctx, flush := s.deferHistory(ctx)
+	failed := -1
+	err := s.repo.WithinTx(ctx, userID, func(ctx context.Context) error {
+		for i, op := range ops {
+			loc, err := s.applyBatchOp(ctx, userID, op, true)
+			if err != nil {
+				failed = i
+				results[i].Err = err
+				return err
+			}
+			results[i].Location = loc
+		}
+		return nil
+	})
+	if failed >= 0 {
+		for i := range results {
+			if i != failed {
+				results[i] = BatchResult{Err: ErrBatchAborted}
+			}
+		}
+		return results, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	flush()
+	return results, nil
+}
+
+func (s *Service) applyBatchOp(ctx context.Context, userID string, op BatchOperation, atomic bool) (domain.Location, error) {
+	loc := op.Location
+	loc.UserID = userID
+	switch op.Op {
+	case BatchCreate:
+		return s.CreateLocation(ctx, loc, op.Force)
+	case BatchUpdate, BatchDelete:
+		if atomic {
+			current, err := s.repo.GetLocation(ctx, loc.Id)
+			if err != nil {
+				return domain.Location{}, err
+			}
+			if current.UserID != userID {
+				return domain.Location{}, ErrLocationNotFound
+			}
+		}
+		if op.Op == BatchUpdate {
+			return s.UpdateLocation(ctx, loc)
+		}
+		return domain.Location{Id: loc.Id}, s.DeleteLocation(ctx, loc.Id, userID, loc.Version)
+	}
+	return domain.Location{}, ErrInvalidOp
+}
@@ -0,0 +1,106 @@
+package location
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/lafetz/weavo/internal/core/domain"
+)
+
+func TestBatch(t *testing.T) {
+	ctx := context.Background()
+	setup := func() (*mockRepo, *mockHistory, *Service) {
+		repo := newMockRepo()
+		repo.locations["a"] = domain.Location{Id: "a", UserID: "owner", Nickname: "A"}
+		repo.locations["b"] = domain.Location{Id: "b", UserID: "owner", Nickname: "B"}
+		repo.locations["other"] = domain.Location{Id: "other", UserID: "someone-else"}
+		history := &mockHistory{entries: make(map[string][]domain.HistoryEntry)}
+		return repo, history, NewService(repo, WithHistory(history), WithAdmins("owner"))
+	}
+	ops := []BatchOperation{
+		{Op: BatchCreate, Location: domain.Location{Nickname: "New", City: "Oslo"}},
+		{Op: BatchUpdate, Location: domain.Location{Id: "a", Nickname: "A2"}},
+		{Op: BatchDelete, Location: domain.Location{Id: "b"}},
+		{Op: BatchUpdate, Location: domain.Location{Id: "missing"}},
+	}
+
+	t.Run("each operation on its own", func(t *testing.T) {
+		repo, _, svc := setup()
+		results, err := svc.Batch(ctx, "owner", ops, false)
+		if err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+		for i, res := range results[:3] {
+			if res.Err != nil {
+				t.Errorf("expected operation %d to succeed, got %v", i, res.Err)
+			}
+		}
+		if !errors.Is(results[3].Err, ErrLocationNotFound) {
+			t.Errorf("expected ErrLocationNotFound, got %v", results[3].Err)
+		}
+		if results[0].Location.UserID != "owner" || repo.locations["a"].Nickname != "A2" {
+			t.Errorf("expected the create and the update to be applied")
+		}
+		if _, exists := repo.trash["b"]; !exists {
+			t.Errorf("expected b to be deleted")
+		}
+	})
+
+	t.Run("atomic failure undoes everything", func(t *testing.T) {
+		repo, history, svc := setup()
+		results, err := svc.Batch(ctx, "owner", ops, true)
+		if err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+		for i, res := range results[:3] {
+			if !errors.Is(res.Err, ErrBatchAborted) {
+				t.Errorf("expected operation %d to be aborted, got %v", i, res.Err)
+			}
+		}
+		if !errors.Is(results[3].Err, ErrLocationNotFound) {
+			t.Errorf("expected the failing operation's own error, got %v", results[3].Err)
+		}
+		if len(repo.locations) != 3 || repo.locations["a"].Nickname != "A" || len(repo.trash) != 0 {
+			t.Errorf("expected the repository to be unchanged, got %v", repo.locations)
+		}
+		if len(history.entries) != 0 {
+			t.Errorf("expected no history for undone operations, got %v", history.entries)
+		}
+	})
+
+	t.Run("atomic success", func(t *testing.T) {
+		_, history, svc := setup()
+		results, err := svc.Batch(ctx, "owner", ops[:3], true)
+		if err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+		for i, res := range results {
+			if res.Err != nil {
+				t.Errorf("expected operation %d to succeed, got %v", i, res.Err)
+			}
+		}
+		if len(history.entries["a"]) != 1 || len(history.entries["b"]) != 1 {
+			t.Errorf("expected history once the batch committed, got %v", history.entries)
+		}
+	})
+
+	t.Run("atomic batches stay within the user's locations", func(t *testing.T) {
+		_, _, svc := setup()
+		update := []BatchOperation{{Op: BatchUpdate, Location: domain.Location{Id: "other"}}}
+		results, _ := svc.Batch(ctx, "owner", update, true)
+		if !errors.Is(results[0].Err, ErrLocationNotFound) {
+			t.Errorf("expected ErrLocationNotFound for another user's location, got %v", results[0].Err)
+		}
+		if results, _ := svc.Batch(ctx, "owner", update, false); results[0].Err != nil {
+			t.Errorf("expected an admin to update it outside an atomic batch, got %v", results[0].Err)
+		}
+	})
+
+	t.Run("too many operations", func(t *testing.T) {
+		_, _, svc := setup()
+		if _, err := svc.Batch(ctx, "owner", make([]BatchOperation, MaxBatchOperations+1), false); !errors.Is(err, ErrBatchTooLarge) {
+			t.Errorf("expected ErrBatchTooLarge, got %v", err)
+		}
+	})
+}
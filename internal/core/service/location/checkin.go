@@ -0,0 +1,86 @@
+package location
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/lafetz/weavo/internal/core/domain"
+)
+
+var (
+	ErrCheckInsOff     = errors.New("check-ins are not enabled")
+	ErrInvalidDateSpan = errors.New("after must be earlier than before")
+)
+
+// WithCheckIns lets users check in at their locations, recording the
+// weather from weather at the time. Without it every check-in call fails
+// with ErrCheckInsOff.
+func WithCheckIns(repo CheckInRepo, weather WeatherSource) Option {
+	return func(s *Service) {
+		s.checkIns = repo
+		s.weather = weather
+	}
+}
+
+// CheckIn records a visit to one of the user's locations, with the current
+// weather in its city. A check-in is still recorded when the weather cannot
+// be looked up, just without it.
+func (s *Service) CheckIn(ctx context.Context, locationID, userID, comment string) (domain.CheckIn, error) {
+	if s.checkIns == nil {
+		return domain.CheckIn{}, ErrCheckInsOff
+	}
+	loc, err := s.authorizedLocation(ctx, locationID, userID, permWrite)
+	if err != nil {
+		return domain.CheckIn{}, err
+	}
+	checkIn := domain.CheckIn{
+		LocationID: loc.Id,
+		UserID:     loc.UserID,
+		Comment:    comment,
+		City:       loc.City,
+		Nickname:   loc.Nickname,
+		CreatedAt:  time.Now(),
+	}
+	if weather, err := s.weather.GetWeather(ctx, loc.City); err == nil {
+		checkIn.Weather = &weather
+	}
+	return s.checkIns.CreateCheckIn(ctx, checkIn)
+}
+
+// GetCheckIns lists the check-ins at a location the user may read, newest
+// first.
+func (s *Service) GetCheckIns(ctx context.Context, locationID, userID string) ([]domain.CheckIn, error) {
+	if s.checkIns == nil {
+		return nil, ErrCheckInsOff
+	}
+	if _, err := s.authorizedLocation(ctx, locationID, userID, permRead); err != nil {
+		return nil, err
+	}
+	return s.checkIns.GetCheckIns(ctx, locationID)
+}
+
+// GetTimeline lists the user's check-ins at all of their locations, newest
+// first, optionally limited to those between filter.After and
+// filter.Before.
+func (s *Service) GetTimeline(ctx context.Context, userID string, filter CheckInFilter) ([]domain.CheckIn, domain.Metadata, error) {
+	if s.checkIns == nil {
+		return nil, domain.Metadata{}, ErrCheckInsOff
+	}
+	if filter.Page < 1 || filter.PageSize < 1 {
+		return nil, domain.Metadata{}, ErrInvalidPage
+	}
+	if !filter.After.IsZero() && !filter.Before.IsZero() && !filter.After.Before(filter.Before) {
+		return nil, domain.Metadata{}, ErrInvalidDateSpan
+	}
+	return s.checkIns.GetTimeline(ctx, userID, filter)
+}
+
+// removeCheckIns deletes the check-ins at locations that have been removed
+// permanently. It is registered with the repository's OnRemove.
+func (s *Service) removeCheckIns(ids []string) {
+	ctx := context.Background()
+	for _, id := range ids {
+		s.checkIns.DeleteCheckIns(ctx, id)
+	}
+}
@@ -0,0 +1,113 @@
+package location
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/lafetz/weavo/internal/core/domain"
+)
+
+type mockCheckIns struct {
+	checkIns []domain.CheckIn
+}
+
+func (m *mockCheckIns) CreateCheckIn(ctx context.Context, c domain.CheckIn) (domain.CheckIn, error) {
+	c.Id = c.LocationID + "-" + c.Comment
+	m.checkIns = append(m.checkIns, c)
+	return c, nil
+}
+
+func (m *mockCheckIns) GetCheckIns(ctx context.Context, locationID string) ([]domain.CheckIn, error) {
+	found := []domain.CheckIn{}
+	for _, c := range m.checkIns {
+		if c.LocationID == locationID {
+			found = append(found, c)
+		}
+	}
+	return found, nil
+}
+
+func (m *mockCheckIns) GetTimeline(ctx context.Context, userID string, filter CheckInFilter) ([]domain.CheckIn, domain.Metadata, error) {
+	return m.checkIns, domain.Metadata{}, nil
+}
+
+func (m *mockCheckIns) DeleteCheckIns(ctx context.Context, locationID string) error {
+	return nil
+}
+
+func (m *mockCheckIns) MoveCheckIns(ctx context.Context, fromUserID, toUserID string) error {
+	for i := range m.checkIns {
+		if m.checkIns[i].UserID == fromUserID {
+			m.checkIns[i].UserID = toUserID
+		}
+	}
+	return nil
+}
+
+// mockWeather reports a fixed temperature for Paris and fails for anywhere
+// else.
+type mockWeather struct {
+	temperature float64
+}
+
+func (m *mockWeather) GetWeather(ctx context.Context, city string) (domain.Weather, error) {
+	if city != "Paris" {
+		return domain.Weather{}, errors.New("city not found")
+	}
+	return domain.Weather{Location: city, Temperature: m.temperature}, nil
+}
+
+func TestCheckIns(t *testing.T) {
+	ctx := context.Background()
+	repo := newMockRepo()
+	repo.locations["a"] = domain.Location{Id: "a", UserID: "owner", City: "Paris", Nickname: "Home"}
+	repo.locations["b"] = domain.Location{Id: "b", UserID: "owner", City: "Atlantis"}
+	checkIns, weather := &mockCheckIns{}, &mockWeather{temperature: 18}
+	svc := NewService(repo, WithCheckIns(checkIns, weather))
+
+	first, err := svc.CheckIn(ctx, "a", "owner", "morning")
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if first.Weather == nil || first.Weather.Temperature != 18 || first.City != "Paris" || first.Nickname != "Home" {
+		t.Fatalf("unexpected check-in %+v", first)
+	}
+
+	// The weather is stored with the check-in and never looked up again.
+	weather.temperature = 25
+	list, err := svc.GetCheckIns(ctx, "a", "owner")
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if len(list) != 1 || list[0].Weather.Temperature != 18 {
+		t.Errorf("expected the snapshot from check-in time, got %+v", list)
+	}
+
+	noWeather, err := svc.CheckIn(ctx, "b", "owner", "")
+	if err != nil {
+		t.Fatalf("expected a check-in without weather to succeed, got %v", err)
+	}
+	if noWeather.Weather != nil {
+		t.Errorf("expected no weather, got %+v", noWeather.Weather)
+	}
+
+	if _, err := svc.CheckIn(ctx, "a", "intruder", ""); !errors.Is(err, ErrLocationNotFound) {
+		t.Errorf("expected ErrLocationNotFound, got %v", err)
+	}
+	if _, err := svc.CheckIn(ctx, "missing", "owner", ""); !errors.Is(err, ErrLocationNotFound) {
+		t.Errorf("expected ErrLocationNotFound, got %v", err)
+	}
+
+	now := time.Now()
+	if _, _, err := svc.GetTimeline(ctx, "owner", CheckInFilter{Page: 1, PageSize: 10, After: now, Before: now}); !errors.Is(err, ErrInvalidDateSpan) {
+		t.Errorf("expected ErrInvalidDateSpan, got %v", err)
+	}
+	if _, _, err := svc.GetTimeline(ctx, "owner", CheckInFilter{}); !errors.Is(err, ErrInvalidPage) {
+		t.Errorf("expected ErrInvalidPage, got %v", err)
+	}
+	if _, err := NewService(repo).CheckIn(ctx, "a", "owner", ""); !errors.Is(err, ErrCheckInsOff) {
+		t.Errorf("expected ErrCheckInsOff, got %v", err)
+	}
+}
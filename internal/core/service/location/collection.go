@@ -0,0 +1,95 @@
+package location
+
+import (
+	"context"
+	"errors"
+	"strings"
+
+	"github.com/lafetz/weavo/internal/core/domain"
+)
+
+var ErrCollectionNotFound = errors.New("collection not found")
+
+func (s *Service) CreateCollection(ctx context.Context, collection domain.Collection) (domain.Collection, error) {
+	collection.Name = strings.TrimSpace(collection.Name)
+	return s.repo.CreateCollection(ctx, collection)
+}
+
+func (s *Service) GetCollection(ctx context.Context, id string, userID string) (domain.Collection, error) {
+	collection, err := s.repo.GetCollection(ctx, id)
+	if err != nil {
+		return domain.Collection{}, err
+	}
+	if collection.UserID != userID {
+		return domain.Collection{}, ErrUnAuthorized
+	}
+	return collection, nil
+}
+
+func (s *Service) GetCollections(ctx context.Context, userID string) ([]domain.Collection, error) {
+	return s.repo.GetCollections(ctx, userID)
+}
+
+func (s *Service) UpdateCollection(ctx context.Context, collection domain.Collection) (domain.Collection, error) {
+	if _, err := s.GetCollection(ctx, collection.Id, collection.UserID); err != nil {
+		return domain.Collection{}, err
+	}
+	collection.Name = strings.TrimSpace(collection.Name)
+	return s.repo.UpdateCollection(ctx, collection)
+}
+
+func (s *Service) DeleteCollection(ctx context.Context, id string, userID string) error {
+	if _, err := s.GetCollection(ctx, id, userID); err != nil {
+		return err
+	}
+	return s.repo.DeleteCollection(ctx, id)
+}
+
+func (s *Service) AddToCollection(ctx context.Context, collectionID, locationID, userID string) error {
+	if err := s.checkMembershipOwner(ctx, collectionID, locationID, userID); err != nil {
+		return err
+	}
+	return s.repo.AddToCollection(ctx, collectionID, locationID)
+}
+
+func (s *Service) RemoveFromCollection(ctx context.Context, collectionID, locationID, userID string) error {
+	if err := s.checkMembershipOwner(ctx, collectionID, locationID, userID); err != nil {
+		return err
+	}
+	return s.repo.RemoveFromCollection(ctx, collectionID, locationID)
+}
+
+func (s *Service) checkMembershipOwner(ctx context.Context, collectionID, locationID, userID string) error {
+	if _, err := s.GetCollection(ctx, collectionID, userID); err != nil {
+		return err
+	}
+	loc, err := s.repo.GetLocation(ctx, locationID)
+	if err != nil {
+		return err
+	}
+	// Only the owner's own locations can go into their collections, so an
+	// admin gets no further here than anyone else.
+	if loc.UserID != userID {
+		return ErrUnAuthorized
+	}
+	return nil
+}
+
+// normalizeTags trims and lower-cases tags and drops empty and duplicate
+// entries, keeping the first occurrence's position.
+func normalizeTags(tags []string) []string {
+	if len(tags) == 0 {
+		return nil
+	}
+	seen := make(map[string]bool, len(tags))
+	normalized := make([]string, 0, len(tags))
+	for _, tag := range tags {
+		tag = strings.ToLower(strings.TrimSpace(tag))
+		if tag == "" || seen[tag] {
+			continue
+		}
+		seen[tag] = true
+		normalized = append(normalized, tag)
+	}
+	return normalized
+}
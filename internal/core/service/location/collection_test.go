@@ -0,0 +1,50 @@
+package location
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/lafetz/weavo/internal/core/domain"
+)
+
+func TestCollectionOwnership(t *testing.T) {
+	repo := newMockRepo()
+	svc := NewService(repo)
+	ctx := context.Background()
+
+	collection, _ := svc.CreateCollection(ctx, domain.Collection{UserID: "owner", Name: "  Family "})
+	if collection.Name != "Family" {
+		t.Fatalf("expected trimmed name, got %q", collection.Name)
+	}
+	own, _ := svc.CreateLocation(ctx, domain.Location{Id: "own", UserID: "owner"}, false)
+	other, _ := svc.CreateLocation(ctx, domain.Location{Id: "other", UserID: "someone-else"}, false)
+
+	if _, err := svc.GetCollection(ctx, collection.Id, "someone-else"); !errors.Is(err, ErrUnAuthorized) {
+		t.Fatalf("expected error %v, got %v", ErrUnAuthorized, err)
+	}
+	if err := svc.DeleteCollection(ctx, collection.Id, "someone-else"); !errors.Is(err, ErrUnAuthorized) {
+		t.Fatalf("expected error %v, got %v", ErrUnAuthorized, err)
+	}
+	if err := svc.AddToCollection(ctx, collection.Id, other.Id, "owner"); !errors.Is(err, ErrUnAuthorized) {
+		t.Fatalf("expected error %v adding another user's location, got %v", ErrUnAuthorized, err)
+	}
+	if err := svc.AddToCollection(ctx, collection.Id, own.Id, "owner"); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if err := svc.AddToCollection(ctx, "missing", own.Id, "owner"); !errors.Is(err, ErrCollectionNotFound) {
+		t.Fatalf("expected error %v, got %v", ErrCollectionNotFound, err)
+	}
+}
+
+func TestNormalizeTags(t *testing.T) {
+	got := normalizeTags([]string{" Ski ", "family", "ski", "", "FAMILY", "beach"})
+	expected := []string{"ski", "family", "beach"}
+	if !reflect.DeepEqual(got, expected) {
+		t.Fatalf("expected %v, got %v", expected, got)
+	}
+	if normalizeTags(nil) != nil {
+		t.Fatalf("expected nil for no tags")
+	}
+}
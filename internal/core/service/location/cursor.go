@@ -0,0 +1,66 @@
+package location
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// cursor is the payload of the opaque pagination token. It is signed so
+// that clients cannot forge positions, and it carries a fingerprint of the
+// filter it was issued for so it cannot be replayed against another listing.
+type cursor struct {
+	Position Position `json:"p"`
+	Backward bool     `json:"b,omitempty"`
+	Filter   string   `json:"f"`
+}
+
+// fingerprint summarizes the parts of a filter that determine which
+// locations are listed and in what order.
+func (f Filter) fingerprint() string {
+	h := sha256.New()
+	fmt.Fprintf(h, "%s|%s|%s|%s|%s|%s|%s|%s",
+		f.SortBy, f.SortDir, strings.ToLower(f.City), strings.ToLower(f.Query),
+		f.CreatedAfter.Format(time.RFC3339Nano), f.CreatedBefore.Format(time.RFC3339Nano),
+		strings.ToLower(f.Tag), f.Collection)
+	if f.Box != nil {
+		fmt.Fprintf(h, "|%v", *f.Box)
+	}
+	return base64.RawURLEncoding.EncodeToString(h.Sum(nil)[:12])
+}
+
+func (s *Service) encodeCursor(c cursor) string {
+	payload, _ := json.Marshal(c)
+	mac := hmac.New(sha256.New, s.cursorKey)
+	mac.Write(payload)
+	return base64.RawURLEncoding.EncodeToString(payload) + "." + base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+func (s *Service) decodeCursor(token string) (cursor, error) {
+	payloadPart, sigPart, found := strings.Cut(token, ".")
+	if !found {
+		return cursor{}, ErrInvalidCursor
+	}
+	payload, err := base64.RawURLEncoding.DecodeString(payloadPart)
+	if err != nil {
+		return cursor{}, ErrInvalidCursor
+	}
+	sig, err := base64.RawURLEncoding.DecodeString(sigPart)
+	if err != nil {
+		return cursor{}, ErrInvalidCursor
+	}
+	mac := hmac.New(sha256.New, s.cursorKey)
+	mac.Write(payload)
+	if !hmac.Equal(sig, mac.Sum(nil)) {
+		return cursor{}, ErrInvalidCursor
+	}
+	var c cursor
+	if err := json.Unmarshal(payload, &c); err != nil {
+		return cursor{}, ErrInvalidCursor
+	}
+	return c, nil
+}
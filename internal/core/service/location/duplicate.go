@@ -0,0 +1,127 @@
+package location
+
+import (
+	"context"
+	"errors"
+	"strings"
+
+	"github.com/lafetz/weavo/internal/core/domain"
+)
+
+// defaultDuplicateRadiusKm is how close two locations in the same city
+// must be to count as the same place unless WithDuplicateRadius says
+// otherwise.
+const defaultDuplicateRadiusKm = 0.1
+
+var ErrDuplicateLocation = errors.New("location looks like a duplicate of an existing one")
+
+// DuplicateError is returned when a new location looks like one the user
+// already has. It matches ErrDuplicateLocation.
+type DuplicateError struct {
+	Matches []domain.Location
+}
+
+func (e *DuplicateError) Error() string {
+	return ErrDuplicateLocation.Error()
+}
+
+func (e *DuplicateError) Is(target error) bool {
+	return target == ErrDuplicateLocation
+}
+
+// WithDuplicateRadius sets how close, in kilometres, two locations in the
+// same city must be to count as likely duplicates.
+func WithDuplicateRadius(km float64) Option {
+	return func(s *Service) {
+		s.duplicateRadiusKm = km
+	}
+}
+
+// normalizeCity folds case and spacing so that "New  York" and "new york"
+// compare equal.
+func normalizeCity(city string) string {
+	return strings.ToLower(strings.Join(strings.Fields(city), " "))
+}
+
+// isDuplicate reports whether a and b are likely the same place: in the
+// same city and within the duplicate radius of each other.
+func (s *Service) isDuplicate(a, b domain.Location) bool {
+	return normalizeCity(a.City) == normalizeCity(b.City) &&
+		domain.DistanceKm(a.Coordinates, b.Coordinates) <= s.duplicateRadiusKm
+}
+
+// findDuplicatesOf returns the user's locations that loc is likely a
+// duplicate of, closest first.
+func (s *Service) findDuplicatesOf(ctx context.Context, loc domain.Location) ([]domain.Location, error) {
+	nearby, err := s.repo.FindNearby(ctx, loc.UserID, NearbyQuery{Center: loc.Coordinates, RadiusKm: s.duplicateRadiusKm})
+	if err != nil {
+		return nil, err
+	}
+	var matches []domain.Location
+	for _, n := range nearby {
+		if s.isDuplicate(loc, n.Location) {
+			matches = append(matches, n.Location)
+		}
+	}
+	return matches, nil
+}
+
+// FindDuplicates groups the user's locations that are likely duplicates of
+// each other. Locations are grouped transitively, so every member of a
+// group is a likely duplicate of at least one other member. Groups, and the
+// locations in them, follow the order of the user's list.
+func (s *Service) FindDuplicates(ctx context.Context, userID string) ([][]domain.Location, error) {
+	byCity := make(map[string][]int)
+	var locations []domain.Location
+	err := s.ExportLocations(ctx, userID, Filter{}, func(loc domain.Location) error {
+		city := normalizeCity(loc.City)
+		byCity[city] = append(byCity[city], len(locations))
+		locations = append(locations, loc)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// Union-find over the locations of each city.
+	parent := make([]int, len(locations))
+	for i := range parent {
+		parent[i] = i
+	}
+	var find func(int) int
+	find = func(i int) int {
+		if parent[i] != i {
+			parent[i] = find(parent[i])
+		}
+		return parent[i]
+	}
+	for _, members := range byCity {
+		for x, i := range members {
+			for _, j := range members[x+1:] {
+				if s.isDuplicate(locations[i], locations[j]) {
+					parent[find(j)] = find(i)
+				}
+			}
+		}
+	}
+
+	groupOf := make(map[int]int)
+	var groups [][]domain.Location
+	for i, loc := range locations {
+		root := find(i)
+		g, exists := groupOf[root]
+		if !exists {
+			g = len(groups)
+			groupOf[root] = g
+			groups = append(groups, nil)
+		}
+		groups[g] = append(groups[g], loc)
+	}
+	duplicates := [][]domain.Location{}
+	for _, group := range groups {
+		if len(group) > 1 {
+			duplicates = append(duplicates, group)
+		}
+	}
+	return duplicates, nil
+}
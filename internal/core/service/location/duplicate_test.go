@@ -0,0 +1,81 @@
+package location
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/lafetz/weavo/internal/core/domain"
+)
+
+func TestCreateLocationDetectsDuplicates(t *testing.T) {
+	ctx := context.Background()
+	repo := newMockRepo()
+	repo.locations["louvre"] = domain.Location{Id: "louvre", UserID: "owner", City: "Paris", Coordinates: domain.Coordinates{Lat: 48.8606, Lon: 2.3376}}
+	svc := NewService(repo)
+
+	tests := []struct {
+		name     string
+		loc      domain.Location
+		force    bool
+		expected error
+	}{
+		{"same place, different spelling", domain.Location{UserID: "owner", City: " paris ", Coordinates: domain.Coordinates{Lat: 48.8610, Lon: 2.3380}}, false, ErrDuplicateLocation},
+		{"forced", domain.Location{UserID: "owner", City: "Paris", Coordinates: domain.Coordinates{Lat: 48.8606, Lon: 2.3376}}, true, nil},
+		{"too far", domain.Location{UserID: "owner", City: "Paris", Coordinates: domain.Coordinates{Lat: 48.8738, Lon: 2.2950}}, false, nil},
+		{"another city", domain.Location{UserID: "owner", City: "Lyon", Coordinates: domain.Coordinates{Lat: 48.8606, Lon: 2.3376}}, false, nil},
+		{"another user", domain.Location{UserID: "someone-else", City: "Paris", Coordinates: domain.Coordinates{Lat: 48.8606, Lon: 2.3376}}, false, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := svc.CreateLocation(ctx, tt.loc, tt.force)
+			if !errors.Is(err, tt.expected) {
+				t.Fatalf("expected %v, got %v", tt.expected, err)
+			}
+			var dup *DuplicateError
+			if errors.As(err, &dup) && (len(dup.Matches) != 1 || dup.Matches[0].Id != "louvre") {
+				t.Errorf("expected the louvre as the only match, got %+v", dup.Matches)
+			}
+		})
+	}
+
+	t.Run("configurable radius", func(t *testing.T) {
+		svc := NewService(repo, WithDuplicateRadius(5))
+		_, err := svc.CreateLocation(ctx, domain.Location{UserID: "owner", City: "Paris", Coordinates: domain.Coordinates{Lat: 48.8738, Lon: 2.2950}}, false)
+		if !errors.Is(err, ErrDuplicateLocation) {
+			t.Fatalf("expected a duplicate within 5 km, got %v", err)
+		}
+	})
+}
+
+func TestFindDuplicates(t *testing.T) {
+	repo := newMockRepo()
+	add := func(id, city string, lat, lon float64, position int) {
+		repo.locations[id] = domain.Location{Id: id, UserID: "owner", City: city, Coordinates: domain.Coordinates{Lat: lat, Lon: lon}, Position: position}
+	}
+	// a-b and b-c are within 100 m of each other, a-c are not.
+	add("a", "Paris", 48.8600, 2.3400, 0)
+	add("b", "PARIS", 48.8607, 2.3400, 1)
+	add("c", "Paris", 48.8614, 2.3400, 2)
+	add("d", "Paris", 48.9, 2.4, 3)
+	add("e", "Lyon", 45.76, 4.83, 4)
+	add("f", "Lyon", 45.76, 4.83, 5)
+	repo.locations["g"] = domain.Location{Id: "g", UserID: "someone-else", City: "Lyon", Coordinates: domain.Coordinates{Lat: 45.76, Lon: 4.83}}
+	svc := NewService(repo)
+
+	groups, err := svc.FindDuplicates(context.Background(), "owner")
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	var ids [][]string
+	for _, group := range groups {
+		var g []string
+		for _, loc := range group {
+			g = append(g, loc.Id)
+		}
+		ids = append(ids, g)
+	}
+	if len(ids) != 2 || len(ids[0]) != 3 || ids[0][0] != "a" || ids[0][2] != "c" || len(ids[1]) != 2 || ids[1][0] != "e" {
+		t.Fatalf("expected groups [a b c] and [e f], got %v", ids)
+	}
+}
@@ -0,0 +1,113 @@
+package location
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/lafetz/weavo/internal/core/domain"
+)
+
+// Matches reports whether loc satisfies every condition set on the filter.
+// Repositories that cannot push the conditions down to their storage can use
+// it to filter in memory.
+func (f Filter) Matches(loc domain.Location) bool {
+	if f.City != "" && !strings.EqualFold(loc.City, f.City) {
+		return false
+	}
+	if f.Query != "" {
+		q := strings.ToLower(f.Query)
+		if !strings.Contains(strings.ToLower(loc.Nickname), q) &&
+			!strings.Contains(strings.ToLower(loc.Notes), q) {
+			return false
+		}
+	}
+	if !f.CreatedAfter.IsZero() && !loc.CreatedAt.After(f.CreatedAfter) {
+		return false
+	}
+	if !f.CreatedBefore.IsZero() && !loc.CreatedAt.Before(f.CreatedBefore) {
+		return false
+	}
+	if f.Box != nil && !f.Box.Contains(loc.Coordinates) {
+		return false
+	}
+	if f.Tag != "" && !loc.HasTag(f.Tag) {
+		return false
+	}
+	if f.Collection != "" && !loc.InCollection(f.Collection) {
+		return false
+	}
+	return true
+}
+
+// PositionOf returns the sort position of loc under the filter's ordering.
+func (f Filter) PositionOf(loc domain.Location) Position {
+	pos := Position{CreatedAt: loc.CreatedAt, Id: loc.Id}
+	switch f.SortBy {
+	case SortByPosition:
+		// Encode pinned-first, then position, as a string that sorts the
+		// same way.
+		unpinned := 1
+		if loc.Pinned {
+			unpinned = 0
+		}
+		pos.SortKey = fmt.Sprintf("%d%019d", unpinned, loc.Position)
+	case SortByNickname:
+		pos.SortKey = strings.ToLower(loc.Nickname)
+	case SortByCity:
+		pos.SortKey = strings.ToLower(loc.City)
+	}
+	return pos
+}
+
+// compare orders two positions in listing order. Ties on the sort field are
+// broken by creation time and then by ID so that the order is total.
+func (f Filter) compare(a, b Position) int {
+	c := strings.Compare(a.SortKey, b.SortKey)
+	if c == 0 {
+		c = a.CreatedAt.Compare(b.CreatedAt)
+	}
+	if c == 0 {
+		c = strings.Compare(a.Id, b.Id)
+	}
+	if f.SortDir == SortDesc {
+		c = -c
+	}
+	return c
+}
+
+// Sort orders locations by the filter's sort field and direction. The order
+// is total, so pages stay stable between calls.
+func (f Filter) Sort(locations []domain.Location) {
+	sort.Slice(locations, func(i, j int) bool {
+		return f.compare(f.PositionOf(locations[i]), f.PositionOf(locations[j])) < 0
+	})
+}
+
+// Window returns the keyset page of an already sorted slice: at most Limit
+// locations strictly after f.After, or the Limit locations immediately
+// before f.Before.
+func (f Filter) Window(sorted []domain.Location) []domain.Location {
+	start, end := 0, len(sorted)
+	if f.After != nil {
+		start = sort.Search(len(sorted), func(i int) bool {
+			return f.compare(f.PositionOf(sorted[i]), *f.After) > 0
+		})
+	}
+	if f.Before != nil {
+		end = sort.Search(len(sorted), func(i int) bool {
+			return f.compare(f.PositionOf(sorted[i]), *f.Before) >= 0
+		})
+		if end < start {
+			end = start
+		}
+		if f.Limit > 0 && end-start > f.Limit {
+			start = end - f.Limit
+		}
+		return sorted[start:end]
+	}
+	if f.Limit > 0 && end-start > f.Limit {
+		end = start + f.Limit
+	}
+	return sorted[start:end]
+}
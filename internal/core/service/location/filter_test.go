@@ -0,0 +1,77 @@
+package location
+
+import (
+	"testing"
+	"time"
+
+	"github.com/lafetz/weavo/internal/core/domain"
+)
+
+func TestFilterMatches(t *testing.T) {
+	created := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	loc := domain.Location{
+		Nickname:  "Beach House",
+		Notes:     "Bring sunscreen",
+		City:      "Lisbon",
+		CreatedAt: created,
+	}
+
+	tests := []struct {
+		name     string
+		filter   Filter
+		expected bool
+	}{
+		{"empty filter", Filter{}, true},
+		{"city equals ignoring case", Filter{City: "lisbon"}, true},
+		{"different city", Filter{City: "Porto"}, false},
+		{"query in nickname", Filter{Query: "beach"}, true},
+		{"query in notes", Filter{Query: "SUNSCREEN"}, true},
+		{"query not found", Filter{Query: "ski"}, false},
+		{"created after", Filter{CreatedAfter: created.Add(-time.Hour)}, true},
+		{"created after is exclusive", Filter{CreatedAfter: created}, false},
+		{"created before", Filter{CreatedBefore: created.Add(time.Hour)}, true},
+		{"created before is exclusive", Filter{CreatedBefore: created}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.filter.Matches(loc); got != tt.expected {
+				t.Errorf("expected %v, got %v", tt.expected, got)
+			}
+		})
+	}
+}
+
+func TestFilterSort(t *testing.T) {
+	base := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	locations := func() []domain.Location {
+		return []domain.Location{
+			{Id: "c", Nickname: "beta", City: "Oslo", CreatedAt: base},
+			{Id: "a", Nickname: "Alpha", City: "Oslo", CreatedAt: base},
+			{Id: "b", Nickname: "gamma", City: "Bergen", CreatedAt: base.Add(time.Hour)},
+		}
+	}
+
+	tests := []struct {
+		name     string
+		filter   Filter
+		expected []string
+	}{
+		{"created_at asc breaks ties by id", Filter{SortBy: SortByCreatedAt, SortDir: SortAsc}, []string{"a", "c", "b"}},
+		{"created_at desc", Filter{SortBy: SortByCreatedAt, SortDir: SortDesc}, []string{"b", "c", "a"}},
+		{"nickname ignores case", Filter{SortBy: SortByNickname, SortDir: SortAsc}, []string{"a", "c", "b"}},
+		{"city asc", Filter{SortBy: SortByCity, SortDir: SortAsc}, []string{"b", "a", "c"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			locs := locations()
+			tt.filter.Sort(locs)
+			for i, id := range tt.expected {
+				if locs[i].Id != id {
+					t.Fatalf("expected order %v, got %v at position %d", tt.expected, locs[i].Id, i)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,178 @@
+package location
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"time"
+
+	"github.com/lafetz/weavo/internal/core/domain"
+)
+
+// nopHistory is the HistoryRepo of a service without history.
+type nopHistory struct{}
+
+func (nopHistory) AppendHistory(ctx context.Context, entry domain.HistoryEntry) error {
+	return nil
+}
+
+func (nopHistory) GetHistory(ctx context.Context, locationID string) ([]domain.HistoryEntry, error) {
+	return []domain.HistoryEntry{}, nil
+}
+
+func (nopHistory) DeleteHistory(ctx context.Context, locationID string) error {
+	return nil
+}
+
+// removeHistory drops the history of locations that have been removed
+// permanently. It is registered with LocationRepo.OnRemove.
+func (s *Service) removeHistory(ids []string) {
+	ctx := context.Background()
+	for _, id := range ids {
+		s.history.DeleteHistory(ctx, id)
+	}
+}
+
+type pendingHistoryKey struct{}
+
+// pendingHistory collects the entries recorded during a transaction.
+type pendingHistory struct {
+	entries []domain.HistoryEntry
+}
+
+// deferHistory returns a copy of ctx in which record collects entries
+// instead of appending them, and a function that appends the collected
+// entries once the changes they describe are committed.
+func (s *Service) deferHistory(ctx context.Context) (context.Context, func()) {
+	pending := &pendingHistory{}
+	flush := func() {
+		for _, entry := range pending.entries {
+			s.appendHistory(ctx, entry)
+		}
+	}
+	return context.WithValue(ctx, pendingHistoryKey{}, pending), flush
+}
+
+// record appends a history entry for a change that turned before into
+// after.
+func (s *Service) record(ctx context.Context, action domain.HistoryAction, actorID string, before, after domain.Location) {
+	entry := domain.HistoryEntry{
+		LocationID: after.Id,
+		UserID:     after.UserID,
+		ActorID:    actorID,
+		RequestID:  domain.RequestIDFromContext(ctx),
+		Action:     action,
+		Version:    after.Version,
+		At:         time.Now(),
+		Changes:    diffLocations(before, after),
+		Snapshot:   after,
+	}
+	if pending, ok := ctx.Value(pendingHistoryKey{}).(*pendingHistory); ok {
+		pending.entries = append(pending.entries, entry)
+		return
+	}
+	s.appendHistory(ctx, entry)
+}
+
+// appendHistory saves an entry for a change that has already been
+// committed. Failing the request then would report a saved change as failed
+// and invite a retry that makes it again, so a failure is only logged and
+// the entry is lost.
+func (s *Service) appendHistory(ctx context.Context, entry domain.HistoryEntry) {
+	if err := s.history.AppendHistory(ctx, entry); err != nil {
+		s.logger.Error("error recording history", "location_id", entry.LocationID, "version", entry.Version, "error", err)
+	}
+}
+
+// diffLocations lists the user-editable fields that differ between two
+// versions of a location.
+func diffLocations(before, after domain.Location) []domain.FieldChange {
+	var changes []domain.FieldChange
+	add := func(field string, old, new any, changed bool) {
+		if !changed {
+			return
+		}
+		if before.Id == "" {
+			old = nil
+		}
+		changes = append(changes, domain.FieldChange{Field: field, Old: old, New: new})
+	}
+	add("nickname", before.Nickname, after.Nickname, before.Nickname != after.Nickname)
+	add("notes", before.Notes, after.Notes, before.Notes != after.Notes)
+	add("city", before.City, after.City, before.City != after.City)
+	add("lat", before.Coordinates.Lat, after.Coordinates.Lat, before.Coordinates.Lat != after.Coordinates.Lat)
+	add("lon", before.Coordinates.Lon, after.Coordinates.Lon, before.Coordinates.Lon != after.Coordinates.Lon)
+	add("tags", before.Tags, after.Tags, !slices.Equal(before.Tags, after.Tags))
+	add("pinned", before.Pinned, after.Pinned, before.Pinned != after.Pinned)
+	return changes
+}
+
+// GetHistory returns the changes made to one of the user's locations,
+// oldest first. History stays available after the location is deleted.
+func (s *Service) GetHistory(ctx context.Context, id string, userID string) ([]domain.HistoryEntry, error) {
+	entries, err := s.history.GetHistory(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if len(entries) == 0 {
+		return nil, ErrLocationNotFound
+	}
+	loc, err := s.historySubject(ctx, id, userID, entries[len(entries)-1].Snapshot)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := s.authorize(ctx, userID, loc, permReadHistory); err != nil {
+		return nil, err
+	}
+	return entries, nil
+}
+
+// historySubject returns the location to authorize a read of id's history
+// against. Snapshots keep the owner at the time of the change, which is
+// stale once the location has moved to another account, so the live
+// location is preferred, then userID's trashed one. The latest snapshot
+// only stands in when neither exists.
+func (s *Service) historySubject(ctx context.Context, id, userID string, latest domain.Location) (domain.Location, error) {
+	loc, err := s.repo.GetLocation(ctx, id)
+	if err == nil {
+		return loc, nil
+	}
+	if !errors.Is(err, ErrLocationNotFound) {
+		return domain.Location{}, err
+	}
+	trash, err := s.repo.GetTrashedLocations(ctx, userID)
+	if err != nil {
+		return domain.Location{}, err
+	}
+	for _, loc := range trash {
+		if loc.Id == id {
+			return loc, nil
+		}
+	}
+	return latest, nil
+}
+
+// RevertLocation sets the editable fields of a location back to how they
+// were at toVersion. The revert is itself a change: it creates a new
+// version and a history entry. A non-zero ifVersion makes it conditional
+// on the current version like UpdateLocation.
+func (s *Service) RevertLocation(ctx context.Context, id string, userID string, toVersion int64, ifVersion int64) (domain.Location, error) {
+	entries, err := s.GetHistory(ctx, id, userID)
+	if err != nil {
+		return domain.Location{}, err
+	}
+	var target *domain.HistoryEntry
+	for i := range entries {
+		if entries[i].Version == toVersion && entries[i].Action != domain.HistoryDeleted {
+			target = &entries[i]
+		}
+	}
+	if target == nil {
+		return domain.Location{}, ErrVersionNotFound
+	}
+
+	loc := target.Snapshot
+	loc.UserID = userID // the actor; updateLocation checks they may write
+	loc.Version = ifVersion
+	return s.updateLocation(ctx, loc, domain.HistoryReverted)
+}
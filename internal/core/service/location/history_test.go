@@ -0,0 +1,134 @@
+package location
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"log/slog"
+	"strings"
+	"testing"
+
+	"github.com/lafetz/weavo/internal/core/domain"
+)
+
+type mockHistory struct {
+	entries map[string][]domain.HistoryEntry
+}
+
+func (m *mockHistory) AppendHistory(ctx context.Context, entry domain.HistoryEntry) error {
+	m.entries[entry.LocationID] = append(m.entries[entry.LocationID], entry)
+	return nil
+}
+
+func (m *mockHistory) GetHistory(ctx context.Context, locationID string) ([]domain.HistoryEntry, error) {
+	return m.entries[locationID], nil
+}
+
+func (m *mockHistory) DeleteHistory(ctx context.Context, locationID string) error {
+	delete(m.entries, locationID)
+	return nil
+}
+
+// versionedRepo bumps versions like a real repository so that history
+// entries can be told apart.
+type versionedRepo struct {
+	*mockRepo
+}
+
+func (r versionedRepo) CreateLocation(ctx context.Context, loc domain.Location) (domain.Location, error) {
+	loc.Version = 1
+	return r.mockRepo.CreateLocation(ctx, loc)
+}
+
+func (r versionedRepo) UpdateLocation(ctx context.Context, loc domain.Location) (domain.Location, error) {
+	current := r.locations[loc.Id]
+	if loc.Version != 0 && loc.Version != current.Version {
+		return domain.Location{}, ErrVersionMismatch
+	}
+	loc.Version = current.Version + 1
+	return r.mockRepo.UpdateLocation(ctx, loc)
+}
+
+func TestHistoryAndRevert(t *testing.T) {
+	history := &mockHistory{entries: make(map[string][]domain.HistoryEntry)}
+	svc := NewService(versionedRepo{newMockRepo()}, WithHistory(history))
+	ctx := domain.ContextWithRequestID(context.Background(), "req-1")
+
+	loc, err := svc.CreateLocation(ctx, domain.Location{UserID: "owner", Nickname: "Home", Notes: "first", City: "Paris"}, false)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	loc.Notes = "second"
+	if _, err := svc.UpdateLocation(ctx, loc); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	entries, err := svc.GetHistory(ctx, loc.Id, "owner")
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if len(entries) != 2 {
+		t.Fatalf("expected 2 entries, got %d", len(entries))
+	}
+	update := entries[1]
+	if update.Action != domain.HistoryUpdated || update.Version != 2 || update.ActorID != "owner" || update.RequestID != "req-1" {
+		t.Errorf("unexpected update entry: %+v", update)
+	}
+	if len(update.Changes) != 1 || update.Changes[0] != (domain.FieldChange{Field: "notes", Old: "first", New: "second"}) {
+		t.Errorf("expected only notes to change, got %+v", update.Changes)
+	}
+	if _, err := svc.GetHistory(ctx, loc.Id, "intruder"); !errors.Is(err, ErrLocationNotFound) {
+		t.Errorf("expected ErrLocationNotFound for another user, got %v", err)
+	}
+
+	if _, err := svc.RevertLocation(ctx, loc.Id, "owner", 1, 1); !errors.Is(err, ErrVersionMismatch) {
+		t.Errorf("expected ErrVersionMismatch for a stale version, got %v", err)
+	}
+	if _, err := svc.RevertLocation(ctx, loc.Id, "owner", 7, 0); !errors.Is(err, ErrVersionNotFound) {
+		t.Errorf("expected ErrVersionNotFound, got %v", err)
+	}
+	reverted, err := svc.RevertLocation(ctx, loc.Id, "owner", 1, 2)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if reverted.Notes != "first" || reverted.Version != 3 {
+		t.Errorf("expected version 3 with the original notes, got %+v", reverted)
+	}
+	entries, _ = svc.GetHistory(ctx, loc.Id, "owner")
+	if last := entries[len(entries)-1]; last.Action != domain.HistoryReverted {
+		t.Errorf("expected the revert to be recorded, got %s", last.Action)
+	}
+}
+
+// failingHistory fails every append.
+type failingHistory struct {
+	mockHistory
+}
+
+func (f *failingHistory) AppendHistory(ctx context.Context, entry domain.HistoryEntry) error {
+	return errors.New("history unavailable")
+}
+
+func TestHistoryFailuresDoNotFailChanges(t *testing.T) {
+	ctx := context.Background()
+	var logs bytes.Buffer
+	svc := NewService(versionedRepo{newMockRepo()},
+		WithHistory(&failingHistory{}),
+		WithLogger(slog.New(slog.NewTextHandler(&logs, nil))),
+	)
+
+	loc, err := svc.CreateLocation(ctx, domain.Location{UserID: "owner", City: "Paris"}, false)
+	if err != nil {
+		t.Fatalf("expected the create to succeed, got %v", err)
+	}
+	loc.Notes = "changed"
+	if _, err := svc.UpdateLocation(ctx, loc); err != nil {
+		t.Fatalf("expected the update to succeed, got %v", err)
+	}
+	if err := svc.DeleteLocation(ctx, loc.Id, "owner", 0); err != nil {
+		t.Fatalf("expected the delete to succeed, got %v", err)
+	}
+	if n := strings.Count(logs.String(), "error recording history"); n != 3 {
+		t.Errorf("expected 3 history failures to be logged, got %d:\n%s", n, logs.String())
+	}
+}
@@ -2,7 +2,10 @@ package location
 
 import (
 	"context"
+	"crypto/rand"
 	"errors"
+	"log/slog"
+	"sync"
 
 	"github.com/lafetz/weavo/internal/core/domain"
 )
@@ -10,47 +13,292 @@ import (
 var (
 	ErrLocationNotFound = errors.New("location not found")
 	ErrUnAuthorized     = errors.New("unauthorized")
+	ErrInvalidCursor    = errors.New("invalid cursor")
+	ErrInvalidPage      = errors.New("page and page size must be positive")
+	ErrInvalidRadius    = errors.New("radius must be positive")
+	ErrInvalidOrder     = errors.New("order must list each location at most once")
+	ErrVersionMismatch  = errors.New("location has been modified")
+	ErrVersionNotFound  = errors.New("version not found in the location's history")
 )
 
+const cursorKeyLength = 32
+
 type Service struct {
-	repo LocationRepo
+	repo      LocationRepo
+	history   HistoryRepo
+	cursorKey []byte
+	admins    map[string]bool
+	quota     Quota
+	logger    *slog.Logger
+
+	attachments AttachmentRepo
+	blobs       BlobStore
+	// attachmentMu makes counting a location's attachments and adding one
+	// atomic.
+	attachmentMu sync.Mutex
+
+	checkIns CheckInRepo
+	weather  WeatherSource
+
+	duplicateRadiusKm float64
+}
+
+type Option func(*Service)
+
+// WithCursorKey sets the key used to sign pagination cursors. Without it a
+// random key is generated, so cursors do not survive a restart and are not
+// shared between replicas.
+func WithCursorKey(key []byte) Option {
+	return func(s *Service) {
+		s.cursorKey = key
+	}
 }
 
-func NewService(repo LocationRepo) *Service {
-	return &Service{repo: repo}
+// WithLogger sets where failures that do not fail the request, such as
+// history that cannot be recorded, are logged. Without it slog.Default is
+// used.
+func WithLogger(logger *slog.Logger) Option {
+	return func(s *Service) {
+		s.logger = logger
+	}
 }
 
-func (s *Service) CreateLocation(ctx context.Context, location domain.Location) (domain.Location, error) {
-	return s.repo.CreateLocation(ctx, location)
+// WithHistory sets where the change history of locations is recorded.
+// Without it no history is kept.
+func WithHistory(history HistoryRepo) Option {
+	return func(s *Service) {
+		s.history = history
+	}
 }
 
-func (s *Service) GetLocation(ctx context.Context, id string) (domain.Location, error) {
-	return s.repo.GetLocation(ctx, id)
+func NewService(repo LocationRepo, opts ...Option) *Service {
+	s := &Service{
+		repo:              repo,
+		history:           nopHistory{},
+		logger:            slog.Default(),
+		admins:            make(map[string]bool),
+		duplicateRadiusKm: defaultDuplicateRadiusKm,
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+	if len(s.cursorKey) == 0 {
+		s.cursorKey = make([]byte, cursorKeyLength)
+		rand.Read(s.cursorKey)
+	}
+	repo.OnRemove(s.removeHistory)
+	if s.attachments != nil {
+		repo.OnRemove(s.removeAttachments)
+	}
+	if s.checkIns != nil {
+		repo.OnRemove(s.removeCheckIns)
+	}
+	return s
 }
 
+// CreateLocation saves a new location. Unless force is set, it fails with a
+// *DuplicateError when the user already has a location in the same city
+// within the duplicate radius. It fails with ErrLocationLimit or
+// ErrNotesTooLong when the location does not fit the user's quota.
+func (s *Service) CreateLocation(ctx context.Context, location domain.Location, force bool) (domain.Location, error) {
+	if err := s.checkNotes(location); err != nil {
+		return domain.Location{}, err
+	}
+	if !force {
+		matches, err := s.findDuplicatesOf(ctx, location)
+		if err != nil {
+			return domain.Location{}, err
+		}
+		if len(matches) > 0 {
+			return domain.Location{}, &DuplicateError{Matches: matches}
+		}
+	}
+	location.Tags = normalizeTags(location.Tags)
+	location.Collections = nil
+	var loc domain.Location
+	err := s.withinLocationLimit(ctx, location.UserID, func(ctx context.Context) error {
+		var err error
+		loc, err = s.repo.CreateLocation(ctx, location)
+		return err
+	})
+	if err != nil {
+		return domain.Location{}, err
+	}
+	s.record(ctx, domain.HistoryCreated, location.UserID, domain.Location{}, loc)
+	return loc, nil
+}
+
+// GetLocation returns a location its owner or an admin asked for, or one
+// covered by the share token in ctx. Through a share the coordinates are
+// reduced to the share's precision.
+func (s *Service) GetLocation(ctx context.Context, id string, userID string) (domain.Location, error) {
+	loc, err := s.repo.GetLocation(ctx, id)
+	if err != nil {
+		return domain.Location{}, err
+	}
+	share, err := s.authorize(ctx, userID, loc, permRead)
+	if err != nil {
+		return domain.Location{}, err
+	}
+	if share != nil {
+		loc = reducePrecision(loc, share.Precision)
+	}
+	return loc, nil
+}
+
+// GetLocations lists a user's locations. When filter.Cursor or filter.Limit
+// is set the listing uses keyset pagination and the returned metadata holds
+// the cursors for the neighbouring pages; otherwise Page/PageSize are used.
 func (s *Service) GetLocations(ctx context.Context, userID string, filter Filter) ([]domain.Location, domain.Metadata, error) {
-	return s.repo.GetLocations(ctx, userID, filter)
+	if filter.Cursor == "" && filter.Limit <= 0 {
+		return s.repo.GetLocations(ctx, userID, filter)
+	}
+	return s.getLocationsByCursor(ctx, userID, filter)
 }
 
+func (s *Service) getLocationsByCursor(ctx context.Context, userID string, filter Filter) ([]domain.Location, domain.Metadata, error) {
+	limit := filter.Limit
+	fingerprint := filter.fingerprint()
+	backward := false
+	if filter.Cursor != "" {
+		c, err := s.decodeCursor(filter.Cursor)
+		if err != nil {
+			return nil, domain.Metadata{}, err
+		}
+		if c.Filter != fingerprint {
+			return nil, domain.Metadata{}, ErrInvalidCursor
+		}
+		pos := c.Position
+		backward = c.Backward
+		if backward {
+			filter.Before = &pos
+		} else {
+			filter.After = &pos
+		}
+	}
+
+	// Ask for one extra record to learn whether another page exists.
+	filter.Limit = limit + 1
+	locations, meta, err := s.repo.GetLocations(ctx, userID, filter)
+	if err != nil {
+		return nil, domain.Metadata{}, err
+	}
+
+	more := len(locations) > limit
+	if more {
+		if backward {
+			locations = locations[1:]
+		} else {
+			locations = locations[:limit]
+		}
+	}
+	hasNext, hasPrev := more, filter.After != nil
+	if backward {
+		hasNext, hasPrev = true, more
+	}
+
+	metadata := domain.Metadata{
+		PageSize:     int32(limit),
+		TotalRecords: meta.TotalRecords,
+	}
+	if len(locations) > 0 {
+		if hasNext {
+			last := filter.PositionOf(locations[len(locations)-1])
+			metadata.NextCursor = s.encodeCursor(cursor{Position: last, Filter: fingerprint})
+		}
+		if hasPrev {
+			first := filter.PositionOf(locations[0])
+			metadata.PrevCursor = s.encodeCursor(cursor{Position: first, Backward: true, Filter: fingerprint})
+		}
+	}
+	return locations, metadata, nil
+}
+
+// UpdateLocation replaces a location on behalf of location.UserID, who must
+// be its owner or an admin. A non-zero location.Version makes the update
+// conditional on the stored version.
 func (s *Service) UpdateLocation(ctx context.Context, location domain.Location) (domain.Location, error) {
+	return s.updateLocation(ctx, location, domain.HistoryUpdated)
+}
+
+func (s *Service) updateLocation(ctx context.Context, location domain.Location, action domain.HistoryAction) (domain.Location, error) {
 	loc, err := s.repo.GetLocation(ctx, location.Id)
 	if err != nil {
 		return domain.Location{}, ErrLocationNotFound
 	}
-	if loc.UserID != location.UserID {
-		return domain.Location{}, ErrUnAuthorized
+	actorID := location.UserID
+	if _, err := s.authorize(ctx, actorID, loc, permWrite); err != nil {
+		return domain.Location{}, err
+	}
+	if err := s.checkNotes(location); err != nil {
+		return domain.Location{}, err
+	}
+	location.UserID = loc.UserID
+	location.Tags = normalizeTags(location.Tags)
+	updated, err := s.repo.UpdateLocation(ctx, location)
+	if err != nil {
+		return domain.Location{}, err
 	}
-	return s.repo.UpdateLocation(ctx, location)
+	s.record(ctx, action, actorID, loc, updated)
+	return updated, nil
 }
 
-func (s *Service) DeleteLocation(ctx context.Context, id string, userID string) error {
+// DeleteLocation moves a location to the trash. A non-zero version makes the
+// delete conditional on the stored version.
+func (s *Service) DeleteLocation(ctx context.Context, id string, userID string, version int64) error {
 	loc, err := s.repo.GetLocation(ctx, id)
 	if err != nil {
 		return ErrLocationNotFound
 	}
-	if loc.UserID != userID {
-		return ErrUnAuthorized
+	if _, err := s.authorize(ctx, userID, loc, permWrite); err != nil {
+		return err
+	}
+
+	deleted, err := s.repo.DeleteLocation(ctx, id, version)
+	if err != nil {
+		return err
 	}
+	s.record(ctx, domain.HistoryDeleted, userID, loc, deleted)
+	return nil
+}
+
+// GetTrash returns the user's deleted locations that have not been purged
+// yet.
+func (s *Service) GetTrash(ctx context.Context, userID string) ([]domain.Location, error) {
+	return s.repo.GetTrashedLocations(ctx, userID)
+}
 
-	return s.repo.DeleteLocation(ctx, id)
+// RestoreLocation takes a location out of the user's trash. Only the owner
+// can restore a location, since the trash is theirs. Restored locations
+// count towards the user's quota again.
+func (s *Service) RestoreLocation(ctx context.Context, id string, userID string) (domain.Location, error) {
+	var loc domain.Location
+	err := s.withinLocationLimit(ctx, userID, func(ctx context.Context) error {
+		var err error
+		loc, err = s.repo.RestoreLocation(ctx, userID, id)
+		return err
+	})
+	if err != nil {
+		return domain.Location{}, err
+	}
+	s.record(ctx, domain.HistoryRestored, userID, loc, loc)
+	return loc, nil
+}
+
+func (s *Service) FindNearby(ctx context.Context, userID string, query NearbyQuery) ([]NearbyLocation, error) {
+	if query.RadiusKm <= 0 {
+		return nil, ErrInvalidRadius
+	}
+	return s.repo.FindNearby(ctx, userID, query)
+}
+
+func (s *Service) ReorderLocations(ctx context.Context, userID string, ids []string) error {
+	seen := make(map[string]bool, len(ids))
+	for _, id := range ids {
+		if seen[id] {
+			return ErrInvalidOrder
+		}
+		seen[id] = true
+	}
+	return s.repo.ReorderLocations(ctx, userID, ids)
 }
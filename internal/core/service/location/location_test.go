@@ -0,0 +1,369 @@
+package location
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"maps"
+	"slices"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/lafetz/weavo/internal/core/domain"
+)
+
+type mockRepo struct {
+	locations   map[string]domain.Location
+	trash       map[string]domain.Location
+	collections map[string]domain.Collection
+	shares      map[string]domain.Share
+	created     int
+}
+
+func newMockRepo() *mockRepo {
+	return &mockRepo{
+		locations:   make(map[string]domain.Location),
+		trash:       make(map[string]domain.Location),
+		collections: make(map[string]domain.Collection),
+		shares:      make(map[string]domain.Share),
+	}
+}
+
+func (m *mockRepo) CreateLocation(ctx context.Context, loc domain.Location) (domain.Location, error) {
+	if loc.Id == "" {
+		loc.Id = fmt.Sprintf("new-%d", m.created)
+		m.created++
+	}
+	m.locations[loc.Id] = loc
+	return loc, nil
+}
+
+func (m *mockRepo) GetLocation(ctx context.Context, id string) (domain.Location, error) {
+	loc, exists := m.locations[id]
+	if !exists {
+		return domain.Location{}, ErrLocationNotFound
+	}
+	return loc, nil
+}
+
+func (m *mockRepo) GetLocations(ctx context.Context, userID string, filter Filter) ([]domain.Location, domain.Metadata, error) {
+	locations := []domain.Location{}
+	for _, loc := range m.locations {
+		if loc.UserID == userID && filter.Matches(loc) {
+			locations = append(locations, loc)
+		}
+	}
+	filter.Sort(locations)
+	return filter.Window(locations), domain.Metadata{TotalRecords: int32(len(locations))}, nil
+}
+
+func (m *mockRepo) CountLocations(ctx context.Context, userID string) (int, error) {
+	count := 0
+	for _, loc := range m.locations {
+		if loc.UserID == userID {
+			count++
+		}
+	}
+	return count, nil
+}
+
+func (m *mockRepo) UpdateLocation(ctx context.Context, loc domain.Location) (domain.Location, error) {
+	m.locations[loc.Id] = loc
+	return loc, nil
+}
+
+func (m *mockRepo) DeleteLocation(ctx context.Context, id string, version int64) (domain.Location, error) {
+	loc := m.locations[id]
+	loc.DeletedAt = time.Now()
+	loc.Version++
+	m.trash[id] = loc
+	delete(m.locations, id)
+	return loc, nil
+}
+
+func (m *mockRepo) GetTrashedLocations(ctx context.Context, userID string) ([]domain.Location, error) {
+	locations := []domain.Location{}
+	for _, loc := range m.trash {
+		if loc.UserID == userID {
+			locations = append(locations, loc)
+		}
+	}
+	return locations, nil
+}
+
+func (m *mockRepo) RestoreLocation(ctx context.Context, userID, id string) (domain.Location, error) {
+	loc, exists := m.trash[id]
+	if !exists || loc.UserID != userID {
+		return domain.Location{}, ErrLocationNotFound
+	}
+	delete(m.trash, id)
+	loc.DeletedAt = time.Time{}
+	m.locations[id] = loc
+	return loc, nil
+}
+
+func (m *mockRepo) FindNearby(ctx context.Context, userID string, query NearbyQuery) ([]NearbyLocation, error) {
+	var nearby []NearbyLocation
+	for _, loc := range m.locations {
+		distance := domain.DistanceKm(query.Center, loc.Coordinates)
+		if loc.UserID == userID && distance <= query.RadiusKm {
+			nearby = append(nearby, NearbyLocation{Location: loc, DistanceKm: distance})
+		}
+	}
+	sort.Slice(nearby, func(i, j int) bool {
+		if nearby[i].DistanceKm != nearby[j].DistanceKm {
+			return nearby[i].DistanceKm < nearby[j].DistanceKm
+		}
+		return nearby[i].Location.Id < nearby[j].Location.Id
+	})
+	return nearby, nil
+}
+
+func (m *mockRepo) CreateCollection(ctx context.Context, c domain.Collection) (domain.Collection, error) {
+	c.Id = fmt.Sprintf("col-%d", len(m.collections))
+	m.collections[c.Id] = c
+	return c, nil
+}
+
+func (m *mockRepo) GetCollection(ctx context.Context, id string) (domain.Collection, error) {
+	c, exists := m.collections[id]
+	if !exists {
+		return domain.Collection{}, ErrCollectionNotFound
+	}
+	return c, nil
+}
+
+func (m *mockRepo) GetCollections(ctx context.Context, userID string) ([]domain.Collection, error) {
+	collections := []domain.Collection{}
+	for _, c := range m.collections {
+		if c.UserID == userID {
+			collections = append(collections, c)
+		}
+	}
+	return collections, nil
+}
+
+func (m *mockRepo) UpdateCollection(ctx context.Context, c domain.Collection) (domain.Collection, error) {
+	m.collections[c.Id] = c
+	return c, nil
+}
+
+func (m *mockRepo) DeleteCollection(ctx context.Context, id string) error {
+	delete(m.collections, id)
+	return nil
+}
+
+func (m *mockRepo) AddToCollection(ctx context.Context, collectionID, locationID string) error {
+	loc := m.locations[locationID]
+	loc.Collections = append(loc.Collections, collectionID)
+	m.locations[locationID] = loc
+	return nil
+}
+
+func (m *mockRepo) RemoveFromCollection(ctx context.Context, collectionID, locationID string) error {
+	loc := m.locations[locationID]
+	loc.Collections = nil
+	m.locations[locationID] = loc
+	return nil
+}
+
+func (m *mockRepo) ReorderLocations(ctx context.Context, userID string, ids []string) error {
+	for i, id := range ids {
+		loc := m.locations[id]
+		loc.Position = i
+		m.locations[id] = loc
+	}
+	return nil
+}
+
+func (m *mockRepo) CreateShare(ctx context.Context, share domain.Share) (domain.Share, error) {
+	m.shares[share.Token] = share
+	return share, nil
+}
+
+func (m *mockRepo) GetShare(ctx context.Context, token string) (domain.Share, error) {
+	share, exists := m.shares[token]
+	if !exists {
+		return domain.Share{}, ErrShareNotFound
+	}
+	return share, nil
+}
+
+func (m *mockRepo) GetShares(ctx context.Context, userID string) ([]domain.Share, error) {
+	shares := []domain.Share{}
+	for _, share := range m.shares {
+		if share.UserID == userID {
+			shares = append(shares, share)
+		}
+	}
+	return shares, nil
+}
+
+func (m *mockRepo) DeleteShare(ctx context.Context, token string) error {
+	delete(m.shares, token)
+	return nil
+}
+
+func (m *mockRepo) MergeUser(ctx context.Context, fromUserID, toUserID string, maxLocations int, isDuplicate func(a, b domain.Location) bool) (MergeResult, error) {
+	var existing, moved []domain.Location
+	for _, loc := range m.locations {
+		switch loc.UserID {
+		case toUserID:
+			existing = append(existing, loc)
+		case fromUserID:
+			moved = append(moved, loc)
+		}
+	}
+	slices.SortFunc(moved, func(a, b domain.Location) int { return strings.Compare(a.Id, b.Id) })
+	count := len(existing)
+	var result MergeResult
+	for _, loc := range moved {
+		loc.UserID = toUserID
+		duplicate := slices.ContainsFunc(existing, func(e domain.Location) bool { return isDuplicate(loc, e) })
+		if duplicate || (maxLocations > 0 && count >= maxLocations) {
+			delete(m.locations, loc.Id)
+			loc.DeletedAt = time.Now()
+			m.trash[loc.Id] = loc
+			if duplicate {
+				result.Duplicates++
+			} else {
+				result.Overflow++
+			}
+			continue
+		}
+		m.locations[loc.Id] = loc
+		count++
+		result.Locations++
+	}
+	return result, nil
+}
+
+// OnRemove does nothing: the mock never removes locations permanently.
+func (m *mockRepo) OnRemove(fn func(ids []string)) {}
+
+func seedLocations(repo *mockRepo, n int) {
+	base := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	for i := 0; i < n; i++ {
+		repo.CreateLocation(context.Background(), domain.Location{
+			Id:        fmt.Sprintf("loc-%02d", i),
+			UserID:    "user1",
+			CreatedAt: base.Add(time.Duration(i) * time.Minute),
+		})
+	}
+}
+
+// WithinTx puts the locations and the trash back if fn fails.
+func (m *mockRepo) WithinTx(ctx context.Context, userID string, fn func(ctx context.Context) error) error {
+	locations, trash := maps.Clone(m.locations), maps.Clone(m.trash)
+	if err := fn(ctx); err != nil {
+		m.locations, m.trash = locations, trash
+		return err
+	}
+	return nil
+}
+
+func TestGetLocationsByCursor(t *testing.T) {
+	repo := newMockRepo()
+	seedLocations(repo, 12)
+	svc := NewService(repo)
+	filter := Filter{SortBy: SortByCreatedAt, SortDir: SortAsc, Limit: 5}
+
+	var pages [][]domain.Location
+	var prev string
+	for {
+		locations, meta, err := svc.GetLocations(context.Background(), "user1", filter)
+		if err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+		if meta.TotalRecords != 12 {
+			t.Fatalf("expected total records 12, got %d", meta.TotalRecords)
+		}
+		pages = append(pages, locations)
+		prev = meta.PrevCursor
+		if meta.NextCursor == "" {
+			break
+		}
+		filter.Cursor = meta.NextCursor
+	}
+
+	if len(pages) != 3 || len(pages[2]) != 2 {
+		t.Fatalf("expected pages of 5, 5 and 2, got %d pages", len(pages))
+	}
+	if pages[1][0].Id != "loc-05" {
+		t.Fatalf("expected second page to start at loc-05, got %s", pages[1][0].Id)
+	}
+
+	filter.Cursor = prev
+	locations, meta, err := svc.GetLocations(context.Background(), "user1", filter)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if len(locations) != 5 || locations[0].Id != "loc-05" {
+		t.Fatalf("expected previous page to be the second page, got %+v", locations)
+	}
+	if meta.NextCursor == "" || meta.PrevCursor == "" {
+		t.Fatalf("expected both cursors on a middle page, got %+v", meta)
+	}
+}
+
+func TestGetLocationsRejectsBadCursor(t *testing.T) {
+	repo := newMockRepo()
+	seedLocations(repo, 6)
+	svc := NewService(repo, WithCursorKey([]byte("key")))
+
+	_, meta, err := svc.GetLocations(context.Background(), "user1", Filter{SortBy: SortByCreatedAt, Limit: 2})
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	tests := []struct {
+		name   string
+		svc    *Service
+		filter Filter
+	}{
+		{"malformed", svc, Filter{SortBy: SortByCreatedAt, Cursor: "not-a-cursor", Limit: 2}},
+		{"tampered", svc, Filter{SortBy: SortByCreatedAt, Cursor: "x" + meta.NextCursor, Limit: 2}},
+		{"different filter", svc, Filter{SortBy: SortByNickname, Cursor: meta.NextCursor, Limit: 2}},
+		{"different key", NewService(repo, WithCursorKey([]byte("other"))), Filter{SortBy: SortByCreatedAt, Cursor: meta.NextCursor, Limit: 2}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, _, err := tt.svc.GetLocations(context.Background(), "user1", tt.filter)
+			if !errors.Is(err, ErrInvalidCursor) {
+				t.Fatalf("expected error %v, got %v", ErrInvalidCursor, err)
+			}
+		})
+	}
+}
+
+func TestReorderLocationsRejectsDuplicates(t *testing.T) {
+	svc := NewService(newMockRepo())
+	err := svc.ReorderLocations(context.Background(), "user1", []string{"a", "b", "a"})
+	if !errors.Is(err, ErrInvalidOrder) {
+		t.Fatalf("expected error %v, got %v", ErrInvalidOrder, err)
+	}
+}
+
+func TestTrashAndRestore(t *testing.T) {
+	repo := newMockRepo()
+	svc := NewService(repo)
+	ctx := context.Background()
+	repo.locations["a"] = domain.Location{Id: "a", UserID: "owner"}
+
+	if err := svc.DeleteLocation(ctx, "a", "owner", 0); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	trash, _ := svc.GetTrash(ctx, "owner")
+	if len(trash) != 1 || trash[0].DeletedAt.IsZero() {
+		t.Fatalf("expected the deleted location in the trash, got %+v", trash)
+	}
+	if _, err := svc.RestoreLocation(ctx, "a", "intruder"); !errors.Is(err, ErrLocationNotFound) {
+		t.Fatalf("expected ErrLocationNotFound for another user's trash, got %v", err)
+	}
+	restored, err := svc.RestoreLocation(ctx, "a", "owner")
+	if err != nil || !restored.DeletedAt.IsZero() {
+		t.Fatalf("expected the location to be restored, got %+v (%v)", restored, err)
+	}
+}
@@ -0,0 +1,30 @@
+package location
+
+import (
+	"context"
+	"fmt"
+)
+
+// MergeUser moves everything fromUserID has saved to toUserID, such as the
+// locations of an anonymous session whose visitor has just signed in to an
+// account. Locations that look like duplicates of ones toUserID already has
+// go to toUserID's trash, from where they can still be restored, and so do
+// the last ones when toUserID has no room left for them under the quota.
+// Each
+// repository moves its records atomically: locations, collections and
+// shares first, then check-ins.
+func (s *Service) MergeUser(ctx context.Context, fromUserID, toUserID string) (MergeResult, error) {
+	if fromUserID == toUserID {
+		return MergeResult{}, nil
+	}
+	result, err := s.repo.MergeUser(ctx, fromUserID, toUserID, s.quota.MaxLocations, s.isDuplicate)
+	if err != nil {
+		return MergeResult{}, err
+	}
+	if s.checkIns != nil {
+		if err := s.checkIns.MoveCheckIns(ctx, fromUserID, toUserID); err != nil {
+			return result, fmt.Errorf("moving check-ins: %w", err)
+		}
+	}
+	return result, nil
+}
@@ -0,0 +1,105 @@
+package location
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/lafetz/weavo/internal/core/domain"
+)
+
+func TestMergeUser(t *testing.T) {
+	ctx := context.Background()
+	repo := newMockRepo()
+	louvre := domain.Coordinates{Lat: 48.8606, Lon: 2.3376}
+	repo.locations["mine"] = domain.Location{Id: "mine", UserID: "account", City: "Paris", Coordinates: louvre}
+	repo.locations["dup"] = domain.Location{Id: "dup", UserID: "anon", City: " paris", Coordinates: louvre}
+	repo.locations["new"] = domain.Location{Id: "new", UserID: "anon", City: "Lyon", Coordinates: domain.Coordinates{Lat: 45.76, Lon: 4.83}}
+	repo.locations["other"] = domain.Location{Id: "other", UserID: "someone-else", City: "Lyon"}
+	checkIns := &mockCheckIns{checkIns: []domain.CheckIn{{Id: "c", LocationID: "new", UserID: "anon"}}}
+	svc := NewService(repo, WithCheckIns(checkIns, &mockWeather{}))
+
+	result, err := svc.MergeUser(ctx, "anon", "account")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if result.Locations != 1 || result.Duplicates != 1 {
+		t.Errorf("expected 1 location and 1 duplicate, got %+v", result)
+	}
+	if loc := repo.locations["new"]; loc.UserID != "account" {
+		t.Errorf("expected the new location to move to the account, got %q", loc.UserID)
+	}
+	if loc, trashed := repo.trash["dup"]; !trashed || loc.UserID != "account" {
+		t.Errorf("expected the duplicate in the account's trash, got %+v", loc)
+	}
+	if loc := repo.locations["other"]; loc.UserID != "someone-else" {
+		t.Errorf("expected other users' locations to stay put, got %q", loc.UserID)
+	}
+	if checkIns.checkIns[0].UserID != "account" {
+		t.Errorf("expected the check-in to move to the account, got %q", checkIns.checkIns[0].UserID)
+	}
+
+	result, err = svc.MergeUser(ctx, "account", "account")
+	if err != nil || result != (MergeResult{}) {
+		t.Errorf("expected merging a user into itself to do nothing, got %+v, %v", result, err)
+	}
+}
+
+func TestMergeUserKeepsToTheQuota(t *testing.T) {
+	ctx := context.Background()
+	repo := newMockRepo()
+	repo.locations["mine"] = domain.Location{Id: "mine", UserID: "account", City: "Paris"}
+	repo.locations["a"] = domain.Location{Id: "a", UserID: "anon", City: "Lyon"}
+	repo.locations["b"] = domain.Location{Id: "b", UserID: "anon", City: "Oslo"}
+	svc := NewService(repo, WithQuota(Quota{MaxLocations: 2}))
+
+	result, err := svc.MergeUser(ctx, "anon", "account")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if result.Locations != 1 || result.Overflow != 1 {
+		t.Errorf("expected 1 location and 1 over the limit, got %+v", result)
+	}
+	if usage, _ := svc.Usage(ctx, "account"); usage.Locations != 2 {
+		t.Errorf("expected the account to stay within its quota, got %d locations", usage.Locations)
+	}
+	if _, err := svc.RestoreLocation(ctx, "b", "account"); !errors.Is(err, ErrLocationLimit) {
+		t.Errorf("expected restoring the overflow to be held to the quota, got %v", err)
+	}
+}
+
+func TestHistoryAfterMerge(t *testing.T) {
+	ctx := context.Background()
+	repo := versionedRepo{newMockRepo()}
+	history := &mockHistory{entries: make(map[string][]domain.HistoryEntry)}
+	svc := NewService(repo, WithHistory(history))
+	louvre := domain.Coordinates{Lat: 48.8606, Lon: 2.3376}
+
+	svc.CreateLocation(ctx, domain.Location{UserID: "account", City: "Paris", Coordinates: louvre}, false)
+	moved, _ := svc.CreateLocation(ctx, domain.Location{UserID: "anon", City: "Lyon", Notes: "first"}, false)
+	dup, _ := svc.CreateLocation(ctx, domain.Location{UserID: "anon", City: "Paris", Coordinates: louvre}, false)
+	moved.Notes = "second"
+	if _, err := svc.UpdateLocation(ctx, moved); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if _, err := svc.MergeUser(ctx, "anon", "account"); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	if entries, err := svc.GetHistory(ctx, moved.Id, "account"); err != nil || len(entries) != 2 {
+		t.Errorf("expected the account to read the moved location's history, got %d entries, %v", len(entries), err)
+	}
+	if _, err := svc.GetHistory(ctx, dup.Id, "account"); err != nil {
+		t.Errorf("expected the account to read the trashed duplicate's history, got %v", err)
+	}
+	if _, err := svc.GetHistory(ctx, moved.Id, "anon"); !errors.Is(err, ErrLocationNotFound) {
+		t.Errorf("expected ErrLocationNotFound for the merged-away user, got %v", err)
+	}
+	reverted, err := svc.RevertLocation(ctx, moved.Id, "account", 1, 0)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if reverted.Notes != "first" || reverted.UserID != "account" {
+		t.Errorf("expected the original notes under the account, got %+v", reverted)
+	}
+}
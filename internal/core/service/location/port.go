@@ -2,25 +2,270 @@ package location
 
 import (
 	"context"
+	"io"
+	"time"
 
 	"github.com/lafetz/weavo/internal/core/domain"
 )
 
+type SortField string
+
+const (
+	// SortByPosition lists pinned locations first, then follows the order
+	// the user set with ReorderLocations.
+	SortByPosition  SortField = "position"
+	SortByCreatedAt SortField = "created_at"
+	SortByNickname  SortField = "nickname"
+	SortByCity      SortField = "city"
+)
+
+type SortDirection string
+
+const (
+	SortAsc  SortDirection = "asc"
+	SortDesc SortDirection = "desc"
+)
+
 type Filter struct {
 	PageSize int
 	Page     int
+
+	SortBy  SortField
+	SortDir SortDirection
+
+	City          string    // exact match, case-insensitive
+	Query         string    // substring of nickname or notes, case-insensitive
+	CreatedAfter  time.Time // zero means unbounded
+	CreatedBefore time.Time // zero means unbounded
+	Box           *domain.BoundingBox
+	Tag           string // case-insensitive
+	Collection    string // collection ID
+
+	// Cursor and Limit select keyset pagination instead of Page/PageSize.
+	// Cursor is the opaque value handed out in Metadata; the service decodes
+	// it into After or Before before calling the repository.
+	Cursor string
+	Limit  int
+	After  *Position
+	Before *Position
 }
+
+// Position identifies where a location sits in a sorted listing.
+type Position struct {
+	SortKey   string    `json:"k,omitempty"`
+	CreatedAt time.Time `json:"t"`
+	Id        string    `json:"i"`
+}
+
+// NearbyQuery selects locations within RadiusKm of Center, optionally
+// restricted further to Box.
+type NearbyQuery struct {
+	Center   domain.Coordinates
+	RadiusKm float64
+	Box      *domain.BoundingBox
+	Limit    int
+}
+
+type NearbyLocation struct {
+	Location   domain.Location
+	DistanceKm float64
+}
+
 type LocationRepo interface {
 	CreateLocation(ctx context.Context, location domain.Location) (domain.Location, error)
+	// GetLocation and GetLocations count as an access to the locations they
+	// return, unless ctx comes from domain.ContextWithoutAccess.
 	GetLocation(ctx context.Context, id string) (domain.Location, error)
+	// GetLocations returns one page of the user's live locations matching
+	// filter. Only the locations on the page count as accessed.
 	GetLocations(ctx context.Context, userID string, filter Filter) ([]domain.Location, domain.Metadata, error)
+	// CountLocations returns how many live locations the user has. It does
+	// not count as an access to any of them.
+	CountLocations(ctx context.Context, userID string) (int, error)
+	// UpdateLocation replaces the location's editable fields. When
+	// location.Version is non-zero it must equal the stored version or
+	// ErrVersionMismatch is returned; the check and the write are atomic.
 	UpdateLocation(ctx context.Context, location domain.Location) (domain.Location, error)
-	DeleteLocation(ctx context.Context, id string) error
+	// DeleteLocation moves the location to the trash, setting DeletedAt.
+	// Trashed locations are invisible to every other read and write until
+	// restored, and are purged permanently once the implementation's purge
+	// window has passed. A non-zero version is checked atomically like in
+	// UpdateLocation. It returns the location as it now is in the trash.
+	DeleteLocation(ctx context.Context, id string, version int64) (domain.Location, error)
+	// GetTrashedLocations returns the user's deleted locations, most
+	// recently deleted first.
+	GetTrashedLocations(ctx context.Context, userID string) ([]domain.Location, error)
+	// RestoreLocation moves one of the user's trashed locations back to the
+	// end of their list. It returns ErrLocationNotFound if the user has no
+	// such location in the trash.
+	RestoreLocation(ctx context.Context, userID, id string) (domain.Location, error)
+	// FindNearby returns the user's locations within query.RadiusKm of
+	// query.Center, closest first. Only the locations returned, after
+	// query.Limit is applied, count as accessed. SQL implementations should pre-filter on
+	// an index over (lat, lon) with domain.BoundingBoxAround and compute the
+	// exact distance only for the remaining candidates.
+	FindNearby(ctx context.Context, userID string, query NearbyQuery) ([]NearbyLocation, error)
+
+	CreateCollection(ctx context.Context, collection domain.Collection) (domain.Collection, error)
+	GetCollection(ctx context.Context, id string) (domain.Collection, error)
+	GetCollections(ctx context.Context, userID string) ([]domain.Collection, error)
+	UpdateCollection(ctx context.Context, collection domain.Collection) (domain.Collection, error)
+	// DeleteCollection removes the collection and its ID from every member
+	// location; the locations themselves are kept.
+	DeleteCollection(ctx context.Context, id string) error
+	AddToCollection(ctx context.Context, collectionID, locationID string) error
+	RemoveFromCollection(ctx context.Context, collectionID, locationID string) error
+
+	// ReorderLocations atomically moves the given locations, in order, to
+	// the front of the user's list. Locations not listed keep their
+	// relative order after them.
+	ReorderLocations(ctx context.Context, userID string, ids []string) error
+
+	// WithinTx runs fn as a transaction over userID's records: the writes
+	// fn makes through the context it is given are applied together or, if
+	// fn returns an error, not at all, and other callers do not observe
+	// them half done.
+	WithinTx(ctx context.Context, userID string, fn func(ctx context.Context) error) error
+
+	CreateShare(ctx context.Context, share domain.Share) (domain.Share, error)
+	// GetShare returns ErrShareNotFound for tokens that were never issued
+	// or have been revoked. Expired shares are still returned.
+	GetShare(ctx context.Context, token string) (domain.Share, error)
+	// GetShares returns the user's shares, newest first.
+	GetShares(ctx context.Context, userID string) ([]domain.Share, error)
+	DeleteShare(ctx context.Context, token string) error
+
+	// MergeUser atomically moves every record of fromUserID to toUserID.
+	// fromUserID's live locations follow toUserID's in list order, except
+	// those isDuplicate reports as the same place as one of toUserID's live
+	// locations, and those that would take toUserID over maxLocations live
+	// locations, which are moved to toUserID's trash instead. A maxLocations
+	// of 0 means no limit.
+	MergeUser(ctx context.Context, fromUserID, toUserID string, maxLocations int, isDuplicate func(a, b domain.Location) bool) (MergeResult, error)
+
+	// OnRemove registers fn to be called with the IDs of locations once
+	// they are removed permanently, by the purge of the trash or by the
+	// retention policy. Moving a location to the trash does not call it.
+	OnRemove(fn func(ids []string))
+}
+
+// MergeResult counts the records MergeUser moved. Locations already in the
+// trash are moved too but not counted.
+type MergeResult struct {
+	Locations   int // added to the end of the target's list
+	Duplicates  int // moved to the target's trash as likely duplicates
+	Overflow    int // moved to the target's trash as over the location limit
+	Collections int
+	Shares      int
+}
+
+// HistoryRepo stores the change history of locations. It is kept apart from
+// LocationRepo so that the audit trail can live in its own, append-only
+// storage.
+type HistoryRepo interface {
+	AppendHistory(ctx context.Context, entry domain.HistoryEntry) error
+	// GetHistory returns every entry of the location, oldest first, or an
+	// empty slice if there are none. Entries outlive the location while it
+	// is in the trash.
+	GetHistory(ctx context.Context, locationID string) ([]domain.HistoryEntry, error)
+	// DeleteHistory removes every entry of the location. It is called once
+	// the location is removed permanently, since snapshots hold its notes.
+	DeleteHistory(ctx context.Context, locationID string) error
+}
+
+// AttachmentRepo stores the metadata of attachments; their content is kept
+// in a BlobStore.
+type AttachmentRepo interface {
+	CreateAttachment(ctx context.Context, attachment domain.Attachment) (domain.Attachment, error)
+	// GetAttachment returns ErrAttachmentNotFound if there is no such
+	// attachment.
+	GetAttachment(ctx context.Context, id string) (domain.Attachment, error)
+	// GetAttachments returns the location's attachments, oldest first, or
+	// an empty slice if there are none.
+	GetAttachments(ctx context.Context, locationID string) ([]domain.Attachment, error)
+	DeleteAttachment(ctx context.Context, id string) error
+	// DeleteAttachments removes every attachment of the location and
+	// returns what was removed.
+	DeleteAttachments(ctx context.Context, locationID string) ([]domain.Attachment, error)
+}
+
+// CheckInFilter selects a page of a user's check-ins, newest first.
+type CheckInFilter struct {
+	After    time.Time // zero means unbounded
+	Before   time.Time // zero means unbounded
+	Page     int
+	PageSize int
 }
+
+// CheckInRepo stores check-ins. Like history, they are kept apart from
+// LocationRepo as an append-only journal.
+type CheckInRepo interface {
+	CreateCheckIn(ctx context.Context, checkIn domain.CheckIn) (domain.CheckIn, error)
+	// GetCheckIns returns the location's check-ins, newest first, or an
+	// empty slice if there are none.
+	GetCheckIns(ctx context.Context, locationID string) ([]domain.CheckIn, error)
+	// GetTimeline returns a page of the check-ins at any of the user's
+	// locations.
+	GetTimeline(ctx context.Context, userID string, filter CheckInFilter) ([]domain.CheckIn, domain.Metadata, error)
+	DeleteCheckIns(ctx context.Context, locationID string) error
+	// MoveCheckIns atomically gives every check-in of fromUserID to
+	// toUserID.
+	MoveCheckIns(ctx context.Context, fromUserID, toUserID string) error
+}
+
+// WeatherSource looks up the current weather of a city.
+type WeatherSource interface {
+	GetWeather(ctx context.Context, city string) (domain.Weather, error)
+}
+
+// BlobStore holds the content of attachments under opaque keys.
+type BlobStore interface {
+	Put(ctx context.Context, key string, r io.Reader) error
+	// Open returns ErrBlobNotFound if nothing is stored under key.
+	Open(ctx context.Context, key string) (io.ReadCloser, error)
+	// Delete succeeds if nothing is stored under key.
+	Delete(ctx context.Context, key string) error
+}
+
 type ServiceApi interface {
-	CreateLocation(ctx context.Context, location domain.Location) (domain.Location, error)
-	GetLocation(ctx context.Context, id string) (domain.Location, error)
+	CreateLocation(ctx context.Context, location domain.Location, force bool) (domain.Location, error)
+	GetLocation(ctx context.Context, id string, userID string) (domain.Location, error)
 	GetLocations(ctx context.Context, userID string, filter Filter) ([]domain.Location, domain.Metadata, error)
 	UpdateLocation(ctx context.Context, location domain.Location) (domain.Location, error)
-	DeleteLocation(ctx context.Context, id string, userID string) error
+	DeleteLocation(ctx context.Context, id string, userID string, version int64) error
+	GetTrash(ctx context.Context, userID string) ([]domain.Location, error)
+	RestoreLocation(ctx context.Context, id string, userID string) (domain.Location, error)
+	GetHistory(ctx context.Context, id string, userID string) ([]domain.HistoryEntry, error)
+	RevertLocation(ctx context.Context, id string, userID string, toVersion int64, ifVersion int64) (domain.Location, error)
+	FindNearby(ctx context.Context, userID string, query NearbyQuery) ([]NearbyLocation, error)
+	FindDuplicates(ctx context.Context, userID string) ([][]domain.Location, error)
+	ExportLocations(ctx context.Context, userID string, filter Filter, fn func(domain.Location) error) error
+	ImportLocations(ctx context.Context, userID string, locations []domain.Location, opts ImportOptions) ([]ImportOutcome, error)
+	Batch(ctx context.Context, userID string, ops []BatchOperation, atomic bool) ([]BatchResult, error)
+	Usage(ctx context.Context, userID string) (Usage, error)
+	MergeUser(ctx context.Context, fromUserID, toUserID string) (MergeResult, error)
+
+	AddAttachment(ctx context.Context, locationID, userID, fileName string, r io.Reader) (domain.Attachment, error)
+	GetAttachments(ctx context.Context, locationID, userID string) ([]domain.Attachment, error)
+	OpenAttachment(ctx context.Context, locationID, id, userID string, thumbnail bool) (domain.Attachment, io.ReadCloser, error)
+	DeleteAttachment(ctx context.Context, locationID, id, userID string) error
+
+	CheckIn(ctx context.Context, locationID, userID, comment string) (domain.CheckIn, error)
+	GetCheckIns(ctx context.Context, locationID, userID string) ([]domain.CheckIn, error)
+	GetTimeline(ctx context.Context, userID string, filter CheckInFilter) ([]domain.CheckIn, domain.Metadata, error)
+
+	CreateCollection(ctx context.Context, collection domain.Collection) (domain.Collection, error)
+	GetCollection(ctx context.Context, id string, userID string) (domain.Collection, error)
+	GetCollections(ctx context.Context, userID string) ([]domain.Collection, error)
+	UpdateCollection(ctx context.Context, collection domain.Collection) (domain.Collection, error)
+	DeleteCollection(ctx context.Context, id string, userID string) error
+	AddToCollection(ctx context.Context, collectionID, locationID, userID string) error
+	RemoveFromCollection(ctx context.Context, collectionID, locationID, userID string) error
+
+	ReorderLocations(ctx context.Context, userID string, ids []string) error
+
+	CreateShare(ctx context.Context, share domain.Share) (domain.Share, error)
+	GetShares(ctx context.Context, userID string) ([]domain.Share, error)
+	RevokeShare(ctx context.Context, token string, userID string) error
+	GetShared(ctx context.Context, token string) (SharedContent, error)
 }
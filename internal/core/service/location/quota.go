@@ -0,0 +1,91 @@
+package location
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"unicode/utf8"
+
+	"github.com/lafetz/weavo/internal/core/domain"
+)
+
+var (
+	ErrLocationLimit = errors.New("location limit reached")
+	ErrNotesTooLong  = errors.New("notes are too long")
+)
+
+// Quota limits what a single user can store. A zero field means no limit.
+type Quota struct {
+	MaxLocations int
+	// MaxNotesLength is counted in characters.
+	MaxNotesLength int
+	// MaxAttachmentBytes is the size limit of a single attachment.
+	MaxAttachmentBytes int64
+}
+
+// Usage is how much a user has stored, next to their quota.
+type Usage struct {
+	Locations int
+	Quota     Quota
+}
+
+// WithQuota sets the limits every user is held to. Without it users can
+// store any number of locations with notes of any length.
+func WithQuota(q Quota) Option {
+	return func(s *Service) {
+		s.quota = q
+	}
+}
+
+// Usage returns how many locations the user has, not counting the trash,
+// and the quota they are held to.
+func (s *Service) Usage(ctx context.Context, userID string) (Usage, error) {
+	count, err := s.countLocations(ctx, userID)
+	if err != nil {
+		return Usage{}, err
+	}
+	return Usage{Locations: count, Quota: s.quota}, nil
+}
+
+func (s *Service) countLocations(ctx context.Context, userID string) (int, error) {
+	return s.repo.CountLocations(ctx, userID)
+}
+
+// checkNotes fails with ErrNotesTooLong if loc's notes are over the quota.
+func (s *Service) checkNotes(loc domain.Location) error {
+	if s.quota.MaxNotesLength > 0 && utf8.RuneCountInString(loc.Notes) > s.quota.MaxNotesLength {
+		return fmt.Errorf("%w: the maximum is %d characters", ErrNotesTooLong, s.quota.MaxNotesLength)
+	}
+	return nil
+}
+
+// checkLocationLimit fails with ErrLocationLimit if adding n locations
+// would take the user over the quota.
+func (s *Service) checkLocationLimit(ctx context.Context, userID string, n int) error {
+	if s.quota.MaxLocations <= 0 {
+		return nil
+	}
+	count, err := s.countLocations(ctx, userID)
+	if err != nil {
+		return err
+	}
+	if count+n > s.quota.MaxLocations {
+		return fmt.Errorf("%w: the maximum is %d locations", ErrLocationLimit, s.quota.MaxLocations)
+	}
+	return nil
+}
+
+// withinLocationLimit runs fn, which adds one location for userID, unless
+// that would take the user over the quota. The count and fn run in one
+// transaction, so concurrent requests cannot overshoot the limit together.
+func (s *Service) withinLocationLimit(ctx context.Context, userID string, fn func(ctx context.Context) error) error {
+	if s.quota.MaxLocations <= 0 {
+		return fn(ctx)
+	}
+	return s.repo.WithinTx(ctx, userID, func(ctx context.Context) error {
+		if err := s.checkLocationLimit(ctx, userID, 1); err != nil {
+			return err
+		}
+		return fn(ctx)
+	})
+}
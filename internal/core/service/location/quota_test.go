@@ -0,0 +1,101 @@
+package location
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/lafetz/weavo/internal/core/domain"
+)
+
+func TestQuota(t *testing.T) {
+	ctx := context.Background()
+	quota := Quota{MaxLocations: 2, MaxNotesLength: 5}
+
+	t.Run("location limit", func(t *testing.T) {
+		repo := newMockRepo()
+		svc := NewService(repo, WithQuota(quota))
+		var first domain.Location
+		for i := range 2 {
+			loc, err := svc.CreateLocation(ctx, domain.Location{UserID: "user1", City: fmt.Sprintf("City %d", i)}, false)
+			if err != nil {
+				t.Fatalf("expected no error, got %v", err)
+			}
+			if i == 0 {
+				first = loc
+			}
+		}
+		if _, err := svc.CreateLocation(ctx, domain.Location{UserID: "user1", City: "One too many"}, false); !errors.Is(err, ErrLocationLimit) {
+			t.Fatalf("expected ErrLocationLimit, got %v", err)
+		}
+		if _, err := svc.CreateLocation(ctx, domain.Location{UserID: "user2", City: "Other user"}, false); err != nil {
+			t.Fatalf("expected other users to be unaffected, got %v", err)
+		}
+
+		// Trashed locations do not count, but restoring one does.
+		if err := svc.DeleteLocation(ctx, first.Id, "user1", 0); err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+		if _, err := svc.CreateLocation(ctx, domain.Location{UserID: "user1", City: "Replacement"}, false); err != nil {
+			t.Fatalf("expected room after a delete, got %v", err)
+		}
+		if _, err := svc.RestoreLocation(ctx, first.Id, "user1"); !errors.Is(err, ErrLocationLimit) {
+			t.Fatalf("expected ErrLocationLimit on restore, got %v", err)
+		}
+
+		usage, err := svc.Usage(ctx, "user1")
+		if err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+		if usage.Locations != 2 || usage.Quota != quota {
+			t.Errorf("expected 2 locations against %+v, got %+v", quota, usage)
+		}
+	})
+
+	t.Run("notes length", func(t *testing.T) {
+		repo := newMockRepo()
+		svc := NewService(repo, WithQuota(quota))
+		// Characters are counted, not bytes.
+		loc, err := svc.CreateLocation(ctx, domain.Location{UserID: "user1", Notes: "ééééé"}, false)
+		if err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+		if _, err := svc.CreateLocation(ctx, domain.Location{UserID: "user1", City: "Oslo", Notes: "too long"}, false); !errors.Is(err, ErrNotesTooLong) {
+			t.Fatalf("expected ErrNotesTooLong on create, got %v", err)
+		}
+		loc.Notes = strings.Repeat("x", 6)
+		if _, err := svc.UpdateLocation(ctx, loc); !errors.Is(err, ErrNotesTooLong) {
+			t.Fatalf("expected ErrNotesTooLong on update, got %v", err)
+		}
+	})
+
+	t.Run("import", func(t *testing.T) {
+		repo := newMockRepo()
+		svc := NewService(repo, WithQuota(quota))
+		rows := []domain.Location{{City: "A"}, {City: "B"}, {City: "C"}}
+		if _, err := svc.ImportLocations(ctx, "user1", rows, ImportOptions{DryRun: true}); !errors.Is(err, ErrLocationLimit) {
+			t.Fatalf("expected ErrLocationLimit, got %v", err)
+		}
+		outcomes, err := svc.ImportLocations(ctx, "user1", []domain.Location{{City: "A", Notes: "too long"}, {City: "B"}}, ImportOptions{})
+		if err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+		if outcomes[0].Status != ImportRejected || !errors.Is(outcomes[0].Err, ErrNotesTooLong) {
+			t.Errorf("expected row 1 to be rejected for its notes, got %s, %v", outcomes[0].Status, outcomes[0].Err)
+		}
+		if outcomes[1].Status != ImportAccepted || len(repo.locations) != 1 {
+			t.Errorf("expected row 2 to be imported, got %s and %d locations", outcomes[1].Status, len(repo.locations))
+		}
+	})
+
+	t.Run("unlimited by default", func(t *testing.T) {
+		svc := NewService(newMockRepo())
+		for i := range 3 {
+			if _, err := svc.CreateLocation(ctx, domain.Location{UserID: "user1", City: fmt.Sprintf("City %d", i), Notes: strings.Repeat("x", 100)}, false); err != nil {
+				t.Fatalf("expected no error, got %v", err)
+			}
+		}
+	})
+}
@@ -0,0 +1,135 @@
+package location
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"time"
+
+	"github.com/lafetz/weavo/internal/core/domain"
+)
+
+var (
+	ErrShareNotFound = errors.New("share not found")
+	ErrShareExpired  = errors.New("share has expired")
+	ErrInvalidShare  = errors.New("a share must target exactly one location or collection")
+	ErrInvalidExpiry = errors.New("expiry must be in the future")
+)
+
+const (
+	shareTokenBytes = 24
+	// maxSharedLocations bounds how many members of a shared collection are
+	// returned.
+	maxSharedLocations = 200
+)
+
+// SharedContent is what a share token grants access to. Coordinates are
+// already reduced to the share's precision.
+type SharedContent struct {
+	Share      domain.Share
+	Location   *domain.Location   // set for a location share
+	Collection *domain.Collection // set for a collection share
+	Locations  []domain.Location  // the shared collection's locations
+}
+
+// CreateShare issues a share token for one of the user's locations or
+// collections.
+func (s *Service) CreateShare(ctx context.Context, share domain.Share) (domain.Share, error) {
+	if (share.LocationID == "") == (share.CollectionID == "") {
+		return domain.Share{}, ErrInvalidShare
+	}
+	if !share.ExpiresAt.IsZero() && !share.ExpiresAt.After(time.Now()) {
+		return domain.Share{}, ErrInvalidExpiry
+	}
+	if share.LocationID != "" {
+		loc, err := s.repo.GetLocation(ctx, share.LocationID)
+		if err != nil {
+			return domain.Share{}, err
+		}
+		if _, err := s.authorize(ctx, share.UserID, loc, permShare); err != nil {
+			return domain.Share{}, err
+		}
+	} else if _, err := s.GetCollection(ctx, share.CollectionID, share.UserID); err != nil {
+		return domain.Share{}, err
+	}
+
+	token := make([]byte, shareTokenBytes)
+	if _, err := rand.Read(token); err != nil {
+		return domain.Share{}, err
+	}
+	share.Token = base64.RawURLEncoding.EncodeToString(token)
+	return s.repo.CreateShare(ctx, share)
+}
+
+func (s *Service) GetShares(ctx context.Context, userID string) ([]domain.Share, error) {
+	return s.repo.GetShares(ctx, userID)
+}
+
+// RevokeShare deletes one of the user's shares; its token stops working
+// immediately.
+func (s *Service) RevokeShare(ctx context.Context, token string, userID string) error {
+	share, err := s.repo.GetShare(ctx, token)
+	if err != nil {
+		return err
+	}
+	if share.UserID != userID {
+		return ErrUnAuthorized
+	}
+	return s.repo.DeleteShare(ctx, token)
+}
+
+// GetShared resolves a share token without any user identity. It only
+// returns what the share is scoped to, and only while the owner still has
+// it. Opening a share does not count as an access to the shared locations,
+// so visitors cannot keep them from expiring.
+func (s *Service) GetShared(ctx context.Context, token string) (SharedContent, error) {
+	ctx = domain.ContextWithoutAccess(ctx)
+	share, err := s.repo.GetShare(ctx, token)
+	if err != nil {
+		return SharedContent{}, err
+	}
+	if share.Expired(time.Now()) {
+		return SharedContent{}, ErrShareExpired
+	}
+	content := SharedContent{Share: share}
+
+	if share.LocationID != "" {
+		loc, err := s.repo.GetLocation(ctx, share.LocationID)
+		if err != nil {
+			return SharedContent{}, err
+		}
+		if !shareCovers(share, loc) {
+			return SharedContent{}, ErrLocationNotFound
+		}
+		loc = reducePrecision(loc, share.Precision)
+		content.Location = &loc
+		return content, nil
+	}
+
+	collection, err := s.GetCollection(ctx, share.CollectionID, share.UserID)
+	if err != nil {
+		return SharedContent{}, err
+	}
+	content.Collection = &collection
+	content.Locations = []domain.Location{}
+	errEnough := errors.New("enough locations")
+	err = s.ExportLocations(ctx, share.UserID, Filter{Collection: collection.Id}, func(loc domain.Location) error {
+		if len(content.Locations) == maxSharedLocations {
+			return errEnough
+		}
+		content.Locations = append(content.Locations, reducePrecision(loc, share.Precision))
+		return nil
+	})
+	if err != nil && !errors.Is(err, errEnough) {
+		return SharedContent{}, err
+	}
+	return content, nil
+}
+
+func reducePrecision(loc domain.Location, places int) domain.Location {
+	if places > 0 {
+		loc.Coordinates = loc.Coordinates.Round(places)
+	}
+	return loc
+}
@@ -0,0 +1,96 @@
+package location
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/lafetz/weavo/internal/core/domain"
+)
+
+func TestShares(t *testing.T) {
+	ctx := context.Background()
+	repo := newMockRepo()
+	repo.locations["a"] = domain.Location{Id: "a", UserID: "owner", City: "Paris", Coordinates: domain.Coordinates{Lat: 48.85661, Lon: 2.35222}}
+	repo.locations["b"] = domain.Location{Id: "b", UserID: "owner", Collections: []string{"col-0"}}
+	repo.locations["c"] = domain.Location{Id: "c", UserID: "owner"}
+	repo.collections["col-0"] = domain.Collection{Id: "col-0", UserID: "owner", Name: "Trips"}
+	svc := NewService(repo)
+
+	t.Run("invalid scope", func(t *testing.T) {
+		for _, share := range []domain.Share{
+			{UserID: "owner"},
+			{UserID: "owner", LocationID: "a", CollectionID: "col-0"},
+		} {
+			if _, err := svc.CreateShare(ctx, share); !errors.Is(err, ErrInvalidShare) {
+				t.Errorf("expected ErrInvalidShare for %+v, got %v", share, err)
+			}
+		}
+	})
+
+	t.Run("someone else's location", func(t *testing.T) {
+		_, err := svc.CreateShare(ctx, domain.Share{UserID: "intruder", LocationID: "a"})
+		if !errors.Is(err, ErrLocationNotFound) {
+			t.Fatalf("expected ErrLocationNotFound, got %v", err)
+		}
+	})
+
+	t.Run("location with reduced precision", func(t *testing.T) {
+		share, err := svc.CreateShare(ctx, domain.Share{UserID: "owner", LocationID: "a", Precision: 2})
+		if err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+		if len(share.Token) < 32 {
+			t.Fatalf("expected a long random token, got %q", share.Token)
+		}
+		content, err := svc.GetShared(ctx, share.Token)
+		if err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+		if content.Location == nil || content.Location.Coordinates != (domain.Coordinates{Lat: 48.86, Lon: 2.35}) {
+			t.Fatalf("expected the location rounded to 2 places, got %+v", content.Location)
+		}
+		if repo.locations["a"].Coordinates.Lat != 48.85661 {
+			t.Fatalf("expected the stored location to keep its precision")
+		}
+	})
+
+	t.Run("collection only shows its members", func(t *testing.T) {
+		share, _ := svc.CreateShare(ctx, domain.Share{UserID: "owner", CollectionID: "col-0"})
+		content, err := svc.GetShared(ctx, share.Token)
+		if err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+		if content.Collection.Name != "Trips" || len(content.Locations) != 1 || content.Locations[0].Id != "b" {
+			t.Fatalf("expected only location b, got %+v", content.Locations)
+		}
+	})
+
+	t.Run("expired and revoked", func(t *testing.T) {
+		share, _ := svc.CreateShare(ctx, domain.Share{UserID: "owner", LocationID: "a", ExpiresAt: time.Now().Add(time.Hour)})
+		expired := share
+		expired.ExpiresAt = time.Now().Add(-time.Minute)
+		repo.shares[share.Token] = expired
+		if _, err := svc.GetShared(ctx, share.Token); !errors.Is(err, ErrShareExpired) {
+			t.Fatalf("expected ErrShareExpired, got %v", err)
+		}
+
+		if err := svc.RevokeShare(ctx, share.Token, "intruder"); !errors.Is(err, ErrUnAuthorized) {
+			t.Fatalf("expected ErrUnAuthorized, got %v", err)
+		}
+		if err := svc.RevokeShare(ctx, share.Token, "owner"); err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+		if _, err := svc.GetShared(ctx, share.Token); !errors.Is(err, ErrShareNotFound) {
+			t.Fatalf("expected ErrShareNotFound after revoking, got %v", err)
+		}
+	})
+
+	t.Run("expiry in the past", func(t *testing.T) {
+		_, err := svc.CreateShare(ctx, domain.Share{UserID: "owner", LocationID: "a", ExpiresAt: time.Now().Add(-time.Hour)})
+		if !errors.Is(err, ErrInvalidExpiry) {
+			t.Fatalf("expected ErrInvalidExpiry, got %v", err)
+		}
+	})
+}
@@ -0,0 +1,98 @@
+package location
+
+import (
+	"bytes"
+	"errors"
+	"image"
+	"image/color"
+	_ "image/gif"
+	"image/jpeg"
+	"image/png"
+	"io"
+)
+
+const (
+	// thumbnailSize is the length of the longer side of a thumbnail.
+	thumbnailSize = 256
+	// maxThumbnailPixels bounds the size of images thumbnails are made of,
+	// so that a small file claiming huge dimensions cannot exhaust memory
+	// when decoded. It allows common 12 megapixel photos, which decode to
+	// about 50MB at most.
+	maxThumbnailPixels = 12_600_000
+)
+
+var errNotThumbnailable = errors.New("no thumbnail can be made of this file")
+
+// thumbnail scales down a GIF, JPEG or PNG image to fit within
+// thumbnailSize. JPEG photos stay JPEG; everything else becomes PNG so that
+// transparency survives. Images already small enough are scaled by one.
+// The image is read twice through open: once for its dimensions, and once
+// more to decode it if they are within maxThumbnailPixels.
+func thumbnail(open func() (io.ReadCloser, error)) ([]byte, error) {
+	cfg, format, err := decodeWith(open, image.DecodeConfig)
+	if err != nil {
+		return nil, errNotThumbnailable
+	}
+	if cfg.Width <= 0 || cfg.Height <= 0 || cfg.Width*cfg.Height > maxThumbnailPixels {
+		return nil, errNotThumbnailable
+	}
+	src, _, err := decodeWith(open, image.Decode)
+	if err != nil {
+		return nil, errNotThumbnailable
+	}
+	dst := scaleDown(src, thumbnailSize)
+
+	var buf bytes.Buffer
+	if format == "jpeg" {
+		err = jpeg.Encode(&buf, dst, &jpeg.Options{Quality: 80})
+	} else {
+		err = png.Encode(&buf, dst)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func decodeWith[T any](open func() (io.ReadCloser, error), decode func(io.Reader) (T, string, error)) (T, string, error) {
+	r, err := open()
+	if err != nil {
+		var zero T
+		return zero, "", err
+	}
+	defer r.Close()
+	return decode(r)
+}
+
+// scaleDown returns src scaled so that its longer side is at most size
+// pixels, averaging the source pixels that fall into each target pixel.
+func scaleDown(src image.Image, size int) *image.RGBA {
+	b := src.Bounds()
+	w, h := b.Dx(), b.Dy()
+	if w > size || h > size {
+		if w >= h {
+			w, h = size, max(1, h*size/b.Dx())
+		} else {
+			w, h = max(1, w*size/b.Dy()), size
+		}
+	}
+	dst := image.NewRGBA(image.Rect(0, 0, w, h))
+	for y := 0; y < h; y++ {
+		y0 := b.Min.Y + y*b.Dy()/h
+		y1 := max(y0+1, b.Min.Y+(y+1)*b.Dy()/h)
+		for x := 0; x < w; x++ {
+			x0 := b.Min.X + x*b.Dx()/w
+			x1 := max(x0+1, b.Min.X+(x+1)*b.Dx()/w)
+			var r, g, bl, a, n uint64
+			for sy := y0; sy < y1; sy++ {
+				for sx := x0; sx < x1; sx++ {
+					c := color.RGBA64Model.Convert(src.At(sx, sy)).(color.RGBA64)
+					r, g, bl, a = r+uint64(c.R), g+uint64(c.G), bl+uint64(c.B), a+uint64(c.A)
+					n++
+				}
+			}
+			dst.SetRGBA64(x, y, color.RGBA64{R: uint16(r / n), G: uint16(g / n), B: uint16(bl / n), A: uint16(a / n)})
+		}
+	}
+	return dst
+}
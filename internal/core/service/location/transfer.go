@@ -0,0 +1,163 @@
+package location
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/lafetz/weavo/internal/core/domain"
+)
+
+const (
+	// exportBatch is how many locations ExportLocations reads from the
+	// repository at a time.
+	exportBatch = 500
+	// MaxImportRows bounds the number of locations a single import may
+	// contain.
+	MaxImportRows = 5000
+)
+
+var ErrImportTooLarge = fmt.Errorf("an import may contain at most %d rows", MaxImportRows)
+
+type ImportStatus string
+
+const (
+	ImportAccepted  ImportStatus = "accepted"
+	ImportDuplicate ImportStatus = "duplicate"
+	// ImportRejected marks a location that is not valid for the user, such
+	// as one with notes over their limit. Err says why.
+	ImportRejected ImportStatus = "rejected"
+)
+
+// DuplicatePolicy decides what happens to an imported location whose city
+// and coordinates match a location the user already has.
+type DuplicatePolicy string
+
+const (
+	DuplicateSkip   DuplicatePolicy = "skip"
+	DuplicateCreate DuplicatePolicy = "create"
+)
+
+type ImportOptions struct {
+	// DryRun reports what the import would do without saving anything.
+	DryRun      bool
+	OnDuplicate DuplicatePolicy
+}
+
+// ImportOutcome is the result for one imported location, in input order.
+type ImportOutcome struct {
+	Status ImportStatus
+	// Location is the saved location, or the one that would be saved on a
+	// dry run.
+	Location domain.Location
+	// DuplicateOf is the ID of the existing location a skipped duplicate
+	// matched. It is empty when the match is an earlier row of the same
+	// import.
+	DuplicateOf string
+	// Err is why a rejected location was not imported.
+	Err error
+}
+
+// ExportLocations calls fn with each of the user's locations matching
+// filter, in list order. Locations are read from the repository in batches,
+// so the full list is never held in memory. Iteration stops at the first
+// error returned by fn.
+func (s *Service) ExportLocations(ctx context.Context, userID string, filter Filter, fn func(domain.Location) error) error {
+	filter.SortBy, filter.SortDir = SortByPosition, SortAsc
+	filter.Cursor, filter.Before = "", nil
+	filter.Limit = exportBatch
+	filter.After = nil
+	for {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		locations, _, err := s.repo.GetLocations(ctx, userID, filter)
+		if err != nil {
+			return err
+		}
+		for _, loc := range locations {
+			if err := fn(loc); err != nil {
+				return err
+			}
+		}
+		if len(locations) < exportBatch {
+			return nil
+		}
+		last := filter.PositionOf(locations[len(locations)-1])
+		filter.After = &last
+	}
+}
+
+// ImportLocations creates the given locations for the user, all of them or
+// none. Locations that are not valid for the user, such as those with notes
+// over their limit, are rejected and the others imported. A location is a
+// duplicate when its normalized city and coordinates
+// match one the user already has, or an earlier location in the same
+// import; duplicates are skipped unless opts.OnDuplicate is DuplicateCreate.
+func (s *Service) ImportLocations(ctx context.Context, userID string, locations []domain.Location, opts ImportOptions) ([]ImportOutcome, error) {
+	if len(locations) > MaxImportRows {
+		return nil, ErrImportTooLarge
+	}
+	existing := make(map[string]string)
+	err := s.ExportLocations(ctx, userID, Filter{}, func(loc domain.Location) error {
+		existing[duplicateKey(loc)] = loc.Id
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	outcomes := make([]ImportOutcome, len(locations))
+	accepted := 0
+	for i, loc := range locations {
+		loc.UserID = userID
+		if err := s.checkNotes(loc); err != nil {
+			outcomes[i] = ImportOutcome{Status: ImportRejected, Location: loc, Err: err}
+			continue
+		}
+		key := duplicateKey(loc)
+		if id, seen := existing[key]; seen && opts.OnDuplicate != DuplicateCreate {
+			outcomes[i] = ImportOutcome{Status: ImportDuplicate, Location: loc, DuplicateOf: id}
+			continue
+		}
+		existing[key] = ""
+		outcomes[i] = ImportOutcome{Status: ImportAccepted, Location: loc}
+		accepted++
+	}
+	// The import is all or nothing: a row that fails to save undoes the
+	// rows before it, so a retry cannot create them twice. History is only
+	// written once the import has committed.
+	txCtx, flush := s.deferHistory(ctx)
+	err = s.repo.WithinTx(txCtx, userID, func(ctx context.Context) error {
+		// An import that would not fit the quota is refused as a whole
+		// rather than cut off part way.
+		if err := s.checkLocationLimit(ctx, userID, accepted); err != nil {
+			return err
+		}
+		if opts.DryRun {
+			return nil
+		}
+		for i := range outcomes {
+			if outcomes[i].Status != ImportAccepted {
+				continue
+			}
+			// Imports apply their own, exact duplicate policy.
+			loc, err := s.CreateLocation(ctx, outcomes[i].Location, true)
+			if err != nil {
+				return fmt.Errorf("importing row %d: %w", i+1, err)
+			}
+			outcomes[i].Location = loc
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	flush()
+	return outcomes, nil
+}
+
+// duplicateKey identifies a place by its city, ignoring case and spacing,
+// and its coordinates rounded to about a metre.
+func duplicateKey(loc domain.Location) string {
+	return fmt.Sprintf("%s|%.5f|%.5f", normalizeCity(loc.City), loc.Coordinates.Lat, loc.Coordinates.Lon)
+}
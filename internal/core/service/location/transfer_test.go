@@ -0,0 +1,135 @@
+package location
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/lafetz/weavo/internal/core/domain"
+)
+
+func TestExportLocationsReadsInBatches(t *testing.T) {
+	repo := newMockRepo()
+	seedLocations(repo, 2*exportBatch+3)
+	svc := NewService(repo)
+
+	seen := make(map[string]bool)
+	err := svc.ExportLocations(context.Background(), "user1", Filter{}, func(loc domain.Location) error {
+		if seen[loc.Id] {
+			t.Fatalf("location %s exported twice", loc.Id)
+		}
+		seen[loc.Id] = true
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if len(seen) != 2*exportBatch+3 {
+		t.Fatalf("expected %d locations, got %d", 2*exportBatch+3, len(seen))
+	}
+}
+
+func TestImportLocations(t *testing.T) {
+	ctx := context.Background()
+	existing := domain.Location{Id: "a", UserID: "user1", City: "Paris", Coordinates: domain.Coordinates{Lat: 48.8566, Lon: 2.3522}}
+	rows := []domain.Location{
+		{City: " paris ", Coordinates: domain.Coordinates{Lat: 48.856600001, Lon: 2.3522}},
+		{City: "Lyon", Coordinates: domain.Coordinates{Lat: 45.764, Lon: 4.8357}},
+		{City: "LYON", Coordinates: domain.Coordinates{Lat: 45.764, Lon: 4.8357}},
+	}
+
+	t.Run("skips duplicates", func(t *testing.T) {
+		repo := newMockRepo()
+		repo.locations["a"] = existing
+		svc := NewService(repo)
+
+		outcomes, err := svc.ImportLocations(ctx, "user1", rows, ImportOptions{OnDuplicate: DuplicateSkip})
+		if err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+		want := []ImportStatus{ImportDuplicate, ImportAccepted, ImportDuplicate}
+		for i, o := range outcomes {
+			if o.Status != want[i] {
+				t.Errorf("row %d: expected %s, got %s", i+1, want[i], o.Status)
+			}
+		}
+		if outcomes[0].DuplicateOf != "a" {
+			t.Errorf("expected row 1 to duplicate a, got %q", outcomes[0].DuplicateOf)
+		}
+		if len(repo.locations) != 2 {
+			t.Errorf("expected 1 location to be created, got %d", len(repo.locations)-1)
+		}
+	})
+
+	t.Run("creates duplicates when asked", func(t *testing.T) {
+		repo := newMockRepo()
+		repo.locations["a"] = existing
+		svc := NewService(repo)
+
+		if _, err := svc.ImportLocations(ctx, "user1", rows, ImportOptions{OnDuplicate: DuplicateCreate}); err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+		if len(repo.locations) != 4 {
+			t.Errorf("expected 3 locations to be created, got %d", len(repo.locations)-1)
+		}
+	})
+
+	t.Run("dry run saves nothing", func(t *testing.T) {
+		repo := newMockRepo()
+		svc := NewService(repo)
+
+		outcomes, err := svc.ImportLocations(ctx, "user1", rows, ImportOptions{DryRun: true})
+		if err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+		if len(repo.locations) != 0 {
+			t.Errorf("expected nothing to be saved, got %d locations", len(repo.locations))
+		}
+		if outcomes[2].Status != ImportDuplicate {
+			t.Errorf("expected a dry run to report duplicates within the import, got %s", outcomes[2].Status)
+		}
+	})
+
+	t.Run("a failed row undoes the import", func(t *testing.T) {
+		repo := &failingCreateRepo{mockRepo: newMockRepo(), failAt: 2}
+		history := &mockHistory{entries: make(map[string][]domain.HistoryEntry)}
+		svc := NewService(repo, WithHistory(history))
+
+		_, err := svc.ImportLocations(ctx, "user1", rows, ImportOptions{OnDuplicate: DuplicateCreate})
+		if !errors.Is(err, errCreateFailed) {
+			t.Fatalf("expected error %v, got %v", errCreateFailed, err)
+		}
+		if len(repo.locations) != 0 {
+			t.Errorf("expected the rows before the failure to be undone, got %d locations", len(repo.locations))
+		}
+		if len(history.entries) != 0 {
+			t.Errorf("expected no history for an undone import, got %d entries", len(history.entries))
+		}
+	})
+
+	t.Run("too many rows", func(t *testing.T) {
+		svc := NewService(newMockRepo())
+		_, err := svc.ImportLocations(ctx, "user1", make([]domain.Location, MaxImportRows+1), ImportOptions{})
+		if !errors.Is(err, ErrImportTooLarge) {
+			t.Fatalf("expected error %v, got %v", ErrImportTooLarge, err)
+		}
+	})
+}
+
+var errCreateFailed = errors.New("create failed")
+
+// failingCreateRepo fails the failAt-th call to CreateLocation, counting
+// from 1.
+type failingCreateRepo struct {
+	*mockRepo
+	failAt int
+	calls  int
+}
+
+func (r *failingCreateRepo) CreateLocation(ctx context.Context, loc domain.Location) (domain.Location, error) {
+	r.calls++
+	if r.calls == r.failAt {
+		return domain.Location{}, errCreateFailed
+	}
+	return r.mockRepo.CreateLocation(ctx, loc)
+}
@@ -0,0 +1,25 @@
+package user
+
+import (
+	"context"
+
+	"github.com/lafetz/weavo/internal/core/domain"
+)
+
+type UserRepo interface {
+	// CreateUser returns ErrEmailTaken if a user with the same email
+	// exists.
+	CreateUser(ctx context.Context, user domain.User) (domain.User, error)
+	// GetUser and GetUserByEmail return ErrUserNotFound if there is no
+	// such user.
+	GetUser(ctx context.Context, id string) (domain.User, error)
+	GetUserByEmail(ctx context.Context, email string) (domain.User, error)
+}
+
+type ServiceApi interface {
+	Register(ctx context.Context, email, password string) (domain.User, error)
+	Login(ctx context.Context, email, password string) (domain.User, error)
+	// IsRegistered reports whether userID belongs to an account rather than
+	// an anonymous session.
+	IsRegistered(userID string) bool
+}
@@ -0,0 +1,204 @@
+package user
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/lafetz/weavo/internal/core/domain"
+)
+
+var (
+	ErrUserNotFound       = errors.New("user not found")
+	ErrEmailTaken         = errors.New("email is already registered")
+	ErrInvalidCredentials = errors.New("invalid email or password")
+)
+
+const (
+	// defaultMaxFailedLogins is how many wrong passwords in a row lock an
+	// account unless WithLockout says otherwise.
+	defaultMaxFailedLogins = 5
+	// defaultLockout is how long a locked account stays locked unless
+	// WithLockout says otherwise.
+	defaultLockout = 15 * time.Minute
+	// maxTrackedEmails bounds the failure counts kept in memory; beyond it
+	// counts that have gone stale are dropped.
+	maxTrackedEmails = 100_000
+)
+
+// LockedError is returned by Login while an account is locked after too
+// many failed attempts.
+type LockedError struct {
+	Until time.Time
+}
+
+func (e *LockedError) Error() string {
+	return "too many failed login attempts"
+}
+
+type Service struct {
+	repo       UserRepo
+	hashParams HashParams
+	now        func() time.Time
+	// dummyHash is checked against when the email is unknown, so that a
+	// login takes as long whether or not the account exists.
+	dummyHash string
+
+	maxFailed int
+	lockout   time.Duration
+	mu        sync.Mutex
+	failures  map[string]*failedLogins // email -> recent failures
+}
+
+type failedLogins struct {
+	count       int
+	last        time.Time
+	lockedUntil time.Time
+}
+
+// stale reports whether f no longer counts towards a lockout: failures
+// are forgotten a lockout period after the last one.
+func (f *failedLogins) stale(now time.Time, lockout time.Duration) bool {
+	return f.lockedUntil.IsZero() && now.Sub(f.last) >= lockout ||
+		!f.lockedUntil.IsZero() && !now.Before(f.lockedUntil)
+}
+
+type Option func(*Service)
+
+// WithHashParams sets the argon2id cost of new password hashes.
+func WithHashParams(p HashParams) Option {
+	return func(s *Service) {
+		s.hashParams = p
+	}
+}
+
+// WithLockout locks an email for d after maxFailed wrong passwords in a
+// row.
+func WithLockout(maxFailed int, d time.Duration) Option {
+	return func(s *Service) {
+		s.maxFailed = maxFailed
+		s.lockout = d
+	}
+}
+
+// WithClock sets the source of the current time, for tests.
+func WithClock(now func() time.Time) Option {
+	return func(s *Service) {
+		s.now = now
+	}
+}
+
+func NewService(repo UserRepo, opts ...Option) *Service {
+	s := &Service{
+		repo:       repo,
+		hashParams: DefaultHashParams,
+		now:        time.Now,
+		maxFailed:  defaultMaxFailedLogins,
+		lockout:    defaultLockout,
+		failures:   make(map[string]*failedLogins),
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+	s.dummyHash = hashPassword(uuid.New().String(), s.hashParams)
+	return s
+}
+
+func normalizeEmail(email string) string {
+	return strings.ToLower(strings.TrimSpace(email))
+}
+
+// Register creates an account with a new user ID. It fails with
+// ErrEmailTaken if the email is already registered.
+func (s *Service) Register(ctx context.Context, email, password string) (domain.User, error) {
+	return s.repo.CreateUser(ctx, domain.User{
+		Id:           uuid.New().String(),
+		Email:        normalizeEmail(email),
+		PasswordHash: hashPassword(password, s.hashParams),
+		CreatedAt:    s.now(),
+	})
+}
+
+// Login returns the user with the given email and password. Wrong
+// passwords and unknown emails both fail with ErrInvalidCredentials; after
+// too many failures in a row the email is locked and Login fails with a
+// *LockedError until the lockout has passed, even with the right password.
+func (s *Service) Login(ctx context.Context, email, password string) (domain.User, error) {
+	email = normalizeEmail(email)
+	if until, locked := s.lockedUntil(email); locked {
+		return domain.User{}, &LockedError{Until: until}
+	}
+
+	u, err := s.repo.GetUserByEmail(ctx, email)
+	hash := u.PasswordHash
+	switch {
+	case errors.Is(err, ErrUserNotFound):
+		hash = s.dummyHash
+	case err != nil:
+		return domain.User{}, err
+	}
+	ok, err := checkPassword(password, hash)
+	if err != nil {
+		return domain.User{}, fmt.Errorf("checking password of %s: %w", u.Id, err)
+	}
+	if !ok || u.Id == "" {
+		s.recordFailure(email)
+		return domain.User{}, ErrInvalidCredentials
+	}
+	s.clearFailures(email)
+	return u, nil
+}
+
+// IsRegistered reports whether userID belongs to an account rather than an
+// anonymous session.
+func (s *Service) IsRegistered(userID string) bool {
+	_, err := s.repo.GetUser(context.Background(), userID)
+	return err == nil
+}
+
+func (s *Service) lockedUntil(email string) (time.Time, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	f, exists := s.failures[email]
+	if !exists {
+		return time.Time{}, false
+	}
+	if f.stale(s.now(), s.lockout) {
+		delete(s.failures, email)
+		return time.Time{}, false
+	}
+	return f.lockedUntil, !f.lockedUntil.IsZero()
+}
+
+func (s *Service) recordFailure(email string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	now := s.now()
+	f, exists := s.failures[email]
+	if !exists {
+		if len(s.failures) >= maxTrackedEmails {
+			for other, f := range s.failures {
+				if f.stale(now, s.lockout) {
+					delete(s.failures, other)
+				}
+			}
+		}
+		f = &failedLogins{}
+		s.failures[email] = f
+	}
+	f.count++
+	f.last = now
+	if f.count >= s.maxFailed {
+		f.lockedUntil = now.Add(s.lockout)
+	}
+}
+
+func (s *Service) clearFailures(email string) {
+	s.mu.Lock()
+	delete(s.failures, email)
+	s.mu.Unlock()
+}
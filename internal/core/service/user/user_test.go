@@ -0,0 +1,148 @@
+package user
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/lafetz/weavo/internal/core/domain"
+)
+
+// testHashParams keep hashing cheap in tests.
+var testHashParams = HashParams{Memory: 64, Time: 1, Threads: 1}
+
+type mockRepo struct {
+	users map[string]domain.User
+}
+
+func newMockRepo() *mockRepo {
+	return &mockRepo{users: make(map[string]domain.User)}
+}
+
+func (m *mockRepo) CreateUser(ctx context.Context, u domain.User) (domain.User, error) {
+	if _, err := m.GetUserByEmail(ctx, u.Email); err == nil {
+		return domain.User{}, ErrEmailTaken
+	}
+	m.users[u.Id] = u
+	return u, nil
+}
+
+func (m *mockRepo) GetUser(ctx context.Context, id string) (domain.User, error) {
+	u, exists := m.users[id]
+	if !exists {
+		return domain.User{}, ErrUserNotFound
+	}
+	return u, nil
+}
+
+func (m *mockRepo) GetUserByEmail(ctx context.Context, email string) (domain.User, error) {
+	for _, u := range m.users {
+		if u.Email == email {
+			return u, nil
+		}
+	}
+	return domain.User{}, ErrUserNotFound
+}
+
+type testClock struct {
+	now time.Time
+}
+
+func (c *testClock) Now() time.Time {
+	return c.now
+}
+
+func TestRegisterAndLogin(t *testing.T) {
+	ctx := context.Background()
+	svc := NewService(newMockRepo(), WithHashParams(testHashParams))
+
+	u, err := svc.Register(ctx, " Ada@Example.com", "correct horse")
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if u.Email != "ada@example.com" || !strings.HasPrefix(u.PasswordHash, "$argon2id$") || strings.Contains(u.PasswordHash, "correct horse") {
+		t.Errorf("unexpected user %+v", u)
+	}
+	if !svc.IsRegistered(u.Id) || svc.IsRegistered("anonymous") {
+		t.Error("expected only the new account to be registered")
+	}
+	if _, err := svc.Register(ctx, "ADA@example.com", "another one"); !errors.Is(err, ErrEmailTaken) {
+		t.Errorf("expected ErrEmailTaken, got %v", err)
+	}
+
+	got, err := svc.Login(ctx, "ada@EXAMPLE.com", "correct horse")
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if got.Id != u.Id {
+		t.Errorf("expected user %s, got %s", u.Id, got.Id)
+	}
+	for _, creds := range [][2]string{{"ada@example.com", "wrong"}, {"nobody@example.com", "correct horse"}} {
+		if _, err := svc.Login(ctx, creds[0], creds[1]); !errors.Is(err, ErrInvalidCredentials) {
+			t.Errorf("expected ErrInvalidCredentials for %v, got %v", creds, err)
+		}
+	}
+}
+
+func TestLockout(t *testing.T) {
+	ctx := context.Background()
+	clock := &testClock{now: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)}
+	svc := NewService(newMockRepo(), WithHashParams(testHashParams), WithLockout(3, time.Minute), WithClock(clock.Now))
+	svc.Register(ctx, "ada@example.com", "correct horse")
+
+	for range 3 {
+		if _, err := svc.Login(ctx, "ada@example.com", "wrong"); !errors.Is(err, ErrInvalidCredentials) {
+			t.Fatalf("expected ErrInvalidCredentials, got %v", err)
+		}
+	}
+	var locked *LockedError
+	if _, err := svc.Login(ctx, "ada@example.com", "correct horse"); !errors.As(err, &locked) {
+		t.Fatalf("expected a LockedError even with the right password, got %v", err)
+	}
+	if want := clock.now.Add(time.Minute); !locked.Until.Equal(want) {
+		t.Errorf("expected the lockout to end at %v, got %v", want, locked.Until)
+	}
+
+	clock.now = clock.now.Add(time.Minute)
+	if _, err := svc.Login(ctx, "ada@example.com", "correct horse"); err != nil {
+		t.Fatalf("expected the lockout to be over, got %v", err)
+	}
+
+	// A success resets the count, and so does waiting a lockout period.
+	svc.Login(ctx, "ada@example.com", "wrong")
+	svc.Login(ctx, "ada@example.com", "wrong")
+	clock.now = clock.now.Add(time.Minute)
+	svc.Login(ctx, "ada@example.com", "wrong")
+	if _, err := svc.Login(ctx, "ada@example.com", "correct horse"); err != nil {
+		t.Errorf("expected stale failures to be forgotten, got %v", err)
+	}
+
+	// Unknown emails are locked too, so that lockouts do not reveal which
+	// accounts exist.
+	for range 3 {
+		svc.Login(ctx, "nobody@example.com", "guess")
+	}
+	if _, err := svc.Login(ctx, "nobody@example.com", "guess"); !errors.As(err, &locked) {
+		t.Errorf("expected a LockedError for an unknown email, got %v", err)
+	}
+}
+
+func TestCheckPassword(t *testing.T) {
+	hash := hashPassword("secret", testHashParams)
+	if ok, err := checkPassword("secret", hash); !ok || err != nil {
+		t.Errorf("expected the password to match, got %v, %v", ok, err)
+	}
+	if ok, _ := checkPassword("Secret", hash); ok {
+		t.Error("expected a different password not to match")
+	}
+	if hashPassword("secret", testHashParams) == hash {
+		t.Error("expected every hash to use a new salt")
+	}
+	for _, malformed := range []string{"", "plain", "$argon2i$v=19$m=64,t=1,p=1$c2FsdA$aGFzaA", "$argon2id$v=19$m=64,t=1,p=1$!!$aGFzaA"} {
+		if _, err := checkPassword("secret", malformed); err == nil {
+			t.Errorf("expected an error for %q", malformed)
+		}
+	}
+}